@@ -0,0 +1,107 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/cloud-gov/billing/internal/focus"
+	"github.com/cloud-gov/billing/internal/usage/report"
+)
+
+// focusMeta is the information FOCUS rows need that is not in the report tree.
+type focusMeta struct {
+	CustomerID pgtype.UUID
+	// CustomerName may be empty if the customer was selected by ID.
+	CustomerName string
+	// PeriodStart and PeriodEnd bound the readings included in the report. The report sums usage over the whole range, so they are the charge period of every row.
+	PeriodStart time.Time
+	PeriodEnd   time.Time
+	// USDPerCredit converts credits to US dollars for the cost columns.
+	USDPerCredit float64
+}
+
+// periodBounds returns the range of readings GetUsageByPath includes: from the start of the period `after` periods from now until the start of the period `before` periods from now, in loc.
+func periodBounds(now time.Time, period string, loc *time.Location, after, before int) (start, end time.Time, err error) {
+	start, err = report.PeriodStart(now, period, loc, after)
+	if err != nil {
+		return start, end, err
+	}
+	end, err = report.PeriodStart(now, period, loc, before)
+	return start, end, err
+}
+
+// focusServices describes the kinds of report nodes in FOCUS.
+var focusServices = map[string]focus.Service{
+	report.CfApp.String(): {Name: "Cloud Foundry Applications", Category: "Compute", ResourceType: "Application"},
+	report.CfSvc.String(): {Name: "Cloud Foundry Services", Category: "Other", ResourceType: "Service Instance"},
+	report.Space.String(): {Name: "Cloud Foundry", Category: "Compute", ResourceType: "Space"},
+}
+
+// writeFOCUS writes a FOCUS row for each leaf of the tree, so rows can be summed without counting usage twice. The report has no quantities or prices, so only costs are given.
+func writeFOCUS(out io.Writer, tree *report.Entry, meta focusMeta) error {
+	w := focus.NewCSVWriter(out)
+	err := report.Walk(tree, 0, func(e *report.Entry, depth int) error {
+		if depth == 0 || len(e.Children) > 0 {
+			return nil
+		}
+		svc, ok := focusServices[e.Kind]
+		if !ok {
+			svc = focus.Service{Name: "Cloud Foundry", Category: "Other", ResourceType: e.Kind}
+		}
+		var orgID, orgName string
+		tags := map[string]string{}
+		if len(e.Ancestors) > 0 {
+			orgID = e.Ancestors[0]
+			orgName = strings.TrimPrefix(orgID, "cforg_")
+		}
+		if len(e.Ancestors) > 1 {
+			tags["cf_space"] = e.Ancestors[len(e.Ancestors)-1]
+		}
+		tagJSON, err := json.Marshal(tags)
+		if err != nil {
+			return err
+		}
+		credits := float64(e.UCredits) / 1e6
+		usd := credits * meta.USDPerCredit
+		return w.Write(focus.Row{
+			BilledCost:                    usd,
+			BillingAccountId:              meta.CustomerID.String(),
+			BillingAccountName:            meta.CustomerName,
+			BillingCurrency:               "USD",
+			BillingPeriodEnd:              meta.PeriodEnd,
+			BillingPeriodStart:            meta.PeriodStart,
+			ChargeCategory:                "Usage",
+			ChargeDescription:             fmt.Sprintf("%s usage of %s", svc.Name, e.Name),
+			ChargeFrequency:               "Usage-Based",
+			ChargePeriodEnd:               meta.PeriodEnd,
+			ChargePeriodStart:             meta.PeriodStart,
+			ContractedCost:                usd,
+			EffectiveCost:                 usd,
+			InvoiceIssuerName:             focus.Provider,
+			ListCost:                      usd,
+			PricingCurrency:               "Credits",
+			PricingCurrencyContractedCost: credits,
+			PricingCurrencyEffectiveCost:  credits,
+			PricingCurrencyListCost:       credits,
+			ProviderName:                  focus.Provider,
+			PublisherName:                 focus.Provider,
+			ResourceId:                    strings.Join(append(append([]string{}, e.Ancestors...), e.Name), "."),
+			ResourceName:                  e.Name,
+			ResourceType:                  svc.ResourceType,
+			ServiceCategory:               svc.Category,
+			ServiceName:                   svc.Name,
+			SubAccountId:                  orgID,
+			SubAccountName:                orgName,
+			Tags:                          string(tagJSON),
+		})
+	})
+	if err != nil {
+		return err
+	}
+	return w.Close()
+}
@@ -9,42 +9,32 @@ import (
 	"io"
 	"log/slog"
 	"os"
-	"regexp"
 	"slices"
 	"strings"
+	"time"
 
 	"github.com/cloud-gov/billing/internal/config"
 	"github.com/cloud-gov/billing/internal/db"
 	"github.com/cloud-gov/billing/internal/dbx"
+	"github.com/cloud-gov/billing/internal/usage/report"
 	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 var (
 	ErrGetCustomer    = errors.New("getting customer")
+	ErrBadTimeZone    = errors.New("loading time zone")
 	ErrBadConfig      = errors.New("reading config from environment")
 	ErrDBConn         = errors.New("connecting to database")
 	ErrGettingNodes   = errors.New("getting nodes")
 	ErrCreatingReport = errors.New("making report")
+	ErrWritingReport  = errors.New("writing report")
 )
 
 func fmtErr(outer, inner error) error {
 	return fmt.Errorf("%w: %w", outer, inner)
 }
 
-var (
-	appReg = regexp.MustCompile(`^app_[^\.]+$`)
-	svcReg = regexp.MustCompile(`^svc_[^\.]+$`)
-)
-
-func isApp(s pgtype.Text) bool {
-	return appReg.MatchString(s.String)
-}
-
-func isService(s pgtype.Text) bool {
-	return svcReg.MatchString(s.String)
-}
-
 var (
 	cid    string
 	cname  string
@@ -54,40 +44,61 @@ var (
 	after  int
 	before int
 	period string
+	since  string
+	until  string
+	tz     string
+	format string
+	file   string
+	basic  bool
+	usd    float64
 )
 
 func init() {
 	flag.StringVar(&cid, "cid", "", "Narrow scope to Customer by ID, falls back to $CG_USAGE_CUSTOMER_ID if neither -cid or -cname defined")
 	flag.StringVar(&cname, "cname", "", "Narrow results to Customer by name")
-	flag.StringVar(&lquery, "lq", "", "Provide an `lquery` to search with; supercedes org & space")
+	flag.StringVar(&lquery, "lq", "", "Provide an `lquery` relative to apps.usage to search with, e.g. .cforg_name%.*; supercedes org & space")
 	flag.StringVar(&org, "org", "", "Filter by org name")
 	flag.StringVar(&space, "space", "", "Filter by space same")
 	flag.IntVar(&after, "a", -1, "Filter [a]fter n-periods")
 	flag.IntVar(&before, "b", 0, "Filter [b]efore n-periods")
-	flag.StringVar(&period, "p", "month", "Time [p]eriod/interval, e.g. month, week, day")
-	flag.Parse()
+	flag.StringVar(&period, "p", "month", "Time [p]eriod/interval: day, week, month, quarter, year, or fiscal_year (federal, beginning October 1)")
+	flag.StringVar(&since, "since", "", "Include usage from this RFC 3339 `time` or date, e.g. 2025-10-01; supercedes -a")
+	flag.StringVar(&until, "until", "", "Include usage before this RFC 3339 `time` or date, e.g. 2026-10-01; supercedes -b")
+	flag.StringVar(&tz, "tz", "", "IANA time `zone` in which periods and dates begin, e.g. America/Chicago; defaults to the customer's time zone")
+	flag.StringVar(&format, "o", FormatTable, "[O]utput format: table, json, csv, or focus (FinOps Open Cost and Usage Specification)")
+	flag.StringVar(&file, "f", "", "Write output to [f]ile instead of stdout")
+	flag.BoolVar(&basic, "basic", false, "Summarize usage by org and space only")
+	flag.Float64Var(&usd, "usd", 50, "US dollars per credit, for total_cost and the cost columns of focus output")
 }
 
 // func getMeasures(ctx context.Context, q db.Querier, nodes []db.ResourceNode) ([]db.Measurement, error) {
 // }
 
 func main() {
+	flag.Parse()
 	ctx := context.Background()
-	out := os.Stdout
-	err := run(ctx, out)
+	err := run(ctx, os.Stdout, os.Stderr)
 	if err != nil {
 		slog.Error(err.Error())
 		os.Exit(1)
 	}
 }
 
-func run(ctx context.Context, out io.Writer) error {
+// run writes the report to out, or to the file given by -f, and logs to logOut.
+func run(ctx context.Context, out, logOut io.Writer) error {
+	if !validFormat(format) {
+		return ErrBadFormat
+	}
+	if !slices.Contains(report.Periods, period) {
+		return report.ErrBadPeriod
+	}
 	c, err := config.New()
 	if err != nil {
 		return fmtErr(ErrBadConfig, err)
 	}
 
-	logger := slog.New(slog.NewJSONHandler(out, &slog.HandlerOptions{
+	// Logs are kept out of out, so the report can be piped to other tools.
+	logger := slog.New(slog.NewJSONHandler(logOut, &slog.HandlerOptions{
 		Level: c.LogLevel,
 	}))
 
@@ -104,137 +115,104 @@ func run(ctx context.Context, out io.Writer) error {
 		return err
 	}
 
-	nodeQuery := buildQuery()
+	nodeQuery, err := report.Path(org, space, lquery)
+	if err != nil {
+		return err
+	}
+
+	loc, err := getLocation(ctx, q, customerID)
+	if err != nil {
+		return err
+	}
+	params, start, end, err := queryParams(time.Now(), loc)
+	if err != nil {
+		return err
+	}
+	params.Path = nodeQuery
+	params.CustomerID = customerID
+	params.UsdPerCredit = usd
+	meta := focusMeta{CustomerID: customerID, CustomerName: cname, PeriodStart: start, PeriodEnd: end, USDPerCredit: usd}
 
-	logger.Debug("run: getting usage", "customerID", customerID, "query", nodeQuery)
-	nodes, err := getNodes(ctx, q, nodeQuery, customerID)
+	logger.Debug("run: getting usage", "customerID", customerID, "query", nodeQuery, "start", start, "end", end)
+	nodes, err := q.GetUsageByPath(ctx, params)
 	if err != nil {
 		return fmtErr(ErrGettingNodes, err)
 	}
 	logger.Debug("run: got usage", "usage", nodes)
 
 	logger.Debug("run: making report")
-	slices.Reverse(nodes)
-	report := NewReporter()
-	var link ReportLinker
-	for i, n := range nodes {
-		uCreds, err := n.TotalMicrocredits.Int64Value()
-		if err != nil {
-			return fmtErr(ErrCreatingReport, err)
-		}
-		uCredsInt := int(uCreds.Int64)
-
-		if i == 0 {
-			report.UCreditSum = uCredsInt
-			continue
-		}
-
-		p := nodes[i-1]
-
-		// org
-		if n.L1.Valid && !n.L2.Valid {
-			// org is always linked to root
-			link, err = report.SetNode(report, uCredsInt, Org, n.L1.String, "")
-			if err != nil {
-				return fmtErr(ErrCreatingReport, err)
-			}
-			continue
-		}
+	root, skipped, err := report.Build(nodes, basic)
+	if err != nil {
+		return fmtErr(ErrCreatingReport, err)
+	}
+	for _, n := range skipped {
+		logger.Debug("weirds gotten in report", "node", n)
+	}
+	tree := report.Flatten(root)
+	logger.Debug("run: got report", "report", tree)
 
-		// space generalized
-		if n.L2.Valid && !n.L3.Valid {
-			if p.L4.Valid { // go back from leaf > space/s > space/g > org
-				link = link.getParent().getParent().getParent()
-			} else if p.L3.Valid { // go back from space/s > space/g > org
-				link = link.getParent().getParent()
-			} else if p.L2.Valid { // go space/g > org
-				link = link.getParent()
-			}
-			link, err = report.SetNode(link, uCredsInt, Space, n.L2.String, "")
-			if err != nil {
-				return fmtErr(ErrCreatingReport, err)
-			}
-			continue
+	if file == "" {
+		if err := writeReport(out, format, tree, meta); err != nil {
+			return fmtErr(ErrWritingReport, err)
 		}
+		return nil
+	}
+	f, err := os.Create(file)
+	if err != nil {
+		return fmtErr(ErrWritingReport, err)
+	}
+	if err := writeReport(f, format, tree, meta); err != nil {
+		f.Close()
+		return fmtErr(ErrWritingReport, err)
+	}
+	if err := f.Close(); err != nil {
+		return fmtErr(ErrWritingReport, err)
+	}
+	return nil
+}
 
-		// space specific
-		if n.L3.Valid && !n.L4.Valid {
-			if p.L4.Valid { // go back from leaf > space/s > space/g
-				link = link.getParent().getParent()
-			} else if p.L3.Valid { // go space/s > space/g
-				link = link.getParent()
-			}
-			link, err = report.SetNode(link, uCredsInt, Space, n.L3.String, "")
-			if err != nil {
-				return fmtErr(ErrCreatingReport, err)
-			}
-			continue
+// queryParams returns the GetUsageByPath parameters for the period flags, and the range of readings they select. Periods and dates begin in loc. -since and -until supersede -a and -b.
+func queryParams(now time.Time, loc *time.Location) (params db.GetUsageByPathParams, start, end time.Time, err error) {
+	start, end, err = periodBounds(now, period, loc, after, before)
+	if err != nil {
+		return params, start, end, err
+	}
+	params = db.GetUsageByPathParams{
+		Period:   period,
+		TimeZone: pgtype.Text{String: loc.String(), Valid: true},
+		Before:   int32(before),
+		After:    int32(after),
+	}
+	if since != "" {
+		if start, err = report.ParseTime(since, loc); err != nil {
+			return params, start, end, err
 		}
-
-		if n.L4.Valid {
-			if p.L4.Valid { // go from leaf > space/s
-				link = link.getParent()
-			}
-
-			var k Kind
-			if isApp(n.L4) {
-				k = CfApp
-			} else if isService(n.L4) {
-				k = CfSvc
-			}
-
-			link, err = report.SetNode(link, uCredsInt, k, n.L4.String, "")
-			if err != nil {
-				return fmtErr(ErrCreatingReport, err)
-			}
-			continue
+		params.Since = pgtype.Timestamptz{Time: start, Valid: true}
+	}
+	if until != "" {
+		if end, err = report.ParseTime(until, loc); err != nil {
+			return params, start, end, err
 		}
-
-		logger.Debug("weirds gotten in report", "node", n)
-
+		params.Until = pgtype.Timestamptz{Time: end, Valid: true}
 	}
-	logger.Debug("run: got report", "report", report)
-
-	return err
+	return params, start, end, nil
 }
 
-func getNodes(ctx context.Context, q db.Querier, query string, customerID pgtype.UUID) ([]db.GetUsageByPathRow, error) {
-	return q.GetUsageByPath(ctx, db.GetUsageByPathParams{
-		Path:       query,
-		CustomerID: customerID,
-		Before:     int32(before),
-		After:      int32(after),
-		Period:     period,
-	})
-}
-
-func buildQuery() string {
-	nodeQuery := strings.Builder{}
-	nodeQuery.WriteString("apps.usage")
-
-	if lquery != "" {
-		nodeQuery.WriteString(lquery)
-		return nodeQuery.String()
-	}
-
-	// L1
-	if org != "" {
-		fmt.Fprintf(&nodeQuery, ".cforg_%v%%", org)
-	} else {
-		nodeQuery.WriteString(".cforg_%")
+// getLocation returns the time zone given by -tz, or the customer's time zone.
+func getLocation(ctx context.Context, q db.Querier, customerID pgtype.UUID) (*time.Location, error) {
+	name := tz
+	if name == "" {
+		c, err := q.GetCustomer(ctx, customerID)
+		if err != nil {
+			return nil, fmtErr(ErrGetCustomer, err)
+		}
+		name = c.TimeZone
 	}
-
-	// L2/3
-	if space != "" {
-		fmt.Fprintf(&nodeQuery, ".space_%v%%", space)
-	} else {
-		nodeQuery.WriteString(".space_%")
+	loc, err := report.LoadLocation(name)
+	if err != nil {
+		return nil, fmtErr(ErrBadTimeZone, err)
 	}
-
-	// Resources Leaves
-	nodeQuery.WriteString(".*{1,}")
-
-	return nodeQuery.String()
+	return loc, nil
 }
 
 func getCustomerID(ctx context.Context, q dbx.Querier) (id pgtype.UUID, err error) {
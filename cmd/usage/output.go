@@ -0,0 +1,106 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/cloud-gov/billing/internal/usage/report"
+)
+
+// Output formats for the -o flag.
+const (
+	FormatTable = "table"
+	FormatJSON  = "json"
+	FormatCSV   = "csv"
+	FormatFOCUS = "focus"
+)
+
+var ErrBadFormat = errors.New("output format must be one of table, json, csv, focus")
+
+func validFormat(f string) bool {
+	switch f {
+	case FormatTable, FormatJSON, FormatCSV, FormatFOCUS:
+		return true
+	}
+	return false
+}
+
+// formatCredits formats microcredits as credits without losing precision.
+func formatCredits(uCredits int) string {
+	sign := ""
+	if uCredits < 0 {
+		sign, uCredits = "-", -uCredits
+	}
+	return fmt.Sprintf("%s%d.%06d", sign, uCredits/1e6, uCredits%1e6)
+}
+
+// writeReport writes the report tree to out in the given format.
+func writeReport(out io.Writer, format string, tree *report.Entry, meta focusMeta) error {
+	switch format {
+	case FormatTable:
+		return writeTable(out, tree)
+	case FormatJSON:
+		enc := json.NewEncoder(out)
+		enc.SetIndent("", "  ")
+		return enc.Encode(tree)
+	case FormatCSV:
+		return writeCSV(out, tree)
+	case FormatFOCUS:
+		return writeFOCUS(out, tree, meta)
+	}
+	return ErrBadFormat
+}
+
+// writeTable writes the tree as an indented table for people to read.
+func writeTable(out io.Writer, tree *report.Entry) error {
+	tw := tabwriter.NewWriter(out, 0, 4, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "NAME\tKIND\tCREDITS\t")
+	err := report.Walk(tree, 0, func(e *report.Entry, depth int) error {
+		name := e.Name
+		if depth == 0 {
+			name = "Total"
+		}
+		_, err := fmt.Fprintf(tw, "%s%s\t%s\t%s\t\n", strings.Repeat("  ", max(depth-1, 0)), name, e.Kind, formatCredits(e.UCredits))
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	return tw.Flush()
+}
+
+// csvHeader names the columns written by writeCSV.
+var csvHeader = []string{"depth", "kind", "path", "name", "leaf", "microcredits", "credits"}
+
+// writeCSV writes one row for each node of the tree below the root. Subtotals are included, so only rows where leaf is true should be summed.
+func writeCSV(out io.Writer, tree *report.Entry) error {
+	cw := csv.NewWriter(out)
+	if err := cw.Write(csvHeader); err != nil {
+		return err
+	}
+	err := report.Walk(tree, 0, func(e *report.Entry, depth int) error {
+		if depth == 0 {
+			return nil
+		}
+		return cw.Write([]string{
+			strconv.Itoa(depth),
+			e.Kind,
+			strings.Join(append(append([]string{}, e.Ancestors...), e.Name), "/"),
+			e.Name,
+			strconv.FormatBool(len(e.Children) == 0),
+			strconv.Itoa(e.UCredits),
+			formatCredits(e.UCredits),
+		})
+	})
+	if err != nil {
+		return err
+	}
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cloud-gov/billing/internal/dbx"
+	"github.com/cloud-gov/billing/internal/usage/report"
+)
+
+// testReport returns the tree of a report with one org, one space, and an app and a service in the space.
+func testReport(t *testing.T) *report.Entry {
+	t.Helper()
+	r := report.NewReporter()
+	r.UCreditSum = 3_000_000
+	org, err := r.SetNode(r, 3_000_000, report.Org, "cforg_agency", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	spaceG, err := r.SetNode(org, 3_000_000, report.Space, "space_web", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	spaceS, err := r.SetNode(spaceG, 3_000_000, report.Space, "space_web_prod", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.SetNode(spaceS, 2_000_000, report.CfApp, "app_frontend", ""); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.SetNode(spaceS, 1_000_000, report.CfSvc, "svc_db", ""); err != nil {
+		t.Fatal(err)
+	}
+	return report.Flatten(r)
+}
+
+func TestWriteReportJSON(t *testing.T) {
+	buf := &bytes.Buffer{}
+	if err := writeReport(buf, FormatJSON, testReport(t), focusMeta{}); err != nil {
+		t.Fatal(err)
+	}
+	var tree report.Entry
+	if err := json.Unmarshal(buf.Bytes(), &tree); err != nil {
+		t.Fatal(err)
+	}
+	if tree.UCredits != 3_000_000 || len(tree.Children) != 1 {
+		t.Fatalf("unexpected tree %+v", tree)
+	}
+	leaves := tree.Children[0].Children[0].Children[0].Children
+	if len(leaves) != 2 || leaves[0].Name != "app_frontend" || leaves[0].Kind != report.CfApp.String() {
+		t.Errorf("unexpected leaves %+v", leaves)
+	}
+}
+
+func TestWriteReportCSV(t *testing.T) {
+	buf := &bytes.Buffer{}
+	if err := writeReport(buf, FormatCSV, testReport(t), focusMeta{}); err != nil {
+		t.Fatal(err)
+	}
+	records, err := csv.NewReader(buf).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Header, org, two spaces, and two leaves.
+	if len(records) != 6 {
+		t.Fatalf("expected 6 records, got %d", len(records))
+	}
+	want := []string{"4", report.CfSvc.String(), "cforg_agency/space_web/space_web_prod/svc_db", "svc_db", "true", "1000000", "1.000000"}
+	if strings.Join(records[5], ",") != strings.Join(want, ",") {
+		t.Errorf("want %v, got %v", want, records[5])
+	}
+}
+
+func TestWriteReportFOCUS(t *testing.T) {
+	meta := focusMeta{
+		CustomerID:   dbx.UtilUUID("11111111-1111-1111-1111-111111111111"),
+		CustomerName: "Agency",
+		PeriodStart:  time.Date(2026, time.September, 1, 0, 0, 0, 0, time.UTC),
+		PeriodEnd:    time.Date(2026, time.October, 1, 0, 0, 0, 0, time.UTC),
+		USDPerCredit: 50,
+	}
+	buf := &bytes.Buffer{}
+	if err := writeReport(buf, FormatFOCUS, testReport(t), meta); err != nil {
+		t.Fatal(err)
+	}
+	records, err := csv.NewReader(buf).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Only leaves are written, so rows can be summed.
+	if len(records) != 3 {
+		t.Fatalf("expected a header and 2 rows, got %d records", len(records))
+	}
+	row := map[string]string{}
+	for i, h := range records[0] {
+		row[h] = records[1][i]
+	}
+	want := map[string]string{
+		"BilledCost":                   "100",
+		"BillingAccountId":             "11111111-1111-1111-1111-111111111111",
+		"BillingPeriodStart":           "2026-09-01T00:00:00Z",
+		"ChargePeriodEnd":              "2026-10-01T00:00:00Z",
+		"PricingCurrencyEffectiveCost": "2",
+		"ResourceId":                   "cforg_agency.space_web.space_web_prod.app_frontend",
+		"ServiceCategory":              "Compute",
+		"SubAccountName":               "agency",
+		"Tags":                         `{"cf_space":"space_web_prod"}`,
+	}
+	for k, v := range want {
+		if row[k] != v {
+			t.Errorf("%v: want %q, got %q", k, v, row[k])
+		}
+	}
+}
+
+func TestWriteReportTableBasic(t *testing.T) {
+	r := report.NewBasicReporter().(*report.BasicReport)
+	r.UCreditsUtilized = 1_500_000
+	org, err := r.SetNode(r, 1_500_000, report.Org, "cforg_agency", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.SetNode(org, 1_500_000, report.Space, "space_web", ""); err != nil {
+		t.Fatal(err)
+	}
+	buf := &bytes.Buffer{}
+	if err := writeReport(buf, FormatTable, report.Flatten(r), focusMeta{}); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 4 || !strings.Contains(lines[3], "space_web") || !strings.HasSuffix(strings.TrimSpace(lines[3]), "1.500000") {
+		t.Errorf("unexpected table:\n%s", buf.String())
+	}
+}
+
+func TestPeriodBounds(t *testing.T) {
+	now := time.Date(2026, time.October, 18, 15, 0, 0, 0, time.UTC) // A Sunday.
+	cases := []struct {
+		period        string
+		after, before int
+		start, end    time.Time
+	}{
+		{"month", -1, 0, time.Date(2026, time.September, 1, 0, 0, 0, 0, time.UTC), time.Date(2026, time.October, 1, 0, 0, 0, 0, time.UTC)},
+		{"day", -2, 1, time.Date(2026, time.October, 16, 0, 0, 0, 0, time.UTC), time.Date(2026, time.October, 19, 0, 0, 0, 0, time.UTC)},
+		{"week", -1, 0, time.Date(2026, time.October, 5, 0, 0, 0, 0, time.UTC), time.Date(2026, time.October, 12, 0, 0, 0, 0, time.UTC)},
+		{"quarter", -1, 0, time.Date(2026, time.July, 1, 0, 0, 0, 0, time.UTC), time.Date(2026, time.October, 1, 0, 0, 0, 0, time.UTC)},
+		{"year", 0, 1, time.Date(2026, time.January, 1, 0, 0, 0, 0, time.UTC), time.Date(2027, time.January, 1, 0, 0, 0, 0, time.UTC)},
+		{"fiscal_year", -1, 0, time.Date(2025, time.October, 1, 0, 0, 0, 0, time.UTC), time.Date(2026, time.October, 1, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, tc := range cases {
+		start, end, err := periodBounds(now, tc.period, time.UTC, tc.after, tc.before)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !start.Equal(tc.start) || !end.Equal(tc.end) {
+			t.Errorf("%v: want %v to %v, got %v to %v", tc.period, tc.start, tc.end, start, end)
+		}
+	}
+	if _, _, err := periodBounds(now, "decade", time.UTC, -1, 0); err != report.ErrBadPeriod {
+		t.Errorf("expected ErrBadPeriod, got %v", err)
+	}
+}
@@ -0,0 +1,195 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/riverqueue/river"
+
+	"github.com/cloud-gov/billing/internal/db"
+	"github.com/cloud-gov/billing/internal/dbx"
+	"github.com/cloud-gov/billing/internal/jobs"
+)
+
+var ErrInvalidThreshold = errors.New("thresholds must be integers from 1 to 100")
+
+// alert is the JSON representation of a balance alert.
+type alert struct {
+	ID                      int32       `json:"id"`
+	CustomerID              pgtype.UUID `json:"customer_id"`
+	CustomerName            string      `json:"customer_name"`
+	Kind                    string      `json:"kind"`
+	PercentConsumed         int32       `json:"percent_consumed"`
+	FundedMicrocredits      int64       `json:"funded_microcredits"`
+	RemainingMicrocredits   int64       `json:"remaining_microcredits"`
+	DailyBurnMicrocredits   int64       `json:"daily_burn_microcredits"`
+	ProjectedExhaustionDate *string     `json:"projected_exhaustion_date"`
+	FundedUntil             *string     `json:"funded_until"`
+	CreatedAt               time.Time   `json:"created_at"`
+	NotifiedAt              *time.Time  `json:"notified_at"`
+}
+
+func newAlert(a db.Alert, customerName string) alert {
+	v := alert{
+		ID:                    a.ID,
+		CustomerID:            a.CustomerID,
+		CustomerName:          customerName,
+		Kind:                  string(a.Kind),
+		PercentConsumed:       a.PercentConsumed,
+		FundedMicrocredits:    a.FundedMicrocredits,
+		RemainingMicrocredits: a.RemainingMicrocredits,
+		DailyBurnMicrocredits: a.DailyBurnMicrocredits,
+		CreatedAt:             a.CreatedAt.Time,
+	}
+	if a.ProjectedExhaustionDate.Valid {
+		s := a.ProjectedExhaustionDate.Time.Format(time.DateOnly)
+		v.ProjectedExhaustionDate = &s
+	}
+	if a.FundedUntil.Valid {
+		s := a.FundedUntil.Time.Format(time.DateOnly)
+		v.FundedUntil = &s
+	}
+	if a.NotifiedAt.Valid {
+		v.NotifiedAt = &a.NotifiedAt.Time
+	}
+	return v
+}
+
+// alertThresholds is the JSON representation of the thresholds that apply to a customer.
+type alertThresholds struct {
+	PercentsConsumed []int32 `json:"percents_consumed"`
+	// Default is true if the customer has no thresholds of their own and [dbx.DefaultAlertThresholds] apply.
+	Default bool `json:"default"`
+}
+
+// handleListAlerts lists the most recent alerts, optionally filtered by the customer_id query parameter. Pages are selected with the limit and offset query parameters.
+func handleListAlerts(logger *slog.Logger, q dbx.Querier) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var customerID pgtype.UUID
+		if s := r.URL.Query().Get("customer_id"); s != "" {
+			var err error
+			if customerID, err = parseUUID(s); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+		limit, offset, err := pageParams(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		rows, err := q.ListAlerts(r.Context(), db.ListAlertsParams{
+			CustomerID: customerID,
+			PageSize:   limit,
+			PageOffset: offset,
+		})
+		if err != nil {
+			logger.ErrorContext(r.Context(), "api: listing alerts", "err", err)
+			http.Error(w, "listing alerts: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		out := make([]alert, len(rows))
+		for i, row := range rows {
+			out[i] = newAlert(row.Alert, row.CustomerName)
+		}
+		writeJSON(w, http.StatusOK, out)
+	}
+}
+
+// handleCreateAlertJob enqueues a job that records alerts for customers whose credits are running low and delivers them.
+func handleCreateAlertJob(riverc *river.Client[pgx.Tx]) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		result, err := riverc.Insert(r.Context(), jobs.CheckBalancesArgs{
+			AsOf: pgtype.Timestamptz{Time: time.Now().UTC(), Valid: true},
+		}, nil)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Failed to insert River job: %v\n", err), http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, http.StatusAccepted, jobResponse{
+			JobID:                    result.Job.ID,
+			UniqueSkippedAsDuplicate: result.UniqueSkippedAsDuplicate,
+		})
+	}
+}
+
+// handleGetAlertThresholds responds with the alert thresholds that apply to the customer.
+func handleGetAlertThresholds(logger *slog.Logger, q dbx.Querier) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		customerID, err := parseUUID(chi.URLParam(r, "id"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		rows, err := q.ListAlertThresholds(r.Context(), customerID)
+		if err != nil {
+			logger.ErrorContext(r.Context(), "api: listing alert thresholds", "err", err)
+			http.Error(w, "listing alert thresholds: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		out := alertThresholds{PercentsConsumed: make([]int32, len(rows))}
+		for i, row := range rows {
+			out.PercentsConsumed[i] = row.PercentConsumed
+		}
+		if len(rows) == 0 {
+			out.PercentsConsumed = dbx.DefaultAlertThresholds
+			out.Default = true
+		}
+		writeJSON(w, http.StatusOK, out)
+	}
+}
+
+// handleSetAlertThresholds replaces the customer's alert thresholds. An empty list reverts the customer to the defaults.
+func handleSetAlertThresholds(logger *slog.Logger, conn dbx.Beginner, q dbx.Querier) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		customerID, err := parseUUID(chi.URLParam(r, "id"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		var req alertThresholds
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "decoding request: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		for _, p := range req.PercentsConsumed {
+			if p < 1 || p > 100 {
+				http.Error(w, ErrInvalidThreshold.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+
+		tx, err := conn.Begin(ctx)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		defer tx.Rollback(ctx)
+
+		err = dbx.SetAlertThresholds(ctx, q.WithTx(tx), customerID, req.PercentsConsumed)
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23503" { // foreign_key_violation
+			http.Error(w, dbx.ErrCustomerNotFound.Error(), http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			logger.ErrorContext(ctx, "api: setting alert thresholds", "err", err)
+			http.Error(w, "setting alert thresholds: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if err := tx.Commit(ctx); err != nil {
+			http.Error(w, "setting alert thresholds: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		handleGetAlertThresholds(logger, q)(w, r)
+	}
+}
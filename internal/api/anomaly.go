@@ -0,0 +1,116 @@
+package api
+
+import (
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/cloud-gov/billing/internal/db"
+	"github.com/cloud-gov/billing/internal/dbx"
+)
+
+// anomaly is the JSON representation of a measurement the meter returned errors for, or that could not be attributed to a CF org.
+type anomaly struct {
+	ReadingID         int32       `json:"reading_id"`
+	Meter             string      `json:"meter"`
+	ResourceNaturalID string      `json:"resource_natural_id"`
+	KindNaturalID     string      `json:"kind_natural_id"`
+	CFOrgID           pgtype.UUID `json:"cf_org_id"`
+	CustomerID        pgtype.UUID `json:"customer_id"`
+	Value             int32       `json:"value"`
+	Errors            []string    `json:"errors"`
+	Recorded          bool        `json:"recorded"`
+}
+
+// anomalySummary is the JSON representation of the anomalies of a meter in a reading.
+type anomalySummary struct {
+	ReadingID        int32     `json:"reading_id"`
+	ReadingCreatedAt time.Time `json:"reading_created_at"`
+	Meter            string    `json:"meter"`
+	Anomalies        int64     `json:"anomalies"`
+	Value            int64     `json:"value"`
+	// Orphaned counts anomalies whose usage could not be attributed to a CF org, and so was not billed.
+	Orphaned      int64 `json:"orphaned"`
+	OrphanedValue int64 `json:"orphaned_value"`
+}
+
+// handleListAnomalies lists measurement anomalies, most recent reading first, optionally filtered by the reading_id and meter query parameters. Pages are selected with the limit and offset query parameters.
+func handleListAnomalies(logger *slog.Logger, q dbx.Querier) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		params := db.ListMeasurementAnomaliesParams{}
+		if s := r.URL.Query().Get("reading_id"); s != "" {
+			id, err := strconv.ParseInt(s, 10, 32)
+			if err != nil {
+				http.Error(w, "reading_id must be an integer", http.StatusBadRequest)
+				return
+			}
+			params.ReadingID = pgtype.Int4{Int32: int32(id), Valid: true}
+		}
+		if s := r.URL.Query().Get("meter"); s != "" {
+			params.Meter = pgtype.Text{String: s, Valid: true}
+		}
+		var err error
+		params.PageSize, params.PageOffset, err = pageParams(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		rows, err := q.ListMeasurementAnomalies(r.Context(), params)
+		if err != nil {
+			logger.ErrorContext(r.Context(), "api: listing anomalies", "err", err)
+			http.Error(w, "listing anomalies: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		out := make([]anomaly, len(rows))
+		for i, row := range rows {
+			out[i] = anomaly{
+				ReadingID:         row.ReadingID,
+				Meter:             row.Meter,
+				ResourceNaturalID: row.ResourceNaturalID,
+				KindNaturalID:     row.KindNaturalID,
+				CFOrgID:           row.CFOrgID,
+				CustomerID:        row.CustomerID,
+				Value:             row.Value,
+				Errors:            row.Errors,
+				Recorded:          row.Recorded,
+			}
+		}
+		writeJSON(w, http.StatusOK, out)
+	}
+}
+
+// handleSummarizeAnomalies counts the anomalies of each meter in each reading taken between the since and until query parameters, including how much usage could not be attributed to a CF org.
+func handleSummarizeAnomalies(logger *slog.Logger, q dbx.Querier) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		since, until, err := timeRange(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		rows, err := q.SummarizeMeasurementAnomalies(r.Context(), db.SummarizeMeasurementAnomaliesParams{
+			Since: pgtype.Timestamptz{Time: since, Valid: true},
+			Until: pgtype.Timestamptz{Time: until, Valid: true},
+		})
+		if err != nil {
+			logger.ErrorContext(r.Context(), "api: summarizing anomalies", "err", err)
+			http.Error(w, "summarizing anomalies: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		out := make([]anomalySummary, len(rows))
+		for i, row := range rows {
+			out[i] = anomalySummary{
+				ReadingID:        row.ReadingID,
+				ReadingCreatedAt: row.ReadingCreatedAt.Time.UTC(),
+				Meter:            row.Meter,
+				Anomalies:        row.Anomalies,
+				Value:            row.Value,
+				Orphaned:         row.Orphaned,
+				OrphanedValue:    row.OrphanedValue,
+			}
+		}
+		writeJSON(w, http.StatusOK, out)
+	}
+}
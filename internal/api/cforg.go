@@ -0,0 +1,167 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/riverqueue/river"
+
+	"github.com/cloud-gov/billing/internal/db"
+	"github.com/cloud-gov/billing/internal/dbx"
+	"github.com/cloud-gov/billing/internal/jobs"
+)
+
+var ErrInvalidCFOrg = errors.New("CF org ID must be a UUID")
+
+// cfOrg is the JSON representation of a Cloud Foundry organization.
+type cfOrg struct {
+	ID          pgtype.UUID     `json:"id"`
+	Name        *string         `json:"name"`
+	CustomerID  pgtype.UUID     `json:"customer_id"`
+	Suspended   bool            `json:"suspended"`
+	Labels      json.RawMessage `json:"labels"`
+	Annotations json.RawMessage `json:"annotations"`
+	SyncedAt    *time.Time      `json:"synced_at"`
+}
+
+func newCFOrg(o db.CFOrg) cfOrg {
+	v := cfOrg{
+		ID:          o.ID,
+		CustomerID:  o.CustomerID,
+		Suspended:   o.Suspended,
+		Labels:      o.Labels,
+		Annotations: o.Annotations,
+	}
+	if o.Name.Valid {
+		v.Name = &o.Name.String
+	}
+	if o.SyncedAt.Valid {
+		v.SyncedAt = &o.SyncedAt.Time
+	}
+	return v
+}
+
+// orphanCFOrg is a CF org with measurements that is not assigned to a customer.
+type orphanCFOrg struct {
+	cfOrg
+	MeasurementCount int64     `json:"measurement_count"`
+	LastMeasuredAt   time.Time `json:"last_measured_at"`
+}
+
+func cfOrgIDParam(r *http.Request) (pgtype.UUID, error) {
+	id, err := parseUUID(chi.URLParam(r, "id"))
+	if err != nil {
+		return pgtype.UUID{}, ErrInvalidCFOrg
+	}
+	return id, nil
+}
+
+// handleListCFOrgs lists all CF orgs, or only orphan orgs if the orphan query parameter is true. Orphan orgs have measurements but are not assigned to a customer, so their usage cannot be billed.
+func handleListCFOrgs(logger *slog.Logger, q dbx.Querier) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("orphan") == "true" {
+			rows, err := q.ListOrphanCFOrgs(r.Context())
+			if err != nil {
+				logger.ErrorContext(r.Context(), "api: listing orphan CF orgs", "err", err)
+				http.Error(w, "listing orphan CF orgs: "+err.Error(), http.StatusInternalServerError)
+				return
+			}
+			out := make([]orphanCFOrg, len(rows))
+			for i, row := range rows {
+				out[i] = orphanCFOrg{
+					cfOrg:            newCFOrg(row.CFOrg),
+					MeasurementCount: row.MeasurementCount,
+					LastMeasuredAt:   row.LastMeasuredAt.Time,
+				}
+			}
+			writeJSON(w, http.StatusOK, out)
+			return
+		}
+
+		rows, err := q.ListCFOrgs(r.Context())
+		if err != nil {
+			logger.ErrorContext(r.Context(), "api: listing CF orgs", "err", err)
+			http.Error(w, "listing CF orgs: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		out := make([]cfOrg, len(rows))
+		for i, row := range rows {
+			out[i] = newCFOrg(row)
+		}
+		writeJSON(w, http.StatusOK, out)
+	}
+}
+
+// handleAssignCFOrg assigns a CF org to the customer in the request body. The org is recorded if the billing service has not seen it yet. Usage that has already been posted is not moved to the new customer.
+func handleAssignCFOrg(logger *slog.Logger, q dbx.Querier) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := cfOrgIDParam(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		var req struct {
+			CustomerID string `json:"customer_id"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "decoding request: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		customerID, err := parseUUID(req.CustomerID)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		setCFOrgCustomer(w, r, logger, q, db.SetCFOrgCustomerParams{ID: id, CustomerID: customerID})
+	}
+}
+
+// handleUnassignCFOrg removes a CF org from its customer. Its usage will not be billed until it is assigned again.
+func handleUnassignCFOrg(logger *slog.Logger, q dbx.Querier) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := cfOrgIDParam(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		setCFOrgCustomer(w, r, logger, q, db.SetCFOrgCustomerParams{ID: id})
+	}
+}
+
+func setCFOrgCustomer(w http.ResponseWriter, r *http.Request, logger *slog.Logger, q dbx.Querier, params db.SetCFOrgCustomerParams) {
+	o, err := q.SetCFOrgCustomer(r.Context(), params)
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23503" { // foreign_key_violation
+		http.Error(w, dbx.ErrCustomerNotFound.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		logger.ErrorContext(r.Context(), "api: setting CF org customer", "err", err)
+		http.Error(w, "setting CF org customer: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, newCFOrg(o))
+}
+
+// handleCreateCFOrgSyncJob enqueues a job that copies the names and metadata of all CF orgs into the database.
+func handleCreateCFOrgSyncJob(riverc *river.Client[pgx.Tx]) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		result, err := riverc.Insert(r.Context(), jobs.SyncCFOrgsArgs{}, nil)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Failed to insert River job: %v\n", err), http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, http.StatusAccepted, jobResponse{
+			JobID:                    result.Job.ID,
+			UniqueSkippedAsDuplicate: result.UniqueSkippedAsDuplicate,
+		})
+	}
+}
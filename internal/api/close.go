@@ -0,0 +1,93 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/riverqueue/river"
+
+	"github.com/cloud-gov/billing/internal/dbx"
+	"github.com/cloud-gov/billing/internal/jobs"
+)
+
+var ErrInvalidMonth = errors.New("month must be formatted as YYYY-MM")
+
+// monthAsOf parses a month formatted as YYYY-MM and returns a time in the following month. Database functions like bounds_month_prev and post_usage operate on the month preceding their as_of argument.
+func monthAsOf(month string) (pgtype.Timestamptz, error) {
+	t, err := time.Parse("2006-01", month)
+	if err != nil {
+		return pgtype.Timestamptz{}, ErrInvalidMonth
+	}
+	// The middle of the next month is unambiguous in any time zone the database might use for month boundaries.
+	return pgtype.Timestamptz{
+		Time:  time.Date(t.Year(), t.Month()+1, 15, 12, 0, 0, 0, time.UTC),
+		Valid: true,
+	}, nil
+}
+
+// handlePreviewCloseMonth responds with the usage that would be posted if the month were closed now. Nothing is written to the ledger.
+func handlePreviewCloseMonth(logger *slog.Logger, conn dbx.Beginner, q dbx.Querier) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		asOf, err := monthAsOf(chi.URLParam(r, "month"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		mc, err := dbx.PreviewCloseMonth(r.Context(), conn, q, asOf)
+		if err != nil {
+			logger.ErrorContext(r.Context(), "api: previewing month close", "err", err)
+			http.Error(w, "previewing month close: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, http.StatusOK, mc)
+	}
+}
+
+// handleCloseMonth enqueues a job that posts usage for the month. Review the result of [handlePreviewCloseMonth] first.
+func handleCloseMonth(riverc *river.Client[pgx.Tx]) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		asOf, err := monthAsOf(chi.URLParam(r, "month"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		result, err := riverc.Insert(r.Context(), jobs.PostUsageArgs{AsOf: asOf}, nil)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Failed to insert River job: %v\n", err), http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, http.StatusAccepted, jobResponse{
+			JobID:                    result.Job.ID,
+			UniqueSkippedAsDuplicate: result.UniqueSkippedAsDuplicate,
+		})
+	}
+}
+
+// handleReverseMonth reverses all usage posted for the month by posting offsetting transactions. The month can then be closed again.
+func handleReverseMonth(logger *slog.Logger, q dbx.Querier) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		asOf, err := monthAsOf(chi.URLParam(r, "month"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		reversals, err := dbx.ReverseMonth(r.Context(), q, asOf)
+		if err != nil {
+			logger.ErrorContext(r.Context(), "api: reversing month", "err", err)
+			http.Error(w, "reversing month: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, http.StatusOK, reversals)
+	}
+}
+
+type jobResponse struct {
+	JobID                    int64 `json:"job_id"`
+	UniqueSkippedAsDuplicate bool  `json:"unique_skipped_as_duplicate"`
+}
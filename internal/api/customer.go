@@ -0,0 +1,306 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/cloud-gov/billing/internal/db"
+	"github.com/cloud-gov/billing/internal/dbx"
+	"github.com/cloud-gov/billing/internal/usage/report"
+)
+
+const (
+	defaultPageSize = 100
+	maxPageSize     = 1000
+)
+
+var (
+	ErrInvalidPage         = errors.New("limit must be an integer from 1 to 1000 and offset must be a non-negative integer")
+	ErrInvalidCustomerName = errors.New("customer name must not be empty")
+	ErrInvalidTimeZone     = errors.New("time_zone must be an IANA time zone name, like America/Chicago")
+	ErrEmptyCustomerUpdate = errors.New("request must change the customer's name or time_zone")
+)
+
+// customer is the JSON representation of a customer. CFOrgs is only included when a single customer is requested.
+type customer struct {
+	ID       pgtype.UUID `json:"id"`
+	Name     string      `json:"name"`
+	TierID   *int32      `json:"tier_id"`
+	TimeZone string      `json:"time_zone"`
+	CFOrgs   []cfOrg     `json:"cf_orgs,omitempty"`
+}
+
+func newCustomer(c db.Customer) customer {
+	v := customer{ID: c.ID, Name: c.Name, TimeZone: c.TimeZone}
+	if c.TierID.Valid {
+		v.TierID = &c.TierID.Int32
+	}
+	return v
+}
+
+// customerRequest is the body of requests to create or update customers. TimeZone is the IANA time zone in which the customer's usage is reported; it defaults to business time.
+type customerRequest struct {
+	Name     string `json:"name"`
+	TierID   *int32 `json:"tier_id"`
+	TimeZone string `json:"time_zone"`
+}
+
+// timeZone returns the requested time zone, or an invalid value if none was requested.
+func (req customerRequest) timeZone() (pgtype.Text, error) {
+	if req.TimeZone == "" {
+		return pgtype.Text{}, nil
+	}
+	if _, err := report.LoadLocation(req.TimeZone); err != nil {
+		return pgtype.Text{}, ErrInvalidTimeZone
+	}
+	return pgtype.Text{String: req.TimeZone, Valid: true}, nil
+}
+
+// pageParams parses the limit and offset query parameters.
+func pageParams(r *http.Request) (limit, offset int32, err error) {
+	limit, offset = defaultPageSize, 0
+	if s := r.URL.Query().Get("limit"); s != "" {
+		v, err := strconv.ParseInt(s, 10, 32)
+		if err != nil || v < 1 || v > maxPageSize {
+			return 0, 0, ErrInvalidPage
+		}
+		limit = int32(v)
+	}
+	if s := r.URL.Query().Get("offset"); s != "" {
+		v, err := strconv.ParseInt(s, 10, 32)
+		if err != nil || v < 0 {
+			return 0, 0, ErrInvalidPage
+		}
+		offset = int32(v)
+	}
+	return limit, offset, nil
+}
+
+// optionalDateParam parses the named query parameter as a date. It returns an invalid date if the parameter is absent.
+func optionalDateParam(r *http.Request, name string) (pgtype.Date, error) {
+	s := r.URL.Query().Get(name)
+	if s == "" {
+		return pgtype.Date{}, nil
+	}
+	return parseDate(s)
+}
+
+// handleGetCustomerBalance responds with the current balance of each of the customer's accounts.
+func handleGetCustomerBalance(logger *slog.Logger, q dbx.Querier) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		customerID, err := parseUUID(chi.URLParam(r, "id"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		b, err := dbx.GetCustomerBalance(r.Context(), q, customerID)
+		if errors.Is(err, dbx.ErrCustomerNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			logger.ErrorContext(r.Context(), "api: getting customer balance", "err", err)
+			http.Error(w, "getting customer balance: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, http.StatusOK, b)
+	}
+}
+
+// handleGetCustomerStatement responds with a page of the customer's transactions and running balances. The optional start and end query parameters are dates in business time; start is inclusive and end is exclusive. Pages are selected with the limit and offset query parameters.
+func handleGetCustomerStatement(logger *slog.Logger, q dbx.Querier) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		customerID, err := parseUUID(chi.URLParam(r, "id"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		start, err := optionalDateParam(r, "start")
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		end, err := optionalDateParam(r, "end")
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		limit, offset, err := pageParams(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		s, err := dbx.GetCustomerStatement(r.Context(), q, customerID, start, end, limit, offset)
+		if errors.Is(err, dbx.ErrCustomerNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			logger.ErrorContext(r.Context(), "api: getting customer statement", "err", err)
+			http.Error(w, "getting customer statement: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, http.StatusOK, s)
+	}
+}
+
+func handleListCustomers(logger *slog.Logger, q dbx.Querier) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		rows, err := q.ListCustomers(r.Context())
+		if err != nil {
+			logger.ErrorContext(r.Context(), "api: listing customers", "err", err)
+			http.Error(w, "listing customers: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		out := make([]customer, len(rows))
+		for i, row := range rows {
+			out[i] = newCustomer(row)
+		}
+		writeJSON(w, http.StatusOK, out)
+	}
+}
+
+// handleCreateCustomer creates a customer and their accounts, and optionally assigns them to a tier and sets their time zone.
+func handleCreateCustomer(logger *slog.Logger, conn dbx.Beginner, q dbx.Querier) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		var req customerRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "decoding request: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		if req.Name == "" {
+			http.Error(w, ErrInvalidCustomerName.Error(), http.StatusBadRequest)
+			return
+		}
+		tz, err := req.timeZone()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		tx, err := conn.Begin(ctx)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		defer tx.Rollback(ctx)
+		txq := q.WithTx(tx)
+
+		id, err := txq.CreateCustomer(ctx, req.Name)
+		if err != nil {
+			logger.ErrorContext(ctx, "api: creating customer", "err", err)
+			http.Error(w, "creating customer: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		params := db.SetCustomerTierParams{ID: id}
+		if req.TierID != nil {
+			params.TierID = pgtype.Int4{Int32: *req.TierID, Valid: true}
+		}
+		created, err := txq.SetCustomerTier(ctx, params)
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23503" { // foreign_key_violation
+			http.Error(w, ErrTierNotFound.Error(), http.StatusBadRequest)
+			return
+		}
+		if err != nil {
+			logger.ErrorContext(ctx, "api: creating customer", "err", err)
+			http.Error(w, "creating customer: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if tz.Valid {
+			created, err = txq.UpdateCustomer(ctx, db.UpdateCustomerParams{ID: id, TimeZone: tz})
+			if err != nil {
+				logger.ErrorContext(ctx, "api: creating customer", "err", err)
+				http.Error(w, "creating customer: "+err.Error(), http.StatusInternalServerError)
+				return
+			}
+		}
+		if err := tx.Commit(ctx); err != nil {
+			http.Error(w, "creating customer: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, http.StatusCreated, newCustomer(created))
+	}
+}
+
+// handleGetCustomer responds with the customer and the CF orgs assigned to them.
+func handleGetCustomer(logger *slog.Logger, q dbx.Querier) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		customerID, err := parseUUID(chi.URLParam(r, "id"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		c, err := q.GetCustomer(r.Context(), customerID)
+		if errors.Is(err, pgx.ErrNoRows) {
+			http.Error(w, dbx.ErrCustomerNotFound.Error(), http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			logger.ErrorContext(r.Context(), "api: getting customer", "err", err)
+			http.Error(w, "getting customer: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		orgs, err := q.ListCFOrgsByCustomer(r.Context(), customerID)
+		if err != nil {
+			logger.ErrorContext(r.Context(), "api: listing customer orgs", "err", err)
+			http.Error(w, "listing customer orgs: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		out := newCustomer(c)
+		out.CFOrgs = make([]cfOrg, len(orgs))
+		for i, o := range orgs {
+			out.CFOrgs[i] = newCFOrg(o)
+		}
+		writeJSON(w, http.StatusOK, out)
+	}
+}
+
+// handleUpdateCustomer renames a customer or changes their time zone. Fields omitted from the request are left unchanged. Use handleSetCustomerTier to change their tier.
+func handleUpdateCustomer(logger *slog.Logger, q dbx.Querier) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		customerID, err := parseUUID(chi.URLParam(r, "id"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		var req customerRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "decoding request: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		tz, err := req.timeZone()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if req.Name == "" && !tz.Valid {
+			http.Error(w, ErrEmptyCustomerUpdate.Error(), http.StatusBadRequest)
+			return
+		}
+		params := db.UpdateCustomerParams{ID: customerID, TimeZone: tz}
+		if req.Name != "" {
+			params.Name = pgtype.Text{String: req.Name, Valid: true}
+		}
+		updated, err := q.UpdateCustomer(r.Context(), params)
+		if errors.Is(err, pgx.ErrNoRows) {
+			http.Error(w, dbx.ErrCustomerNotFound.Error(), http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			logger.ErrorContext(r.Context(), "api: updating customer", "err", err)
+			http.Error(w, "updating customer: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, http.StatusOK, newCustomer(updated))
+	}
+}
@@ -0,0 +1,128 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5"
+
+	"github.com/cloud-gov/billing/internal/db"
+	"github.com/cloud-gov/billing/internal/dbx"
+	"github.com/cloud-gov/billing/internal/focus"
+)
+
+// maxFOCUSRange is the longest range that can be exported at once.
+const maxFOCUSRange = 366 * 24 * time.Hour
+
+var (
+	ErrFOCUSRangeTooLong = errors.New("cost exports may cover at most 366 days")
+	ErrCustomerRequired  = errors.New("customer_id is required because you have a role in organizations belonging to more than one customer")
+)
+
+// focusParams parses the since, until, granularity, and format query parameters of a cost export. Granularity defaults to day and format to csv.
+func focusParams(r *http.Request, usdPerCredit float64) (focus.ExportParams, string, error) {
+	since, until, err := timeRange(r)
+	if err != nil {
+		return focus.ExportParams{}, "", err
+	}
+	if until.Sub(since) > maxFOCUSRange {
+		return focus.ExportParams{}, "", ErrFOCUSRangeTooLong
+	}
+	params := focus.ExportParams{
+		Since:        since,
+		Until:        until,
+		Granularity:  focus.GranularityDay,
+		USDPerCredit: usdPerCredit,
+	}
+	query := r.URL.Query()
+	if s := query.Get("granularity"); s != "" {
+		if s != focus.GranularityHour && s != focus.GranularityDay {
+			return params, "", focus.ErrBadGranularity
+		}
+		params.Granularity = s
+	}
+	format := focus.FormatCSV
+	if s := query.Get("format"); s != "" {
+		if s != focus.FormatCSV && s != focus.FormatParquet {
+			return params, "", focus.ErrBadFormat
+		}
+		format = s
+	}
+	return params, format, nil
+}
+
+// writeFOCUS streams the customer's costs to w as a FOCUS dataset. The response is committed once the first rows are written, so later errors can only be logged; the dataset is left incomplete, which clients detect as a truncated CSV or an unreadable Parquet file.
+func writeFOCUS(w http.ResponseWriter, r *http.Request, logger *slog.Logger, q db.Querier, c db.Customer, params focus.ExportParams, format string) {
+	ctx := r.Context()
+	params.CustomerID = c.ID
+	fw, err := focus.NewWriter(w, format)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", focus.ContentType(format))
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="focus-%s-%s-%s.%s"`,
+		c.ID.String(), params.Since.Format("20060102T15"), params.Until.Format("20060102T15"), format))
+	if err := focus.Export(ctx, q, fw, params); err != nil {
+		logger.ErrorContext(ctx, "api: exporting costs", "err", err)
+		return
+	}
+	if err := fw.Close(); err != nil {
+		logger.ErrorContext(ctx, "api: exporting costs", "err", err)
+	}
+}
+
+// handleExportCustomerCosts streams a customer's costs as a FOCUS dataset, with a row for the usage of each resource in each hour or day.
+func handleExportCustomerCosts(logger *slog.Logger, q dbx.Querier, usdPerCredit float64) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		customerID, err := parseUUID(chi.URLParam(r, "id"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		params, format, err := focusParams(r, usdPerCredit)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		c, err := q.GetCustomer(r.Context(), customerID)
+		if errors.Is(err, pgx.ErrNoRows) {
+			http.Error(w, dbx.ErrCustomerNotFound.Error(), http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			logger.ErrorContext(r.Context(), "api: getting customer", "err", err)
+			http.Error(w, "getting customer: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		writeFOCUS(w, r, logger, q, c, params, format)
+	}
+}
+
+// handleExportCosts streams the costs of a customer that owns a Cloud Foundry organization in which the caller has a role as a FOCUS dataset. Only costs in the organizations in which the caller has a role are included. Datasets cover one customer, so the customer_id query parameter is required if the caller may see more than one.
+func handleExportCosts(logger *slog.Logger, orgs OrgResolver, q dbx.Querier, usdPerCredit float64) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		params, format, err := focusParams(r, usdPerCredit)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		customers, orgIDs, ok := callerCustomers(w, r, logger, orgs, q)
+		if !ok {
+			return
+		}
+		params.CFOrgIDs = orgIDs
+		switch len(customers) {
+		case 0:
+			http.Error(w, ErrCustomerDenied.Error(), http.StatusForbidden)
+		case 1:
+			writeFOCUS(w, r, logger, q, customers[0], params, format)
+		default:
+			http.Error(w, ErrCustomerRequired.Error(), http.StatusBadRequest)
+		}
+	}
+}
@@ -0,0 +1,232 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/cloud-gov/billing/internal/db"
+	"github.com/cloud-gov/billing/internal/dbx"
+)
+
+var (
+	ErrInvalidIAAID = errors.New("agreement ID must be an integer")
+	ErrInvalidIAA   = errors.New("agreement amount must be positive and pop_start must not be after pop_end")
+)
+
+// iaa is the JSON representation of an interagency agreement.
+type iaa struct {
+	ID                     int32       `json:"id"`
+	CustomerID             pgtype.UUID `json:"customer_id"`
+	Number                 string      `json:"number"`
+	AmountMicrocredits     int64       `json:"amount_microcredits"`
+	RolledOverMicrocredits int64       `json:"rolled_over_microcredits"`
+	PopStart               string      `json:"pop_start"`
+	PopEnd                 string      `json:"pop_end"`
+	Rollover               bool        `json:"rollover"`
+	StartTransactionID     *int32      `json:"start_transaction_id"`
+	EndTransactionID       *int32      `json:"end_transaction_id"`
+	EndedAt                *time.Time  `json:"ended_at"`
+}
+
+func newIAA(i db.IAA) iaa {
+	v := iaa{
+		ID:                     i.ID,
+		CustomerID:             i.CustomerID,
+		Number:                 i.Number,
+		AmountMicrocredits:     i.AmountMicrocredits,
+		RolledOverMicrocredits: i.RolledOverMicrocredits,
+		PopStart:               i.PopStart.Time.Format(time.DateOnly),
+		PopEnd:                 i.PopEnd.Time.Format(time.DateOnly),
+		Rollover:               i.Rollover,
+	}
+	if i.StartTransactionID.Valid {
+		v.StartTransactionID = &i.StartTransactionID.Int32
+	}
+	if i.EndTransactionID.Valid {
+		v.EndTransactionID = &i.EndTransactionID.Int32
+	}
+	if i.EndedAt.Valid {
+		v.EndedAt = &i.EndedAt.Time
+	}
+	return v
+}
+
+// iaaRequest is the body of requests to create or amend agreements. Fields omitted when amending are left unchanged.
+type iaaRequest struct {
+	CustomerID         string  `json:"customer_id"`
+	Number             *string `json:"number"`
+	AmountMicrocredits *int64  `json:"amount_microcredits"`
+	PopStart           *string `json:"pop_start"`
+	PopEnd             *string `json:"pop_end"`
+	Rollover           *bool   `json:"rollover"`
+}
+
+// apply sets the fields present in the request on dst and validates the result.
+func (req iaaRequest) apply(dst *db.UpdateIAAParams) error {
+	if req.Number != nil {
+		dst.Number = *req.Number
+	}
+	if req.AmountMicrocredits != nil {
+		dst.AmountMicrocredits = *req.AmountMicrocredits
+	}
+	if req.Rollover != nil {
+		dst.Rollover = *req.Rollover
+	}
+	var err error
+	if req.PopStart != nil {
+		if dst.PopStart, err = parseDate(*req.PopStart); err != nil {
+			return err
+		}
+	}
+	if req.PopEnd != nil {
+		if dst.PopEnd, err = parseDate(*req.PopEnd); err != nil {
+			return err
+		}
+	}
+	if dst.AmountMicrocredits <= 0 || !dst.PopStart.Valid || !dst.PopEnd.Valid || dst.PopStart.Time.After(dst.PopEnd.Time) {
+		return ErrInvalidIAA
+	}
+	return nil
+}
+
+func iaaIDParam(r *http.Request) (int32, error) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 32)
+	if err != nil {
+		return 0, ErrInvalidIAAID
+	}
+	return int32(id), nil
+}
+
+// handleCreateIAA creates an agreement. Its credits are added to the customer's credit pool by the post-iaa-pop job when its period of performance starts.
+func handleCreateIAA(logger *slog.Logger, q dbx.Querier) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req iaaRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "decoding request: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		customerID, err := parseUUID(req.CustomerID)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		var params db.UpdateIAAParams
+		if err := req.apply(&params); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		created, err := q.CreateIAA(r.Context(), db.CreateIAAParams{
+			CustomerID:         customerID,
+			Number:             params.Number,
+			AmountMicrocredits: params.AmountMicrocredits,
+			PopStart:           params.PopStart,
+			PopEnd:             params.PopEnd,
+			Rollover:           params.Rollover,
+		})
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23503" { // foreign_key_violation
+			http.Error(w, dbx.ErrCustomerNotFound.Error(), http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			logger.ErrorContext(r.Context(), "api: creating agreement", "err", err)
+			http.Error(w, "creating agreement: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, http.StatusCreated, newIAA(created))
+	}
+}
+
+// handleListIAAs lists agreements, optionally filtered by the customer_id query parameter.
+func handleListIAAs(logger *slog.Logger, q dbx.Querier) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var customerID pgtype.UUID
+		if s := r.URL.Query().Get("customer_id"); s != "" {
+			var err error
+			if customerID, err = parseUUID(s); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+		rows, err := q.ListIAAs(r.Context(), customerID)
+		if err != nil {
+			logger.ErrorContext(r.Context(), "api: listing agreements", "err", err)
+			http.Error(w, "listing agreements: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		out := make([]iaa, len(rows))
+		for i, row := range rows {
+			out[i] = newIAA(row)
+		}
+		writeJSON(w, http.StatusOK, out)
+	}
+}
+
+func handleGetIAA(logger *slog.Logger, q dbx.Querier) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := iaaIDParam(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		row, err := q.GetIAA(r.Context(), id)
+		if errors.Is(err, pgx.ErrNoRows) {
+			http.Error(w, dbx.ErrIAANotFound.Error(), http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			logger.ErrorContext(r.Context(), "api: getting agreement", "err", err)
+			http.Error(w, "getting agreement: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, http.StatusOK, newIAA(row))
+	}
+}
+
+// handleAmendIAA amends an agreement. See [dbx.AmendIAA].
+func handleAmendIAA(logger *slog.Logger, conn dbx.Beginner, q dbx.Querier) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		id, err := iaaIDParam(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		var req iaaRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "decoding request: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		var applyErr error
+		amended, err := dbx.AmendIAA(ctx, conn, q, id, func(params *db.UpdateIAAParams) error {
+			applyErr = req.apply(params)
+			return applyErr
+		})
+		switch {
+		case applyErr != nil:
+			http.Error(w, applyErr.Error(), http.StatusBadRequest)
+			return
+		case errors.Is(err, dbx.ErrIAANotFound):
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		case errors.Is(err, dbx.ErrIAAEnded), errors.Is(err, dbx.ErrIAAStarted):
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		case err != nil:
+			logger.ErrorContext(ctx, "api: amending agreement", "err", err)
+			http.Error(w, "amending agreement: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, http.StatusOK, newIAA(amended))
+	}
+}
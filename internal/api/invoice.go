@@ -0,0 +1,125 @@
+package api
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/cloud-gov/billing/internal/dbx"
+	"github.com/cloud-gov/billing/internal/invoice"
+)
+
+var (
+	ErrInvalidInvoiceNumber = errors.New("invoice number must be a positive integer")
+	ErrInvalidInvoiceFormat = errors.New("format must be json, csv, or pdf")
+)
+
+// handleListInvoices lists invoices without their lines, most recent first, optionally filtered by the customer_id query parameter. Pages are selected with the limit and offset query parameters.
+func handleListInvoices(logger *slog.Logger, q dbx.Querier) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var customerID pgtype.UUID
+		if s := r.URL.Query().Get("customer_id"); s != "" {
+			var err error
+			if customerID, err = parseUUID(s); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+		limit, offset, err := pageParams(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		invoices, err := dbx.ListInvoices(r.Context(), q, customerID, limit, offset)
+		if err != nil {
+			logger.ErrorContext(r.Context(), "api: listing invoices", "err", err)
+			http.Error(w, "listing invoices: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, http.StatusOK, invoices)
+	}
+}
+
+// handleCreateInvoices creates invoices for usage posted for the month that does not have them yet, e.g. because the month was closed before invoices were introduced. Closing a month creates its invoices, so this is rarely needed.
+func handleCreateInvoices(logger *slog.Logger, conn dbx.Beginner, q dbx.Querier) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		asOf, err := monthAsOf(chi.URLParam(r, "month"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		tx, err := conn.Begin(ctx)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		defer tx.Rollback(ctx)
+
+		invoices, err := dbx.CreateInvoices(ctx, q.WithTx(tx), asOf)
+		if err != nil {
+			logger.ErrorContext(ctx, "api: creating invoices", "err", err)
+			http.Error(w, "creating invoices: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if err := tx.Commit(ctx); err != nil {
+			http.Error(w, "creating invoices: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, http.StatusCreated, invoices)
+	}
+}
+
+// handleGetInvoice responds with an invoice and its lines. The format query parameter selects JSON (the default), CSV, or PDF.
+func handleGetInvoice(logger *slog.Logger, q dbx.Querier) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		number, err := strconv.ParseInt(chi.URLParam(r, "number"), 10, 32)
+		if err != nil || number < 1 {
+			http.Error(w, ErrInvalidInvoiceNumber.Error(), http.StatusBadRequest)
+			return
+		}
+		format := r.URL.Query().Get("format")
+		if format != "" && format != "json" && format != "csv" && format != "pdf" {
+			http.Error(w, ErrInvalidInvoiceFormat.Error(), http.StatusBadRequest)
+			return
+		}
+		inv, err := dbx.GetInvoice(r.Context(), q, int32(number))
+		if errors.Is(err, dbx.ErrInvoiceNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			logger.ErrorContext(r.Context(), "api: getting invoice", "err", err)
+			http.Error(w, "getting invoice: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		if format == "" || format == "json" {
+			writeJSON(w, http.StatusOK, inv)
+			return
+		}
+		// Render before responding, so rendering errors can be reported.
+		buf := &bytes.Buffer{}
+		contentType := "text/csv"
+		if format == "csv" {
+			err = invoice.WriteCSV(buf, inv)
+		} else {
+			contentType = "application/pdf"
+			err = invoice.WritePDF(buf, inv)
+		}
+		if err != nil {
+			logger.ErrorContext(r.Context(), "api: rendering invoice", "format", format, "err", err)
+			http.Error(w, "rendering invoice: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", contentType)
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", "invoice-"+invoice.Number(inv)+"."+format))
+		_, _ = buf.WriteTo(w)
+	}
+}
@@ -16,8 +16,21 @@ var ctxKey struct{}
 type Claims struct {
 	Email  string   `json:"email"`
 	Scopes []string `json:"scope"`
+	// Subject identifies the principal the token was issued to. For UAA user tokens, it is the user's GUID; for client credentials tokens, it is the client ID.
+	Subject string `json:"sub"`
+	// UserID is the user's GUID. UAA only includes it in tokens issued to users.
+	UserID string `json:"user_id"`
 }
 
+// UserGUID returns the GUID of the user the token was issued to. In Cloud Foundry, the UAA user GUID is also the CF user GUID. For tokens that were not issued to a user, the subject is returned; it will not match any CF user.
+func (c Claims) UserGUID() string {
+	if c.UserID != "" {
+		return c.UserID
+	}
+	return c.Subject
+}
+
+// NewHasScope returns middleware that verifies the request's bearer token and responds 403 Forbidden unless the token grants scope. Verifiers that skip the audience check rely on scope to reject tokens that were not meant for this service.
 func NewHasScope(logger *slog.Logger, verifier *oidc.IDTokenVerifier, scope string) func(http.Handler) http.Handler {
 	return func(h http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -40,18 +53,23 @@ func NewHasScope(logger *slog.Logger, verifier *oidc.IDTokenVerifier, scope stri
 				return
 			}
 
-			if scope != "" && !slices.Contains(c.Scopes, scope) {
+			if !slices.Contains(c.Scopes, scope) {
 				http.Error(w, "forbidden", http.StatusForbidden)
 				return
 			}
 
-			rc := r.WithContext(context.WithValue(r.Context(), ctxKey, c))
+			rc := r.WithContext(WithClaims(r.Context(), c))
 
 			h.ServeHTTP(w, rc)
 		})
 	}
 }
 
+// WithClaims returns a copy of ctx that carries c, as if it had been verified by [NewHasScope].
+func WithClaims(ctx context.Context, c Claims) context.Context {
+	return context.WithValue(ctx, ctxKey, c)
+}
+
 func ClaimsFrom(ctx context.Context) (Claims, bool) {
 	c, ok := ctx.Value(ctxKey).(Claims)
 	return c, ok
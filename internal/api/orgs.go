@@ -0,0 +1,38 @@
+package api
+
+import (
+	"context"
+
+	"github.com/cloudfoundry/go-cfclient/v3/client"
+)
+
+// OrgResolver lists the Cloud Foundry organizations in which a user has a role.
+type OrgResolver interface {
+	UserOrgGUIDs(ctx context.Context, userGUID string) ([]string, error)
+}
+
+// CFOrgResolver resolves users' organizations with the Cloud Foundry API.
+type CFOrgResolver struct {
+	*client.Client
+}
+
+// UserOrgGUIDs returns the GUIDs of organizations in which the user has an organization role. Users with a space role always have the organization_user role in the space's organization, so space roles do not need to be checked.
+func (c *CFOrgResolver) UserOrgGUIDs(ctx context.Context, userGUID string) ([]string, error) {
+	opts := client.NewRoleListOptions()
+	opts.UserGUIDs.EqualTo(userGUID)
+	roles, err := c.Roles.ListAll(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	seen := make(map[string]bool)
+	guids := []string{}
+	for _, r := range roles {
+		guid := r.Relationships.Org.Data.GUID
+		if guid == "" || seen[guid] {
+			continue
+		}
+		seen[guid] = true
+		guids = append(guids, guid)
+	}
+	return guids, nil
+}
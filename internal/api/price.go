@@ -0,0 +1,173 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/cloud-gov/billing/internal/db"
+	"github.com/cloud-gov/billing/internal/dbx"
+)
+
+var (
+	ErrInvalidPriceID       = errors.New("price ID must be an integer")
+	ErrInvalidPrice         = errors.New("meter, kind_natural_id, unit_of_measure, and effective_date are required, microcredits_per_unit must not be negative, and unit must be positive")
+	ErrResourceKindNotFound = errors.New("resource kind not found")
+	ErrPriceOverlap         = errors.New("a price for the resource kind takes effect on or after the effective date")
+)
+
+// price is the JSON representation of a price. A null valid_from or valid_until is unbounded.
+type price struct {
+	ID                  int32      `json:"id"`
+	Meter               string     `json:"meter"`
+	KindNaturalID       string     `json:"kind_natural_id"`
+	UnitOfMeasure       string     `json:"unit_of_measure"`
+	MicrocreditsPerUnit int64      `json:"microcredits_per_unit"`
+	Unit                int64      `json:"unit"`
+	ValidFrom           *time.Time `json:"valid_from"`
+	ValidUntil          *time.Time `json:"valid_until"`
+}
+
+func newPrice(p db.Price) price {
+	v := price{
+		ID:                  p.ID,
+		Meter:               p.Meter,
+		KindNaturalID:       p.KindNaturalID,
+		UnitOfMeasure:       p.UnitOfMeasure,
+		MicrocreditsPerUnit: p.MicrocreditsPerUnit,
+		Unit:                p.Unit,
+	}
+	if p.ValidDuring.LowerType != pgtype.Unbounded && p.ValidDuring.Lower.Valid {
+		v.ValidFrom = &p.ValidDuring.Lower.Time
+	}
+	if p.ValidDuring.UpperType != pgtype.Unbounded && p.ValidDuring.Upper.Valid {
+		v.ValidUntil = &p.ValidDuring.Upper.Time
+	}
+	return v
+}
+
+func priceIDParam(r *http.Request) (int32, error) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 32)
+	if err != nil {
+		return 0, ErrInvalidPriceID
+	}
+	return int32(id), nil
+}
+
+// handleListPrices lists prices, optionally filtered by the meter and kind_natural_id query parameters.
+func handleListPrices(logger *slog.Logger, q dbx.Querier) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var params db.ListPricesParams
+		if s := r.URL.Query().Get("meter"); s != "" {
+			params.Meter = pgtype.Text{String: s, Valid: true}
+		}
+		if s := r.URL.Query().Get("kind_natural_id"); s != "" {
+			params.KindNaturalID = pgtype.Text{String: s, Valid: true}
+		}
+		rows, err := q.ListPrices(r.Context(), params)
+		if err != nil {
+			logger.ErrorContext(r.Context(), "api: listing prices", "err", err)
+			http.Error(w, "listing prices: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		out := make([]price, len(rows))
+		for i, row := range rows {
+			out[i] = newPrice(row)
+		}
+		writeJSON(w, http.StatusOK, out)
+	}
+}
+
+func handleGetPrice(logger *slog.Logger, q dbx.Querier) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := priceIDParam(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		row, err := q.GetPrice(r.Context(), id)
+		if errors.Is(err, pgx.ErrNoRows) {
+			http.Error(w, dbx.ErrPriceNotFound.Error(), http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			logger.ErrorContext(r.Context(), "api: getting price", "err", err)
+			http.Error(w, "getting price: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, http.StatusOK, newPrice(row))
+	}
+}
+
+// handlePublishPrice publishes a new price for a resource kind, effective from the start of effective_date (YYYY-MM-DD) in business time. See [dbx.PublishPrice].
+func handlePublishPrice(logger *slog.Logger, conn dbx.Beginner, q dbx.Querier) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		var req struct {
+			Meter               string `json:"meter"`
+			KindNaturalID       string `json:"kind_natural_id"`
+			UnitOfMeasure       string `json:"unit_of_measure"`
+			MicrocreditsPerUnit int64  `json:"microcredits_per_unit"`
+			Unit                int64  `json:"unit"`
+			EffectiveDate       string `json:"effective_date"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "decoding request: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		if req.Meter == "" || req.KindNaturalID == "" || req.UnitOfMeasure == "" || req.MicrocreditsPerUnit < 0 || req.Unit <= 0 {
+			http.Error(w, ErrInvalidPrice.Error(), http.StatusBadRequest)
+			return
+		}
+		effective, err := parseDate(req.EffectiveDate)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		tx, err := conn.Begin(ctx)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		defer tx.Rollback(ctx)
+
+		created, err := dbx.PublishPrice(ctx, q.WithTx(tx), db.CreatePriceParams{
+			Meter:               req.Meter,
+			KindNaturalID:       req.KindNaturalID,
+			UnitOfMeasure:       req.UnitOfMeasure,
+			MicrocreditsPerUnit: req.MicrocreditsPerUnit,
+			Unit:                req.Unit,
+			EffectiveDate:       effective,
+		})
+		var pgErr *pgconn.PgError
+		switch {
+		case errors.As(err, &pgErr) && pgErr.Code == "23503": // foreign_key_violation
+			http.Error(w, ErrResourceKindNotFound.Error(), http.StatusNotFound)
+			return
+		case errors.As(err, &pgErr) && pgErr.Code == "23P01": // exclusion_violation
+			http.Error(w, ErrPriceOverlap.Error(), http.StatusConflict)
+			return
+		case errors.Is(err, dbx.ErrPriceInUse):
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		case err != nil:
+			logger.ErrorContext(ctx, "api: publishing price", "err", err)
+			http.Error(w, "publishing price: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if err := tx.Commit(ctx); err != nil {
+			http.Error(w, "publishing price: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, http.StatusCreated, newPrice(created))
+	}
+}
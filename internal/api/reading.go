@@ -0,0 +1,94 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/riverqueue/river"
+
+	"github.com/cloud-gov/billing/internal/db"
+	"github.com/cloud-gov/billing/internal/dbx"
+	"github.com/cloud-gov/billing/internal/jobs"
+)
+
+// defaultRangeWindow is how far back a range extends if the since query parameter is not given, e.g. when gaps are listed or backfilled.
+const defaultRangeWindow = 31 * 24 * time.Hour
+
+var ErrInvalidRange = errors.New("since and until must be RFC 3339 timestamps, and since must be before until")
+
+// readingGaps is the JSON representation of the hours without readings in a range.
+type readingGaps struct {
+	Since time.Time   `json:"since"`
+	Until time.Time   `json:"until"`
+	Gaps  []time.Time `json:"gaps"`
+}
+
+// timeRange returns the range given by the since and until query parameters. Until defaults to the start of the current hour, which may not have a reading yet, and since defaults to [defaultRangeWindow] before until.
+func timeRange(r *http.Request) (since, until time.Time, err error) {
+	until = time.Now().UTC().Truncate(time.Hour)
+	if s := r.URL.Query().Get("until"); s != "" {
+		if until, err = time.Parse(time.RFC3339, s); err != nil {
+			return since, until, ErrInvalidRange
+		}
+	}
+	since = until.Add(-defaultRangeWindow)
+	if s := r.URL.Query().Get("since"); s != "" {
+		if since, err = time.Parse(time.RFC3339, s); err != nil {
+			return since, until, ErrInvalidRange
+		}
+	}
+	if !since.Before(until) {
+		return since, until, ErrInvalidRange
+	}
+	return since.UTC(), until.UTC(), nil
+}
+
+// handleListReadingGaps lists the hours in a range in which no reading was taken, e.g. because the service was down.
+func handleListReadingGaps(logger *slog.Logger, q dbx.Querier) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		since, until, err := timeRange(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		rows, err := q.ListReadingGaps(r.Context(), db.ListReadingGapsParams{
+			Since: pgtype.Timestamptz{Time: since, Valid: true},
+			Until: pgtype.Timestamptz{Time: until, Valid: true},
+		})
+		if err != nil {
+			logger.ErrorContext(r.Context(), "api: listing reading gaps", "err", err)
+			http.Error(w, "listing reading gaps: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		out := readingGaps{Since: since, Until: until, Gaps: make([]time.Time, len(rows))}
+		for i, row := range rows {
+			out.Gaps[i] = row.Time.UTC()
+		}
+		writeJSON(w, http.StatusOK, out)
+	}
+}
+
+// handleCreateBackfillJob enqueues a job that backfills readings for the hours in a range in which none were taken. Backfilled readings are marked as estimated.
+func handleCreateBackfillJob(riverc *river.Client[pgx.Tx]) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		since, until, err := timeRange(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		result, err := riverc.Insert(r.Context(), jobs.BackfillReadingsArgs{Since: since, Until: until}, nil)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Failed to insert River job: %v\n", err), http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, http.StatusAccepted, jobResponse{
+			JobID:                    result.Job.ID,
+			UniqueSkippedAsDuplicate: result.UniqueSkippedAsDuplicate,
+		})
+	}
+}
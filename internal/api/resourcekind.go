@@ -0,0 +1,111 @@
+package api
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/riverqueue/river"
+
+	"github.com/cloud-gov/billing/internal/db"
+	"github.com/cloud-gov/billing/internal/dbx"
+	"github.com/cloud-gov/billing/internal/jobs"
+)
+
+// resourceKind is the JSON representation of a kind of billable resource.
+type resourceKind struct {
+	Meter         string     `json:"meter"`
+	NaturalID     string     `json:"natural_id"`
+	Name          *string    `json:"name"`
+	Broker        *string    `json:"broker"`
+	UnitOfMeasure *string    `json:"unit_of_measure"`
+	Accrues       bool       `json:"accrues"`
+	Active        bool       `json:"active"`
+	DeprecatedAt  *time.Time `json:"deprecated_at"`
+	SyncedAt      *time.Time `json:"synced_at"`
+}
+
+func newResourceKind(k db.ResourceKind) resourceKind {
+	v := resourceKind{
+		Meter:     k.Meter,
+		NaturalID: k.NaturalID,
+		Accrues:   k.Accrues,
+		Active:    k.Active,
+	}
+	if k.Name.Valid {
+		v.Name = &k.Name.String
+	}
+	if k.Broker.Valid {
+		v.Broker = &k.Broker.String
+	}
+	if k.UnitOfMeasure.Valid {
+		v.UnitOfMeasure = &k.UnitOfMeasure.String
+	}
+	if k.DeprecatedAt.Valid {
+		v.DeprecatedAt = &k.DeprecatedAt.Time
+	}
+	if k.SyncedAt.Valid {
+		v.SyncedAt = &k.SyncedAt.Time
+	}
+	return v
+}
+
+// unpricedResourceKind is a resource kind with measurements taken when it had no price.
+type unpricedResourceKind struct {
+	resourceKind
+	MeasurementCount int64     `json:"measurement_count"`
+	LastMeasuredAt   time.Time `json:"last_measured_at"`
+}
+
+// handleListResourceKinds lists all resource kinds, or only unpriced kinds if the unpriced query parameter is true. Unpriced kinds have measurements taken when no price for the kind was valid, so that usage is not billed.
+func handleListResourceKinds(logger *slog.Logger, q dbx.Querier) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("unpriced") == "true" {
+			rows, err := q.ListUnpricedResourceKinds(r.Context())
+			if err != nil {
+				logger.ErrorContext(r.Context(), "api: listing unpriced resource kinds", "err", err)
+				http.Error(w, "listing unpriced resource kinds: "+err.Error(), http.StatusInternalServerError)
+				return
+			}
+			out := make([]unpricedResourceKind, len(rows))
+			for i, row := range rows {
+				out[i] = unpricedResourceKind{
+					resourceKind:     newResourceKind(row.ResourceKind),
+					MeasurementCount: row.MeasurementCount,
+					LastMeasuredAt:   row.LastMeasuredAt.Time,
+				}
+			}
+			writeJSON(w, http.StatusOK, out)
+			return
+		}
+
+		rows, err := q.ListResourceKind(r.Context())
+		if err != nil {
+			logger.ErrorContext(r.Context(), "api: listing resource kinds", "err", err)
+			http.Error(w, "listing resource kinds: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		out := make([]resourceKind, len(rows))
+		for i, row := range rows {
+			out[i] = newResourceKind(row)
+		}
+		writeJSON(w, http.StatusOK, out)
+	}
+}
+
+// handleCreateCatalogSyncJob enqueues a job that copies the CF service catalog into resource kinds.
+func handleCreateCatalogSyncJob(riverc *river.Client[pgx.Tx]) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		result, err := riverc.Insert(r.Context(), jobs.SyncCFCatalogArgs{}, nil)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Failed to insert River job: %v\n", err), http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, http.StatusAccepted, jobResponse{
+			JobID:                    result.Job.ID,
+			UniqueSkippedAsDuplicate: result.UniqueSkippedAsDuplicate,
+		})
+	}
+}
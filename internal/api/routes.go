@@ -2,6 +2,9 @@
 package api
 
 import (
+	"encoding/json"
+	"errors"
+	"expvar"
 	"fmt"
 	"io"
 	"log/slog"
@@ -23,45 +26,94 @@ import (
 	"github.com/cloud-gov/billing/internal/jobs"
 )
 
-// Routes registers all customer-facing HTTP routes for the server.
-func Routes(logger *slog.Logger, cf *client.Client, q db.Querier, riverc *river.Client[pgx.Tx], verifier *oidc.IDTokenVerifier, config config.Config) http.Handler {
+// userScope is the scope required of tokens for customer-facing routes. UAA grants it to Cloud Foundry users in tokens issued to clients like the cf CLI.
+const userScope = "cloud_controller.read"
+
+// Routes registers all customer-facing HTTP routes for the server. adminVerifier verifies tokens for admin routes, which are issued to the billing service's client. userVerifier verifies tokens for customer-facing routes, which are issued to other clients.
+func Routes(logger *slog.Logger, cf *client.Client, conn dbx.Beginner, q dbx.Querier, riverc *river.Client[pgx.Tx], adminVerifier, userVerifier *oidc.IDTokenVerifier, config config.Config) http.Handler {
 	mux := chi.NewMux()
 	mux.Use(httplog.RequestLogger(logger, &httplog.Options{
 		Level: slog.LevelInfo,
 	}))
 
-	mux.Mount("/admin", adminMux(logger, cf, q, riverc, verifier, config))
+	mux.Mount("/admin", adminMux(logger, cf, conn, q, riverc, adminVerifier, config))
+	mux.Mount("/v1", v1Mux(logger, cf, q, userVerifier, config))
+	return mux
+}
+
+// v1Mux returns a Handler for customer-facing routes. Any authenticated Cloud Foundry user may call them; responses are limited to the customers that own the Cloud Foundry organizations in which the user has a role.
+func v1Mux(logger *slog.Logger, cf *client.Client, q dbx.Querier, verifier *oidc.IDTokenVerifier, config config.Config) http.Handler {
+	mux := chi.NewMux()
+
+	mux.Use(middleware.NewHasScope(logger, verifier, userScope))
+
+	orgs := &CFOrgResolver{Client: cf}
+	mux.Get("/usage", handleGetUsage(logger, orgs, q, config.USDPerCredit))
+	mux.Get("/usage/report", handleGetUsageReport(logger, orgs, q, config.USDPerCredit))
+	mux.Get("/focus", handleExportCosts(logger, orgs, q, config.USDPerCredit))
+
 	return mux
 }
 
 // adminMux returns a Handler for admin routes with access restricted to authorized subjects.
-func adminMux(logger *slog.Logger, cf *client.Client, q db.Querier, riverc *river.Client[pgx.Tx], verifier *oidc.IDTokenVerifier, config config.Config) http.Handler {
+func adminMux(logger *slog.Logger, cf *client.Client, conn dbx.Beginner, q dbx.Querier, riverc *river.Client[pgx.Tx], verifier *oidc.IDTokenVerifier, config config.Config) http.Handler {
 	mux := chi.NewMux()
 
 	hasAdminScope := middleware.NewHasScope(logger, verifier, "usage.admin")
 	mux.Use(hasAdminScope)
 
-	mux.Post("/tier", handleCreateTier(q))
+	mux.Get("/tier", handleListTiers(logger, q))
+	mux.Post("/tier", handleCreateTier(logger, q))
+	mux.Get("/tier/{id}", handleGetTier(logger, q))
+	mux.Put("/tier/{id}", handleUpdateTier(logger, q))
+	mux.Delete("/tier/{id}", handleDeleteTier(logger, q))
+	mux.Post("/tier/grant/{month}", handleGrantTierCredits(riverc))
 	mux.Post("/usage/job", handleCreateUsageJob(riverc))
 	mux.Post("/usage/app/{guid}", handleCreateAppUsageJob(logger, cf, q))
+	mux.Get("/reading/gap", handleListReadingGaps(logger, q))
+	mux.Post("/reading/backfill/job", handleCreateBackfillJob(riverc))
+	mux.Get("/anomaly", handleListAnomalies(logger, q))
+	mux.Get("/anomaly/summary", handleSummarizeAnomalies(logger, q))
+	mux.Handle("/metrics", expvar.Handler())
+	mux.Get("/usage/close/{month}", handlePreviewCloseMonth(logger, conn, q))
+	mux.Post("/usage/close/{month}", handleCloseMonth(riverc))
+	mux.Post("/usage/close/{month}/reversal", handleReverseMonth(logger, q))
+	mux.Get("/invoice", handleListInvoices(logger, q))
+	mux.Post("/invoice/month/{month}", handleCreateInvoices(logger, conn, q))
+	mux.Get("/invoice/{number}", handleGetInvoice(logger, q))
+	mux.Get("/transaction/{id}", handleGetTransaction(logger, q))
+	mux.Post("/transaction/{id}/reversal", handleReverseTransaction(logger, q))
+	mux.Post("/transaction/{id}/adjustment", handleAdjustTransaction(logger, q))
+	mux.Get("/customer", handleListCustomers(logger, q))
+	mux.Post("/customer", handleCreateCustomer(logger, conn, q))
+	mux.Get("/customer/{id}", handleGetCustomer(logger, q))
+	mux.Patch("/customer/{id}", handleUpdateCustomer(logger, q))
+	mux.Get("/customer/{id}/balance", handleGetCustomerBalance(logger, q))
+	mux.Get("/customer/{id}/statement", handleGetCustomerStatement(logger, q))
+	mux.Get("/customer/{id}/usage", handleGetCustomerUsageReport(logger, q, config.USDPerCredit))
+	mux.Get("/customer/{id}/focus", handleExportCustomerCosts(logger, q, config.USDPerCredit))
+	mux.Put("/customer/{id}/tier", handleSetCustomerTier(logger, q))
+	mux.Get("/cf-org", handleListCFOrgs(logger, q))
+	mux.Post("/cf-org/sync/job", handleCreateCFOrgSyncJob(riverc))
+	mux.Put("/cf-org/{id}/customer", handleAssignCFOrg(logger, q))
+	mux.Delete("/cf-org/{id}/customer", handleUnassignCFOrg(logger, q))
+	mux.Get("/iaa", handleListIAAs(logger, q))
+	mux.Post("/iaa", handleCreateIAA(logger, q))
+	mux.Get("/iaa/{id}", handleGetIAA(logger, q))
+	mux.Patch("/iaa/{id}", handleAmendIAA(logger, conn, q))
+	mux.Get("/resource-kind", handleListResourceKinds(logger, q))
+	mux.Post("/resource-kind/sync/job", handleCreateCatalogSyncJob(riverc))
+	mux.Get("/price", handleListPrices(logger, q))
+	mux.Post("/price", handlePublishPrice(logger, conn, q))
+	mux.Get("/price/{id}", handleGetPrice(logger, q))
+	mux.Get("/alert", handleListAlerts(logger, q))
+	mux.Post("/alert/job", handleCreateAlertJob(riverc))
+	mux.Get("/customer/{id}/alert-threshold", handleGetAlertThresholds(logger, q))
+	mux.Put("/customer/{id}/alert-threshold", handleSetAlertThresholds(logger, conn, q))
 
 	return mux
 }
 
-func handleCreateTier(q db.Querier) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		tier, err := q.CreateTier(r.Context(), db.CreateTierParams{
-			Name:        "",
-			TierCredits: 0,
-		})
-		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-			return
-		}
-		io.WriteString(w, fmt.Sprintf("%v", tier.ID))
-	}
-}
-
 func handleCreateUsageJob(riverc *river.Client[pgx.Tx]) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		result, err := riverc.Insert(r.Context(), jobs.MeasureUsageArgs{}, nil)
@@ -126,3 +178,31 @@ func handleCreateAppUsageJob(logger *slog.Logger, cf *client.Client, q db.Querie
 		_, _ = io.WriteString(w, "Created reading.\n")
 	})
 }
+
+var (
+	ErrInvalidDate     = errors.New("dates must be formatted as YYYY-MM-DD")
+	ErrInvalidCustomer = errors.New("customer ID must be a UUID")
+)
+
+func parseDate(s string) (pgtype.Date, error) {
+	t, err := time.Parse(time.DateOnly, s)
+	if err != nil {
+		return pgtype.Date{}, ErrInvalidDate
+	}
+	return pgtype.Date{Time: t, Valid: true}, nil
+}
+
+func parseUUID(s string) (pgtype.UUID, error) {
+	var u pgtype.UUID
+	if err := u.Scan(s); err != nil || !u.Valid {
+		return pgtype.UUID{}, ErrInvalidCustomer
+	}
+	return u, nil
+}
+
+// writeJSON writes v to w as JSON with the given status code.
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,229 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/riverqueue/river"
+
+	"github.com/cloud-gov/billing/internal/db"
+	"github.com/cloud-gov/billing/internal/dbx"
+	"github.com/cloud-gov/billing/internal/jobs"
+)
+
+var (
+	ErrInvalidTierID = errors.New("tier ID must be an integer")
+	ErrInvalidTier   = errors.New("tier name must not be empty and tier_credits must not be negative")
+	ErrTierNotFound  = errors.New("tier not found")
+	ErrTierInUse     = errors.New("tier is assigned to one or more customers")
+)
+
+// tier is the JSON representation of a tier. TierCredits is the number of whole credits granted to each customer in the tier every month.
+type tier struct {
+	ID          int32  `json:"id"`
+	Name        string `json:"name"`
+	TierCredits int64  `json:"tier_credits"`
+}
+
+func tierIDParam(r *http.Request) (int32, error) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 32)
+	if err != nil {
+		return 0, ErrInvalidTierID
+	}
+	return int32(id), nil
+}
+
+// decodeTier decodes a tier from the request body and validates it.
+func decodeTier(r *http.Request) (tier, error) {
+	var t tier
+	if err := json.NewDecoder(r.Body).Decode(&t); err != nil {
+		return tier{}, fmt.Errorf("decoding request: %w", err)
+	}
+	if t.Name == "" || t.TierCredits < 0 {
+		return tier{}, ErrInvalidTier
+	}
+	return t, nil
+}
+
+func handleListTiers(logger *slog.Logger, q dbx.Querier) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		rows, err := q.ListTiers(r.Context())
+		if err != nil {
+			logger.ErrorContext(r.Context(), "api: listing tiers", "err", err)
+			http.Error(w, "listing tiers: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		out := make([]tier, len(rows))
+		for i, row := range rows {
+			out[i] = tier(row)
+		}
+		writeJSON(w, http.StatusOK, out)
+	}
+}
+
+func handleCreateTier(logger *slog.Logger, q dbx.Querier) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		t, err := decodeTier(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		created, err := q.CreateTier(r.Context(), db.CreateTierParams{
+			Name:        t.Name,
+			TierCredits: t.TierCredits,
+		})
+		if err != nil {
+			logger.ErrorContext(r.Context(), "api: creating tier", "err", err)
+			http.Error(w, "creating tier: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, http.StatusCreated, tier(created))
+	}
+}
+
+func handleGetTier(logger *slog.Logger, q dbx.Querier) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := tierIDParam(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		row, err := q.GetTier(r.Context(), id)
+		if errors.Is(err, pgx.ErrNoRows) {
+			http.Error(w, ErrTierNotFound.Error(), http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			logger.ErrorContext(r.Context(), "api: getting tier", "err", err)
+			http.Error(w, "getting tier: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, http.StatusOK, tier(row))
+	}
+}
+
+// handleUpdateTier replaces a tier's name and credits. Grants already posted are not changed; the new amount applies from the next grant.
+func handleUpdateTier(logger *slog.Logger, q dbx.Querier) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := tierIDParam(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		t, err := decodeTier(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		updated, err := q.UpdateTier(r.Context(), db.UpdateTierParams{
+			ID:          id,
+			Name:        t.Name,
+			TierCredits: t.TierCredits,
+		})
+		if errors.Is(err, pgx.ErrNoRows) {
+			http.Error(w, ErrTierNotFound.Error(), http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			logger.ErrorContext(r.Context(), "api: updating tier", "err", err)
+			http.Error(w, "updating tier: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, http.StatusOK, tier(updated))
+	}
+}
+
+// handleDeleteTier deletes a tier. Tiers that are assigned to customers cannot be deleted.
+func handleDeleteTier(logger *slog.Logger, q dbx.Querier) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := tierIDParam(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		n, err := q.DeleteTier(r.Context(), id)
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23503" { // foreign_key_violation
+			http.Error(w, ErrTierInUse.Error(), http.StatusConflict)
+			return
+		}
+		if err != nil {
+			logger.ErrorContext(r.Context(), "api: deleting tier", "err", err)
+			http.Error(w, "deleting tier: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if n == 0 {
+			http.Error(w, ErrTierNotFound.Error(), http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// handleSetCustomerTier assigns the customer to the tier in the request body, or removes them from their tier if tier_id is null. The change applies from the next monthly grant.
+func handleSetCustomerTier(logger *slog.Logger, q dbx.Querier) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		customerID, err := parseUUID(chi.URLParam(r, "id"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		var req struct {
+			TierID *int32 `json:"tier_id"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "decoding request: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		params := db.SetCustomerTierParams{ID: customerID}
+		if req.TierID != nil {
+			params.TierID = pgtype.Int4{Int32: *req.TierID, Valid: true}
+		}
+		c, err := q.SetCustomerTier(r.Context(), params)
+		var pgErr *pgconn.PgError
+		switch {
+		case errors.Is(err, pgx.ErrNoRows):
+			http.Error(w, dbx.ErrCustomerNotFound.Error(), http.StatusNotFound)
+			return
+		case errors.As(err, &pgErr) && pgErr.Code == "23503": // foreign_key_violation
+			http.Error(w, ErrTierNotFound.Error(), http.StatusNotFound)
+			return
+		case err != nil:
+			logger.ErrorContext(r.Context(), "api: setting customer tier", "err", err)
+			http.Error(w, "setting customer tier: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, http.StatusOK, newCustomer(c))
+	}
+}
+
+// handleGrantTierCredits enqueues a job that grants tier credits for the month, formatted as YYYY-MM. Customers who already received the month's grant are skipped.
+func handleGrantTierCredits(riverc *river.Client[pgx.Tx]) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		t, err := time.Parse("2006-01", chi.URLParam(r, "month"))
+		if err != nil {
+			http.Error(w, ErrInvalidMonth.Error(), http.StatusBadRequest)
+			return
+		}
+		// The middle of the month is unambiguous in any time zone.
+		asOf := pgtype.Timestamptz{Time: time.Date(t.Year(), t.Month(), 15, 12, 0, 0, 0, time.UTC), Valid: true}
+		result, err := riverc.Insert(r.Context(), jobs.PostTierGrantsArgs{AsOf: asOf}, nil)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Failed to insert River job: %v\n", err), http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, http.StatusAccepted, jobResponse{
+			JobID:                    result.Job.ID,
+			UniqueSkippedAsDuplicate: result.UniqueSkippedAsDuplicate,
+		})
+	}
+}
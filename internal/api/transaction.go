@@ -0,0 +1,110 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/cloud-gov/billing/internal/dbx"
+)
+
+var ErrInvalidTransactionID = errors.New("transaction ID must be an integer")
+
+type reversalRequest struct {
+	Description string `json:"description"`
+}
+
+type adjustmentRequest struct {
+	// AmountMicrocredits is the change to the original transaction's amount. It may be negative.
+	AmountMicrocredits int64  `json:"amount_microcredits"`
+	Description        string `json:"description"`
+}
+
+func transactionIDParam(r *http.Request) (int32, error) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 32)
+	if err != nil {
+		return 0, ErrInvalidTransactionID
+	}
+	return int32(id), nil
+}
+
+// handleGetTransaction responds with a transaction and the reversals and adjustments posted against it.
+func handleGetTransaction(logger *slog.Logger, q dbx.Querier) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := transactionIDParam(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		h, err := dbx.GetTransactionHistory(r.Context(), q, id)
+		if err != nil {
+			writeLedgerError(w, r, logger, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, h)
+	}
+}
+
+// handleReverseTransaction posts a reversal of a transaction. The request body is optional.
+func handleReverseTransaction(logger *slog.Logger, q dbx.Querier) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := transactionIDParam(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		var req reversalRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+			http.Error(w, "decoding request: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		s, err := dbx.ReverseTransaction(r.Context(), q, id, req.Description)
+		if err != nil {
+			writeLedgerError(w, r, logger, err)
+			return
+		}
+		writeJSON(w, http.StatusCreated, s)
+	}
+}
+
+// handleAdjustTransaction posts an adjustment to a transaction.
+func handleAdjustTransaction(logger *slog.Logger, q dbx.Querier) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := transactionIDParam(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		var req adjustmentRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "decoding request: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		s, err := dbx.AdjustTransaction(r.Context(), q, id, req.AmountMicrocredits, req.Description)
+		if err != nil {
+			writeLedgerError(w, r, logger, err)
+			return
+		}
+		writeJSON(w, http.StatusCreated, s)
+	}
+}
+
+// writeLedgerError responds with a status code appropriate for errors returned by the ledger functions in [dbx].
+func writeLedgerError(w http.ResponseWriter, r *http.Request, logger *slog.Logger, err error) {
+	switch {
+	case errors.Is(err, dbx.ErrTransactionNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, dbx.ErrAlreadyReversed), errors.Is(err, dbx.ErrCorrectReversal), errors.Is(err, dbx.ErrHasAdjustments):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, dbx.ErrAdjustmentZero), errors.Is(err, dbx.ErrAdjustmentInvalid):
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+	default:
+		logger.ErrorContext(r.Context(), "api: correcting transaction", "err", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,323 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/cloud-gov/billing/internal/api/middleware"
+	"github.com/cloud-gov/billing/internal/db"
+	"github.com/cloud-gov/billing/internal/dbx"
+	"github.com/cloud-gov/billing/internal/usage/report"
+)
+
+var (
+	ErrInvalidPeriod   = errors.New("period must be one of day, week, month, quarter, year, or fiscal_year")
+	ErrInvalidOffset   = errors.New("after and before must be integers")
+	ErrCustomerDenied  = errors.New("you do not have a role in any organization belonging to the customer")
+	ErrMissingIdentity = errors.New("token does not identify a user")
+)
+
+// usageRow is the JSON representation of one row of aggregated usage.
+type usageRow struct {
+	Period            pgtype.Timestamptz `json:"period"`
+	L1                pgtype.Text        `json:"l1"`
+	L2                pgtype.Text        `json:"l2"`
+	L3                pgtype.Text        `json:"l3"`
+	L4                pgtype.Text        `json:"l4"`
+	TotalMicrocredits pgtype.Numeric     `json:"total_microcredits"`
+	TotalCredits      pgtype.Numeric     `json:"total_credits"`
+	TotalCost         pgtype.Numeric     `json:"total_cost"`
+}
+
+// customerUsage is the usage of one customer. Periods begin at midnight in TimeZone.
+type customerUsage struct {
+	CustomerID   pgtype.UUID `json:"customer_id"`
+	CustomerName string      `json:"customer_name"`
+	TimeZone     string      `json:"time_zone"`
+	Usage        []usageRow  `json:"usage"`
+}
+
+// usageReport is a customer's usage rolled up into a tree of orgs, spaces, and resources, like the json output of cmd/usage.
+type usageReport struct {
+	CustomerID   pgtype.UUID `json:"customer_id"`
+	CustomerName string      `json:"customer_name"`
+	// Path is the lquery that selected the usage.
+	Path     string `json:"path"`
+	Period   string `json:"period"`
+	TimeZone string `json:"time_zone"`
+	After    int32  `json:"after"`
+	Before   int32  `json:"before"`
+	// Since and Until are the absolute bounds of the usage, if they superseded After and Before.
+	Since  pgtype.Timestamptz `json:"since"`
+	Until  pgtype.Timestamptz `json:"until"`
+	Report *report.Entry      `json:"report"`
+}
+
+// usageQuery is a query for usage that has not been resolved for a customer. Dates in since and until begin at midnight in the customer's time zone, unless the time_zone query parameter overrides it.
+type usageQuery struct {
+	db.GetUsageByPathParams
+	since, until string
+}
+
+// forCustomer returns the parameters of the query for the customer.
+func (uq usageQuery) forCustomer(c db.Customer) (db.GetUsageByPathParams, error) {
+	params := uq.GetUsageByPathParams
+	params.CustomerID = c.ID
+	if !params.TimeZone.Valid {
+		params.TimeZone = pgtype.Text{String: c.TimeZone, Valid: true}
+	}
+	loc, err := report.LoadLocation(params.TimeZone.String)
+	if err != nil {
+		return params, fmt.Errorf("loading time zone: %w", err)
+	}
+	for _, b := range []struct {
+		s   string
+		dst *pgtype.Timestamptz
+	}{{uq.since, &params.Since}, {uq.until, &params.Until}} {
+		if b.s == "" {
+			continue
+		}
+		t, err := report.ParseTime(b.s, loc)
+		if err != nil {
+			return params, err
+		}
+		*b.dst = pgtype.Timestamptz{Time: t, Valid: true}
+	}
+	return params, nil
+}
+
+// usageParams parses the org, space, lq, period, after, before, since, until, and time_zone query parameters. They have the same meaning as the flags to cmd/usage: lq is an lquery relative to apps.usage, like .cforg_sandbox%.*, and supersedes org and space. since and until are RFC 3339 timestamps or dates, and supersede after and before. Periods and dates begin in time_zone, which defaults to the customer's time zone. Costs are converted to US dollars at usdPerCredit.
+func usageParams(r *http.Request, usdPerCredit float64) (usageQuery, error) {
+	uq := usageQuery{GetUsageByPathParams: db.GetUsageByPathParams{
+		Period:       "month",
+		After:        -1,
+		Before:       0,
+		UsdPerCredit: usdPerCredit,
+	}}
+	query := r.URL.Query()
+	path, err := report.Path(query.Get("org"), query.Get("space"), query.Get("lq"))
+	if err != nil {
+		return uq, err
+	}
+	uq.Path = path
+	if s := query.Get("period"); s != "" {
+		if !slices.Contains(report.Periods, s) {
+			return uq, ErrInvalidPeriod
+		}
+		uq.Period = s
+	}
+	for name, dst := range map[string]*int32{"after": &uq.After, "before": &uq.Before} {
+		if s := query.Get(name); s != "" {
+			v, err := strconv.ParseInt(s, 10, 32)
+			if err != nil {
+				return uq, ErrInvalidOffset
+			}
+			*dst = int32(v)
+		}
+	}
+	if s := query.Get("time_zone"); s != "" {
+		if _, err := report.LoadLocation(s); err != nil {
+			return uq, ErrInvalidTimeZone
+		}
+		uq.TimeZone = pgtype.Text{String: s, Valid: true}
+	}
+	uq.since, uq.until = query.Get("since"), query.Get("until")
+	// Check the syntax of since and until now, so bad requests fail before customers are looked up.
+	for _, s := range []string{uq.since, uq.until} {
+		if s == "" {
+			continue
+		}
+		if _, err := report.ParseTime(s, time.UTC); err != nil {
+			return uq, err
+		}
+	}
+	return uq, nil
+}
+
+// callerCustomers returns the customers that own a Cloud Foundry organization in which the caller has a role, and the IDs of those organizations. Callers must limit responses to usage in orgIDs, because a role in one of a customer's organizations does not grant access to the others. If the customer_id query parameter is given, only that customer is returned. If the caller may not see any customers, callerCustomers responds with an error and returns false.
+func callerCustomers(w http.ResponseWriter, r *http.Request, logger *slog.Logger, orgs OrgResolver, q db.Querier) (customers []db.Customer, orgIDs []pgtype.UUID, ok bool) {
+	ctx := r.Context()
+	claims, ok := middleware.ClaimsFrom(ctx)
+	if !ok || claims.UserGUID() == "" {
+		http.Error(w, ErrMissingIdentity.Error(), http.StatusUnauthorized)
+		return nil, nil, false
+	}
+	var customerID pgtype.UUID
+	if s := r.URL.Query().Get("customer_id"); s != "" {
+		var err error
+		if customerID, err = parseUUID(s); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return nil, nil, false
+		}
+	}
+
+	guids, err := orgs.UserOrgGUIDs(ctx, claims.UserGUID())
+	if err != nil {
+		logger.ErrorContext(ctx, "api: listing user organizations", "err", err)
+		http.Error(w, "listing user organizations: "+err.Error(), http.StatusBadGateway)
+		return nil, nil, false
+	}
+	orgIDs = make([]pgtype.UUID, 0, len(guids))
+	for _, guid := range guids {
+		if id, err := parseUUID(guid); err == nil {
+			orgIDs = append(orgIDs, id)
+		}
+	}
+	customers, err = q.ListCustomersByCFOrgIDs(ctx, orgIDs)
+	if err != nil {
+		logger.ErrorContext(ctx, "api: listing customers", "err", err)
+		http.Error(w, "listing customers: "+err.Error(), http.StatusInternalServerError)
+		return nil, nil, false
+	}
+	if customerID.Valid {
+		customers = slices.DeleteFunc(customers, func(c db.Customer) bool {
+			return c.ID != customerID
+		})
+		if len(customers) == 0 {
+			http.Error(w, ErrCustomerDenied.Error(), http.StatusForbidden)
+			return nil, nil, false
+		}
+	}
+	return customers, orgIDs, true
+}
+
+// handleGetUsage responds with the usage of each customer that owns a Cloud Foundry organization in which the caller has a role. Only usage in those organizations is included. The optional customer_id query parameter limits the response to one of those customers.
+func handleGetUsage(logger *slog.Logger, orgs OrgResolver, q dbx.Querier, usdPerCredit float64) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		uq, err := usageParams(r, usdPerCredit)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		customers, orgIDs, ok := callerCustomers(w, r, logger, orgs, q)
+		if !ok {
+			return
+		}
+		uq.CfOrgIds = orgIDs
+
+		out := make([]customerUsage, 0, len(customers))
+		for _, c := range customers {
+			params, err := uq.forCustomer(c)
+			if err != nil {
+				logger.ErrorContext(ctx, "api: getting usage", "err", err)
+				http.Error(w, "getting usage: "+err.Error(), http.StatusInternalServerError)
+				return
+			}
+			rows, err := q.GetUsageByPath(ctx, params)
+			if err != nil {
+				logger.ErrorContext(ctx, "api: getting usage", "err", err)
+				http.Error(w, "getting usage: "+err.Error(), http.StatusInternalServerError)
+				return
+			}
+			cu := customerUsage{CustomerID: c.ID, CustomerName: c.Name, TimeZone: params.TimeZone.String, Usage: make([]usageRow, len(rows))}
+			for i, row := range rows {
+				cu.Usage[i] = usageRow(row)
+			}
+			out = append(out, cu)
+		}
+		writeJSON(w, http.StatusOK, out)
+	}
+}
+
+// getUsageReport returns the customer's usage as a tree. If basic is true, the tree has only orgs and spaces.
+func getUsageReport(ctx context.Context, q db.Querier, c db.Customer, uq usageQuery, basic bool) (usageReport, error) {
+	params, err := uq.forCustomer(c)
+	if err != nil {
+		return usageReport{}, err
+	}
+	rows, err := q.GetUsageByPath(ctx, params)
+	if err != nil {
+		return usageReport{}, fmt.Errorf("getting usage: %w", err)
+	}
+	root, _, err := report.Build(rows, basic)
+	if err != nil {
+		return usageReport{}, fmt.Errorf("making report: %w", err)
+	}
+	return usageReport{
+		CustomerID:   c.ID,
+		CustomerName: c.Name,
+		Path:         params.Path,
+		Period:       params.Period,
+		TimeZone:     params.TimeZone.String,
+		After:        params.After,
+		Before:       params.Before,
+		Since:        params.Since,
+		Until:        params.Until,
+		Report:       report.Flatten(root),
+	}, nil
+}
+
+// handleGetUsageReport responds with the usage of each customer that owns a Cloud Foundry organization in which the caller has a role, rolled up into a tree. It accepts the query parameters of [handleGetUsage] and basic, which limits the tree to orgs and spaces.
+func handleGetUsageReport(logger *slog.Logger, orgs OrgResolver, q dbx.Querier, usdPerCredit float64) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		uq, err := usageParams(r, usdPerCredit)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		customers, orgIDs, ok := callerCustomers(w, r, logger, orgs, q)
+		if !ok {
+			return
+		}
+		uq.CfOrgIds = orgIDs
+		basic := r.URL.Query().Get("basic") == "true"
+
+		out := make([]usageReport, 0, len(customers))
+		for _, c := range customers {
+			ur, err := getUsageReport(ctx, q, c, uq, basic)
+			if err != nil {
+				logger.ErrorContext(ctx, "api: getting usage report", "err", err)
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			out = append(out, ur)
+		}
+		writeJSON(w, http.StatusOK, out)
+	}
+}
+
+// handleGetCustomerUsageReport responds with a customer's usage rolled up into a tree. It accepts the same query parameters as [handleGetUsageReport].
+func handleGetCustomerUsageReport(logger *slog.Logger, q dbx.Querier, usdPerCredit float64) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		customerID, err := parseUUID(chi.URLParam(r, "id"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		uq, err := usageParams(r, usdPerCredit)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		c, err := q.GetCustomer(ctx, customerID)
+		if errors.Is(err, pgx.ErrNoRows) {
+			http.Error(w, dbx.ErrCustomerNotFound.Error(), http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			logger.ErrorContext(ctx, "api: getting customer", "err", err)
+			http.Error(w, "getting customer: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		ur, err := getUsageReport(ctx, q, c, uq, r.URL.Query().Get("basic") == "true")
+		if err != nil {
+			logger.ErrorContext(ctx, "api: getting usage report", "err", err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, http.StatusOK, ur)
+	}
+}
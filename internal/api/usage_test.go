@@ -0,0 +1,140 @@
+package api
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/cloud-gov/billing/internal/api/middleware"
+	"github.com/cloud-gov/billing/internal/db"
+	"github.com/cloud-gov/billing/internal/dbx"
+)
+
+var (
+	orgA = pgtype.UUID{Bytes: [16]byte{0xa}, Valid: true}
+	orgB = pgtype.UUID{Bytes: [16]byte{0xb}, Valid: true}
+	orgC = pgtype.UUID{Bytes: [16]byte{0xc}, Valid: true}
+
+	customer1 = db.Customer{ID: pgtype.UUID{Bytes: [16]byte{1}, Valid: true}, Name: "customer1", TimeZone: "America/New_York"}
+	customer2 = db.Customer{ID: pgtype.UUID{Bytes: [16]byte{2}, Valid: true}, Name: "customer2", TimeZone: "America/New_York"}
+)
+
+// fakeOrgs maps user GUIDs to the GUIDs of the organizations in which they have a role.
+type fakeOrgs map[string][]string
+
+func (f fakeOrgs) UserOrgGUIDs(ctx context.Context, userGUID string) ([]string, error) {
+	return f[userGUID], nil
+}
+
+// usageQuerier is a [dbx.Querier] whose customers own orgs A and B (customer1) and C (customer2). It records the usage queries it receives. Other methods panic.
+type usageQuerier struct {
+	dbx.Querier
+	usage []db.GetUsageByPathParams
+}
+
+func (q *usageQuerier) ListCustomersByCFOrgIDs(ctx context.Context, cfOrgIds []pgtype.UUID) ([]db.Customer, error) {
+	var out []db.Customer
+	if slices.Contains(cfOrgIds, orgA) || slices.Contains(cfOrgIds, orgB) {
+		out = append(out, customer1)
+	}
+	if slices.Contains(cfOrgIds, orgC) {
+		out = append(out, customer2)
+	}
+	return out, nil
+}
+
+func (q *usageQuerier) GetUsageByPath(ctx context.Context, arg db.GetUsageByPathParams) ([]db.GetUsageByPathRow, error) {
+	q.usage = append(q.usage, arg)
+	return nil, nil
+}
+
+// testOrgs gives user-a a role in org A only, user-ac a role in orgs A and C, and user-none no roles. A malformed GUID is ignored.
+var testOrgs = fakeOrgs{
+	"user-a":  {orgA.String(), "not-a-guid"},
+	"user-ac": {orgA.String(), orgC.String()},
+}
+
+func newUsageRequest(target string, user string) *http.Request {
+	r := httptest.NewRequest(http.MethodGet, target, nil)
+	if user != "" {
+		r = r.WithContext(middleware.WithClaims(r.Context(), middleware.Claims{UserID: user}))
+	}
+	return r
+}
+
+func TestCallerCustomers(t *testing.T) {
+	cases := []struct {
+		name          string
+		target        string
+		user          string
+		wantOK        bool
+		wantStatus    int
+		wantCustomers []db.Customer
+		wantOrgIDs    []pgtype.UUID
+	}{
+		{"no identity", "/usage", "", false, http.StatusUnauthorized, nil, nil},
+		{"no roles", "/usage", "user-none", true, 0, nil, []pgtype.UUID{}},
+		{"one org of a customer", "/usage", "user-a", true, 0, []db.Customer{customer1}, []pgtype.UUID{orgA}},
+		{"two customers", "/usage", "user-ac", true, 0, []db.Customer{customer1, customer2}, []pgtype.UUID{orgA, orgC}},
+		{"visible customer_id", "/usage?customer_id=" + customer2.ID.String(), "user-ac", true, 0, []db.Customer{customer2}, []pgtype.UUID{orgA, orgC}},
+		{"customer_id not visible", "/usage?customer_id=" + customer2.ID.String(), "user-a", false, http.StatusForbidden, nil, nil},
+		{"malformed customer_id", "/usage?customer_id=nope", "user-a", false, http.StatusBadRequest, nil, nil},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			customers, orgIDs, ok := callerCustomers(w, newUsageRequest(tc.target, tc.user), slog.New(slog.DiscardHandler), testOrgs, &usageQuerier{})
+			if ok != tc.wantOK {
+				t.Fatalf("expected ok %v, got %v with status %v", tc.wantOK, ok, w.Code)
+			}
+			if !ok {
+				if w.Code != tc.wantStatus {
+					t.Errorf("expected status %v, got %v", tc.wantStatus, w.Code)
+				}
+				return
+			}
+			if !slices.Equal(customers, tc.wantCustomers) {
+				t.Errorf("expected customers %v, got %v", tc.wantCustomers, customers)
+			}
+			if !slices.Equal(orgIDs, tc.wantOrgIDs) {
+				t.Errorf("expected org IDs %v, got %v", tc.wantOrgIDs, orgIDs)
+			}
+		})
+	}
+}
+
+func TestHandleGetUsageLimitsOrgs(t *testing.T) {
+	q := &usageQuerier{}
+	w := httptest.NewRecorder()
+	handleGetUsage(slog.New(slog.DiscardHandler), testOrgs, q, 50)(w, newUsageRequest("/usage", "user-a"))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %v: %v", w.Code, w.Body)
+	}
+	// Only customer1 is queried, and only for org A, although it also owns org B.
+	if len(q.usage) != 1 {
+		t.Fatalf("expected one usage query, got %+v", q.usage)
+	}
+	got := q.usage[0]
+	if got.CustomerID != customer1.ID || !slices.Equal(got.CfOrgIds, []pgtype.UUID{orgA}) {
+		t.Errorf("expected usage of customer1 in org A, got customer %v in orgs %v", got.CustomerID, got.CfOrgIds)
+	}
+	if got.UsdPerCredit != 50 {
+		t.Errorf("expected 50 US dollars per credit, got %v", got.UsdPerCredit)
+	}
+
+	// Callers without a role in any of the customer's orgs are denied.
+	q = &usageQuerier{}
+	w = httptest.NewRecorder()
+	handleGetUsage(slog.New(slog.DiscardHandler), testOrgs, q, 50)(w, newUsageRequest("/usage?customer_id="+customer2.ID.String(), "user-a"))
+	if w.Code != http.StatusForbidden {
+		t.Errorf("expected status 403, got %v", w.Code)
+	}
+	if len(q.usage) != 0 {
+		t.Errorf("expected no usage queries, got %+v", q.usage)
+	}
+}
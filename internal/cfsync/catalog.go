@@ -0,0 +1,108 @@
+package cfsync
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/cloudfoundry/go-cfclient/v3/client"
+	"github.com/cloudfoundry/go-cfclient/v3/resource"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/cloud-gov/billing/internal/db"
+	"github.com/cloud-gov/billing/internal/usage/meter"
+)
+
+// CF is the subset of the Cloud Foundry API used to sync orgs and the service catalog.
+type CF interface {
+	meter.Organizations
+	Catalog
+}
+
+// Catalog is the subset of the Cloud Foundry API used to sync the service catalog.
+type Catalog interface {
+	meter.ServicePlans
+	meter.ServiceBrokers
+}
+
+// CatalogDB is the subset of [db.Querier] used to sync the service catalog.
+type CatalogDB interface {
+	SyncResourceKind(ctx context.Context, arg db.SyncResourceKindParams) (db.ResourceKind, error)
+	DeprecateResourceKinds(ctx context.Context, arg db.DeprecateResourceKindsParams) ([]db.ResourceKind, error)
+}
+
+// CatalogResult summarizes a catalog sync.
+type CatalogResult struct {
+	// Synced is the number of service plans whose resource kinds were written.
+	Synced int
+	// Deprecated is the number of resource kinds that were newly found to be missing from the catalog.
+	Deprecated int
+}
+
+// SyncServicePlans upserts a resource kind for every service plan in Cloud Foundry, under the meter [meter.ServiceMeterName]. Each kind is named for its service offering and plan, and records the broker that provides it and whether new instances of the plan can be created. Kinds are created for plans that have not been measured yet, so they can be priced before they are used.
+//
+// Kinds of the service meter whose plans are no longer in the catalog are deprecated. If Cloud Foundry returns no plans at all, nothing is deprecated, since that is more likely a problem with the API than an empty catalog.
+//
+// SyncServicePlans makes many changes to the database, so q should be scoped to a transaction.
+func SyncServicePlans(ctx context.Context, logger *slog.Logger, cf Catalog, q CatalogDB, now time.Time) (CatalogResult, error) {
+	var res CatalogResult
+	plans, offerings, err := cf.ServicePlansOfferingsList(ctx, nil)
+	if err != nil {
+		return res, fmt.Errorf("listing service plans: %w", err)
+	}
+	brokers, err := cf.ServiceBrokersList(ctx, client.NewServiceBrokerListOptions())
+	if err != nil {
+		return res, fmt.Errorf("listing service brokers: %w", err)
+	}
+	offeringMap := make(map[string]*resource.ServiceOffering, len(offerings))
+	for _, o := range offerings {
+		offeringMap[o.GUID] = o
+	}
+	brokerMap := make(map[string]*resource.ServiceBroker, len(brokers))
+	for _, b := range brokers {
+		brokerMap[b.GUID] = b
+	}
+
+	syncedAt := pgtype.Timestamptz{Time: now, Valid: true}
+	ids := make([]string, 0, len(plans))
+	for _, plan := range plans {
+		params := db.SyncResourceKindParams{
+			Meter:     meter.ServiceMeterName,
+			NaturalID: plan.GUID,
+			Name:      pgtype.Text{String: plan.Name, Valid: true},
+			Active:    plan.Available,
+			SyncedAt:  syncedAt,
+		}
+		offering, ok := offeringMap[plan.Relationships.ServiceOffering.Data.GUID]
+		if ok {
+			params.Name.String = offering.Name + " " + plan.Name
+			params.Active = plan.Available && offering.Available
+			if b, ok := brokerMap[offering.Relationships.ServiceBroker.Data.GUID]; ok {
+				params.Broker = pgtype.Text{String: b.Name, Valid: true}
+			}
+		} else {
+			logger.WarnContext(ctx, "cfsync: service plan has no offering", "plan_guid", plan.GUID)
+		}
+		if _, err := q.SyncResourceKind(ctx, params); err != nil {
+			return res, fmt.Errorf("syncing service plan %v: %w", plan.GUID, err)
+		}
+		ids = append(ids, plan.GUID)
+		res.Synced++
+	}
+
+	if len(plans) == 0 {
+		logger.WarnContext(ctx, "cfsync: no service plans found; not deprecating resource kinds")
+		return res, nil
+	}
+	deprecated, err := q.DeprecateResourceKinds(ctx, db.DeprecateResourceKindsParams{
+		DeprecatedAt: syncedAt,
+		Meter:        meter.ServiceMeterName,
+		NaturalIds:   ids,
+	})
+	if err != nil {
+		return res, fmt.Errorf("deprecating resource kinds: %w", err)
+	}
+	res.Deprecated = len(deprecated)
+	return res, nil
+}
@@ -0,0 +1,127 @@
+package cfsync_test
+
+import (
+	"context"
+	"log/slog"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/cloudfoundry/go-cfclient/v3/client"
+	"github.com/cloudfoundry/go-cfclient/v3/resource"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/cloud-gov/billing/internal/cfsync"
+	"github.com/cloud-gov/billing/internal/db"
+	"github.com/cloud-gov/billing/internal/usage/meter"
+)
+
+type mockCatalog struct {
+	plans     []*resource.ServicePlan
+	offerings []*resource.ServiceOffering
+	brokers   []*resource.ServiceBroker
+}
+
+func (m mockCatalog) ServicePlansOfferingsList(context.Context, *client.ServicePlanListOptions) ([]*resource.ServicePlan, []*resource.ServiceOffering, error) {
+	return m.plans, m.offerings, nil
+}
+
+func (m mockCatalog) ServiceBrokersList(context.Context, *client.ServiceBrokerListOptions) ([]*resource.ServiceBroker, error) {
+	return m.brokers, nil
+}
+
+// stubCatalogDB keeps resource kinds of the service meter in memory, keyed by natural ID.
+type stubCatalogDB map[string]db.ResourceKind
+
+func (s stubCatalogDB) SyncResourceKind(_ context.Context, arg db.SyncResourceKindParams) (db.ResourceKind, error) {
+	k := db.ResourceKind{
+		Meter:     arg.Meter,
+		NaturalID: arg.NaturalID,
+		Name:      arg.Name,
+		Broker:    arg.Broker,
+		Active:    arg.Active,
+		SyncedAt:  arg.SyncedAt,
+	}
+	s[arg.NaturalID] = k
+	return k, nil
+}
+
+func (s stubCatalogDB) DeprecateResourceKinds(_ context.Context, arg db.DeprecateResourceKindsParams) ([]db.ResourceKind, error) {
+	var out []db.ResourceKind
+	for id, k := range s {
+		if k.Meter != arg.Meter || slices.Contains(arg.NaturalIds, id) || k.DeprecatedAt.Valid {
+			continue
+		}
+		k.Active, k.DeprecatedAt = false, arg.DeprecatedAt
+		s[id] = k
+		out = append(out, k)
+	}
+	return out, nil
+}
+
+func plan(guid, name, offeringGUID string, available bool) *resource.ServicePlan {
+	p := &resource.ServicePlan{Name: name, Available: available, Resource: resource.Resource{GUID: guid}}
+	p.Relationships.ServiceOffering.Data = &resource.Relationship{GUID: offeringGUID}
+	return p
+}
+
+func TestSyncServicePlans(t *testing.T) {
+	cf := mockCatalog{
+		plans: []*resource.ServicePlan{
+			plan("plan-micro", "micro-psql", "offering-rds", true),
+			plan("plan-large", "large-psql", "offering-rds", false),
+		},
+		offerings: []*resource.ServiceOffering{
+			{Name: "aws-rds", Available: true, Resource: resource.Resource{GUID: "offering-rds"}},
+		},
+		brokers: []*resource.ServiceBroker{
+			{Name: "aws-broker", Resource: resource.Resource{GUID: "broker-aws"}},
+		},
+	}
+	cf.offerings[0].Relationships.ServiceBroker.Data = &resource.Relationship{GUID: "broker-aws"}
+	q := stubCatalogDB{
+		// A plan that was measured before it was removed from the catalog.
+		"plan-removed": {Meter: meter.ServiceMeterName, NaturalID: "plan-removed", Active: true},
+		// Kinds of other meters are not deprecated.
+		"app-memory": {Meter: "cfapps", NaturalID: "app-memory", Active: true},
+	}
+	now := time.Date(2025, time.March, 1, 0, 0, 0, 0, time.UTC)
+
+	res, err := cfsync.SyncServicePlans(t.Context(), slog.New(slog.DiscardHandler), cf, q, now)
+	if err != nil {
+		t.Fatal("syncing service plans:", err)
+	}
+	if res.Synced != 2 || res.Deprecated != 1 {
+		t.Fatalf("expected 2 synced and 1 deprecated, got %+v", res)
+	}
+
+	want := db.ResourceKind{
+		Meter:     meter.ServiceMeterName,
+		NaturalID: "plan-micro",
+		Name:      pgtype.Text{String: "aws-rds micro-psql", Valid: true},
+		Broker:    pgtype.Text{String: "aws-broker", Valid: true},
+		Active:    true,
+		SyncedAt:  pgtype.Timestamptz{Time: now, Valid: true},
+	}
+	if got := q["plan-micro"]; got != want {
+		t.Errorf("expected %+v, got %+v", want, got)
+	}
+	if q["plan-large"].Active {
+		t.Error("expected unavailable plan to be inactive")
+	}
+	if got := q["plan-removed"]; got.Active || !got.DeprecatedAt.Time.Equal(now) {
+		t.Errorf("expected removed plan to be deprecated, got %+v", got)
+	}
+	if got := q["app-memory"]; !got.Active || got.DeprecatedAt.Valid {
+		t.Errorf("expected kind of another meter to be unchanged, got %+v", got)
+	}
+
+	// An empty catalog does not deprecate every kind.
+	res, err = cfsync.SyncServicePlans(t.Context(), slog.New(slog.DiscardHandler), mockCatalog{}, q, now)
+	if err != nil {
+		t.Fatal("syncing empty catalog:", err)
+	}
+	if res.Deprecated != 0 || !q["plan-micro"].Active {
+		t.Fatalf("expected no kinds to be deprecated, got %+v", res)
+	}
+}
@@ -0,0 +1,129 @@
+// Package cfsync copies information about Cloud Foundry organizations into the billing database, so reports can show org names and usage can be attributed to customers without manual data entry.
+package cfsync
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/cloudfoundry/go-cfclient/v3/client"
+	"github.com/cloudfoundry/go-cfclient/v3/resource"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/cloud-gov/billing/internal/db"
+	"github.com/cloud-gov/billing/internal/usage/meter"
+)
+
+// DB is the subset of [db.Querier] used to sync orgs.
+type DB interface {
+	SyncCFOrg(ctx context.Context, arg db.SyncCFOrgParams) (db.CFOrg, error)
+	GetCustomer(ctx context.Context, id pgtype.UUID) (db.Customer, error)
+	SetCFOrgCustomer(ctx context.Context, arg db.SetCFOrgCustomerParams) (db.CFOrg, error)
+}
+
+// Result summarizes a sync.
+type Result struct {
+	// Synced is the number of orgs whose name and metadata were written.
+	Synced int
+	// Assigned is the number of orgs assigned to a different customer because of their customer label.
+	Assigned int
+}
+
+// SyncOrgs upserts the name, suspended status, labels, and annotations of every org in Cloud Foundry into cf_org.
+//
+// If customerLabel is not empty, orgs with that label are assigned to the customer whose ID is the label's value. Orgs without the label keep their current customer, so manual assignments are preserved. Label values that are not the ID of an existing customer are logged and ignored.
+//
+// SyncOrgs makes many changes to the database, so q should be scoped to a transaction.
+func SyncOrgs(ctx context.Context, logger *slog.Logger, cf meter.Organizations, q DB, customerLabel string, now time.Time) (Result, error) {
+	var res Result
+	orgs, err := cf.OrgsList(ctx, client.NewOrganizationListOptions())
+	if err != nil {
+		return res, fmt.Errorf("listing orgs: %w", err)
+	}
+	for _, org := range orgs {
+		params, err := syncParams(org, now)
+		if err != nil {
+			return res, err
+		}
+		synced, err := q.SyncCFOrg(ctx, params)
+		if err != nil {
+			return res, fmt.Errorf("syncing org %v: %w", org.GUID, err)
+		}
+		res.Synced++
+
+		if customerLabel == "" {
+			continue
+		}
+		customerID, ok := labelCustomerID(logger, org, customerLabel)
+		if !ok || customerID == synced.CustomerID {
+			continue
+		}
+		_, err = q.GetCustomer(ctx, customerID)
+		if errors.Is(err, pgx.ErrNoRows) {
+			logger.WarnContext(ctx, "cfsync: org label refers to a customer that does not exist", "org_guid", org.GUID, "customer_id", customerID.String())
+			continue
+		}
+		if err != nil {
+			return res, fmt.Errorf("getting customer for org %v: %w", org.GUID, err)
+		}
+		_, err = q.SetCFOrgCustomer(ctx, db.SetCFOrgCustomerParams{ID: synced.ID, CustomerID: customerID})
+		if err != nil {
+			return res, fmt.Errorf("assigning org %v: %w", org.GUID, err)
+		}
+		res.Assigned++
+	}
+	return res, nil
+}
+
+func syncParams(org *resource.Organization, now time.Time) (db.SyncCFOrgParams, error) {
+	var id pgtype.UUID
+	if err := id.Scan(org.GUID); err != nil {
+		return db.SyncCFOrgParams{}, fmt.Errorf("parsing org GUID %q: %w", org.GUID, err)
+	}
+	labels, annotations := map[string]*string{}, map[string]*string{}
+	if org.Metadata != nil {
+		if org.Metadata.Labels != nil {
+			labels = org.Metadata.Labels
+		}
+		if org.Metadata.Annotations != nil {
+			annotations = org.Metadata.Annotations
+		}
+	}
+	labelsJSON, err := json.Marshal(labels)
+	if err != nil {
+		return db.SyncCFOrgParams{}, err
+	}
+	annotationsJSON, err := json.Marshal(annotations)
+	if err != nil {
+		return db.SyncCFOrgParams{}, err
+	}
+	return db.SyncCFOrgParams{
+		ID:          id,
+		Name:        pgtype.Text{String: org.Name, Valid: true},
+		Suspended:   org.Suspended,
+		Labels:      labelsJSON,
+		Annotations: annotationsJSON,
+		SyncedAt:    pgtype.Timestamptz{Time: now, Valid: true},
+	}, nil
+}
+
+// labelCustomerID returns the customer ID in the org's customer label, if it has one.
+func labelCustomerID(logger *slog.Logger, org *resource.Organization, customerLabel string) (pgtype.UUID, bool) {
+	if org.Metadata == nil {
+		return pgtype.UUID{}, false
+	}
+	v := org.Metadata.Labels[customerLabel]
+	if v == nil || *v == "" {
+		return pgtype.UUID{}, false
+	}
+	var id pgtype.UUID
+	if err := id.Scan(*v); err != nil {
+		logger.Warn("cfsync: org customer label is not a UUID", "org_guid", org.GUID, "label", customerLabel, "value", *v)
+		return pgtype.UUID{}, false
+	}
+	return id, true
+}
@@ -0,0 +1,136 @@
+package cfsync_test
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/cloudfoundry/go-cfclient/v3/client"
+	"github.com/cloudfoundry/go-cfclient/v3/resource"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/cloud-gov/billing/internal/cfsync"
+	"github.com/cloud-gov/billing/internal/db"
+)
+
+const customerLabel = "billing.cloud.gov/customer-id"
+
+type mockOrgs []*resource.Organization
+
+func (m mockOrgs) OrgsList(context.Context, *client.OrganizationListOptions) ([]*resource.Organization, error) {
+	return m, nil
+}
+
+// stubDB keeps orgs in memory. Customers that exist are listed in customers.
+type stubDB struct {
+	orgs      map[pgtype.UUID]db.CFOrg
+	customers map[pgtype.UUID]bool
+}
+
+func (s *stubDB) SyncCFOrg(_ context.Context, arg db.SyncCFOrgParams) (db.CFOrg, error) {
+	o := s.orgs[arg.ID]
+	o.ID, o.Name, o.Suspended, o.Labels, o.Annotations, o.SyncedAt = arg.ID, arg.Name, arg.Suspended, arg.Labels, arg.Annotations, arg.SyncedAt
+	s.orgs[arg.ID] = o
+	return o, nil
+}
+
+func (s *stubDB) GetCustomer(_ context.Context, id pgtype.UUID) (db.Customer, error) {
+	if !s.customers[id] {
+		return db.Customer{}, pgx.ErrNoRows
+	}
+	return db.Customer{ID: id}, nil
+}
+
+func (s *stubDB) SetCFOrgCustomer(_ context.Context, arg db.SetCFOrgCustomerParams) (db.CFOrg, error) {
+	o := s.orgs[arg.ID]
+	o.CustomerID = arg.CustomerID
+	s.orgs[arg.ID] = o
+	return o, nil
+}
+
+func uuid(s string) pgtype.UUID {
+	var u pgtype.UUID
+	if err := u.Scan(s); err != nil {
+		panic(err)
+	}
+	return u
+}
+
+func org(guid, name string, labels map[string]*string) *resource.Organization {
+	return &resource.Organization{
+		Name:     name,
+		Metadata: &resource.Metadata{Labels: labels},
+		Resource: resource.Resource{GUID: guid},
+	}
+}
+
+func ptr(s string) *string {
+	return &s
+}
+
+func TestSyncOrgs(t *testing.T) {
+	var (
+		labeled     = "0b1b6c8e-8d0a-4d0e-9a55-5a0e3b0e0001"
+		unlabeled   = "0b1b6c8e-8d0a-4d0e-9a55-5a0e3b0e0002"
+		badLabel    = "0b1b6c8e-8d0a-4d0e-9a55-5a0e3b0e0003"
+		customer    = "5e0f4d3c-2b1a-4c9d-8e7f-6a5b4c3d2e01"
+		manual      = "5e0f4d3c-2b1a-4c9d-8e7f-6a5b4c3d2e02"
+		nonexistent = "5e0f4d3c-2b1a-4c9d-8e7f-6a5b4c3d2e03"
+	)
+	cf := mockOrgs{
+		org(labeled, "labeled", map[string]*string{customerLabel: ptr(customer), "env": ptr("prod")}),
+		org(unlabeled, "unlabeled", nil),
+		org(badLabel, "bad-label", map[string]*string{customerLabel: ptr(nonexistent)}),
+	}
+	cf[1].Suspended = true
+	q := &stubDB{
+		orgs: map[pgtype.UUID]db.CFOrg{
+			// Orgs assigned manually keep their customer if they have no label.
+			uuid(unlabeled): {ID: uuid(unlabeled), CustomerID: uuid(manual)},
+		},
+		customers: map[pgtype.UUID]bool{uuid(customer): true, uuid(manual): true},
+	}
+	now := time.Date(2025, time.March, 1, 0, 0, 0, 0, time.UTC)
+
+	res, err := cfsync.SyncOrgs(t.Context(), slog.New(slog.DiscardHandler), cf, q, customerLabel, now)
+	if err != nil {
+		t.Fatal("syncing orgs:", err)
+	}
+	if res.Synced != 3 || res.Assigned != 1 {
+		t.Fatalf("expected 3 synced and 1 assigned, got %+v", res)
+	}
+
+	got := q.orgs[uuid(labeled)]
+	if got.Name.String != "labeled" || got.CustomerID != uuid(customer) || !got.SyncedAt.Time.Equal(now) {
+		t.Errorf("unexpected labeled org %+v", got)
+	}
+	var labels map[string]string
+	if err := json.Unmarshal(got.Labels, &labels); err != nil || labels["env"] != "prod" {
+		t.Errorf("expected labels to be stored as JSON, got %s", got.Labels)
+	}
+	if string(got.Annotations) != "{}" {
+		t.Errorf("expected empty annotations, got %s", got.Annotations)
+	}
+
+	got = q.orgs[uuid(unlabeled)]
+	if !got.Suspended || got.CustomerID != uuid(manual) {
+		t.Errorf("unexpected unlabeled org %+v", got)
+	}
+
+	got = q.orgs[uuid(badLabel)]
+	if got.CustomerID.Valid {
+		t.Errorf("expected org labeled with a nonexistent customer to stay unassigned, got %+v", got)
+	}
+
+	// Syncing without a customer label only updates names and metadata.
+	res, err = cfsync.SyncOrgs(t.Context(), slog.New(slog.DiscardHandler), cf, q, "", now)
+	if err != nil {
+		t.Fatal("syncing orgs:", err)
+	}
+	if res.Synced != 3 || res.Assigned != 0 {
+		t.Fatalf("expected 3 synced and 0 assigned, got %+v", res)
+	}
+}
@@ -2,10 +2,20 @@ package config
 
 import (
 	"errors"
+	"fmt"
 	"log/slog"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 )
 
+// JobTimeout is how long background jobs may run before their context is cancelled.
+const JobTimeout = 10 * time.Minute
+
+// MaxMeterTimeout is the longest a meter may take to read usage. Meters must time out before the job reading them does, with time left to record the measurements of the meters that succeeded.
+const MaxMeterTimeout = JobTimeout - 2*time.Minute
+
 type Config struct {
 	CFApiUrl string
 	// CFClientID is the ID of the client created in UAA with permission to read data from CAPI. Because the billing service is the resource server in the OIDC relationship, it doubles as the audience claim in JWTs.
@@ -15,6 +25,44 @@ type Config struct {
 	Port           string
 	LogLevel       slog.Level
 	Issuer         string
+	// CFOrgCustomerLabel is the key of the CF org label whose value is the ID of the customer the org belongs to, e.g. billing.cloud.gov/customer-id. If empty, orgs are not assigned to customers automatically.
+	CFOrgCustomerLabel string
+	// UsageEventMeter enables the meter that measures usage from CF usage events alongside the snapshot meters, so the two can be compared.
+	UsageEventMeter bool
+	// AWSCURDir is a local directory containing AWS Cost and Usage Report files. If neither it nor AWSCURS3Bucket is set, AWS costs are not measured.
+	AWSCURDir string
+	// AWSCURS3Bucket is the S3 bucket containing AWS Cost and Usage Report files. AWSCURS3Endpoint may point to any S3-compatible object store, like a local MinIO server.
+	AWSCURS3Bucket          string
+	AWSCURS3Prefix          string
+	AWSCURS3Endpoint        string
+	AWSCURS3Region          string
+	AWSCURS3AccessKeyID     string
+	AWSCURS3SecretAccessKey string
+	// AWSCURS3Insecure disables TLS when connecting to AWSCURS3Endpoint. It is for local development only.
+	AWSCURS3Insecure bool
+	// AWSCUROrgTag is the key of the AWS resource tag whose value is the GUID of the CF org that owns the resource.
+	AWSCUROrgTag string
+	// AWSCURDelay is how long AWS takes to include usage in reports. Each reading measures costs from this long before the reading.
+	AWSCURDelay time.Duration
+	// MeterTimeout is how long each meter may take to read usage. MeterTimeouts overrides it for individual meters, keyed by meter name. Neither may exceed MaxMeterTimeout.
+	MeterTimeout  time.Duration
+	MeterTimeouts map[string]time.Duration
+	// WorkshopMeter enables the meter that measures usage of Kubernetes namespaces on the Workshop platform.
+	WorkshopMeter bool
+	// WorkshopKubeconfig is the path to a kubeconfig file for the Workshop cluster. If empty, the in-cluster configuration is used.
+	WorkshopKubeconfig string
+	// WorkshopOrgLabel is the key of the namespace label whose value is the GUID of the CF org that owns the namespace.
+	WorkshopOrgLabel string
+	// AlertSMTPAddr is the host and port of the mail server used to send balance alerts. If empty, alerts are not sent by email.
+	AlertSMTPAddr     string
+	AlertSMTPFrom     string
+	AlertSMTPTo       []string
+	AlertSMTPUsername string
+	AlertSMTPPassword string
+	// AlertWebhookURL is the URL to which balance alerts are posted as JSON. If empty, alerts are not posted.
+	AlertWebhookURL string
+	// USDPerCredit is the price of a credit in US dollars. It converts credits to dollars in cost exports, which must be billed in a real currency.
+	USDPerCredit float64
 }
 
 func New() (Config, error) {
@@ -46,5 +94,94 @@ func New() (Config, error) {
 	if c.Issuer == "" {
 		return Config{}, errors.New("reading OIDC_ISSUER")
 	}
+
+	c.CFOrgCustomerLabel = os.Getenv("CF_ORG_CUSTOMER_LABEL")
+	c.UsageEventMeter = os.Getenv("USAGE_EVENT_METER") == "true"
+
+	c.MeterTimeout = 5 * time.Minute
+	if s := os.Getenv("METER_TIMEOUT"); s != "" {
+		c.MeterTimeout, err = time.ParseDuration(s)
+		if err != nil {
+			return Config{}, fmt.Errorf("reading METER_TIMEOUT: %w", err)
+		}
+		if c.MeterTimeout > MaxMeterTimeout {
+			return Config{}, fmt.Errorf("reading METER_TIMEOUT: %v exceeds the maximum of %v", c.MeterTimeout, MaxMeterTimeout)
+		}
+	}
+	// METER_TIMEOUTS is a comma-separated list of meter=duration pairs, e.g. awscur=30m,cfapps=5m.
+	c.MeterTimeouts = map[string]time.Duration{}
+	for _, pair := range strings.Split(os.Getenv("METER_TIMEOUTS"), ",") {
+		if pair = strings.TrimSpace(pair); pair == "" {
+			continue
+		}
+		name, d, ok := strings.Cut(pair, "=")
+		if !ok {
+			return Config{}, fmt.Errorf("reading METER_TIMEOUTS: expected meter=duration, got %q", pair)
+		}
+		c.MeterTimeouts[name], err = time.ParseDuration(d)
+		if err != nil {
+			return Config{}, fmt.Errorf("reading METER_TIMEOUTS: %w", err)
+		}
+		if c.MeterTimeouts[name] > MaxMeterTimeout {
+			return Config{}, fmt.Errorf("reading METER_TIMEOUTS: %v for %v exceeds the maximum of %v", c.MeterTimeouts[name], name, MaxMeterTimeout)
+		}
+	}
+
+	c.WorkshopMeter = os.Getenv("WORKSHOP_METER") == "true"
+	c.WorkshopKubeconfig = os.Getenv("WORKSHOP_KUBECONFIG")
+	c.WorkshopOrgLabel = os.Getenv("WORKSHOP_ORG_LABEL")
+	if c.WorkshopOrgLabel == "" {
+		c.WorkshopOrgLabel = "billing.cloud.gov/cf-org-guid"
+	}
+
+	c.AWSCURDir = os.Getenv("AWS_CUR_DIR")
+	c.AWSCURS3Bucket = os.Getenv("AWS_CUR_S3_BUCKET")
+	c.AWSCURS3Prefix = os.Getenv("AWS_CUR_S3_PREFIX")
+	c.AWSCURS3Endpoint = os.Getenv("AWS_CUR_S3_ENDPOINT")
+	if c.AWSCURS3Endpoint == "" {
+		c.AWSCURS3Endpoint = "s3.amazonaws.com"
+	}
+	c.AWSCURS3Region = os.Getenv("AWS_CUR_S3_REGION")
+	c.AWSCURS3AccessKeyID = os.Getenv("AWS_CUR_S3_ACCESS_KEY_ID")
+	c.AWSCURS3SecretAccessKey = os.Getenv("AWS_CUR_S3_SECRET_ACCESS_KEY")
+	c.AWSCURS3Insecure = os.Getenv("AWS_CUR_S3_INSECURE") == "true"
+	c.AWSCUROrgTag = os.Getenv("AWS_CUR_ORG_TAG")
+	if c.AWSCUROrgTag == "" {
+		c.AWSCUROrgTag = "Organization GUID"
+	}
+	c.AWSCURDelay = 48 * time.Hour
+	if s := os.Getenv("AWS_CUR_DELAY"); s != "" {
+		c.AWSCURDelay, err = time.ParseDuration(s)
+		if err != nil {
+			return Config{}, fmt.Errorf("reading AWS_CUR_DELAY: %w", err)
+		}
+	}
+
+	c.AlertSMTPAddr = os.Getenv("ALERT_SMTP_ADDR")
+	if c.AlertSMTPAddr != "" {
+		c.AlertSMTPFrom = os.Getenv("ALERT_SMTP_FROM")
+		if c.AlertSMTPFrom == "" {
+			return Config{}, errors.New("reading ALERT_SMTP_FROM")
+		}
+		for _, addr := range strings.Split(os.Getenv("ALERT_SMTP_TO"), ",") {
+			if addr = strings.TrimSpace(addr); addr != "" {
+				c.AlertSMTPTo = append(c.AlertSMTPTo, addr)
+			}
+		}
+		if len(c.AlertSMTPTo) == 0 {
+			return Config{}, errors.New("reading ALERT_SMTP_TO")
+		}
+		c.AlertSMTPUsername = os.Getenv("ALERT_SMTP_USERNAME")
+		c.AlertSMTPPassword = os.Getenv("ALERT_SMTP_PASSWORD")
+	}
+	c.AlertWebhookURL = os.Getenv("ALERT_WEBHOOK_URL")
+
+	c.USDPerCredit = 50
+	if s := os.Getenv("USD_PER_CREDIT"); s != "" {
+		c.USDPerCredit, err = strconv.ParseFloat(s, 64)
+		if err != nil {
+			return Config{}, fmt.Errorf("reading USD_PER_CREDIT: %w", err)
+		}
+	}
 	return c, nil
 }
@@ -7,6 +7,8 @@ package db
 
 import (
 	"context"
+
+	"github.com/jackc/pgx/v5/pgtype"
 )
 
 const getAccountForCustomerAndType = `-- name: GetAccountForCustomerAndType :one
@@ -29,3 +31,59 @@ func (q *Queries) GetAccountForCustomerAndType(ctx context.Context, arg GetAccou
 	err := row.Scan(&i.ID, &i.Type, &i.CustomerID)
 	return i, err
 }
+
+const getCustomerBalances = `-- name: GetCustomerBalances :many
+SELECT
+  at.id AS account_type,
+  at.name AS account_type_name,
+  a.id AS account_id,
+  COALESCE(SUM(e.amount_microcredits) FILTER (WHERE e.direction = 1), 0)::bigint AS debits_microcredits,
+  COALESCE(SUM(e.amount_microcredits) FILTER (WHERE e.direction = -1), 0)::bigint AS credits_microcredits,
+  (
+    CASE at.name WHEN 'liabilities' THEN -1 ELSE 1 END
+    * COALESCE(SUM(e.amount_microcredits * e.direction), 0)
+  )::bigint AS balance_microcredits
+FROM account AS a
+INNER JOIN account_type AS at ON a.type = at.id
+LEFT JOIN entry AS e ON a.id = e.account_id
+WHERE a.customer_id = $1
+GROUP BY at.id, at.name, a.id
+ORDER BY at.id
+`
+
+type GetCustomerBalancesRow struct {
+	AccountType         int32
+	AccountTypeName     string
+	AccountID           int32
+	DebitsMicrocredits  int64
+	CreditsMicrocredits int64
+	BalanceMicrocredits int64
+}
+
+// GetCustomerBalances returns the total debits, credits, and balance of each of the customer's accounts. Balances are positive when the account holds value in the sense the billing service uses it: credit_pool is the number of credits remaining, credits_used is the number consumed, and liabilities is the number owed to the customer. Liabilities increase with credits and every other account increases with debits.
+func (q *Queries) GetCustomerBalances(ctx context.Context, customerID pgtype.UUID) ([]GetCustomerBalancesRow, error) {
+	rows, err := q.db.Query(ctx, getCustomerBalances, customerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetCustomerBalancesRow
+	for rows.Next() {
+		var i GetCustomerBalancesRow
+		if err := rows.Scan(
+			&i.AccountType,
+			&i.AccountTypeName,
+			&i.AccountID,
+			&i.DebitsMicrocredits,
+			&i.CreditsMicrocredits,
+			&i.BalanceMicrocredits,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
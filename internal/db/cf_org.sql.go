@@ -27,7 +27,7 @@ func (q *Queries) BulkCreateCFOrgs(ctx context.Context, ids []pgtype.UUID) error
 const createCFOrg = `-- name: CreateCFOrg :one
 INSERT INTO cf_org (id, name, customer_id)
 VALUES ($1, $2, $3)
-RETURNING id, name, customer_id
+RETURNING id, name, customer_id, suspended, labels, annotations, synced_at
 `
 
 type CreateCFOrgParams struct {
@@ -39,7 +39,15 @@ type CreateCFOrgParams struct {
 func (q *Queries) CreateCFOrg(ctx context.Context, arg CreateCFOrgParams) (CFOrg, error) {
 	row := q.db.QueryRow(ctx, createCFOrg, arg.ID, arg.Name, arg.CustomerID)
 	var i CFOrg
-	err := row.Scan(&i.ID, &i.Name, &i.CustomerID)
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.CustomerID,
+		&i.Suspended,
+		&i.Labels,
+		&i.Annotations,
+		&i.SyncedAt,
+	)
 	return i, err
 }
 
@@ -54,19 +62,27 @@ func (q *Queries) DeleteCFOrg(ctx context.Context, id pgtype.UUID) error {
 }
 
 const getCFOrg = `-- name: GetCFOrg :one
-SELECT id, name, customer_id FROM cf_org
+SELECT id, name, customer_id, suspended, labels, annotations, synced_at FROM cf_org
 WHERE id = $1 LIMIT 1
 `
 
 func (q *Queries) GetCFOrg(ctx context.Context, id pgtype.UUID) (CFOrg, error) {
 	row := q.db.QueryRow(ctx, getCFOrg, id)
 	var i CFOrg
-	err := row.Scan(&i.ID, &i.Name, &i.CustomerID)
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.CustomerID,
+		&i.Suspended,
+		&i.Labels,
+		&i.Annotations,
+		&i.SyncedAt,
+	)
 	return i, err
 }
 
 const listCFOrgs = `-- name: ListCFOrgs :many
-SELECT id, name, customer_id FROM cf_org
+SELECT id, name, customer_id, suspended, labels, annotations, synced_at FROM cf_org
 ORDER BY name
 `
 
@@ -79,7 +95,15 @@ func (q *Queries) ListCFOrgs(ctx context.Context) ([]CFOrg, error) {
 	var items []CFOrg
 	for rows.Next() {
 		var i CFOrg
-		if err := rows.Scan(&i.ID, &i.Name, &i.CustomerID); err != nil {
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.CustomerID,
+			&i.Suspended,
+			&i.Labels,
+			&i.Annotations,
+			&i.SyncedAt,
+		); err != nil {
 			return nil, err
 		}
 		items = append(items, i)
@@ -90,6 +114,172 @@ func (q *Queries) ListCFOrgs(ctx context.Context) ([]CFOrg, error) {
 	return items, nil
 }
 
+const listCFOrgsByCustomer = `-- name: ListCFOrgsByCustomer :many
+SELECT id, name, customer_id, suspended, labels, annotations, synced_at FROM cf_org
+WHERE customer_id = $1
+ORDER BY name, id
+`
+
+func (q *Queries) ListCFOrgsByCustomer(ctx context.Context, customerID pgtype.UUID) ([]CFOrg, error) {
+	rows, err := q.db.Query(ctx, listCFOrgsByCustomer, customerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []CFOrg
+	for rows.Next() {
+		var i CFOrg
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.CustomerID,
+			&i.Suspended,
+			&i.Labels,
+			&i.Annotations,
+			&i.SyncedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listOrphanCFOrgs = `-- name: ListOrphanCFOrgs :many
+SELECT
+  o.id, o.name, o.customer_id, o.suspended, o.labels, o.annotations, o.synced_at,
+  COUNT(*)::bigint AS measurement_count,
+  MAX(rd.created_at_utc)::timestamptz AS last_measured_at
+FROM cf_org AS o
+INNER JOIN resource AS r ON o.id = r.cf_org_id
+INNER JOIN measurement AS m ON r.meter = m.meter AND r.natural_id = m.resource_natural_id
+INNER JOIN reading AS rd ON m.reading_id = rd.id
+WHERE o.customer_id IS NULL
+GROUP BY o.id
+ORDER BY last_measured_at DESC, o.id
+`
+
+type ListOrphanCFOrgsRow struct {
+	CFOrg            CFOrg
+	MeasurementCount int64
+	LastMeasuredAt   pgtype.Timestamptz
+}
+
+// ListOrphanCFOrgs lists CF orgs that are not assigned to a customer but have measurements, with the number of measurements and when the most recent was taken. Usage in these orgs cannot be billed until they are assigned.
+func (q *Queries) ListOrphanCFOrgs(ctx context.Context) ([]ListOrphanCFOrgsRow, error) {
+	rows, err := q.db.Query(ctx, listOrphanCFOrgs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListOrphanCFOrgsRow
+	for rows.Next() {
+		var i ListOrphanCFOrgsRow
+		if err := rows.Scan(
+			&i.CFOrg.ID,
+			&i.CFOrg.Name,
+			&i.CFOrg.CustomerID,
+			&i.CFOrg.Suspended,
+			&i.CFOrg.Labels,
+			&i.CFOrg.Annotations,
+			&i.CFOrg.SyncedAt,
+			&i.MeasurementCount,
+			&i.LastMeasuredAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const setCFOrgCustomer = `-- name: SetCFOrgCustomer :one
+INSERT INTO cf_org (id, customer_id)
+VALUES ($1, $2)
+ON CONFLICT (id) DO UPDATE
+SET customer_id = excluded.customer_id
+RETURNING id, name, customer_id, suspended, labels, annotations, synced_at
+`
+
+type SetCFOrgCustomerParams struct {
+	ID         pgtype.UUID
+	CustomerID pgtype.UUID
+}
+
+// SetCFOrgCustomer assigns the CF org to a customer, or unassigns it if customer_id is null. The org is created if it does not exist yet, so orgs can be assigned before their usage is first recorded.
+func (q *Queries) SetCFOrgCustomer(ctx context.Context, arg SetCFOrgCustomerParams) (CFOrg, error) {
+	row := q.db.QueryRow(ctx, setCFOrgCustomer, arg.ID, arg.CustomerID)
+	var i CFOrg
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.CustomerID,
+		&i.Suspended,
+		&i.Labels,
+		&i.Annotations,
+		&i.SyncedAt,
+	)
+	return i, err
+}
+
+const syncCFOrg = `-- name: SyncCFOrg :one
+INSERT INTO cf_org (id, name, suspended, labels, annotations, synced_at)
+VALUES (
+  $1,
+  $2,
+  $3,
+  $4,
+  $5,
+  $6
+)
+ON CONFLICT (id) DO UPDATE
+SET
+  name = excluded.name,
+  suspended = excluded.suspended,
+  labels = excluded.labels,
+  annotations = excluded.annotations,
+  synced_at = excluded.synced_at
+RETURNING id, name, customer_id, suspended, labels, annotations, synced_at
+`
+
+type SyncCFOrgParams struct {
+	ID          pgtype.UUID
+	Name        pgtype.Text
+	Suspended   bool
+	Labels      []byte
+	Annotations []byte
+	SyncedAt    pgtype.Timestamptz
+}
+
+// SyncCFOrg creates or updates a CF org with the name and metadata read from Cloud Foundry. The org's customer is not changed.
+func (q *Queries) SyncCFOrg(ctx context.Context, arg SyncCFOrgParams) (CFOrg, error) {
+	row := q.db.QueryRow(ctx, syncCFOrg,
+		arg.ID,
+		arg.Name,
+		arg.Suspended,
+		arg.Labels,
+		arg.Annotations,
+		arg.SyncedAt,
+	)
+	var i CFOrg
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.CustomerID,
+		&i.Suspended,
+		&i.Labels,
+		&i.Annotations,
+		&i.SyncedAt,
+	)
+	return i, err
+}
+
 const updateCFOrg = `-- name: UpdateCFOrg :exec
 UPDATE cf_org
   set name = $2
@@ -51,7 +51,7 @@ func (q *Queries) DeleteCustomer(ctx context.Context, id pgtype.UUID) error {
 }
 
 const getCustomer = `-- name: GetCustomer :one
-SELECT old_id, name, tier_id, id, path, slug FROM customer
+SELECT old_id, name, tier_id, id, path, slug, time_zone FROM customer
 WHERE id = $1 LIMIT 1
 `
 
@@ -65,12 +65,13 @@ func (q *Queries) GetCustomer(ctx context.Context, id pgtype.UUID) (Customer, er
 		&i.ID,
 		&i.Path,
 		&i.Slug,
+		&i.TimeZone,
 	)
 	return i, err
 }
 
 const getCustomersByName = `-- name: GetCustomersByName :many
-SELECT old_id, name, tier_id, id, path, slug FROM customer
+SELECT old_id, name, tier_id, id, path, slug, time_zone FROM customer
 WHERE name ~* $1
 `
 
@@ -90,6 +91,7 @@ func (q *Queries) GetCustomersByName(ctx context.Context, name string) ([]Custom
 			&i.ID,
 			&i.Path,
 			&i.Slug,
+			&i.TimeZone,
 		); err != nil {
 			return nil, err
 		}
@@ -102,7 +104,7 @@ func (q *Queries) GetCustomersByName(ctx context.Context, name string) ([]Custom
 }
 
 const listCustomers = `-- name: ListCustomers :many
-SELECT old_id, name, tier_id, id, path, slug FROM customer
+SELECT old_id, name, tier_id, id, path, slug, time_zone FROM customer
 ORDER BY name
 `
 
@@ -122,6 +124,7 @@ func (q *Queries) ListCustomers(ctx context.Context) ([]Customer, error) {
 			&i.ID,
 			&i.Path,
 			&i.Slug,
+			&i.TimeZone,
 		); err != nil {
 			return nil, err
 		}
@@ -133,18 +136,75 @@ func (q *Queries) ListCustomers(ctx context.Context) ([]Customer, error) {
 	return items, nil
 }
 
-const updateCustomer = `-- name: UpdateCustomer :exec
+const listCustomersByCFOrgIDs = `-- name: ListCustomersByCFOrgIDs :many
+SELECT c.old_id, c.name, c.tier_id, c.id, c.path, c.slug, c.time_zone
+FROM customer AS c
+WHERE EXISTS (
+  SELECT 1
+  FROM cf_org AS o
+  WHERE
+    o.customer_id = c.id
+    AND o.id = ANY($1::uuid [])
+)
+ORDER BY c.name
+`
+
+// ListCustomersByCFOrgIDs lists the customers that own any of the given CF orgs.
+func (q *Queries) ListCustomersByCFOrgIDs(ctx context.Context, cfOrgIds []pgtype.UUID) ([]Customer, error) {
+	rows, err := q.db.Query(ctx, listCustomersByCFOrgIDs, cfOrgIds)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Customer
+	for rows.Next() {
+		var i Customer
+		if err := rows.Scan(
+			&i.OldID,
+			&i.Name,
+			&i.TierID,
+			&i.ID,
+			&i.Path,
+			&i.Slug,
+			&i.TimeZone,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const updateCustomer = `-- name: UpdateCustomer :one
 UPDATE customer
-  set name = $2
-WHERE id = $1
+SET
+  name = coalesce($1, name),
+  time_zone = coalesce($2, time_zone)
+WHERE id = $3
+RETURNING old_id, name, tier_id, id, path, slug, time_zone
 `
 
 type UpdateCustomerParams struct {
-	ID   pgtype.UUID
-	Name string
+	Name     pgtype.Text
+	TimeZone pgtype.Text
+	ID       pgtype.UUID
 }
 
-func (q *Queries) UpdateCustomer(ctx context.Context, arg UpdateCustomerParams) error {
-	_, err := q.db.Exec(ctx, updateCustomer, arg.ID, arg.Name)
-	return err
+// UpdateCustomer changes the customer's name and time zone. Null arguments leave their columns unchanged.
+func (q *Queries) UpdateCustomer(ctx context.Context, arg UpdateCustomerParams) (Customer, error) {
+	row := q.db.QueryRow(ctx, updateCustomer, arg.Name, arg.TimeZone, arg.ID)
+	var i Customer
+	err := row.Scan(
+		&i.OldID,
+		&i.Name,
+		&i.TierID,
+		&i.ID,
+		&i.Path,
+		&i.Slug,
+		&i.TimeZone,
+	)
+	return i, err
 }
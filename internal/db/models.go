@@ -11,16 +11,64 @@ import (
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
+type AlertKind string
+
+const (
+	AlertKindConsumed            AlertKind = "consumed"
+	AlertKindProjectedExhaustion AlertKind = "projected_exhaustion"
+)
+
+func (e *AlertKind) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case []byte:
+		*e = AlertKind(s)
+	case string:
+		*e = AlertKind(s)
+	default:
+		return fmt.Errorf("unsupported scan type for AlertKind: %T", src)
+	}
+	return nil
+}
+
+type NullAlertKind struct {
+	AlertKind AlertKind
+	Valid     bool // Valid is true if AlertKind is not NULL
+}
+
+// Scan implements the Scanner interface.
+func (ns *NullAlertKind) Scan(value interface{}) error {
+	if value == nil {
+		ns.AlertKind, ns.Valid = "", false
+		return nil
+	}
+	ns.Valid = true
+	return ns.AlertKind.Scan(value)
+}
+
+// Value implements the driver Valuer interface.
+func (ns NullAlertKind) Value() (driver.Value, error) {
+	if !ns.Valid {
+		return nil, nil
+	}
+	return string(ns.AlertKind), nil
+}
+
 // TransactionType explains why the transaction was made. Each means:
 //   - iaa_pop_start: The IAA Period of Performance started.
 //   - iaa_pop_end: The IAA Period of Performance ended.
 //   - usage_post: Customer usage of was posted, i.e. their account balance was updated to reflect their usage.
+//   - reversal: A previous transaction was undone by posting offsetting entries. original_transaction_id refers to the reversed transaction.
+//   - adjustment: The amount of a previous transaction was corrected by posting the difference to the same accounts. original_transaction_id refers to the adjusted transaction.
+//   - tier_grant: The credits included in the customer's tier were added to their credit pool for the month.
 type TransactionType string
 
 const (
 	TransactionTypeIaaPopStart TransactionType = "iaa_pop_start"
 	TransactionTypeIaaPopEnd   TransactionType = "iaa_pop_end"
 	TransactionTypeUsagePost   TransactionType = "usage_post"
+	TransactionTypeReversal    TransactionType = "reversal"
+	TransactionTypeAdjustment  TransactionType = "adjustment"
+	TransactionTypeTierGrant   TransactionType = "tier_grant"
 )
 
 func (e *TransactionType) Scan(src interface{}) error {
@@ -70,10 +118,57 @@ type AccountType struct {
 	Normal pgtype.Int4
 }
 
+// Alert records that a customer's credits are running low. Each alert is recorded once per threshold and funding level, so alerts are sent again after a customer purchases more credits.
+type Alert struct {
+	ID         int32
+	CustomerID pgtype.UUID
+	// Kind is consumed if a percent_consumed threshold was reached, or projected_exhaustion if the customer's credits are projected to run out before the end of their latest period of performance.
+	Kind AlertKind
+	// PercentConsumed is the threshold that was reached, for alerts of kind consumed. It is 0 for other kinds.
+	PercentConsumed int32
+	// FundedMicrocredits is the number of credits provided by the customer's active agreements when the alert was recorded.
+	FundedMicrocredits int64
+	// RemainingMicrocredits is the customer's credit pool balance less usage that has been measured but not yet posted.
+	RemainingMicrocredits int64
+	// DailyBurnMicrocredits is the customer's average daily usage over the recent window used for projection.
+	DailyBurnMicrocredits int64
+	// ProjectedExhaustionDate is the date in business time (America/New_York) on which remaining credits are projected to run out at the daily burn rate. It is null if the customer has no recent usage.
+	ProjectedExhaustionDate pgtype.Date
+	// FundedUntil is the last day of the latest period of performance of the customer's active agreements.
+	FundedUntil pgtype.Date
+	CreatedAt   pgtype.Timestamptz
+	// NotifiedAt is when the alert was delivered. It is null until then.
+	NotifiedAt pgtype.Timestamptz
+}
+
+// AlertDelivery records that a notifier, like email or a webhook, delivered an alert. When some notifiers fail, only they are retried, so recipients of the others do not receive the alert again. The alert is marked notified once every notifier has delivered it.
+type AlertDelivery struct {
+	AlertID int32
+	// Notifier is the name of the notifier, e.g. email or webhook.
+	Notifier    string
+	DeliveredAt pgtype.Timestamptz
+}
+
+// AlertThreshold is a percentage of a customer's funded credits that, once consumed, causes an alert to be sent. Customers without thresholds use the defaults in the application.
+type AlertThreshold struct {
+	CustomerID pgtype.UUID
+	// PercentConsumed is the percentage of funded credits that must be consumed for the alert to be sent.
+	PercentConsumed int32
+}
+
 type CFOrg struct {
-	ID         pgtype.UUID
+	ID pgtype.UUID
+	// Name is the name of the organization in Cloud Foundry. It is null until the organization is first synced.
 	Name       pgtype.Text
 	CustomerID pgtype.UUID
+	// Suspended is true if the organization is suspended in Cloud Foundry.
+	Suspended bool
+	// Labels are the organization's Cloud Foundry metadata labels, as a JSON object.
+	Labels []byte
+	// Annotations are the organization's Cloud Foundry metadata annotations, as a JSON object.
+	Annotations []byte
+	// SyncedAt is when the organization was last synced from Cloud Foundry. It is null if it has never been synced, e.g. because it was deleted before the first sync.
+	SyncedAt pgtype.Timestamptz
 }
 
 type Customer struct {
@@ -83,6 +178,8 @@ type Customer struct {
 	ID     pgtype.UUID
 	Path   pgtype.Text
 	Slug   pgtype.Text
+	// TimeZone is the IANA time zone in which the customer's usage is reported, e.g. America/Chicago. Usage periods, like days and fiscal years, begin at midnight in this zone. Months are still closed and invoiced in business time; see bounds_month_prev.
+	TimeZone string
 }
 
 type Entry struct {
@@ -92,6 +189,80 @@ type Entry struct {
 	AmountMicrocredits pgtype.Int8
 }
 
+// IAA is an interagency agreement through which a customer purchases credits. Credits are added to the customer's credit pool when the Period of Performance (PoP) starts and unused credits expire when it ends.
+type IAA struct {
+	ID         int32
+	CustomerID pgtype.UUID
+	// Number is the agreement number assigned outside the billing service, e.g. in G-Invoicing.
+	Number string
+	// AmountMicrocredits is the number of microcredits purchased through the agreement.
+	AmountMicrocredits int64
+	// RolledOverMicrocredits is the number of unused microcredits carried over from a previous agreement instead of expiring. They expire when this agreement ends.
+	RolledOverMicrocredits int64
+	// PopStart is the first day of the Period of Performance in business time (America/New_York).
+	PopStart pgtype.Date
+	// PopEnd is the last day of the Period of Performance in business time (America/New_York), inclusive.
+	PopEnd pgtype.Date
+	// Rollover is true if unused credits should be carried over to the customer's next agreement when this agreement ends, rather than expiring.
+	Rollover bool
+	// StartTransactionID is the iaa_pop_start transaction that added the purchased credits to the customer's credit pool. It is null until the PoP starts.
+	StartTransactionID pgtype.Int4
+	// EndTransactionID is the iaa_pop_end transaction that expired unused credits. It is null until the PoP ends, and remains null if no credits expired.
+	EndTransactionID pgtype.Int4
+	// EndedAt is when the end of the PoP was processed. It is null until then.
+	EndedAt   pgtype.Timestamptz
+	CreatedAt pgtype.Timestamptz
+}
+
+// Invoice documents the usage a customer was charged for in a closed month. One invoice is created for each usage_post transaction, and invoices are never modified: if the transaction is reversed and the month is closed again, the new transaction gets a new invoice.
+type Invoice struct {
+	ID int32
+	// Number identifies the invoice to customers. Invoices are numbered sequentially without gaps, in the order they are created.
+	Number int32
+	// TransactionID is the usage_post transaction the invoice documents.
+	TransactionID int32
+	CustomerID    pgtype.UUID
+	// CustomerName is the name of the customer when the invoice was created.
+	CustomerName string
+	// IAANumber is the number of the customer's interagency agreement whose Period of Performance included the last day of the period, if any.
+	IAANumber   pgtype.Text
+	PeriodStart pgtype.Timestamptz
+	PeriodEnd   pgtype.Timestamptz
+	// UsageMicrocredits is the amount of the transaction: the credits drawn from the customer's credit pool for the period. It is the sum of the amounts of the invoice's lines.
+	UsageMicrocredits int64
+	// CreditPoolBeforeMicrocredits is the balance of the customer's credit_pool account before the transaction, following the conventions of GetCustomerBalances.
+	CreditPoolBeforeMicrocredits int64
+	// CreditPoolAfterMicrocredits is the balance of the customer's credit_pool account after the transaction.
+	CreditPoolAfterMicrocredits int64
+	CreatedAt                   pgtype.Timestamptz
+}
+
+// InvoiceLine is the usage of one resource kind in one CF org and space, at one price, on an invoice.
+type InvoiceLine struct {
+	InvoiceID int32
+	Line      int32
+	CFOrgID   pgtype.UUID
+	// CFOrgName is the name of the CF org when the invoice was created, or its ID if the org has not been synced.
+	CFOrgName string
+	// Space is the slug of the CF space of the resources, as recorded in their resource nodes. It is empty for resources that do not belong to a space, like AWS resources.
+	Space         string
+	Meter         string
+	KindNaturalID string
+	KindName      string
+	PriceID       int32
+	UnitOfMeasure string
+	// Accrues is true if the kind accrues cost over time, so Quantity is in unit-hours.
+	Accrues bool
+	// MicrocreditsPerUnit and Unit are the unit price of the line: MicrocreditsPerUnit microcredits per Unit units of measure, or unit-hours if the kind accrues.
+	MicrocreditsPerUnit int64
+	Unit                int64
+	// Quantity is the total measured value, in units of measure, or unit-hours if the kind accrues.
+	Quantity           pgtype.Numeric
+	AmountMicrocredits int64
+	// EstimatedQuantity is the part of Quantity from backfilled readings, whose usage was estimated rather than measured. See reading.provenance.
+	EstimatedQuantity pgtype.Numeric
+}
+
 type Measurement struct {
 	ReadingID         int32
 	Meter             string
@@ -104,6 +275,23 @@ type Measurement struct {
 	PriceID       pgtype.Int8
 }
 
+// MeasurementAnomaly is a problem a meter found while measuring a resource, like an app whose space could not be found. Meters return as much of the measurement as they can, so anomalies are kept alongside measurements to show how much usage may be misattributed.
+type MeasurementAnomaly struct {
+	ReadingID int32
+	// Meter and ResourceNaturalID identify the resource. If Recorded is true, they refer to a row in resource and, with ReadingID, a row in measurement. Otherwise the resource could not be recorded.
+	Meter             string
+	ResourceNaturalID string
+	KindNaturalID     string
+	// CFOrgID is the CF org the meter attributed the usage to. It is null if the meter could not determine the org, i.e. the usage is orphaned.
+	CFOrgID    pgtype.UUID
+	CustomerID pgtype.UUID
+	Value      int32
+	// Errors are the messages of the errors the meter returned for the measurement.
+	Errors []string
+	// Recorded is true if the measurement was recorded. Measurements without a CF org cannot be recorded, so they are only recorded as anomalies.
+	Recorded bool
+}
+
 // A Meter reads usage information from a system in Cloud.gov. It also namespaces natural IDs for resources and resource_kinds; meter + natural_id is a primary key.
 type Meter struct {
 	Name string
@@ -116,7 +304,8 @@ type Price struct {
 	UnitOfMeasure       string
 	MicrocreditsPerUnit int64
 	Unit                int64
-	ValidDuring         pgtype.Range[pgtype.Timestamptz]
+	// ValidDuring is the period during which the price applies to readings. Periods for the same resource kind do not overlap. An unbounded upper bound means the price applies until a new price is published.
+	ValidDuring pgtype.Range[pgtype.Timestamptz]
 }
 
 type Reading struct {
@@ -127,6 +316,21 @@ type Reading struct {
 	Periodic bool
 	// CreatedAtUTC supplements CreatedAt, which does not have a timezone. Values must be inserted into CreatedAt in UTC by the client. CreatedAt has a unique index on it to enforce readings being taken at most hourly. Because the index uses functions that are not volatility level IMMUTABLE, it cannot be used on a column with a timezone; hence the supplementary generated column.
 	CreatedAtUTC pgtype.Timestamptz
+	// Provenance is how the reading's measurements were obtained. Measured readings were read from meters. Interpolated and reconstructed readings were backfilled for hours in which no reading was taken: interpolated measurements are estimated from the readings before and after, and reconstructed measurements are derived from usage events. Usage in backfilled readings is estimated and should be disclosed as such.
+	Provenance string
+}
+
+// ReadingMeterStatus is the result of one meter during a reading. Meters are read concurrently and independently, so a reading may include measurements from some meters and not others. Readings taken before statuses were recorded have none.
+type ReadingMeterStatus struct {
+	ReadingID  int32
+	Meter      string
+	StartedAt  pgtype.Timestamptz
+	DurationMs int32
+	// Succeeded is false if the meter returned an error or timed out. Measurements from meters that did not succeed are not recorded.
+	Succeeded bool
+	// Error is the error returned by the meter, if it did not succeed.
+	Error            pgtype.Text
+	MeasurementCount int32
 }
 
 type Resource struct {
@@ -140,7 +344,20 @@ type Resource struct {
 type ResourceKind struct {
 	Meter     string
 	NaturalID string
-	Name      pgtype.Text
+	// Name is a human-readable name for the kind. For service plans, it is the name of the service offering followed by the name of the plan.
+	Name pgtype.Text
+	// Broker is the name of the service broker that provides the kind, if it is a service plan.
+	Broker pgtype.Text
+	// Active is false if new resources of this kind cannot be created, e.g. because the service plan is not available in Cloud Foundry.
+	Active bool
+	// DeprecatedAt is when the kind was first found to be missing from the catalog of the system it is read from. It is null if the kind is still in the catalog. Existing resources of a deprecated kind may still be measured.
+	DeprecatedAt pgtype.Timestamptz
+	// SyncedAt is when the kind was last synced from the catalog of the system it is read from. It is null if the kind has never been synced.
+	SyncedAt pgtype.Timestamptz
+	// UnitOfMeasure is the unit of the values of measurements of this kind, e.g. MB.
+	UnitOfMeasure pgtype.Text
+	// Accrues is true if measurements of this kind are a level of allocation, like MB of memory, that accrues cost over time. When priced, they are multiplied by the hours since the previous reading, so prices for the kind are per unit-hour. Other measurements are priced once per reading.
+	Accrues bool
 }
 
 type ResourceNode struct {
@@ -151,8 +368,9 @@ type ResourceNode struct {
 }
 
 type Tier struct {
-	ID          int32
-	Name        string
+	ID   int32
+	Name string
+	// TierCredits is the number of whole credits granted to each customer in the tier at the start of every month.
 	TierCredits int64
 }
 
@@ -163,4 +381,34 @@ type Transaction struct {
 	Type        TransactionType
 	// CustomerID is somewhat redundant because the Entry rows associated with a Transaction are associated with Accounts, which are associated with a Customer. However, we have to create a Transaction before we create an Entry (see post_usage, ins_tx as an example). To join Measurements, Transactions, Entries, and Accounts, Transaction needs a CustomerID.
 	CustomerID pgtype.UUID
+	// OriginalTransactionID is the transaction that this transaction corrects. For example, the transaction undone by a reversal. Posted transactions and their entries are never modified; corrections are posted as new transactions that refer to the original.
+	OriginalTransactionID pgtype.Int4
+}
+
+// UsageEventCheckpoint records the last usage event a meter processed from each source, e.g. CF app usage events, so the next reading only requests newer events.
+type UsageEventCheckpoint struct {
+	Meter string
+	// Source is the stream of events the checkpoint is for, e.g. app or service.
+	Source        string
+	LastEventGuid string
+	LastEventAt   pgtype.Timestamptz
+	UpdatedAt     pgtype.Timestamptz
+}
+
+// UsageEventInterval is a period during which a resource had a constant value, e.g. memory allocated to a process, as derived from usage events. A resource has a new interval each time its value changes. Intervals are kept until no reading can include them.
+type UsageEventInterval struct {
+	Meter             string
+	ResourceNaturalID string
+	KindNaturalID     string
+	Value             int32
+	StartedAt         pgtype.Timestamptz
+	// StoppedAt is when the resource stopped or its value changed. It is null if the interval is open.
+	StoppedAt pgtype.Timestamptz
+	CFOrgID   pgtype.UUID
+	SpaceGuid string
+	SpaceName string
+	// Name is a human-readable name for the resource, used to create its resource node.
+	Name string
+	// Instances is the number of instances the resource ran during the interval, e.g. of an app process. Value is the total of all instances. Intervals recorded before instances were counted have one.
+	Instances int32
 }
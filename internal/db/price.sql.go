@@ -11,6 +11,104 @@ import (
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
+const closePrice = `-- name: ClosePrice :one
+UPDATE price
+SET valid_during = tstzrange(lower(valid_during), $1::date::timestamp AT TIME ZONE 'America/New_York')
+WHERE meter = $2
+  AND kind_natural_id = $3
+  AND valid_during @> ($1::date::timestamp AT TIME ZONE 'America/New_York')
+  AND (lower_inf(valid_during) OR lower(valid_during) < $1::date::timestamp AT TIME ZONE 'America/New_York')
+RETURNING id, meter, kind_natural_id, unit_of_measure, microcredits_per_unit, unit, valid_during
+`
+
+type ClosePriceParams struct {
+	EffectiveDate pgtype.Date
+	Meter         string
+	KindNaturalID string
+}
+
+// ClosePrice ends the price for the resource kind that is valid at the start of effective_date in business time (America/New_York), so a new price can take effect then. It returns pgx.ErrNoRows if no price is valid then, or if the valid price takes effect at the same time.
+func (q *Queries) ClosePrice(ctx context.Context, arg ClosePriceParams) (Price, error) {
+	row := q.db.QueryRow(ctx, closePrice, arg.EffectiveDate, arg.Meter, arg.KindNaturalID)
+	var i Price
+	err := row.Scan(
+		&i.ID,
+		&i.Meter,
+		&i.KindNaturalID,
+		&i.UnitOfMeasure,
+		&i.MicrocreditsPerUnit,
+		&i.Unit,
+		&i.ValidDuring,
+	)
+	return i, err
+}
+
+const countPricedMeasurements = `-- name: CountPricedMeasurements :one
+SELECT COUNT(*)::bigint AS measurement_count
+FROM measurement AS m
+INNER JOIN reading AS rd ON m.reading_id = rd.id
+WHERE m.price_id = $1
+  AND rd.created_at_utc >= $2::date::timestamp AT TIME ZONE 'America/New_York'
+`
+
+type CountPricedMeasurementsParams struct {
+	PriceID       pgtype.Int8
+	EffectiveDate pgtype.Date
+}
+
+// CountPricedMeasurements counts measurements priced with the price whose readings were taken at or after the start of effective_date in business time (America/New_York).
+func (q *Queries) CountPricedMeasurements(ctx context.Context, arg CountPricedMeasurementsParams) (int64, error) {
+	row := q.db.QueryRow(ctx, countPricedMeasurements, arg.PriceID, arg.EffectiveDate)
+	var measurement_count int64
+	err := row.Scan(&measurement_count)
+	return measurement_count, err
+}
+
+const createPrice = `-- name: CreatePrice :one
+INSERT INTO price (meter, kind_natural_id, unit_of_measure, microcredits_per_unit, unit, valid_during)
+VALUES (
+  $1,
+  $2,
+  $3,
+  $4,
+  $5,
+  tstzrange($6::date::timestamp AT TIME ZONE 'America/New_York', NULL)
+)
+RETURNING id, meter, kind_natural_id, unit_of_measure, microcredits_per_unit, unit, valid_during
+`
+
+type CreatePriceParams struct {
+	Meter               string
+	KindNaturalID       string
+	UnitOfMeasure       string
+	MicrocreditsPerUnit int64
+	Unit                int64
+	EffectiveDate       pgtype.Date
+}
+
+// CreatePrice creates a price for the resource kind that is valid from the start of effective_date in business time (America/New_York) until a new price is published.
+func (q *Queries) CreatePrice(ctx context.Context, arg CreatePriceParams) (Price, error) {
+	row := q.db.QueryRow(ctx, createPrice,
+		arg.Meter,
+		arg.KindNaturalID,
+		arg.UnitOfMeasure,
+		arg.MicrocreditsPerUnit,
+		arg.Unit,
+		arg.EffectiveDate,
+	)
+	var i Price
+	err := row.Scan(
+		&i.ID,
+		&i.Meter,
+		&i.KindNaturalID,
+		&i.UnitOfMeasure,
+		&i.MicrocreditsPerUnit,
+		&i.Unit,
+		&i.ValidDuring,
+	)
+	return i, err
+}
+
 const createPriceWithID = `-- name: CreatePriceWithID :one
 INSERT INTO price (id, meter, kind_natural_id, unit_of_measure, microcredits_per_unit, unit, valid_during)
 VALUES ($1, $2, $3, $4, $5, $6, $7)
@@ -49,3 +147,66 @@ func (q *Queries) CreatePriceWithID(ctx context.Context, arg CreatePriceWithIDPa
 	)
 	return i, err
 }
+
+const getPrice = `-- name: GetPrice :one
+SELECT id, meter, kind_natural_id, unit_of_measure, microcredits_per_unit, unit, valid_during
+FROM price
+WHERE id = $1
+`
+
+func (q *Queries) GetPrice(ctx context.Context, id int32) (Price, error) {
+	row := q.db.QueryRow(ctx, getPrice, id)
+	var i Price
+	err := row.Scan(
+		&i.ID,
+		&i.Meter,
+		&i.KindNaturalID,
+		&i.UnitOfMeasure,
+		&i.MicrocreditsPerUnit,
+		&i.Unit,
+		&i.ValidDuring,
+	)
+	return i, err
+}
+
+const listPrices = `-- name: ListPrices :many
+SELECT id, meter, kind_natural_id, unit_of_measure, microcredits_per_unit, unit, valid_during
+FROM price
+WHERE ($1::text IS NULL OR meter = $1)
+  AND ($2::text IS NULL OR kind_natural_id = $2)
+ORDER BY meter, kind_natural_id, lower(valid_during) NULLS FIRST
+`
+
+type ListPricesParams struct {
+	Meter         pgtype.Text
+	KindNaturalID pgtype.Text
+}
+
+// ListPrices lists prices, optionally only those for one meter or resource kind, ordered by resource kind and then by when each price takes effect.
+func (q *Queries) ListPrices(ctx context.Context, arg ListPricesParams) ([]Price, error) {
+	rows, err := q.db.Query(ctx, listPrices, arg.Meter, arg.KindNaturalID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Price
+	for rows.Next() {
+		var i Price
+		if err := rows.Scan(
+			&i.ID,
+			&i.Meter,
+			&i.KindNaturalID,
+			&i.UnitOfMeasure,
+			&i.MicrocreditsPerUnit,
+			&i.Unit,
+			&i.ValidDuring,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
@@ -16,13 +16,19 @@ type Querier interface {
 	//   liabilities
 	//   expenses
 	AccountingEquation(ctx context.Context) ([]string, error)
+	// AdjustTransaction posts a transaction that changes the amount of transaction_id by amount_microcredits and returns the new transaction's ID.
+	AdjustTransaction(ctx context.Context, arg AdjustTransactionParams) (int32, error)
 	// BoundsMonthPrev calculates bounds that encapsulate the month previous to the parameter, as_of. The first bound is inclusive and the second is exclusive.
 	BoundsMonthPrev(ctx context.Context, asOf pgtype.Timestamptz) (BoundsMonthPrevRow, error)
 	// BulkCreateCFOrgs creates CFOrg rows in bulk with the minimum required columns. If a row with the given primary key already exists, that input item is ignored.
 	BulkCreateCFOrgs(ctx context.Context, ids []pgtype.UUID) error
 	BulkCreateMeasurement(ctx context.Context, arg BulkCreateMeasurementParams) error
+	// BulkCreateMeasurementAnomalies records anomalies in bulk. Errors are joined with newlines, because sqlc cannot unnest a two-dimensional array. If an anomaly already exists for the Reading and resource, that input item is ignored.
+	BulkCreateMeasurementAnomalies(ctx context.Context, arg BulkCreateMeasurementAnomaliesParams) error
 	// BulkCreateMeters creates Meter rows in bulk with the minimum required columns. If a row with the given primary key already exists, that input item is ignored.
 	BulkCreateMeters(ctx context.Context, names []string) error
+	// BulkCreateReadingMeterStatuses records the result of each meter in a Reading. Empty errors are stored as null. If a status already exists for the Reading and meter, that input item is ignored.
+	BulkCreateReadingMeterStatuses(ctx context.Context, arg BulkCreateReadingMeterStatusesParams) error
 	// BulkCreateResourceKinds creates ResourceKind rows in bulk with the minimum required columns. If a row with the given primary key already exists, that input item is ignored.
 	// The bulk insert pattern using multiple arrays is sourced from: https://github.com/sqlc-dev/sqlc/issues/218#issuecomment-829263172
 	BulkCreateResourceKinds(ctx context.Context, arg BulkCreateResourceKindsParams) error
@@ -31,12 +37,34 @@ type Querier interface {
 	// BulkCreateResources creates Resource rows in bulk with the minimum required columns. If a row with the given primary key already exists, that input item is ignored.
 	// The bulk insert pattern using multiple arrays is sourced from: https://github.com/sqlc-dev/sqlc/issues/218#issuecomment-829263172
 	BulkCreateResources(ctx context.Context, arg BulkCreateResourcesParams) error
+	// CanReconstructAppMemory returns true if the usage event meter has processed app usage events past the given time and has intervals that started at or before it, so the memory allocated to app processes at that time can be reconstructed.
+	CanReconstructAppMemory(ctx context.Context, at pgtype.Timestamptz) (pgtype.Bool, error)
+	// ClosePrice ends the price for the resource kind that is valid at the start of effective_date in business time (America/New_York), so a new price can take effect then. It returns pgx.ErrNoRows if no price is valid then, or if the valid price takes effect at the same time.
+	ClosePrice(ctx context.Context, arg ClosePriceParams) (Price, error)
+	// CloseUsageEventIntervals stops the open intervals of a resource that started at or before stopped_at, except the interval that started at except_started_at, if given.
+	CloseUsageEventIntervals(ctx context.Context, arg CloseUsageEventIntervalsParams) error
+	// CountPricedMeasurements counts measurements priced with the price whose readings were taken at or after the start of effective_date in business time (America/New_York).
+	CountPricedMeasurements(ctx context.Context, arg CountPricedMeasurementsParams) (int64, error)
+	// CountPricedReadingMeasurements returns the number of the Reading's measurements that have been priced.
+	CountPricedReadingMeasurements(ctx context.Context, readingID int32) (int64, error)
+	// CreateAlert records an alert. If an alert of the same kind has already been recorded for the threshold and funding level, no row is returned.
+	CreateAlert(ctx context.Context, arg CreateAlertParams) (Alert, error)
+	// CreateAlertDelivery records that the notifier delivered the alert. It does nothing if the delivery was already recorded.
+	CreateAlertDelivery(ctx context.Context, arg CreateAlertDeliveryParams) error
+	CreateAlertThresholds(ctx context.Context, arg CreateAlertThresholdsParams) error
+	// CreateBackfillReading creates a Reading for an hour in which no Reading was taken, with the given provenance. It returns [pgx.ErrNoRows] if a Reading already exists for the hour.
+	CreateBackfillReading(ctx context.Context, arg CreateBackfillReadingParams) (Reading, error)
 	CreateCFOrg(ctx context.Context, arg CreateCFOrgParams) (CFOrg, error)
 	// CreateCustomer adds a customer to the database and creates Accounts for the customer for every AccountType available. Returns the ID of the new Customer.
 	CreateCustomer(ctx context.Context, name string) (pgtype.UUID, error)
+	CreateIAA(ctx context.Context, arg CreateIAAParams) (IAA, error)
+	// CreateInvoice creates the invoice for a usage_post transaction, with the next invoice number, and returns its ID.
+	CreateInvoice(ctx context.Context, transactionID int32) (int32, error)
 	CreateMeasurement(ctx context.Context, arg CreateMeasurementParams) (Measurement, error)
 	CreateMeasurements(ctx context.Context, arg []CreateMeasurementsParams) (int64, error)
 	CreateMeter(ctx context.Context, name string) (string, error)
+	// CreatePrice creates a price for the resource kind that is valid from the start of effective_date in business time (America/New_York) until a new price is published.
+	CreatePrice(ctx context.Context, arg CreatePriceParams) (Price, error)
 	CreatePriceWithID(ctx context.Context, arg CreatePriceWithIDParams) (Price, error)
 	CreateReading(ctx context.Context, arg CreateReadingParams) (Reading, error)
 	CreateReadingWithID(ctx context.Context, arg CreateReadingWithIDParams) (Reading, error)
@@ -46,46 +74,140 @@ type Querier interface {
 	CreateTransaction(ctx context.Context, arg CreateTransactionParams) (Transaction, error)
 	// CreateUniqueReading creates a Reading if one does not exist for the hour specified in created_at. It returns [pgx.ErrNoRows] if a Reading already exists.
 	CreateUniqueReading(ctx context.Context, arg CreateUniqueReadingParams) (Reading, error)
+	DeleteAlertThresholds(ctx context.Context, customerID pgtype.UUID) error
 	DeleteCFOrg(ctx context.Context, id pgtype.UUID) error
 	DeleteCustomer(ctx context.Context, id pgtype.UUID) error
 	DeleteResource(ctx context.Context, arg DeleteResourceParams) error
 	DeleteResourceKind(ctx context.Context, arg DeleteResourceKindParams) error
-	DeleteTier(ctx context.Context, id int32) error
+	DeleteTier(ctx context.Context, id int32) (int64, error)
+	// DeleteUsageEventIntervals deletes the meter's intervals that stopped at or before the given time.
+	DeleteUsageEventIntervals(ctx context.Context, arg DeleteUsageEventIntervalsParams) (int64, error)
+	// DeprecateResourceKinds marks kinds of the meter whose natural IDs are not in natural_ids as deprecated and inactive, unless they are already deprecated. Use it after syncing every kind in a meter's catalog.
+	DeprecateResourceKinds(ctx context.Context, arg DeprecateResourceKindsParams) ([]ResourceKind, error)
 	GetAccountForCustomerAndType(ctx context.Context, arg GetAccountForCustomerAndTypeParams) (Account, error)
 	GetAppsUsageBySpace(ctx context.Context, customerID pgtype.UUID) ([]GetAppsUsageBySpaceRow, error)
 	GetCFOrg(ctx context.Context, id pgtype.UUID) (CFOrg, error)
 	GetCustomer(ctx context.Context, id pgtype.UUID) (Customer, error)
+	// GetCustomerBalances returns the total debits, credits, and balance of each of the customer's accounts. Balances are positive when the account holds value in the sense the billing service uses it: credit_pool is the number of credits remaining, credits_used is the number consumed, and liabilities is the number owed to the customer. Liabilities increase with credits and every other account increases with debits.
+	GetCustomerBalances(ctx context.Context, customerID pgtype.UUID) ([]GetCustomerBalancesRow, error)
 	GetCustomersByName(ctx context.Context, name string) ([]Customer, error)
 	GetEntriesForCustomerAndType(ctx context.Context, arg GetEntriesForCustomerAndTypeParams) ([]Entry, error)
 	GetEntry(ctx context.Context, arg GetEntryParams) (Entry, error)
+	GetIAA(ctx context.Context, id int32) (IAA, error)
+	GetIAAForUpdate(ctx context.Context, id int32) (IAA, error)
+	// GetInvoice returns an invoice by ID. Reversed is true if its transaction has been reversed, in which case the invoice is void.
+	GetInvoice(ctx context.Context, id int32) (GetInvoiceRow, error)
+	// GetInvoiceByNumber returns an invoice by number. See GetInvoice.
+	GetInvoiceByNumber(ctx context.Context, number int32) (GetInvoiceByNumberRow, error)
+	// GetLatestReadingTime returns the time of the most recent measured Reading in which the meter succeeded; see reading_succeeded. Manual one-off Readings only measure a single app, so they are ignored, as are backfilled Readings. It returns [pgx.ErrNoRows] if no such Readings exist.
+	GetLatestReadingTime(ctx context.Context, meter string) (pgtype.Timestamptz, error)
+	GetPrice(ctx context.Context, id int32) (Price, error)
+	// GetReadingAfter returns the earliest Reading taken at or after the given time. It returns [pgx.ErrNoRows] if there is none.
+	GetReadingAfter(ctx context.Context, after pgtype.Timestamptz) (Reading, error)
+	// GetReadingBefore returns the latest Reading taken before the given time. It returns [pgx.ErrNoRows] if there is none.
+	GetReadingBefore(ctx context.Context, before pgtype.Timestamptz) (Reading, error)
 	GetResource(ctx context.Context, arg GetResourceParams) (Resource, error)
 	GetResourceKind(ctx context.Context, arg GetResourceKindParams) (ResourceKind, error)
 	GetResourceNode(ctx context.Context, arg GetResourceNodeParams) (ResourceNode, error)
 	GetTier(ctx context.Context, id int32) (Tier, error)
 	GetTransaction(ctx context.Context, id int32) (Transaction, error)
+	// GetUsageByPath sums the usage of a customer's nodes that match path, rolled up by period, org, generalized space, space, and resource. Readings are included from since until until. If either is null, it is the start of the period after (or before) periods from now. Periods begin in time_zone, or the customer's time zone if it is null. Costs are in US dollars at usd_per_credit. If cf_org_ids is not null, only usage of resources in those CF orgs is included.
 	GetUsageByPath(ctx context.Context, arg GetUsageByPathParams) ([]GetUsageByPathRow, error)
+	// GetUsageEventCheckpoint returns the last event processed by the meter from the source. It returns [pgx.ErrNoRows] if the meter has not processed any events from the source.
+	GetUsageEventCheckpoint(ctx context.Context, arg GetUsageEventCheckpointParams) (UsageEventCheckpoint, error)
+	// InterpolateMeasurements estimates measurements of accruing resource kinds for a backfilled Reading, by linear interpolation between the Readings before and after it. fraction is how far the backfilled Reading is from the previous one, between 0 and 1. Only resources measured in both adjacent Readings are estimated. Existing measurements, like reconstructed ones, are not replaced. Other kinds are not estimated, because their meters measure usage since their last measured Reading.
+	InterpolateMeasurements(ctx context.Context, arg InterpolateMeasurementsParams) (int64, error)
 	LQueryResourceNodes(ctx context.Context, arg LQueryResourceNodesParams) ([]ResourceNode, error)
+	// ListAlertThresholds lists the thresholds for the customer, or for all customers if customer_id is null.
+	ListAlertThresholds(ctx context.Context, customerID pgtype.UUID) ([]AlertThreshold, error)
+	// ListAlerts lists the most recent alerts for the customer, or for all customers if customer_id is null.
+	ListAlerts(ctx context.Context, arg ListAlertsParams) ([]ListAlertsRow, error)
 	ListCFOrgs(ctx context.Context) ([]CFOrg, error)
+	ListCFOrgsByCustomer(ctx context.Context, customerID pgtype.UUID) ([]CFOrg, error)
+	// ListCustomerFunding returns, for each customer, the inputs needed to decide whether their credits are running low as of as_of: the credit pool balance, usage that has been measured but not yet posted, usage measured since window_start, and the credits and last day of the Period of Performance of agreements that have started and not ended. Usage is not priced until its month is closed, so the cost of unpriced usage is estimated from the price that was valid when it was read, as in ListResourceCosts. Closing a month posts the usage of every customer that has any, so usage of customers with no posts is unposted only since the end of the last closed month, or the start of the previous month if none has been closed.
+	ListCustomerFunding(ctx context.Context, arg ListCustomerFundingParams) ([]ListCustomerFundingRow, error)
+	// ListCustomerStatement lists a customer's transactions that occurred from the start of start_date until the start of end_date in business time (America/New_York), oldest first, with the change each made to the customer's accounts and the running balance of each account after it. Balances follow the conventions of GetCustomerBalances. Running balances include transactions before start_date. A null bound is unbounded. TotalCount is the number of transactions in the range, regardless of page_size and page_offset.
+	ListCustomerStatement(ctx context.Context, arg ListCustomerStatementParams) ([]ListCustomerStatementRow, error)
 	ListCustomers(ctx context.Context) ([]Customer, error)
+	// ListCustomersByCFOrgIDs lists the customers that own any of the given CF orgs.
+	ListCustomersByCFOrgIDs(ctx context.Context, cfOrgIds []pgtype.UUID) ([]Customer, error)
+	// ListIAAs lists agreements for the customer, or all agreements if customer_id is null.
+	ListIAAs(ctx context.Context, customerID pgtype.UUID) ([]IAA, error)
+	ListInvoiceLines(ctx context.Context, invoiceID int32) ([]InvoiceLine, error)
+	// ListInvoices lists invoices, most recent first, optionally filtered by customer. See GetInvoice.
+	ListInvoices(ctx context.Context, arg ListInvoicesParams) ([]ListInvoicesRow, error)
+	// ListMeasurementAnomalies lists anomalies, most recent Reading first, optionally filtered by Reading and meter.
+	ListMeasurementAnomalies(ctx context.Context, arg ListMeasurementAnomaliesParams) ([]MeasurementAnomaly, error)
 	ListMeasurements(ctx context.Context) ([]Measurement, error)
+	// ListOrphanCFOrgs lists CF orgs that are not assigned to a customer but have measurements, with the number of measurements and when the most recent was taken. Usage in these orgs cannot be billed until they are assigned.
+	ListOrphanCFOrgs(ctx context.Context) ([]ListOrphanCFOrgsRow, error)
+	// ListPrices lists prices, optionally only those for one meter or resource kind, ordered by resource kind and then by when each price takes effect.
+	ListPrices(ctx context.Context, arg ListPricesParams) ([]Price, error)
+	// ListReadingGaps returns the start of each hour in [since, until) in which no Reading was taken. Hours are in UTC, like reading_hourly_uq.
+	ListReadingGaps(ctx context.Context, arg ListReadingGapsParams) ([]pgtype.Timestamptz, error)
+	// ListReadingMeterStatuses returns the status of each meter in a Reading.
+	ListReadingMeterStatuses(ctx context.Context, readingID int32) ([]ReadingMeterStatus, error)
+	// ListResourceCosts sums the usage and cost of each of a customer's resources over each hour or day (granularity) of the readings taken in [since, until). Usage in months that have not been closed is not priced yet, so its cost is estimated from the price that was valid when it was read, which is the price it gets when its month is closed. billed_microcredits is only the cost of priced measurements. Estimated is true if any of the usage was in backfilled readings. If cf_org_ids is not null, only resources in those CF orgs are included.
+	ListResourceCosts(ctx context.Context, arg ListResourceCostsParams) ([]ListResourceCostsRow, error)
 	ListResourceKind(ctx context.Context) ([]ResourceKind, error)
 	ListResourceNodeAncestors(ctx context.Context, path string) ([]ResourceNode, error)
 	ListResourceNodeDescendants(ctx context.Context, path string) ([]ResourceNode, error)
 	ListResources(ctx context.Context) ([]Resource, error)
 	ListTiers(ctx context.Context) ([]Tier, error)
+	// ListTransactionCorrections lists the reversals and adjustments posted against a transaction.
+	ListTransactionCorrections(ctx context.Context, originalTransactionID pgtype.Int4) ([]Transaction, error)
+	// ListTransactionSummaries returns one row for each transaction in ids, with the name of its customer and the total amount debited by its entries.
+	ListTransactionSummaries(ctx context.Context, ids []int32) ([]ListTransactionSummariesRow, error)
 	ListTransactions(ctx context.Context) ([]Transaction, error)
 	ListTransactionsWide(ctx context.Context) ([]ListTransactionsWideRow, error)
+	// ListUninvoicedUsagePostIDs lists the usage_post transactions that occurred at period_end, the end of a posting period, have not been reversed, and have no invoice.
+	ListUninvoicedUsagePostIDs(ctx context.Context, periodEnd pgtype.Timestamptz) ([]int32, error)
+	// ListUnnotifiedAlerts lists the alerts that have not been delivered by every notifier, with the names of the notifiers that have delivered them.
+	ListUnnotifiedAlerts(ctx context.Context) ([]ListUnnotifiedAlertsRow, error)
+	// ListUnpricedResourceKinds lists kinds that have measurements taken when no price for the kind was valid, with the number of such measurements and when the most recent was taken. These measurements will not be billed.
+	ListUnpricedResourceKinds(ctx context.Context) ([]ListUnpricedResourceKindsRow, error)
+	// ListUsageEventIntervals lists the meter's intervals that overlap the period [since, until).
+	ListUsageEventIntervals(ctx context.Context, arg ListUsageEventIntervalsParams) ([]UsageEventInterval, error)
+	// ListUsagePostIDs lists the usage_post transactions that occurred at period_end, the end of a posting period, and have not been reversed.
+	ListUsagePostIDs(ctx context.Context, periodEnd pgtype.Timestamptz) ([]int32, error)
+	MarkAlertNotified(ctx context.Context, arg MarkAlertNotifiedParams) error
+	// OpenUsageEventInterval starts an interval for a resource. If the interval already exists, for instance because an event was processed twice, it is not changed.
+	OpenUsageEventInterval(ctx context.Context, arg OpenUsageEventIntervalParams) error
+	// PostIAAPop adds credits to customer credit pools for agreements whose Period of Performance has started and expires unused credits for agreements whose PoP has ended, as of as_of. Returns the IDs of the transactions created.
+	PostIAAPop(ctx context.Context, asOf pgtype.Timestamptz) ([]pgtype.Int4, error)
+	// PostTierGrants adds the credits included in each customer's tier to their credit pool for the month containing as_of. Returns the IDs of the transactions created.
+	PostTierGrants(ctx context.Context, asOf pgtype.Timestamptz) ([]pgtype.Int4, error)
 	PostUsage(ctx context.Context, asOf pgtype.Timestamptz) ([]pgtype.Int4, error)
+	// ReconstructAppMemoryMeasurements creates measurements of the cfapps meter's memory kind for a backfilled Reading from the intervals recorded by the usage event meter. The memory of each instance of a process is its share of the value of the process's interval that was open at the given time. Only instances already known to the cfapps meter are measured.
+	ReconstructAppMemoryMeasurements(ctx context.Context, arg ReconstructAppMemoryMeasurementsParams) (int64, error)
+	// ReverseTransaction posts a transaction that undoes the entries of transaction_id and returns the new transaction's ID.
+	ReverseTransaction(ctx context.Context, arg ReverseTransactionParams) (int32, error)
+	// ReverseUsage reverses the usage posted for the month preceding as_of by posting offsetting transactions. It returns the IDs of the reversal transactions.
+	ReverseUsage(ctx context.Context, asOf pgtype.Timestamptz) ([]pgtype.Int4, error)
+	// SetCFOrgCustomer assigns the CF org to a customer, or unassigns it if customer_id is null. The org is created if it does not exist yet, so orgs can be assigned before their usage is first recorded.
+	SetCFOrgCustomer(ctx context.Context, arg SetCFOrgCustomerParams) (CFOrg, error)
+	// SetCustomerTier assigns the customer to a tier, or removes them from their tier if tier_id is null.
+	SetCustomerTier(ctx context.Context, arg SetCustomerTierParams) (Customer, error)
+	SetReadingProvenance(ctx context.Context, arg SetReadingProvenanceParams) error
 	// SumEntries calculates the sum of all entries in the ledger. If the result is not 0, a transaction is imbalanced.
 	SumEntries(ctx context.Context) ([]pgtype.Numeric, error)
+	// SummarizeMeasurementAnomalies counts the anomalies of each meter in each Reading taken in [since, until). Orphaned anomalies are those whose usage could not be attributed to a CF org.
+	SummarizeMeasurementAnomalies(ctx context.Context, arg SummarizeMeasurementAnomaliesParams) ([]SummarizeMeasurementAnomaliesRow, error)
+	// SyncCFOrg creates or updates a CF org with the name and metadata read from Cloud Foundry. The org's customer is not changed.
+	SyncCFOrg(ctx context.Context, arg SyncCFOrgParams) (CFOrg, error)
+	// SyncResourceKind creates or updates a kind with information from the catalog of the system it is read from. Kinds that were deprecated and have returned to the catalog are no longer deprecated.
+	SyncResourceKind(ctx context.Context, arg SyncResourceKindParams) (ResourceKind, error)
 	UpdateCFOrg(ctx context.Context, arg UpdateCFOrgParams) error
-	UpdateCustomer(ctx context.Context, arg UpdateCustomerParams) error
+	// UpdateCustomer changes the customer's name and time zone. Null arguments leave their columns unchanged.
+	UpdateCustomer(ctx context.Context, arg UpdateCustomerParams) (Customer, error)
+	UpdateIAA(ctx context.Context, arg UpdateIAAParams) (IAA, error)
 	// UpdateMeasurementMicrocredits updates the amount of microcredits associated with measurements made in the month preceding as_of based on the prices that were valid for each resource_kind at the time of reading.
 	UpdateMeasurementMicrocredits(ctx context.Context, asOf pgtype.Timestamptz) (pgtype.Int8, error)
 	UpdateResource(ctx context.Context, arg UpdateResourceParams) error
-	UpdateTier(ctx context.Context, arg UpdateTierParams) error
+	UpdateTier(ctx context.Context, arg UpdateTierParams) (Tier, error)
 	// UpsertResource upserts a Resource and creates minimal rows in foreign tables -- namely meter, cf_org, and resource_kind -- to which Resource has foreign keys. Efficient for single inserts. For bulk inserts, review Bulk* functions.
 	UpsertResource(ctx context.Context, arg UpsertResourceParams) (Resource, error)
+	UpsertUsageEventCheckpoint(ctx context.Context, arg UpsertUsageEventCheckpointParams) error
 }
 
 var _ Querier = (*Queries)(nil)
@@ -11,13 +11,122 @@ import (
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
+const bulkCreateReadingMeterStatuses = `-- name: BulkCreateReadingMeterStatuses :exec
+INSERT INTO reading_meter_status (
+	reading_id,
+	meter,
+	started_at,
+	duration_ms,
+	succeeded,
+	error,
+	measurement_count
+) SELECT reading_id, meter, started_at, duration_ms, succeeded, nullif(error, ''), measurement_count FROM
+	UNNEST(
+		$1::int[],
+		$2::text[],
+		$3::timestamptz[],
+		$4::int[],
+		$5::boolean[],
+		$6::text[],
+		$7::int[]
+	) AS s (reading_id, meter, started_at, duration_ms, succeeded, error, measurement_count)
+ON CONFLICT DO NOTHING
+`
+
+type BulkCreateReadingMeterStatusesParams struct {
+	ReadingID        []int32
+	Meter            []string
+	StartedAt        []pgtype.Timestamptz
+	DurationMs       []int32
+	Succeeded        []bool
+	Error            []string
+	MeasurementCount []int32
+}
+
+// BulkCreateReadingMeterStatuses records the result of each meter in a Reading. Empty errors are stored as null. If a status already exists for the Reading and meter, that input item is ignored.
+func (q *Queries) BulkCreateReadingMeterStatuses(ctx context.Context, arg BulkCreateReadingMeterStatusesParams) error {
+	_, err := q.db.Exec(ctx, bulkCreateReadingMeterStatuses,
+		arg.ReadingID,
+		arg.Meter,
+		arg.StartedAt,
+		arg.DurationMs,
+		arg.Succeeded,
+		arg.Error,
+		arg.MeasurementCount,
+	)
+	return err
+}
+
+const canReconstructAppMemory = `-- name: CanReconstructAppMemory :one
+SELECT
+	EXISTS (
+		SELECT 1 FROM usage_event_checkpoint
+		WHERE meter = 'cfusageevents' AND source = 'app' AND last_event_at >= $1::timestamptz
+	)
+	AND EXISTS (
+		SELECT 1 FROM usage_event_interval
+		WHERE meter = 'cfusageevents' AND started_at <= $1::timestamptz
+	)
+`
+
+// CanReconstructAppMemory returns true if the usage event meter has processed app usage events past the given time and has intervals that started at or before it, so the memory allocated to app processes at that time can be reconstructed.
+func (q *Queries) CanReconstructAppMemory(ctx context.Context, at pgtype.Timestamptz) (pgtype.Bool, error) {
+	row := q.db.QueryRow(ctx, canReconstructAppMemory, at)
+	var column_1 pgtype.Bool
+	err := row.Scan(&column_1)
+	return column_1, err
+}
+
+const countPricedReadingMeasurements = `-- name: CountPricedReadingMeasurements :one
+SELECT count(*) FROM measurement
+WHERE reading_id = $1 AND amount_microcredits IS NOT NULL
+`
+
+// CountPricedReadingMeasurements returns the number of the Reading's measurements that have been priced.
+func (q *Queries) CountPricedReadingMeasurements(ctx context.Context, readingID int32) (int64, error) {
+	row := q.db.QueryRow(ctx, countPricedReadingMeasurements, readingID)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const createBackfillReading = `-- name: CreateBackfillReading :one
+INSERT INTO reading (
+	created_at, periodic, provenance
+) VALUES (
+	$1, false, $2
+)
+ON CONFLICT (date_trunc('hour', created_at))
+DO NOTHING
+RETURNING id, created_at, periodic, created_at_utc, provenance
+`
+
+type CreateBackfillReadingParams struct {
+	CreatedAt  pgtype.Timestamp
+	Provenance string
+}
+
+// CreateBackfillReading creates a Reading for an hour in which no Reading was taken, with the given provenance. It returns [pgx.ErrNoRows] if a Reading already exists for the hour.
+func (q *Queries) CreateBackfillReading(ctx context.Context, arg CreateBackfillReadingParams) (Reading, error) {
+	row := q.db.QueryRow(ctx, createBackfillReading, arg.CreatedAt, arg.Provenance)
+	var i Reading
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.Periodic,
+		&i.CreatedAtUTC,
+		&i.Provenance,
+	)
+	return i, err
+}
+
 const createReading = `-- name: CreateReading :one
 INSERT INTO reading (
 	created_at, periodic
 ) VALUES (
 	$1, $2
 )
-RETURNING id, created_at, periodic, created_at_utc
+RETURNING id, created_at, periodic, created_at_utc, provenance
 `
 
 type CreateReadingParams struct {
@@ -33,6 +142,7 @@ func (q *Queries) CreateReading(ctx context.Context, arg CreateReadingParams) (R
 		&i.CreatedAt,
 		&i.Periodic,
 		&i.CreatedAtUTC,
+		&i.Provenance,
 	)
 	return i, err
 }
@@ -43,7 +153,7 @@ INSERT INTO reading (
 ) VALUES (
 	$1, $2, $3
 )
-RETURNING id, created_at, periodic, created_at_utc
+RETURNING id, created_at, periodic, created_at_utc, provenance
 `
 
 type CreateReadingWithIDParams struct {
@@ -60,6 +170,7 @@ func (q *Queries) CreateReadingWithID(ctx context.Context, arg CreateReadingWith
 		&i.CreatedAt,
 		&i.Periodic,
 		&i.CreatedAtUTC,
+		&i.Provenance,
 	)
 	return i, err
 }
@@ -72,7 +183,7 @@ INSERT INTO reading (
 )
 ON CONFLICT (date_trunc('hour', created_at))
 DO NOTHING
-RETURNING id, created_at, periodic, created_at_utc
+RETURNING id, created_at, periodic, created_at_utc, provenance
 `
 
 type CreateUniqueReadingParams struct {
@@ -89,6 +200,231 @@ func (q *Queries) CreateUniqueReading(ctx context.Context, arg CreateUniqueReadi
 		&i.CreatedAt,
 		&i.Periodic,
 		&i.CreatedAtUTC,
+		&i.Provenance,
+	)
+	return i, err
+}
+
+const getLatestReadingTime = `-- name: GetLatestReadingTime :one
+SELECT r.created_at_utc FROM reading r
+WHERE r.provenance = 'measured'
+AND reading_succeeded(r, $1::text)
+ORDER BY r.created_at DESC
+LIMIT 1
+`
+
+// GetLatestReadingTime returns the time of the most recent measured Reading in which the meter succeeded; see reading_succeeded. Manual one-off Readings only measure a single app, so they are ignored, as are backfilled Readings. It returns [pgx.ErrNoRows] if no such Readings exist.
+func (q *Queries) GetLatestReadingTime(ctx context.Context, meter string) (pgtype.Timestamptz, error) {
+	row := q.db.QueryRow(ctx, getLatestReadingTime, meter)
+	var created_at_utc pgtype.Timestamptz
+	err := row.Scan(&created_at_utc)
+	return created_at_utc, err
+}
+
+const getReadingAfter = `-- name: GetReadingAfter :one
+SELECT id, created_at, periodic, created_at_utc, provenance FROM reading
+WHERE created_at_utc >= $1::timestamptz
+ORDER BY created_at_utc
+LIMIT 1
+`
+
+// GetReadingAfter returns the earliest Reading taken at or after the given time. It returns [pgx.ErrNoRows] if there is none.
+func (q *Queries) GetReadingAfter(ctx context.Context, after pgtype.Timestamptz) (Reading, error) {
+	row := q.db.QueryRow(ctx, getReadingAfter, after)
+	var i Reading
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.Periodic,
+		&i.CreatedAtUTC,
+		&i.Provenance,
+	)
+	return i, err
+}
+
+const getReadingBefore = `-- name: GetReadingBefore :one
+SELECT id, created_at, periodic, created_at_utc, provenance FROM reading
+WHERE created_at_utc < $1::timestamptz
+ORDER BY created_at_utc DESC
+LIMIT 1
+`
+
+// GetReadingBefore returns the latest Reading taken before the given time. It returns [pgx.ErrNoRows] if there is none.
+func (q *Queries) GetReadingBefore(ctx context.Context, before pgtype.Timestamptz) (Reading, error) {
+	row := q.db.QueryRow(ctx, getReadingBefore, before)
+	var i Reading
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.Periodic,
+		&i.CreatedAtUTC,
+		&i.Provenance,
 	)
 	return i, err
 }
+
+const interpolateMeasurements = `-- name: InterpolateMeasurements :execrows
+INSERT INTO measurement (reading_id, meter, resource_natural_id, value)
+SELECT
+	$1::int,
+	prev.meter,
+	prev.resource_natural_id,
+	round(prev.value + (next.value - prev.value) * $2::float8)::int
+FROM measurement prev
+JOIN measurement next
+	ON next.meter = prev.meter AND next.resource_natural_id = prev.resource_natural_id
+JOIN resource r
+	ON r.meter = prev.meter AND r.natural_id = prev.resource_natural_id
+JOIN resource_kind k
+	ON k.meter = r.meter AND k.natural_id = r.kind_natural_id
+WHERE prev.reading_id = $3::int
+	AND next.reading_id = $4::int
+	AND k.accrues
+ON CONFLICT DO NOTHING
+`
+
+type InterpolateMeasurementsParams struct {
+	ReadingID     int32
+	Fraction      float64
+	PrevReadingID int32
+	NextReadingID int32
+}
+
+// InterpolateMeasurements estimates measurements of accruing resource kinds for a backfilled Reading, by linear interpolation between the Readings before and after it. fraction is how far the backfilled Reading is from the previous one, between 0 and 1. Only resources measured in both adjacent Readings are estimated. Existing measurements, like reconstructed ones, are not replaced. Other kinds are not estimated, because their meters measure usage since their last measured Reading.
+func (q *Queries) InterpolateMeasurements(ctx context.Context, arg InterpolateMeasurementsParams) (int64, error) {
+	result, err := q.db.Exec(ctx, interpolateMeasurements,
+		arg.ReadingID,
+		arg.Fraction,
+		arg.PrevReadingID,
+		arg.NextReadingID,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const listReadingGaps = `-- name: ListReadingGaps :many
+SELECT h.hour::timestamptz AS hour
+FROM generate_series(
+	date_trunc('hour', $1::timestamptz AT TIME ZONE 'UTC') AT TIME ZONE 'UTC',
+	$2::timestamptz - interval '1 hour',
+	interval '1 hour'
+) AS h (hour)
+WHERE NOT EXISTS (
+	SELECT 1 FROM reading r
+	WHERE r.created_at_utc >= h.hour
+		AND r.created_at_utc < h.hour + interval '1 hour'
+)
+ORDER BY h.hour
+`
+
+type ListReadingGapsParams struct {
+	Since pgtype.Timestamptz
+	Until pgtype.Timestamptz
+}
+
+// ListReadingGaps returns the start of each hour in [since, until) in which no Reading was taken. Hours are in UTC, like reading_hourly_uq.
+func (q *Queries) ListReadingGaps(ctx context.Context, arg ListReadingGapsParams) ([]pgtype.Timestamptz, error) {
+	rows, err := q.db.Query(ctx, listReadingGaps, arg.Since, arg.Until)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []pgtype.Timestamptz
+	for rows.Next() {
+		var hour pgtype.Timestamptz
+		if err := rows.Scan(&hour); err != nil {
+			return nil, err
+		}
+		items = append(items, hour)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listReadingMeterStatuses = `-- name: ListReadingMeterStatuses :many
+SELECT reading_id, meter, started_at, duration_ms, succeeded, error, measurement_count FROM reading_meter_status
+WHERE reading_id = $1
+ORDER BY meter
+`
+
+// ListReadingMeterStatuses returns the status of each meter in a Reading.
+func (q *Queries) ListReadingMeterStatuses(ctx context.Context, readingID int32) ([]ReadingMeterStatus, error) {
+	rows, err := q.db.Query(ctx, listReadingMeterStatuses, readingID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ReadingMeterStatus
+	for rows.Next() {
+		var i ReadingMeterStatus
+		if err := rows.Scan(
+			&i.ReadingID,
+			&i.Meter,
+			&i.StartedAt,
+			&i.DurationMs,
+			&i.Succeeded,
+			&i.Error,
+			&i.MeasurementCount,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const reconstructAppMemoryMeasurements = `-- name: ReconstructAppMemoryMeasurements :execrows
+INSERT INTO measurement (reading_id, meter, resource_natural_id, value)
+SELECT DISTINCT ON (r.natural_id)
+	$1::int,
+	r.meter,
+	r.natural_id,
+	iv.value / iv.instances
+FROM usage_event_interval iv
+CROSS JOIN LATERAL generate_series(0, iv.instances - 1) AS i (index)
+JOIN resource r
+	ON r.meter = 'cfapps'
+	AND r.kind_natural_id = 'memory'
+	AND r.natural_id = replace(iv.resource_natural_id, ':cfusageevents', ':' || i.index || ':memory')
+WHERE iv.meter = 'cfusageevents'
+	AND iv.kind_natural_id = 'memory'
+	AND iv.started_at <= $2::timestamptz
+	AND (iv.stopped_at IS NULL OR iv.stopped_at > $2::timestamptz)
+ORDER BY r.natural_id, iv.started_at DESC
+ON CONFLICT DO NOTHING
+`
+
+type ReconstructAppMemoryMeasurementsParams struct {
+	ReadingID int32
+	At        pgtype.Timestamptz
+}
+
+// ReconstructAppMemoryMeasurements creates measurements of the cfapps meter's memory kind for a backfilled Reading from the intervals recorded by the usage event meter. The memory of each instance of a process is its share of the value of the process's interval that was open at the given time. Only instances already known to the cfapps meter are measured.
+func (q *Queries) ReconstructAppMemoryMeasurements(ctx context.Context, arg ReconstructAppMemoryMeasurementsParams) (int64, error) {
+	result, err := q.db.Exec(ctx, reconstructAppMemoryMeasurements, arg.ReadingID, arg.At)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const setReadingProvenance = `-- name: SetReadingProvenance :exec
+UPDATE reading SET provenance = $2 WHERE id = $1
+`
+
+type SetReadingProvenanceParams struct {
+	ID         int32
+	Provenance string
+}
+
+func (q *Queries) SetReadingProvenance(ctx context.Context, arg SetReadingProvenanceParams) error {
+	_, err := q.db.Exec(ctx, setReadingProvenance, arg.ID, arg.Provenance)
+	return err
+}
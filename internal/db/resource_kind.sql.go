@@ -7,6 +7,8 @@ package db
 
 import (
 	"context"
+
+	"github.com/jackc/pgx/v5/pgtype"
 )
 
 const bulkCreateResourceKinds = `-- name: BulkCreateResourceKinds :exec
@@ -38,7 +40,7 @@ INSERT INTO resource_kind (
 ) VALUES (
   $1, $2
 )
-RETURNING meter, natural_id, name
+RETURNING meter, natural_id, name, broker, active, deprecated_at, synced_at, unit_of_measure, accrues
 `
 
 type CreateResourceKindParams struct {
@@ -49,7 +51,17 @@ type CreateResourceKindParams struct {
 func (q *Queries) CreateResourceKind(ctx context.Context, arg CreateResourceKindParams) (ResourceKind, error) {
 	row := q.db.QueryRow(ctx, createResourceKind, arg.Meter, arg.NaturalID)
 	var i ResourceKind
-	err := row.Scan(&i.Meter, &i.NaturalID, &i.Name)
+	err := row.Scan(
+		&i.Meter,
+		&i.NaturalID,
+		&i.Name,
+		&i.Broker,
+		&i.Active,
+		&i.DeprecatedAt,
+		&i.SyncedAt,
+		&i.UnitOfMeasure,
+		&i.Accrues,
+	)
 	return i, err
 }
 
@@ -68,8 +80,56 @@ func (q *Queries) DeleteResourceKind(ctx context.Context, arg DeleteResourceKind
 	return err
 }
 
+const deprecateResourceKinds = `-- name: DeprecateResourceKinds :many
+UPDATE resource_kind
+SET
+  active = false,
+  deprecated_at = $1
+WHERE meter = $2
+  AND NOT (natural_id = ANY($3::text[]))
+  AND deprecated_at IS NULL
+RETURNING meter, natural_id, name, broker, active, deprecated_at, synced_at, unit_of_measure, accrues
+`
+
+type DeprecateResourceKindsParams struct {
+	DeprecatedAt pgtype.Timestamptz
+	Meter        string
+	NaturalIds   []string
+}
+
+// DeprecateResourceKinds marks kinds of the meter whose natural IDs are not in natural_ids as deprecated and inactive, unless they are already deprecated. Use it after syncing every kind in a meter's catalog.
+func (q *Queries) DeprecateResourceKinds(ctx context.Context, arg DeprecateResourceKindsParams) ([]ResourceKind, error) {
+	rows, err := q.db.Query(ctx, deprecateResourceKinds, arg.DeprecatedAt, arg.Meter, arg.NaturalIds)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ResourceKind
+	for rows.Next() {
+		var i ResourceKind
+		if err := rows.Scan(
+			&i.Meter,
+			&i.NaturalID,
+			&i.Name,
+			&i.Broker,
+			&i.Active,
+			&i.DeprecatedAt,
+			&i.SyncedAt,
+			&i.UnitOfMeasure,
+			&i.Accrues,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const getResourceKind = `-- name: GetResourceKind :one
-SELECT meter, natural_id, name FROM resource_kind
+SELECT meter, natural_id, name, broker, active, deprecated_at, synced_at, unit_of_measure, accrues FROM resource_kind
 WHERE meter = $1 AND natural_id = $2 LIMIT 1
 `
 
@@ -81,12 +141,22 @@ type GetResourceKindParams struct {
 func (q *Queries) GetResourceKind(ctx context.Context, arg GetResourceKindParams) (ResourceKind, error) {
 	row := q.db.QueryRow(ctx, getResourceKind, arg.Meter, arg.NaturalID)
 	var i ResourceKind
-	err := row.Scan(&i.Meter, &i.NaturalID, &i.Name)
+	err := row.Scan(
+		&i.Meter,
+		&i.NaturalID,
+		&i.Name,
+		&i.Broker,
+		&i.Active,
+		&i.DeprecatedAt,
+		&i.SyncedAt,
+		&i.UnitOfMeasure,
+		&i.Accrues,
+	)
 	return i, err
 }
 
 const listResourceKind = `-- name: ListResourceKind :many
-SELECT meter, natural_id, name FROM resource_kind
+SELECT meter, natural_id, name, broker, active, deprecated_at, synced_at, unit_of_measure, accrues FROM resource_kind
 ORDER BY natural_id
 `
 
@@ -99,7 +169,76 @@ func (q *Queries) ListResourceKind(ctx context.Context) ([]ResourceKind, error)
 	var items []ResourceKind
 	for rows.Next() {
 		var i ResourceKind
-		if err := rows.Scan(&i.Meter, &i.NaturalID, &i.Name); err != nil {
+		if err := rows.Scan(
+			&i.Meter,
+			&i.NaturalID,
+			&i.Name,
+			&i.Broker,
+			&i.Active,
+			&i.DeprecatedAt,
+			&i.SyncedAt,
+			&i.UnitOfMeasure,
+			&i.Accrues,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listUnpricedResourceKinds = `-- name: ListUnpricedResourceKinds :many
+SELECT
+  k.meter, k.natural_id, k.name, k.broker, k.active, k.deprecated_at, k.synced_at, k.unit_of_measure, k.accrues,
+  COUNT(*)::bigint AS measurement_count,
+  MAX(rd.created_at_utc)::timestamptz AS last_measured_at
+FROM resource_kind AS k
+INNER JOIN resource AS r ON k.meter = r.meter AND k.natural_id = r.kind_natural_id
+INNER JOIN measurement AS m ON r.meter = m.meter AND r.natural_id = m.resource_natural_id
+INNER JOIN reading AS rd ON m.reading_id = rd.id
+WHERE NOT EXISTS (
+  SELECT 1
+  FROM price AS p
+  WHERE p.meter = k.meter
+    AND p.kind_natural_id = k.natural_id
+    AND p.valid_during @> rd.created_at_utc
+)
+GROUP BY k.meter, k.natural_id
+ORDER BY last_measured_at DESC, k.meter, k.natural_id
+`
+
+type ListUnpricedResourceKindsRow struct {
+	ResourceKind     ResourceKind
+	MeasurementCount int64
+	LastMeasuredAt   pgtype.Timestamptz
+}
+
+// ListUnpricedResourceKinds lists kinds that have measurements taken when no price for the kind was valid, with the number of such measurements and when the most recent was taken. These measurements will not be billed.
+func (q *Queries) ListUnpricedResourceKinds(ctx context.Context) ([]ListUnpricedResourceKindsRow, error) {
+	rows, err := q.db.Query(ctx, listUnpricedResourceKinds)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListUnpricedResourceKindsRow
+	for rows.Next() {
+		var i ListUnpricedResourceKindsRow
+		if err := rows.Scan(
+			&i.ResourceKind.Meter,
+			&i.ResourceKind.NaturalID,
+			&i.ResourceKind.Name,
+			&i.ResourceKind.Broker,
+			&i.ResourceKind.Active,
+			&i.ResourceKind.DeprecatedAt,
+			&i.ResourceKind.SyncedAt,
+			&i.ResourceKind.UnitOfMeasure,
+			&i.ResourceKind.Accrues,
+			&i.MeasurementCount,
+			&i.LastMeasuredAt,
+		); err != nil {
 			return nil, err
 		}
 		items = append(items, i)
@@ -109,3 +248,50 @@ func (q *Queries) ListResourceKind(ctx context.Context) ([]ResourceKind, error)
 	}
 	return items, nil
 }
+
+const syncResourceKind = `-- name: SyncResourceKind :one
+INSERT INTO resource_kind (meter, natural_id, name, broker, active, deprecated_at, synced_at)
+VALUES ($1, $2, $3, $4, $5, NULL, $6)
+ON CONFLICT (meter, natural_id) DO UPDATE
+SET
+  name = excluded.name,
+  broker = excluded.broker,
+  active = excluded.active,
+  deprecated_at = NULL,
+  synced_at = excluded.synced_at
+RETURNING meter, natural_id, name, broker, active, deprecated_at, synced_at, unit_of_measure, accrues
+`
+
+type SyncResourceKindParams struct {
+	Meter     string
+	NaturalID string
+	Name      pgtype.Text
+	Broker    pgtype.Text
+	Active    bool
+	SyncedAt  pgtype.Timestamptz
+}
+
+// SyncResourceKind creates or updates a kind with information from the catalog of the system it is read from. Kinds that were deprecated and have returned to the catalog are no longer deprecated.
+func (q *Queries) SyncResourceKind(ctx context.Context, arg SyncResourceKindParams) (ResourceKind, error) {
+	row := q.db.QueryRow(ctx, syncResourceKind,
+		arg.Meter,
+		arg.NaturalID,
+		arg.Name,
+		arg.Broker,
+		arg.Active,
+		arg.SyncedAt,
+	)
+	var i ResourceKind
+	err := row.Scan(
+		&i.Meter,
+		&i.NaturalID,
+		&i.Name,
+		&i.Broker,
+		&i.Active,
+		&i.DeprecatedAt,
+		&i.SyncedAt,
+		&i.UnitOfMeasure,
+		&i.Accrues,
+	)
+	return i, err
+}
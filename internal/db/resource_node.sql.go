@@ -122,24 +122,35 @@ func (q *Queries) GetResourceNode(ctx context.Context, arg GetResourceNodeParams
 
 const getUsageByPath = `-- name: GetUsageByPath :many
 with
-  reads (id, created_at) as (
+  bounds as (
     select
-      id,
-      created_at
-    from reading
-    where created_at >= date_trunc(
-        $1,
-        current_date + ($4::int || ' ' || $1)::interval
-      )
-      and created_at < date_trunc(
-        $1,
-        current_date + ($5::int || ' ' || $1)::interval
-      )
-    order by created_at desc
+      coalesce($5::text, c.time_zone) as time_zone,
+      coalesce(
+        $6::timestamptz,
+        period_start($1::text, now(), coalesce($5::text, c.time_zone), $7::int)
+      ) as since,
+      coalesce(
+        $8::timestamptz,
+        period_start($1::text, now(), coalesce($5::text, c.time_zone), $9::int)
+      ) as until
+    from customer as c
+    where c.id = $2
+  ),
+
+  reads (id, created_at, time_zone) as (
+    select
+      r.id,
+      r.created_at_utc,
+      b.time_zone
+    from reading as r
+      cross join bounds as b
+    where
+      r.created_at_utc >= b.since
+      and r.created_at_utc < b.until
   )
 
 select
-  date_trunc($1, r.created_at) as period,
+  period_start($1::text, r.created_at, r.time_zone)::timestamptz as period,
   coalesce(subltree(rn.path, 2, 3)::text, '') as l1,
   coalesce(regexp_replace(
     subltree(rn.path, 3, 4)::text,
@@ -150,27 +161,34 @@ select
   coalesce(subpath(rn.path, 4)::text, '') as l4,
   sum(m.amount_microcredits) as total_microcredits,
   round(sum(m.amount_microcredits) * 1e-6, 3) as total_credits,
-  round(sum(m.amount_microcredits) * 1e-6 * 50, 2) as total_cost
+  round(sum(m.amount_microcredits) * 1e-6 * $10::float8::numeric, 2) as total_cost
 from resource_node as rn
   inner join measurement as m on rn.resource_natural_id = m.resource_natural_id
   inner join reads as r on m.reading_id = r.id
+  inner join resource as res on m.meter = res.meter and m.resource_natural_id = res.natural_id
 where
   rn.customer_id = $2
   and rn.path ~ $3::lquery
+  and ($4::uuid [] is null or res.cf_org_id = any($4::uuid []))
 group by rollup (period, l1, l2, l3, l4)
 order by l1
 `
 
 type GetUsageByPathParams struct {
-	Period     string
-	CustomerID pgtype.UUID
-	Path       string
-	After      int32
-	Before     int32
+	Period       string
+	CustomerID   pgtype.UUID
+	Path         string
+	CfOrgIds     []pgtype.UUID
+	TimeZone     pgtype.Text
+	Since        pgtype.Timestamptz
+	After        int32
+	Until        pgtype.Timestamptz
+	Before       int32
+	UsdPerCredit float64
 }
 
 type GetUsageByPathRow struct {
-	Period            pgtype.Timestamp
+	Period            pgtype.Timestamptz
 	L1                pgtype.Text
 	L2                pgtype.Text
 	L3                pgtype.Text
@@ -180,13 +198,19 @@ type GetUsageByPathRow struct {
 	TotalCost         pgtype.Numeric
 }
 
+// GetUsageByPath sums the usage of a customer's nodes that match path, rolled up by period, org, generalized space, space, and resource. Readings are included from since until until. If either is null, it is the start of the period after (or before) periods from now. Periods begin in time_zone, or the customer's time zone if it is null. Costs are in US dollars at usd_per_credit. If cf_org_ids is not null, only usage of resources in those CF orgs is included.
 func (q *Queries) GetUsageByPath(ctx context.Context, arg GetUsageByPathParams) ([]GetUsageByPathRow, error) {
 	rows, err := q.db.Query(ctx, getUsageByPath,
 		arg.Period,
 		arg.CustomerID,
 		arg.Path,
+		arg.CfOrgIds,
+		arg.TimeZone,
+		arg.Since,
 		arg.After,
+		arg.Until,
 		arg.Before,
+		arg.UsdPerCredit,
 	)
 	if err != nil {
 		return nil, err
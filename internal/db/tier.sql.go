@@ -7,6 +7,8 @@ package db
 
 import (
 	"context"
+
+	"github.com/jackc/pgx/v5/pgtype"
 )
 
 const createTier = `-- name: CreateTier :one
@@ -30,14 +32,17 @@ func (q *Queries) CreateTier(ctx context.Context, arg CreateTierParams) (Tier, e
 	return i, err
 }
 
-const deleteTier = `-- name: DeleteTier :exec
+const deleteTier = `-- name: DeleteTier :execrows
 DELETE FROM tier
 WHERE id = $1
 `
 
-func (q *Queries) DeleteTier(ctx context.Context, id int32) error {
-	_, err := q.db.Exec(ctx, deleteTier, id)
-	return err
+func (q *Queries) DeleteTier(ctx context.Context, id int32) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteTier, id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
 }
 
 const getTier = `-- name: GetTier :one
@@ -77,11 +82,66 @@ func (q *Queries) ListTiers(ctx context.Context) ([]Tier, error) {
 	return items, nil
 }
 
-const updateTier = `-- name: UpdateTier :exec
+const postTierGrants = `-- name: PostTierGrants :many
+SELECT transaction_id
+FROM POST_TIER_GRANTS($1)
+`
+
+// PostTierGrants adds the credits included in each customer's tier to their credit pool for the month containing as_of. Returns the IDs of the transactions created.
+func (q *Queries) PostTierGrants(ctx context.Context, asOf pgtype.Timestamptz) ([]pgtype.Int4, error) {
+	rows, err := q.db.Query(ctx, postTierGrants, asOf)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []pgtype.Int4
+	for rows.Next() {
+		var transaction_id pgtype.Int4
+		if err := rows.Scan(&transaction_id); err != nil {
+			return nil, err
+		}
+		items = append(items, transaction_id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const setCustomerTier = `-- name: SetCustomerTier :one
+UPDATE customer
+SET tier_id = $1
+WHERE id = $2
+RETURNING old_id, name, tier_id, id, path, slug, time_zone
+`
+
+type SetCustomerTierParams struct {
+	TierID pgtype.Int4
+	ID     pgtype.UUID
+}
+
+// SetCustomerTier assigns the customer to a tier, or removes them from their tier if tier_id is null.
+func (q *Queries) SetCustomerTier(ctx context.Context, arg SetCustomerTierParams) (Customer, error) {
+	row := q.db.QueryRow(ctx, setCustomerTier, arg.TierID, arg.ID)
+	var i Customer
+	err := row.Scan(
+		&i.OldID,
+		&i.Name,
+		&i.TierID,
+		&i.ID,
+		&i.Path,
+		&i.Slug,
+		&i.TimeZone,
+	)
+	return i, err
+}
+
+const updateTier = `-- name: UpdateTier :one
 UPDATE tier
   set name = $2,
   tier_credits = $3
   WHERE id = $1
+RETURNING id, name, tier_credits
 `
 
 type UpdateTierParams struct {
@@ -90,7 +150,9 @@ type UpdateTierParams struct {
 	TierCredits int64
 }
 
-func (q *Queries) UpdateTier(ctx context.Context, arg UpdateTierParams) error {
-	_, err := q.db.Exec(ctx, updateTier, arg.ID, arg.Name, arg.TierCredits)
-	return err
+func (q *Queries) UpdateTier(ctx context.Context, arg UpdateTierParams) (Tier, error) {
+	row := q.db.QueryRow(ctx, updateTier, arg.ID, arg.Name, arg.TierCredits)
+	var i Tier
+	err := row.Scan(&i.ID, &i.Name, &i.TierCredits)
+	return i, err
 }
@@ -11,13 +11,42 @@ import (
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
+const adjustTransaction = `-- name: AdjustTransaction :one
+SELECT ADJUST_TRANSACTION(
+  $1::int,
+  $2::bigint,
+  $3::timestamptz,
+  $4::text
+)::int AS id
+`
+
+type AdjustTransactionParams struct {
+	TransactionID      int32
+	AmountMicrocredits int64
+	OccurredAt         pgtype.Timestamptz
+	Description        pgtype.Text
+}
+
+// AdjustTransaction posts a transaction that changes the amount of transaction_id by amount_microcredits and returns the new transaction's ID.
+func (q *Queries) AdjustTransaction(ctx context.Context, arg AdjustTransactionParams) (int32, error) {
+	row := q.db.QueryRow(ctx, adjustTransaction,
+		arg.TransactionID,
+		arg.AmountMicrocredits,
+		arg.OccurredAt,
+		arg.Description,
+	)
+	var id int32
+	err := row.Scan(&id)
+	return id, err
+}
+
 const createTransaction = `-- name: CreateTransaction :one
 INSERT INTO transaction (
   occurred_at, description, type
 ) VALUES (
   $1, $2, $3
 )
-RETURNING id, occurred_at, description, type, customer_id
+RETURNING id, occurred_at, description, type, customer_id, original_transaction_id
 `
 
 type CreateTransactionParams struct {
@@ -35,12 +64,13 @@ func (q *Queries) CreateTransaction(ctx context.Context, arg CreateTransactionPa
 		&i.Description,
 		&i.Type,
 		&i.CustomerID,
+		&i.OriginalTransactionID,
 	)
 	return i, err
 }
 
 const getTransaction = `-- name: GetTransaction :one
-SELECT id, occurred_at, description, type, customer_id FROM transaction
+SELECT id, occurred_at, description, type, customer_id, original_transaction_id FROM transaction
 WHERE id = $1 LIMIT 1
 `
 
@@ -53,12 +83,234 @@ func (q *Queries) GetTransaction(ctx context.Context, id int32) (Transaction, er
 		&i.Description,
 		&i.Type,
 		&i.CustomerID,
+		&i.OriginalTransactionID,
 	)
 	return i, err
 }
 
+const listCustomerStatement = `-- name: ListCustomerStatement :many
+WITH changes AS (
+  SELECT
+    t.id,
+    t.occurred_at,
+    t.type,
+    t.description,
+    t.original_transaction_id,
+    COALESCE(SUM(e.amount_microcredits * e.direction) FILTER (WHERE at.name = 'credit_pool'), 0)::bigint AS credit_pool_change,
+    COALESCE(SUM(e.amount_microcredits * e.direction) FILTER (WHERE at.name = 'credits_used'), 0)::bigint AS credits_used_change,
+    COALESCE(-SUM(e.amount_microcredits * e.direction) FILTER (WHERE at.name = 'liabilities'), 0)::bigint AS liabilities_change
+  FROM transaction AS t
+  INNER JOIN entry AS e ON t.id = e.transaction_id
+  INNER JOIN account AS a ON e.account_id = a.id
+  INNER JOIN account_type AS at ON a.type = at.id
+  WHERE t.customer_id = $5
+  GROUP BY t.id
+),
+
+running AS (
+  SELECT
+    c.id,
+    c.occurred_at,
+    c.type,
+    c.description,
+    c.original_transaction_id,
+    c.credit_pool_change,
+    c.credits_used_change,
+    c.liabilities_change,
+    (SUM(c.credit_pool_change) OVER w)::bigint AS credit_pool_balance,
+    (SUM(c.credits_used_change) OVER w)::bigint AS credits_used_balance,
+    (SUM(c.liabilities_change) OVER w)::bigint AS liabilities_balance
+  FROM changes AS c
+  WINDOW w AS (ORDER BY c.occurred_at, c.id)
+)
+
+SELECT
+  r.id,
+  r.occurred_at,
+  r.type,
+  r.description,
+  r.original_transaction_id,
+  r.credit_pool_change,
+  r.credits_used_change,
+  r.liabilities_change,
+  r.credit_pool_balance,
+  r.credits_used_balance,
+  r.liabilities_balance,
+  (COUNT(*) OVER ())::bigint AS total_count
+FROM running AS r
+WHERE
+  (
+    $1::date IS NULL
+    OR r.occurred_at >= $1::date::timestamp AT TIME ZONE 'America/New_York'
+  )
+  AND (
+    $2::date IS NULL
+    OR r.occurred_at < $2::date::timestamp AT TIME ZONE 'America/New_York'
+  )
+ORDER BY r.occurred_at, r.id
+LIMIT $4::int
+OFFSET $3::int
+`
+
+type ListCustomerStatementParams struct {
+	StartDate  pgtype.Date
+	EndDate    pgtype.Date
+	PageOffset int32
+	PageSize   int32
+	CustomerID pgtype.UUID
+}
+
+type ListCustomerStatementRow struct {
+	ID                    int32
+	OccurredAt            pgtype.Timestamptz
+	Type                  TransactionType
+	Description           pgtype.Text
+	OriginalTransactionID pgtype.Int4
+	CreditPoolChange      int64
+	CreditsUsedChange     int64
+	LiabilitiesChange     int64
+	CreditPoolBalance     int64
+	CreditsUsedBalance    int64
+	LiabilitiesBalance    int64
+	TotalCount            int64
+}
+
+// ListCustomerStatement lists a customer's transactions that occurred from the start of start_date until the start of end_date in business time (America/New_York), oldest first, with the change each made to the customer's accounts and the running balance of each account after it. Balances follow the conventions of GetCustomerBalances. Running balances include transactions before start_date. A null bound is unbounded. TotalCount is the number of transactions in the range, regardless of page_size and page_offset.
+func (q *Queries) ListCustomerStatement(ctx context.Context, arg ListCustomerStatementParams) ([]ListCustomerStatementRow, error) {
+	rows, err := q.db.Query(ctx, listCustomerStatement,
+		arg.StartDate,
+		arg.EndDate,
+		arg.PageOffset,
+		arg.PageSize,
+		arg.CustomerID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListCustomerStatementRow
+	for rows.Next() {
+		var i ListCustomerStatementRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.OccurredAt,
+			&i.Type,
+			&i.Description,
+			&i.OriginalTransactionID,
+			&i.CreditPoolChange,
+			&i.CreditsUsedChange,
+			&i.LiabilitiesChange,
+			&i.CreditPoolBalance,
+			&i.CreditsUsedBalance,
+			&i.LiabilitiesBalance,
+			&i.TotalCount,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listTransactionCorrections = `-- name: ListTransactionCorrections :many
+SELECT id, occurred_at, description, type, customer_id, original_transaction_id
+FROM transaction
+WHERE original_transaction_id = $1
+ORDER BY id
+`
+
+// ListTransactionCorrections lists the reversals and adjustments posted against a transaction.
+func (q *Queries) ListTransactionCorrections(ctx context.Context, originalTransactionID pgtype.Int4) ([]Transaction, error) {
+	rows, err := q.db.Query(ctx, listTransactionCorrections, originalTransactionID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Transaction
+	for rows.Next() {
+		var i Transaction
+		if err := rows.Scan(
+			&i.ID,
+			&i.OccurredAt,
+			&i.Description,
+			&i.Type,
+			&i.CustomerID,
+			&i.OriginalTransactionID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listTransactionSummaries = `-- name: ListTransactionSummaries :many
+SELECT
+  t.id,
+  t.occurred_at,
+  t.type,
+  t.description,
+  t.customer_id,
+  c.name AS customer_name,
+  t.original_transaction_id,
+  COALESCE(SUM(e.amount_microcredits) FILTER (WHERE e.direction = 1), 0)::bigint AS amount_microcredits
+FROM transaction AS t
+INNER JOIN customer AS c ON t.customer_id = c.id
+LEFT JOIN entry AS e ON t.id = e.transaction_id
+WHERE t.id = ANY($1::int [])
+GROUP BY t.id, c.name
+ORDER BY c.name, t.id
+`
+
+type ListTransactionSummariesRow struct {
+	ID                    int32
+	OccurredAt            pgtype.Timestamptz
+	Type                  TransactionType
+	Description           pgtype.Text
+	CustomerID            pgtype.UUID
+	CustomerName          string
+	OriginalTransactionID pgtype.Int4
+	AmountMicrocredits    int64
+}
+
+// ListTransactionSummaries returns one row for each transaction in ids, with the name of its customer and the total amount debited by its entries.
+func (q *Queries) ListTransactionSummaries(ctx context.Context, ids []int32) ([]ListTransactionSummariesRow, error) {
+	rows, err := q.db.Query(ctx, listTransactionSummaries, ids)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListTransactionSummariesRow
+	for rows.Next() {
+		var i ListTransactionSummariesRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.OccurredAt,
+			&i.Type,
+			&i.Description,
+			&i.CustomerID,
+			&i.CustomerName,
+			&i.OriginalTransactionID,
+			&i.AmountMicrocredits,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const listTransactions = `-- name: ListTransactions :many
-SELECT id, occurred_at, description, type, customer_id FROM transaction
+SELECT id, occurred_at, description, type, customer_id, original_transaction_id FROM transaction
 ORDER BY id
 `
 
@@ -77,6 +329,7 @@ func (q *Queries) ListTransactions(ctx context.Context) ([]Transaction, error) {
 			&i.Description,
 			&i.Type,
 			&i.CustomerID,
+			&i.OriginalTransactionID,
 		); err != nil {
 			return nil, err
 		}
@@ -139,3 +392,88 @@ func (q *Queries) ListTransactionsWide(ctx context.Context) ([]ListTransactionsW
 	}
 	return items, nil
 }
+
+const listUsagePostIDs = `-- name: ListUsagePostIDs :many
+SELECT t.id
+FROM transaction AS t
+WHERE
+  t.type = 'usage_post'
+  AND t.occurred_at = $1
+  AND NOT EXISTS (
+    SELECT 1
+    FROM transaction AS rev
+    WHERE
+      rev.original_transaction_id = t.id
+      AND rev.type = 'reversal'
+  )
+ORDER BY t.id
+`
+
+// ListUsagePostIDs lists the usage_post transactions that occurred at period_end, the end of a posting period, and have not been reversed.
+func (q *Queries) ListUsagePostIDs(ctx context.Context, periodEnd pgtype.Timestamptz) ([]int32, error) {
+	rows, err := q.db.Query(ctx, listUsagePostIDs, periodEnd)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []int32
+	for rows.Next() {
+		var id int32
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		items = append(items, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const reverseTransaction = `-- name: ReverseTransaction :one
+SELECT REVERSE_TRANSACTION(
+  $1::int,
+  $2::timestamptz,
+  $3::text
+)::int AS id
+`
+
+type ReverseTransactionParams struct {
+	TransactionID int32
+	OccurredAt    pgtype.Timestamptz
+	Description   pgtype.Text
+}
+
+// ReverseTransaction posts a transaction that undoes the entries of transaction_id and returns the new transaction's ID.
+func (q *Queries) ReverseTransaction(ctx context.Context, arg ReverseTransactionParams) (int32, error) {
+	row := q.db.QueryRow(ctx, reverseTransaction, arg.TransactionID, arg.OccurredAt, arg.Description)
+	var id int32
+	err := row.Scan(&id)
+	return id, err
+}
+
+const reverseUsage = `-- name: ReverseUsage :many
+SELECT transaction_id
+FROM REVERSE_USAGE($1)
+`
+
+// ReverseUsage reverses the usage posted for the month preceding as_of by posting offsetting transactions. It returns the IDs of the reversal transactions.
+func (q *Queries) ReverseUsage(ctx context.Context, asOf pgtype.Timestamptz) ([]pgtype.Int4, error) {
+	rows, err := q.db.Query(ctx, reverseUsage, asOf)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []pgtype.Int4
+	for rows.Next() {
+		var transaction_id pgtype.Int4
+		if err := rows.Scan(&transaction_id); err != nil {
+			return nil, err
+		}
+		items = append(items, transaction_id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
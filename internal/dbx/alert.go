@@ -0,0 +1,120 @@
+package dbx
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/cloud-gov/billing/internal/db"
+)
+
+// DefaultAlertThresholds are the percentages of funded credits that trigger alerts for customers who have no thresholds of their own.
+var DefaultAlertThresholds = []int32{50, 80, 100}
+
+// BurnRateWindow is the period of recent usage used to compute a customer's daily burn rate.
+const BurnRateWindow = 30 * 24 * time.Hour
+
+// AssessFunding returns the alerts that should be recorded for a customer given their funding and the thresholds that apply to them. Only the highest threshold reached is returned, so a customer who first crosses several thresholds at once receives one alert.
+//
+// Consumption is measured against the credits provided by agreements that have started and not ended. Customers without such agreements are considered to have consumed all their credits once their remaining balance is negative.
+func AssessFunding(f db.ListCustomerFundingRow, thresholds []int32) []db.CreateAlertParams {
+	remaining := f.PoolMicrocredits - f.UnpostedMicrocredits
+	if f.FundedMicrocredits <= 0 && remaining >= 0 {
+		return nil
+	}
+
+	var percent int64 = 100
+	if f.FundedMicrocredits > 0 {
+		percent = max(0, (f.FundedMicrocredits-remaining)*100/f.FundedMicrocredits)
+	}
+
+	daily := f.RecentMicrocredits / int64(BurnRateWindow/(24*time.Hour))
+	var exhaustion pgtype.Date
+	if daily > 0 && f.BusinessDate.Valid {
+		days := max(0, remaining/daily)
+		exhaustion = pgtype.Date{Time: f.BusinessDate.Time.AddDate(0, 0, int(days)), Valid: true}
+	}
+
+	base := db.CreateAlertParams{
+		CustomerID:              f.CustomerID,
+		FundedMicrocredits:      f.FundedMicrocredits,
+		RemainingMicrocredits:   remaining,
+		DailyBurnMicrocredits:   daily,
+		ProjectedExhaustionDate: exhaustion,
+		FundedUntil:             f.FundedUntil,
+	}
+
+	var alerts []db.CreateAlertParams
+	sorted := slices.Sorted(slices.Values(thresholds))
+	for i := len(sorted) - 1; i >= 0; i-- {
+		if percent >= int64(sorted[i]) {
+			a := base
+			a.Kind = db.AlertKindConsumed
+			a.PercentConsumed = sorted[i]
+			alerts = append(alerts, a)
+			break
+		}
+	}
+	if exhaustion.Valid && f.FundedUntil.Valid && exhaustion.Time.Before(f.FundedUntil.Time) {
+		a := base
+		a.Kind = db.AlertKindProjectedExhaustion
+		alerts = append(alerts, a)
+	}
+	return alerts
+}
+
+// RecordAlerts assesses every customer's funding as of asOf and records new alerts. Alerts that were already recorded for the same threshold and funding level are not recorded again, so RecordAlerts is idempotent. It returns the alerts it recorded.
+func RecordAlerts(ctx context.Context, q db.Querier, asOf time.Time) ([]db.Alert, error) {
+	funding, err := q.ListCustomerFunding(ctx, db.ListCustomerFundingParams{
+		AsOf:        pgtype.Timestamptz{Time: asOf, Valid: true},
+		WindowStart: pgtype.Timestamptz{Time: asOf.Add(-BurnRateWindow), Valid: true},
+	})
+	if err != nil {
+		return nil, err
+	}
+	rows, err := q.ListAlertThresholds(ctx, pgtype.UUID{})
+	if err != nil {
+		return nil, err
+	}
+	thresholds := make(map[pgtype.UUID][]int32)
+	for _, row := range rows {
+		thresholds[row.CustomerID] = append(thresholds[row.CustomerID], row.PercentConsumed)
+	}
+
+	var recorded []db.Alert
+	for _, f := range funding {
+		t, ok := thresholds[f.CustomerID]
+		if !ok {
+			t = DefaultAlertThresholds
+		}
+		for _, params := range AssessFunding(f, t) {
+			a, err := q.CreateAlert(ctx, params)
+			if errors.Is(err, pgx.ErrNoRows) {
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+			recorded = append(recorded, a)
+		}
+	}
+	return recorded, nil
+}
+
+// SetAlertThresholds replaces the customer's alert thresholds. If percents is empty, the customer reverts to [DefaultAlertThresholds]. q should be scoped to a transaction.
+func SetAlertThresholds(ctx context.Context, q db.Querier, customerID pgtype.UUID, percents []int32) error {
+	if err := q.DeleteAlertThresholds(ctx, customerID); err != nil {
+		return err
+	}
+	if len(percents) == 0 {
+		return nil
+	}
+	return q.CreateAlertThresholds(ctx, db.CreateAlertThresholdsParams{
+		CustomerID:       customerID,
+		PercentsConsumed: percents,
+	})
+}
@@ -0,0 +1,185 @@
+package dbx_test
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/cloud-gov/billing/internal/db"
+	"github.com/cloud-gov/billing/internal/dbx"
+	. "github.com/cloud-gov/billing/internal/testutil"
+)
+
+func TestAssessFunding(t *testing.T) {
+	today := pgDate(2025, time.February, 1)
+	popEnd := pgDate(2025, time.September, 30)
+	testCases := []struct {
+		name    string
+		funding db.ListCustomerFundingRow
+		want    []db.CreateAlertParams
+	}{
+		{
+			name:    "no agreement and no overrun",
+			funding: db.ListCustomerFundingRow{BusinessDate: today},
+		},
+		{
+			name: "below thresholds",
+			funding: db.ListCustomerFundingRow{
+				BusinessDate:       today,
+				PoolMicrocredits:   1000,
+				FundedMicrocredits: 1000,
+				FundedUntil:        popEnd,
+			},
+		},
+		{
+			name: "highest threshold only, with unposted usage",
+			funding: db.ListCustomerFundingRow{
+				BusinessDate:         today,
+				PoolMicrocredits:     500,
+				UnpostedMicrocredits: 350,
+				FundedMicrocredits:   1000,
+				FundedUntil:          popEnd,
+			},
+			want: []db.CreateAlertParams{
+				{Kind: db.AlertKindConsumed, PercentConsumed: 80, FundedMicrocredits: 1000, RemainingMicrocredits: 150, FundedUntil: popEnd},
+			},
+		},
+		{
+			name: "projected to run out before the end of the period of performance",
+			funding: db.ListCustomerFundingRow{
+				BusinessDate:       today,
+				PoolMicrocredits:   900,
+				RecentMicrocredits: 300, // 10 per day over 30 days
+				FundedMicrocredits: 1000,
+				FundedUntil:        popEnd,
+			},
+			want: []db.CreateAlertParams{
+				{
+					Kind:                    db.AlertKindProjectedExhaustion,
+					FundedMicrocredits:      1000,
+					RemainingMicrocredits:   900,
+					DailyBurnMicrocredits:   10,
+					ProjectedExhaustionDate: pgDate(2025, time.May, 2),
+					FundedUntil:             popEnd,
+				},
+			},
+		},
+		{
+			name: "overrun without an agreement",
+			funding: db.ListCustomerFundingRow{
+				BusinessDate:         today,
+				UnpostedMicrocredits: 5,
+			},
+			want: []db.CreateAlertParams{
+				{Kind: db.AlertKindConsumed, PercentConsumed: 100, RemainingMicrocredits: -5},
+			},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := dbx.AssessFunding(tc.funding, dbx.DefaultAlertThresholds)
+			if len(got) != len(tc.want) {
+				t.Fatalf("expected %v alerts, got %+v", len(tc.want), got)
+			}
+			for i := range got {
+				if got[i] != tc.want[i] {
+					t.Errorf("expected %+v, got %+v", tc.want[i], got[i])
+				}
+			}
+		})
+	}
+}
+
+func TestDBRecordAlerts(t *testing.T) {
+	conn, err := pgxpool.New(t.Context(), "")
+	if err != nil {
+		t.Fatal("creating database connection failed", err)
+	}
+	q := newTx(t, conn, false)
+	td := usageTestData()
+	createTestData(t, q, td)
+	customerID := td.CustomerIDs["customer1"]
+
+	_, err = q.CreateIAA(t.Context(), db.CreateIAAParams{
+		CustomerID:         customerID,
+		AmountMicrocredits: 100,
+		PopStart:           pgDate(2025, time.January, 1),
+		PopEnd:             pgDate(2025, time.December, 31),
+	})
+	if err != nil {
+		t.Fatal("creating agreement:", err)
+	}
+	if _, err = q.PostIAAPop(t.Context(), PgTimestamptz(time.Date(2025, time.January, 2, 12, 0, 0, 0, time.UTC))); err != nil {
+		t.Fatal("posting agreement:", err)
+	}
+	if err = dbx.SetAlertThresholds(t.Context(), q, customerID, []int32{25}); err != nil {
+		t.Fatal("setting thresholds:", err)
+	}
+
+	// 30 microcredits were measured in February and have not been posted.
+	asOf := time.Date(2025, time.February, 5, 12, 0, 0, 0, time.UTC)
+	alerts, err := dbx.RecordAlerts(t.Context(), q, asOf)
+	if err != nil {
+		t.Fatal("recording alerts:", err)
+	}
+	alerts = slices.DeleteFunc(alerts, func(a db.Alert) bool { return a.CustomerID != customerID })
+	if len(alerts) != 2 {
+		t.Fatalf("expected two alerts, got %+v", alerts)
+	}
+	if a := alerts[0]; a.Kind != db.AlertKindConsumed || a.PercentConsumed != 25 || a.RemainingMicrocredits != 70 {
+		t.Errorf("unexpected consumed alert %+v", a)
+	}
+	if a := alerts[1]; a.Kind != db.AlertKindProjectedExhaustion || a.DailyBurnMicrocredits != 1 || a.ProjectedExhaustionDate != pgDate(2025, time.April, 16) {
+		t.Errorf("unexpected projected exhaustion alert %+v", a)
+	}
+
+	// Alerts are recorded once per threshold and funding level.
+	alerts, err = dbx.RecordAlerts(t.Context(), q, asOf.Add(time.Hour))
+	if err != nil {
+		t.Fatal("recording alerts again:", err)
+	}
+	alerts = slices.DeleteFunc(alerts, func(a db.Alert) bool { return a.CustomerID != customerID })
+	if len(alerts) != 0 {
+		t.Fatalf("expected no new alerts, got %+v", alerts)
+	}
+
+	pending, err := q.ListAlerts(t.Context(), db.ListAlertsParams{CustomerID: customerID, PageSize: 10})
+	if err != nil {
+		t.Fatal("listing alerts:", err)
+	}
+	if len(pending) != 2 || pending[0].Alert.NotifiedAt.Valid {
+		t.Fatalf("expected two unnotified alerts, got %+v", pending)
+	}
+}
+
+func TestDBListCustomerFundingEstimatesUnpricedUsage(t *testing.T) {
+	td := usageTestData()
+	// The second measurement has not been priced, so its cost is estimated from the price: 15 microcredits per unit.
+	td.Measurements[1].AmountMicrocredits = pgtype.Int8{}
+
+	conn, err := pgxpool.New(t.Context(), "")
+	if err != nil {
+		t.Fatal("creating database connection failed", err)
+	}
+	q := newTx(t, conn, false)
+	createTestData(t, q, td)
+	customerID := td.CustomerIDs["customer1"]
+
+	rows, err := q.ListCustomerFunding(t.Context(), db.ListCustomerFundingParams{
+		AsOf:        PgTimestamptz(time.Date(2025, time.February, 5, 12, 0, 0, 0, time.UTC)),
+		WindowStart: PgTimestamptz(time.Date(2025, time.February, 4, 0, 0, 0, 0, time.UTC)),
+	})
+	if err != nil {
+		t.Fatal("listing customer funding:", err)
+	}
+	i := slices.IndexFunc(rows, func(r db.ListCustomerFundingRow) bool { return r.CustomerID == customerID })
+	if i < 0 {
+		t.Fatal("customer not found")
+	}
+	if rows[i].UnpostedMicrocredits != 25 || rows[i].RecentMicrocredits != 15 {
+		t.Errorf("expected 25 unposted and 15 recent microcredits, got %+v", rows[i])
+	}
+}
@@ -0,0 +1,67 @@
+package dbx_test
+
+import (
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/cloud-gov/billing/internal/db"
+	. "github.com/cloud-gov/billing/internal/testutil"
+)
+
+func TestDBOrphanCFOrgs(t *testing.T) {
+	conn, err := pgxpool.New(t.Context(), "")
+	if err != nil {
+		t.Fatal("creating database connection failed", err)
+	}
+	q := newTx(t, conn, false)
+	td := usageTestData()
+	createTestData(t, q, td)
+	orgID := td.CFOrgs[0].ID
+
+	findOrphan := func() (db.ListOrphanCFOrgsRow, bool) {
+		t.Helper()
+		rows, err := q.ListOrphanCFOrgs(t.Context())
+		if err != nil {
+			t.Fatal("listing orphan orgs:", err)
+		}
+		for _, row := range rows {
+			if row.CFOrg.ID == orgID {
+				return row, true
+			}
+		}
+		return db.ListOrphanCFOrgsRow{}, false
+	}
+
+	if _, ok := findOrphan(); ok {
+		t.Fatal("expected assigned org not to be an orphan")
+	}
+
+	if _, err = q.SetCFOrgCustomer(t.Context(), db.SetCFOrgCustomerParams{ID: orgID}); err != nil {
+		t.Fatal("unassigning org:", err)
+	}
+	row, ok := findOrphan()
+	if !ok {
+		t.Fatal("expected unassigned org with measurements to be an orphan")
+	}
+	if row.MeasurementCount != 2 {
+		t.Fatalf("expected 2 measurements, got %v", row.MeasurementCount)
+	}
+
+	if _, err = q.SetCFOrgCustomer(t.Context(), db.SetCFOrgCustomerParams{ID: orgID, CustomerID: td.CustomerIDs["customer1"]}); err != nil {
+		t.Fatal("assigning org:", err)
+	}
+	if _, ok := findOrphan(); ok {
+		t.Fatal("expected reassigned org not to be an orphan")
+	}
+
+	// Orgs can be assigned before they are first seen.
+	o, err := q.SetCFOrgCustomer(t.Context(), db.SetCFOrgCustomerParams{ID: PgUUID(), CustomerID: td.CustomerIDs["customer1"]})
+	if err != nil {
+		t.Fatal("assigning new org:", err)
+	}
+	if o.CustomerID != td.CustomerIDs["customer1"] || o.Name != (pgtype.Text{}) {
+		t.Fatalf("unexpected org %+v", o)
+	}
+}
@@ -0,0 +1,132 @@
+package dbx
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/cloud-gov/billing/internal/db"
+)
+
+var ErrCustomerNotFound = errors.New("customer not found")
+
+// AccountBalance is the balance of one of a customer's accounts. See [db.Queries.GetCustomerBalances] for how balances are signed.
+type AccountBalance struct {
+	AccountType         string `json:"account_type"`
+	DebitsMicrocredits  int64  `json:"debits_microcredits"`
+	CreditsMicrocredits int64  `json:"credits_microcredits"`
+	BalanceMicrocredits int64  `json:"balance_microcredits"`
+}
+
+// CustomerBalance is the current balance of each of a customer's accounts.
+type CustomerBalance struct {
+	CustomerID   pgtype.UUID `json:"customer_id"`
+	CustomerName string      `json:"customer_name"`
+	// RemainingMicrocredits is the balance of the customer's credit_pool account: the credits they have left to spend.
+	RemainingMicrocredits int64            `json:"remaining_microcredits"`
+	Accounts              []AccountBalance `json:"accounts"`
+}
+
+// GetCustomerBalance returns the current balance of each of the customer's accounts.
+func GetCustomerBalance(ctx context.Context, q db.Querier, customerID pgtype.UUID) (CustomerBalance, error) {
+	c, err := q.GetCustomer(ctx, customerID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return CustomerBalance{}, ErrCustomerNotFound
+	}
+	if err != nil {
+		return CustomerBalance{}, err
+	}
+	rows, err := q.GetCustomerBalances(ctx, customerID)
+	if err != nil {
+		return CustomerBalance{}, err
+	}
+	b := CustomerBalance{
+		CustomerID:   c.ID,
+		CustomerName: c.Name,
+		Accounts:     make([]AccountBalance, len(rows)),
+	}
+	for i, r := range rows {
+		b.Accounts[i] = AccountBalance{
+			AccountType:         r.AccountTypeName,
+			DebitsMicrocredits:  r.DebitsMicrocredits,
+			CreditsMicrocredits: r.CreditsMicrocredits,
+			BalanceMicrocredits: r.BalanceMicrocredits,
+		}
+		if r.AccountTypeName == "credit_pool" {
+			b.RemainingMicrocredits = r.BalanceMicrocredits
+		}
+	}
+	return b, nil
+}
+
+// StatementLine is a transaction on a customer's statement, with the change it made to each account and the balance of each account after it.
+type StatementLine struct {
+	TransactionID         int32              `json:"transaction_id"`
+	OccurredAt            time.Time          `json:"occurred_at"`
+	Type                  db.TransactionType `json:"type"`
+	Description           string             `json:"description"`
+	OriginalTransactionID int32              `json:"original_transaction_id,omitempty"`
+	CreditPoolChange      int64              `json:"credit_pool_change_microcredits"`
+	CreditsUsedChange     int64              `json:"credits_used_change_microcredits"`
+	LiabilitiesChange     int64              `json:"liabilities_change_microcredits"`
+	CreditPoolBalance     int64              `json:"credit_pool_balance_microcredits"`
+	CreditsUsedBalance    int64              `json:"credits_used_balance_microcredits"`
+	LiabilitiesBalance    int64              `json:"liabilities_balance_microcredits"`
+}
+
+// Statement is one page of a customer's transactions.
+type Statement struct {
+	CustomerID pgtype.UUID `json:"customer_id"`
+	// Total is the number of transactions in the statement's date range, across all pages.
+	Total  int64           `json:"total"`
+	Limit  int32           `json:"limit"`
+	Offset int32           `json:"offset"`
+	Lines  []StatementLine `json:"lines"`
+}
+
+// GetCustomerStatement returns a page of the customer's transactions that occurred from the start of the start date until the start of the end date in business time. Invalid dates are unbounded.
+func GetCustomerStatement(ctx context.Context, q db.Querier, customerID pgtype.UUID, start, end pgtype.Date, limit, offset int32) (Statement, error) {
+	_, err := q.GetCustomer(ctx, customerID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return Statement{}, ErrCustomerNotFound
+	}
+	if err != nil {
+		return Statement{}, err
+	}
+	rows, err := q.ListCustomerStatement(ctx, db.ListCustomerStatementParams{
+		CustomerID: customerID,
+		StartDate:  start,
+		EndDate:    end,
+		PageSize:   limit,
+		PageOffset: offset,
+	})
+	if err != nil {
+		return Statement{}, err
+	}
+	s := Statement{
+		CustomerID: customerID,
+		Limit:      limit,
+		Offset:     offset,
+		Lines:      make([]StatementLine, len(rows)),
+	}
+	for i, r := range rows {
+		s.Total = r.TotalCount
+		s.Lines[i] = StatementLine{
+			TransactionID:         r.ID,
+			OccurredAt:            r.OccurredAt.Time,
+			Type:                  r.Type,
+			Description:           r.Description.String,
+			OriginalTransactionID: r.OriginalTransactionID.Int32,
+			CreditPoolChange:      r.CreditPoolChange,
+			CreditsUsedChange:     r.CreditsUsedChange,
+			LiabilitiesChange:     r.LiabilitiesChange,
+			CreditPoolBalance:     r.CreditPoolBalance,
+			CreditsUsedBalance:    r.CreditsUsedBalance,
+			LiabilitiesBalance:    r.LiabilitiesBalance,
+		}
+	}
+	return s, nil
+}
@@ -0,0 +1,70 @@
+package dbx_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/cloud-gov/billing/internal/db"
+	"github.com/cloud-gov/billing/internal/dbx"
+	. "github.com/cloud-gov/billing/internal/testutil"
+)
+
+func TestDBCustomerBalanceAndStatement(t *testing.T) {
+	tz, _ := time.LoadLocation("America/New_York")
+
+	conn, err := pgxpool.New(t.Context(), "")
+	if err != nil {
+		t.Fatal("creating database connection failed", err)
+	}
+	q := newTx(t, conn, false)
+	td := usageTestData()
+	createTestData(t, q, td)
+	customerID := td.CustomerIDs["customer1"]
+
+	// 100 credits are granted in January, and 30 are used in February.
+	_, err = q.CreateIAA(t.Context(), db.CreateIAAParams{
+		CustomerID:         customerID,
+		AmountMicrocredits: 100,
+		PopStart:           pgDate(2025, time.January, 1),
+		PopEnd:             pgDate(2025, time.December, 31),
+	})
+	if err != nil {
+		t.Fatal("creating agreement:", err)
+	}
+	if _, err = q.PostIAAPop(t.Context(), PgTimestamptz(time.Date(2025, time.January, 2, 0, 0, 0, 0, tz))); err != nil {
+		t.Fatal("posting agreement:", err)
+	}
+	if _, err = dbx.CloseMonth(t.Context(), q, PgTimestamptz(time.Date(2025, time.March, 1, 0, 0, 0, 0, tz))); err != nil {
+		t.Fatal("closing month:", err)
+	}
+
+	b, err := dbx.GetCustomerBalance(t.Context(), q, customerID)
+	if err != nil {
+		t.Fatal("getting balance:", err)
+	}
+	if b.RemainingMicrocredits != 70 {
+		t.Fatalf("expected 70 microcredits remaining, got %+v", b)
+	}
+	for _, a := range b.Accounts {
+		want := map[string]int64{"credit_pool": 70, "credits_used": 30, "liabilities": 100, "expenses": 0}[a.AccountType]
+		if a.BalanceMicrocredits != want {
+			t.Errorf("expected %v balance %v, got %v", a.AccountType, want, a.BalanceMicrocredits)
+		}
+	}
+
+	s, err := dbx.GetCustomerStatement(t.Context(), q, customerID, pgDate(2025, time.February, 1), pgtype.Date{}, 10, 0)
+	if err != nil {
+		t.Fatal("getting statement:", err)
+	}
+	// The January grant is outside the range, but is included in running balances.
+	if s.Total != 1 || len(s.Lines) != 1 {
+		t.Fatalf("expected one statement line, got %+v", s)
+	}
+	line := s.Lines[0]
+	if line.CreditPoolChange != -30 || line.CreditPoolBalance != 70 || line.CreditsUsedBalance != 30 || line.LiabilitiesBalance != 100 {
+		t.Fatalf("unexpected statement line %+v", line)
+	}
+}
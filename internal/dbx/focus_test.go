@@ -0,0 +1,50 @@
+package dbx_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/cloud-gov/billing/internal/db"
+	. "github.com/cloud-gov/billing/internal/testutil"
+)
+
+func TestDBListResourceCosts(t *testing.T) {
+	td := usageTestData()
+	// The second measurement has not been priced, so its cost is estimated from the price: 15 microcredits per unit.
+	td.Measurements[1].AmountMicrocredits = pgtype.Int8{}
+
+	conn, err := pgxpool.New(t.Context(), "")
+	if err != nil {
+		t.Fatal("creating database connection failed", err)
+	}
+	q := newTx(t, conn, false)
+	createTestData(t, q, td)
+
+	rows, err := q.ListResourceCosts(t.Context(), db.ListResourceCostsParams{
+		Granularity: "day",
+		CustomerID:  td.CustomerIDs["customer1"],
+		Since:       PgTimestamptz(time.Date(2025, time.February, 1, 0, 0, 0, 0, time.UTC)),
+		Until:       PgTimestamptz(time.Date(2025, time.March, 1, 0, 0, 0, 0, time.UTC)),
+	})
+	if err != nil {
+		t.Fatal("listing resource costs:", err)
+	}
+	if len(rows) != 2 {
+		t.Fatalf("expected a row for each day with a reading, got %+v", rows)
+	}
+	if !rows[0].ChargePeriodStart.Time.Equal(time.Date(2025, time.February, 3, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("unexpected charge period start %v", rows[0].ChargePeriodStart.Time)
+	}
+	if rows[0].BilledMicrocredits != 10 || rows[0].EffectiveMicrocredits != 10 || rows[0].Quantity != 1 || rows[0].PriceID != 1 {
+		t.Errorf("unexpected priced row %+v", rows[0])
+	}
+	if rows[1].BilledMicrocredits != 0 || rows[1].EffectiveMicrocredits != 15 {
+		t.Errorf("expected unpriced usage to be estimated but not billed, got %+v", rows[1])
+	}
+	if rows[0].CustomerName != "customer1" || rows[0].ResourceNaturalID != "resource-1" || rows[0].Estimated {
+		t.Errorf("unexpected row %+v", rows[0])
+	}
+}
@@ -0,0 +1,80 @@
+package dbx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/cloud-gov/billing/internal/db"
+)
+
+var (
+	ErrIAANotFound = errors.New("agreement not found")
+	ErrIAAEnded    = errors.New("agreement has ended and cannot be amended")
+	ErrIAAStarted  = errors.New("the start of an agreement's period of performance cannot change after it has started")
+)
+
+// AmendIAA updates agreement id in a transaction. amend is called with the agreement's current values while it is locked, and changes them to the amended values; if amend returns an error, the agreement is not changed and the error is returned. If the agreement's period of performance has started, a change to its amount is posted as an adjustment to the transaction that added its credits; the original transaction is not modified. Agreements cannot be amended after their period of performance has ended.
+func AmendIAA(ctx context.Context, conn Beginner, q Querier, id int32, amend func(*db.UpdateIAAParams) error) (db.IAA, error) {
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		return db.IAA{}, err
+	}
+	defer tx.Rollback(ctx)
+	txq := q.WithTx(tx)
+
+	current, err := txq.GetIAAForUpdate(ctx, id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return db.IAA{}, ErrIAANotFound
+	}
+	if err != nil {
+		return db.IAA{}, err
+	}
+	if current.EndedAt.Valid {
+		return db.IAA{}, ErrIAAEnded
+	}
+	arg := db.UpdateIAAParams{
+		ID:                 current.ID,
+		Number:             current.Number,
+		AmountMicrocredits: current.AmountMicrocredits,
+		PopStart:           current.PopStart,
+		PopEnd:             current.PopEnd,
+		Rollover:           current.Rollover,
+	}
+	if err := amend(&arg); err != nil {
+		return db.IAA{}, err
+	}
+	arg.ID = current.ID
+
+	if current.StartTransactionID.Valid {
+		if !current.PopStart.Time.Equal(arg.PopStart.Time) {
+			return db.IAA{}, ErrIAAStarted
+		}
+		delta := arg.AmountMicrocredits - current.AmountMicrocredits
+		if delta != 0 {
+			_, err = AdjustTransaction(ctx, txq, current.StartTransactionID.Int32, delta, fmt.Sprintf("IAA %v amended", iaaLabel(current)))
+			if err != nil {
+				return db.IAA{}, err
+			}
+		}
+	}
+
+	amended, err := txq.UpdateIAA(ctx, arg)
+	if err != nil {
+		return db.IAA{}, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return db.IAA{}, err
+	}
+	return amended, nil
+}
+
+// iaaLabel returns the agreement's number, or its ID if it has no number. This matches the descriptions used by post_iaa_pop.
+func iaaLabel(i db.IAA) string {
+	if i.Number != "" {
+		return i.Number
+	}
+	return fmt.Sprintf("#%v", i.ID)
+}
@@ -0,0 +1,197 @@
+package dbx_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/cloud-gov/billing/internal/db"
+	"github.com/cloud-gov/billing/internal/dbx"
+	. "github.com/cloud-gov/billing/internal/testutil"
+)
+
+func pgDate(y int, m time.Month, d int) pgtype.Date {
+	return pgtype.Date{Time: time.Date(y, m, d, 0, 0, 0, 0, time.UTC), Valid: true}
+}
+
+func TestDBPostIAAPop(t *testing.T) {
+	tz, _ := time.LoadLocation("America/New_York")
+
+	conn, err := pgxpool.New(t.Context(), "")
+	if err != nil {
+		t.Fatal("creating database connection failed", err)
+	}
+	tx, q := newTxBeginner(t, conn, false)
+	td := testData{
+		CustomerIDs: map[string]pgtype.UUID{},
+		Customers:   []db.Customer{{Name: "customer1"}},
+	}
+	createTestData(t, q, td)
+	customerID := td.CustomerIDs["customer1"]
+
+	first, err := q.CreateIAA(t.Context(), db.CreateIAAParams{
+		CustomerID:         customerID,
+		Number:             "IAA-1",
+		AmountMicrocredits: 100,
+		PopStart:           pgDate(2025, time.January, 1),
+		PopEnd:             pgDate(2025, time.January, 31),
+		Rollover:           true,
+	})
+	if err != nil {
+		t.Fatal("creating agreement:", err)
+	}
+	second, err := q.CreateIAA(t.Context(), db.CreateIAAParams{
+		CustomerID:         customerID,
+		Number:             "IAA-2",
+		AmountMicrocredits: 50,
+		PopStart:           pgDate(2025, time.February, 1),
+		PopEnd:             pgDate(2025, time.February, 28),
+	})
+	if err != nil {
+		t.Fatal("creating agreement:", err)
+	}
+
+	// Before either PoP starts, nothing is posted.
+	ids, err := q.PostIAAPop(t.Context(), PgTimestamptz(time.Date(2024, time.December, 31, 23, 0, 0, 0, tz)))
+	if err != nil {
+		t.Fatal("posting:", err)
+	}
+	if len(ids) != 0 {
+		t.Fatalf("expected no transactions before PoP start, got %v", ids)
+	}
+
+	// The first agreement starts.
+	ids, err = q.PostIAAPop(t.Context(), PgTimestamptz(time.Date(2025, time.January, 1, 0, 30, 0, 0, tz)))
+	if err != nil {
+		t.Fatal("posting:", err)
+	}
+	if len(ids) != 1 {
+		t.Fatalf("expected one start transaction, got %v", ids)
+	}
+
+	// Amending the amount after the start posts an adjustment.
+	amended, err := dbx.AmendIAA(t.Context(), tx, q, first.ID, func(p *db.UpdateIAAParams) error {
+		p.AmountMicrocredits = 120
+		return nil
+	})
+	if err != nil {
+		t.Fatal("amending agreement:", err)
+	}
+	h, err := dbx.GetTransactionHistory(t.Context(), q, amended.StartTransactionID.Int32)
+	if err != nil {
+		t.Fatal("getting start transaction:", err)
+	}
+	if h.AmountMicrocredits != 100 || len(h.Corrections) != 1 || h.Corrections[0].AmountMicrocredits != 20 {
+		t.Fatalf("expected start transaction of 100 with an adjustment of 20, got %+v", h)
+	}
+	_, err = dbx.AmendIAA(t.Context(), tx, q, first.ID, func(p *db.UpdateIAAParams) error {
+		p.PopStart = pgDate(2025, time.January, 2)
+		return nil
+	})
+	if !errors.Is(err, dbx.ErrIAAStarted) {
+		t.Fatalf("expected ErrIAAStarted, got %v", err)
+	}
+
+	// The first agreement ends and rolls its unused credits over to the second, which starts.
+	ids, err = q.PostIAAPop(t.Context(), PgTimestamptz(time.Date(2025, time.February, 1, 0, 30, 0, 0, tz)))
+	if err != nil {
+		t.Fatal("posting:", err)
+	}
+	if len(ids) != 1 {
+		t.Fatalf("expected only a start transaction for the second agreement, got %v", ids)
+	}
+	second, err = q.GetIAA(t.Context(), second.ID)
+	if err != nil {
+		t.Fatal("getting agreement:", err)
+	}
+	if second.RolledOverMicrocredits != 120 {
+		t.Fatalf("expected 120 microcredits rolled over, got %v", second.RolledOverMicrocredits)
+	}
+
+	// The second agreement ends and all unused credits expire.
+	ids, err = q.PostIAAPop(t.Context(), PgTimestamptz(time.Date(2025, time.March, 1, 0, 30, 0, 0, tz)))
+	if err != nil {
+		t.Fatal("posting:", err)
+	}
+	if len(ids) != 1 {
+		t.Fatalf("expected one end transaction, got %v", ids)
+	}
+	h, err = dbx.GetTransactionHistory(t.Context(), q, ids[0].Int32)
+	if err != nil {
+		t.Fatal("getting end transaction:", err)
+	}
+	if h.Type != db.TransactionTypeIaaPopEnd || h.AmountMicrocredits != 170 {
+		t.Fatalf("expected 170 microcredits to expire, got %+v", h)
+	}
+	_, err = dbx.AmendIAA(t.Context(), tx, q, second.ID, func(*db.UpdateIAAParams) error { return nil })
+	if !errors.Is(err, dbx.ErrIAAEnded) {
+		t.Fatalf("expected ErrIAAEnded, got %v", err)
+	}
+}
+
+func TestDBPostIAAPopKeepsTierCredits(t *testing.T) {
+	tz, _ := time.LoadLocation("America/New_York")
+
+	conn, err := pgxpool.New(t.Context(), "")
+	if err != nil {
+		t.Fatal("creating database connection failed", err)
+	}
+	q := newTx(t, conn, false)
+	td := testData{
+		CustomerIDs: map[string]pgtype.UUID{},
+		Customers:   []db.Customer{{Name: "customer1"}},
+	}
+	createTestData(t, q, td)
+	customerID := td.CustomerIDs["customer1"]
+
+	tier, err := q.CreateTier(t.Context(), db.CreateTierParams{Name: "Test", TierCredits: 5})
+	if err != nil {
+		t.Fatal("creating tier:", err)
+	}
+	if _, err = q.SetCustomerTier(t.Context(), db.SetCustomerTierParams{ID: customerID, TierID: PgInt4(tier.ID)}); err != nil {
+		t.Fatal("assigning tier:", err)
+	}
+	agreement, err := q.CreateIAA(t.Context(), db.CreateIAAParams{
+		CustomerID:         customerID,
+		AmountMicrocredits: 100,
+		PopStart:           pgDate(2025, time.January, 1),
+		PopEnd:             pgDate(2025, time.January, 31),
+	})
+	if err != nil {
+		t.Fatal("creating agreement:", err)
+	}
+
+	start := PgTimestamptz(time.Date(2025, time.January, 1, 0, 30, 0, 0, tz))
+	if _, err = q.PostTierGrants(t.Context(), start); err != nil {
+		t.Fatal("posting tier grants:", err)
+	}
+	if _, err = q.PostIAAPop(t.Context(), start); err != nil {
+		t.Fatal("posting:", err)
+	}
+	// Remove the agreement's credits, so only tier credits remain in the pool.
+	if agreement, err = q.GetIAA(t.Context(), agreement.ID); err != nil {
+		t.Fatal("getting agreement:", err)
+	}
+	if _, err = dbx.ReverseTransaction(t.Context(), q, agreement.StartTransactionID.Int32, "test"); err != nil {
+		t.Fatal("reversing start transaction:", err)
+	}
+
+	// The agreement ends with no unused credits of its own, so tier credits do not expire with it.
+	ids, err := q.PostIAAPop(t.Context(), PgTimestamptz(time.Date(2025, time.February, 1, 0, 30, 0, 0, tz)))
+	if err != nil {
+		t.Fatal("posting:", err)
+	}
+	if len(ids) != 0 {
+		t.Fatalf("expected no end transaction, got %v", ids)
+	}
+	b, err := dbx.GetCustomerBalance(t.Context(), q, customerID)
+	if err != nil {
+		t.Fatal("getting balance:", err)
+	}
+	if b.RemainingMicrocredits != 5_000_000 {
+		t.Fatalf("expected 5 tier credits to remain, got %v microcredits", b.RemainingMicrocredits)
+	}
+}
@@ -0,0 +1,176 @@
+package dbx
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/cloud-gov/billing/internal/db"
+)
+
+var ErrInvoiceNotFound = errors.New("invoice not found")
+
+// InvoiceLine is the usage of one resource kind in one CF org and space, at one price.
+type InvoiceLine struct {
+	Line          int32       `json:"line"`
+	CFOrgID       pgtype.UUID `json:"cf_org_id"`
+	CFOrgName     string      `json:"cf_org_name"`
+	Space         string      `json:"space"`
+	Meter         string      `json:"meter"`
+	KindNaturalID string      `json:"kind_natural_id"`
+	KindName      string      `json:"kind_name"`
+	PriceID       int32       `json:"price_id"`
+	UnitOfMeasure string      `json:"unit_of_measure"`
+	// Accrues is true if Quantity is in unit-hours.
+	Accrues bool `json:"accrues"`
+	// MicrocreditsPerUnit is the price of Unit units of measure, or unit-hours if the kind accrues.
+	MicrocreditsPerUnit int64 `json:"microcredits_per_unit"`
+	Unit                int64 `json:"unit"`
+	// Quantity is a decimal number formatted as a string, so it is not rounded by JSON parsers.
+	Quantity string `json:"quantity"`
+	// EstimatedQuantity is the part of Quantity that was estimated for hours in which usage was not read, formatted like Quantity.
+	EstimatedQuantity  string `json:"estimated_quantity"`
+	AmountMicrocredits int64  `json:"amount_microcredits"`
+}
+
+// Invoice documents the usage a customer was charged for in a closed month. See [CreateInvoices].
+type Invoice struct {
+	ID            int32       `json:"id"`
+	Number        int32       `json:"number"`
+	TransactionID int32       `json:"transaction_id"`
+	CustomerID    pgtype.UUID `json:"customer_id"`
+	CustomerName  string      `json:"customer_name"`
+	IAANumber     string      `json:"iaa_number,omitempty"`
+	// PeriodStart is the inclusive start of the month.
+	PeriodStart time.Time `json:"period_start"`
+	// PeriodEnd is the exclusive end of the month.
+	PeriodEnd time.Time `json:"period_end"`
+	// UsageMicrocredits is the total of the lines: the credits drawn from the customer's credit pool.
+	UsageMicrocredits            int64     `json:"usage_microcredits"`
+	CreditPoolBeforeMicrocredits int64     `json:"credit_pool_before_microcredits"`
+	CreditPoolAfterMicrocredits  int64     `json:"credit_pool_after_microcredits"`
+	CreatedAt                    time.Time `json:"created_at"`
+	// Reversed is true if the invoice's transaction was reversed, so the invoice is void. If the month was closed again, the usage is on a new invoice.
+	Reversed bool `json:"reversed"`
+	// Lines are omitted when invoices are listed.
+	Lines []InvoiceLine `json:"lines,omitempty"`
+}
+
+// CreateInvoices creates an invoice for each customer whose usage was posted for the month preceding asOf, and returns them. Customers whose posted usage already has an invoice are skipped, so CreateInvoices may be run more than once. Invoices are numbered in the order they are created.
+//
+// CreateInvoices makes several changes to the database, so q should be scoped to a transaction.
+func CreateInvoices(ctx context.Context, q db.Querier, asOf pgtype.Timestamptz) ([]Invoice, error) {
+	bounds, err := q.BoundsMonthPrev(ctx, asOf)
+	if err != nil {
+		return nil, err
+	}
+	txIDs, err := q.ListUninvoicedUsagePostIDs(ctx, bounds.PeriodEnd)
+	if err != nil {
+		return nil, err
+	}
+	invoices := make([]Invoice, 0, len(txIDs))
+	for _, txID := range txIDs {
+		id, err := q.CreateInvoice(ctx, txID)
+		if err != nil {
+			return nil, err
+		}
+		row, err := q.GetInvoice(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		inv, err := withLines(ctx, q, newInvoice(row.Invoice, row.Reversed))
+		if err != nil {
+			return nil, err
+		}
+		invoices = append(invoices, inv)
+	}
+	return invoices, nil
+}
+
+// GetInvoice returns the invoice with the given number and its lines.
+func GetInvoice(ctx context.Context, q db.Querier, number int32) (Invoice, error) {
+	row, err := q.GetInvoiceByNumber(ctx, number)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return Invoice{}, ErrInvoiceNotFound
+	}
+	if err != nil {
+		return Invoice{}, err
+	}
+	return withLines(ctx, q, newInvoice(row.Invoice, row.Reversed))
+}
+
+// ListInvoices returns a page of invoices without their lines, most recent first. If customerID is invalid, invoices of all customers are listed.
+func ListInvoices(ctx context.Context, q db.Querier, customerID pgtype.UUID, limit, offset int32) ([]Invoice, error) {
+	rows, err := q.ListInvoices(ctx, db.ListInvoicesParams{
+		CustomerID: customerID,
+		PageSize:   limit,
+		PageOffset: offset,
+	})
+	if err != nil {
+		return nil, err
+	}
+	invoices := make([]Invoice, len(rows))
+	for i, row := range rows {
+		invoices[i] = newInvoice(row.Invoice, row.Reversed)
+	}
+	return invoices, nil
+}
+
+func newInvoice(i db.Invoice, reversed bool) Invoice {
+	return Invoice{
+		ID:                           i.ID,
+		Number:                       i.Number,
+		TransactionID:                i.TransactionID,
+		CustomerID:                   i.CustomerID,
+		CustomerName:                 i.CustomerName,
+		IAANumber:                    i.IAANumber.String,
+		PeriodStart:                  i.PeriodStart.Time,
+		PeriodEnd:                    i.PeriodEnd.Time,
+		UsageMicrocredits:            i.UsageMicrocredits,
+		CreditPoolBeforeMicrocredits: i.CreditPoolBeforeMicrocredits,
+		CreditPoolAfterMicrocredits:  i.CreditPoolAfterMicrocredits,
+		CreatedAt:                    i.CreatedAt.Time,
+		Reversed:                     reversed,
+	}
+}
+
+func withLines(ctx context.Context, q db.Querier, inv Invoice) (Invoice, error) {
+	rows, err := q.ListInvoiceLines(ctx, inv.ID)
+	if err != nil {
+		return Invoice{}, err
+	}
+	inv.Lines = make([]InvoiceLine, len(rows))
+	for i, r := range rows {
+		inv.Lines[i] = InvoiceLine{
+			Line:                r.Line,
+			CFOrgID:             r.CFOrgID,
+			CFOrgName:           r.CFOrgName,
+			Space:               r.Space,
+			Meter:               r.Meter,
+			KindNaturalID:       r.KindNaturalID,
+			KindName:            r.KindName,
+			PriceID:             r.PriceID,
+			UnitOfMeasure:       r.UnitOfMeasure,
+			Accrues:             r.Accrues,
+			MicrocreditsPerUnit: r.MicrocreditsPerUnit,
+			Unit:                r.Unit,
+			Quantity:            numericString(r.Quantity),
+			EstimatedQuantity:   numericString(r.EstimatedQuantity),
+			AmountMicrocredits:  r.AmountMicrocredits,
+		}
+	}
+	return inv, nil
+}
+
+// numericString formats n as a decimal number, or returns an empty string if n is null or not finite.
+func numericString(n pgtype.Numeric) string {
+	v, err := n.Value()
+	if err != nil {
+		return ""
+	}
+	s, _ := v.(string)
+	return s
+}
@@ -0,0 +1,104 @@
+package dbx_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/cloud-gov/billing/internal/db"
+	"github.com/cloud-gov/billing/internal/dbx"
+	. "github.com/cloud-gov/billing/internal/testutil"
+)
+
+func TestDBCreateInvoices(t *testing.T) {
+	tz, _ := time.LoadLocation("America/New_York")
+	asOf := PgTimestamptz(time.Date(2025, time.March, 1, 0, 0, 0, 0, tz))
+	td := usageTestData()
+
+	conn, err := pgxpool.New(t.Context(), "")
+	if err != nil {
+		t.Fatal("creating database connection failed", err)
+	}
+	// Clear the measurements' amounts so they are priced by the database when the month is closed, which records their prices.
+	for i := range td.Measurements {
+		td.Measurements[i].AmountMicrocredits = pgtype.Int8{}
+	}
+	q := newTx(t, conn, false)
+	createTestData(t, q, td)
+
+	// Fund the customer with 100 credits, so the invoice shows the credits it drew down.
+	if _, err := q.CreateIAA(t.Context(), db.CreateIAAParams{
+		CustomerID:         td.CustomerIDs["customer1"],
+		AmountMicrocredits: 100_000_000,
+		PopStart:           pgDate(2025, time.January, 1),
+		PopEnd:             pgDate(2025, time.December, 31),
+	}); err != nil {
+		t.Fatal("creating agreement:", err)
+	}
+	if _, err := q.PostIAAPop(t.Context(), PgTimestamptz(time.Date(2025, time.January, 1, 0, 30, 0, 0, tz))); err != nil {
+		t.Fatal("posting agreement:", err)
+	}
+
+	closed, err := dbx.CloseMonth(t.Context(), q, asOf)
+	if err != nil {
+		t.Fatal("closing month:", err)
+	}
+
+	invoices, err := dbx.CreateInvoices(t.Context(), q, asOf)
+	if err != nil {
+		t.Fatal("creating invoices:", err)
+	}
+	if len(invoices) != 1 {
+		t.Fatalf("expected one invoice, got %+v", invoices)
+	}
+	inv := invoices[0]
+	if inv.TransactionID != closed.Pending[0].ID || inv.UsageMicrocredits != 30 || inv.Reversed {
+		t.Fatalf("unexpected invoice %+v", inv)
+	}
+	if inv.CreditPoolBeforeMicrocredits != 100_000_000 || inv.CreditPoolAfterMicrocredits != 99_999_970 {
+		t.Errorf("expected the credit pool to go from 100,000,000 to 99,999,970 microcredits, got %v to %v", inv.CreditPoolBeforeMicrocredits, inv.CreditPoolAfterMicrocredits)
+	}
+	if !inv.PeriodStart.Equal(time.Date(2025, time.February, 1, 0, 0, 0, 0, tz)) {
+		t.Errorf("unexpected period start %v", inv.PeriodStart)
+	}
+	var total int64
+	for _, l := range inv.Lines {
+		total += l.AmountMicrocredits
+	}
+	if len(inv.Lines) != 1 || total != 30 || inv.Lines[0].Quantity != "2.0000" || inv.Lines[0].EstimatedQuantity != "0.0000" {
+		t.Errorf("unexpected lines %+v", inv.Lines)
+	}
+
+	// Creating invoices again skips transactions that have them.
+	again, err := dbx.CreateInvoices(t.Context(), q, asOf)
+	if err != nil {
+		t.Fatal("creating invoices again:", err)
+	}
+	if len(again) != 0 {
+		t.Fatalf("expected no new invoices, got %+v", again)
+	}
+
+	// Reversing the month voids the invoice without modifying it, and closing it again creates the next invoice.
+	if _, err := dbx.ReverseMonth(t.Context(), q, asOf); err != nil {
+		t.Fatal("reversing month:", err)
+	}
+	voided, err := dbx.GetInvoice(t.Context(), q, inv.Number)
+	if err != nil {
+		t.Fatal("getting invoice:", err)
+	}
+	if !voided.Reversed {
+		t.Errorf("expected invoice to be reversed")
+	}
+	if _, err := dbx.CloseMonth(t.Context(), q, asOf); err != nil {
+		t.Fatal("closing month:", err)
+	}
+	next, err := dbx.CreateInvoices(t.Context(), q, asOf)
+	if err != nil {
+		t.Fatal("creating invoices after reversal:", err)
+	}
+	if len(next) != 1 || next[0].Number != inv.Number+1 {
+		t.Fatalf("expected the next invoice number, got %+v", next)
+	}
+}
@@ -0,0 +1,255 @@
+package dbx
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/cloud-gov/billing/internal/db"
+)
+
+var (
+	ErrTransactionNotFound = errors.New("transaction not found")
+	ErrCorrectReversal     = errors.New("reversals cannot be reversed or adjusted")
+	ErrAlreadyReversed     = errors.New("transaction was already reversed")
+	ErrHasAdjustments      = errors.New("transaction has adjustments that must be reversed first")
+	ErrAdjustmentZero      = errors.New("adjustment amount must not be zero")
+	ErrAdjustmentInvalid   = errors.New("transaction cannot be adjusted")
+)
+
+// Beginner starts database transactions. It is implemented by [pgxpool.Pool] and [pgx.Conn].
+type Beginner interface {
+	Begin(ctx context.Context) (pgx.Tx, error)
+}
+
+// TransactionSummary describes a ledger transaction and the total amount it moved between a customer's accounts.
+type TransactionSummary struct {
+	ID           int32              `json:"id"`
+	OccurredAt   time.Time          `json:"occurred_at"`
+	Type         db.TransactionType `json:"type"`
+	Description  string             `json:"description"`
+	CustomerID   pgtype.UUID        `json:"customer_id"`
+	CustomerName string             `json:"customer_name"`
+	// OriginalTransactionID is the transaction this transaction corrects, or 0 if it does not correct another transaction.
+	OriginalTransactionID int32 `json:"original_transaction_id,omitempty"`
+	// AmountMicrocredits is the sum of the transaction's debit entries. Because transactions balance, it is also the sum of its credit entries.
+	AmountMicrocredits int64 `json:"amount_microcredits"`
+}
+
+// MonthClose describes the state of usage posting for one month.
+type MonthClose struct {
+	// PeriodStart is the inclusive start of the month.
+	PeriodStart time.Time `json:"period_start"`
+	// PeriodEnd is the exclusive end of the month.
+	PeriodEnd time.Time `json:"period_end"`
+	// Posted are usage transactions for the month that existed before the close and have not been reversed.
+	Posted []TransactionSummary `json:"posted"`
+	// Pending are usage transactions created by the close.
+	Pending []TransactionSummary `json:"pending"`
+}
+
+// CloseMonth prices measurements for the month preceding asOf and posts each customer's usage to their accounts. Customers whose usage was already posted for the month are skipped, so CloseMonth may be run more than once. If usage for a month must be posted again, reverse it first with [ReverseMonth].
+//
+// CloseMonth makes several changes to the database, so q should be scoped to a transaction.
+func CloseMonth(ctx context.Context, q db.Querier, asOf pgtype.Timestamptz) (MonthClose, error) {
+	bounds, err := q.BoundsMonthPrev(ctx, asOf)
+	if err != nil {
+		return MonthClose{}, err
+	}
+	// List existing transactions before posting, so they can be told apart from the ones we create.
+	postedIDs, err := q.ListUsagePostIDs(ctx, bounds.PeriodEnd)
+	if err != nil {
+		return MonthClose{}, err
+	}
+	_, err = q.UpdateMeasurementMicrocredits(ctx, asOf)
+	if err != nil {
+		return MonthClose{}, err
+	}
+	pendingIDs, err := q.PostUsage(ctx, asOf)
+	if err != nil {
+		return MonthClose{}, err
+	}
+
+	mc := MonthClose{
+		PeriodStart: bounds.PeriodStart.Time,
+		PeriodEnd:   bounds.PeriodEnd.Time,
+	}
+	mc.Posted, err = summarizeTransactions(ctx, q, postedIDs)
+	if err != nil {
+		return MonthClose{}, err
+	}
+	mc.Pending, err = summarizeTransactions(ctx, q, int4sToInt32s(pendingIDs))
+	if err != nil {
+		return MonthClose{}, err
+	}
+	return mc, nil
+}
+
+// PreviewCloseMonth returns the result of [CloseMonth] without changing the database. The close is performed in a transaction that is always rolled back.
+func PreviewCloseMonth(ctx context.Context, conn Beginner, q Querier, asOf pgtype.Timestamptz) (MonthClose, error) {
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		return MonthClose{}, err
+	}
+	defer tx.Rollback(ctx)
+
+	return CloseMonth(ctx, q.WithTx(tx), asOf)
+}
+
+// ReverseMonth reverses all usage posted for the month preceding asOf and clears the prices calculated for its measurements, so the month can be closed again. Posted transactions are not modified; each is undone by a new transaction with offsetting entries. ReverseMonth returns the reversal transactions.
+func ReverseMonth(ctx context.Context, q db.Querier, asOf pgtype.Timestamptz) ([]TransactionSummary, error) {
+	ids, err := q.ReverseUsage(ctx, asOf)
+	if err != nil {
+		return nil, err
+	}
+	return summarizeTransactions(ctx, q, int4sToInt32s(ids))
+}
+
+// TransactionHistory is a transaction and the reversals and adjustments posted against it, oldest first.
+type TransactionHistory struct {
+	TransactionSummary
+	Corrections []TransactionSummary `json:"corrections"`
+}
+
+// GetTransactionHistory returns the transaction with the given ID and its corrections.
+func GetTransactionHistory(ctx context.Context, q db.Querier, id int32) (TransactionHistory, error) {
+	summaries, err := summarizeTransactions(ctx, q, []int32{id})
+	if err != nil {
+		return TransactionHistory{}, err
+	}
+	if len(summaries) == 0 {
+		return TransactionHistory{}, ErrTransactionNotFound
+	}
+	corrections, err := q.ListTransactionCorrections(ctx, pgtype.Int4{Int32: id, Valid: true})
+	if err != nil {
+		return TransactionHistory{}, err
+	}
+	ids := make([]int32, len(corrections))
+	for i, c := range corrections {
+		ids[i] = c.ID
+	}
+	h := TransactionHistory{TransactionSummary: summaries[0]}
+	h.Corrections, err = summarizeTransactions(ctx, q, ids)
+	if err != nil {
+		return TransactionHistory{}, err
+	}
+	return h, nil
+}
+
+// ReverseTransaction posts a transaction that undoes the transaction with the given ID by swapping the direction of each of its entries. The original transaction is not modified. A transaction can only be reversed once, and only after its adjustments are reversed. Reversals cannot themselves be reversed. If description is empty, a default is used.
+func ReverseTransaction(ctx context.Context, q db.Querier, id int32, description string) (TransactionSummary, error) {
+	if err := checkCorrectable(ctx, q, id); err != nil {
+		return TransactionSummary{}, err
+	}
+	reversalID, err := q.ReverseTransaction(ctx, db.ReverseTransactionParams{
+		TransactionID: id,
+		OccurredAt:    pgtype.Timestamptz{Time: time.Now().UTC(), Valid: true},
+		Description:   pgtype.Text{String: description, Valid: description != ""},
+	})
+	if err != nil {
+		return TransactionSummary{}, ledgerError(err)
+	}
+	return summarizeTransaction(ctx, q, reversalID)
+}
+
+// AdjustTransaction posts a transaction that changes the amount of the transaction with the given ID by amountMicrocredits. A positive amount moves more credits between the original transaction's accounts in the same direction; a negative amount moves credits back. The original transaction is not modified. Reversed transactions cannot be adjusted. If description is empty, a default is used.
+//
+// Only transactions whose entries all have the same amount can be adjusted, which includes every transaction the billing service posts.
+func AdjustTransaction(ctx context.Context, q db.Querier, id int32, amountMicrocredits int64, description string) (TransactionSummary, error) {
+	if amountMicrocredits == 0 {
+		return TransactionSummary{}, ErrAdjustmentZero
+	}
+	if err := checkCorrectable(ctx, q, id); err != nil {
+		return TransactionSummary{}, err
+	}
+	adjustmentID, err := q.AdjustTransaction(ctx, db.AdjustTransactionParams{
+		TransactionID:      id,
+		AmountMicrocredits: amountMicrocredits,
+		OccurredAt:         pgtype.Timestamptz{Time: time.Now().UTC(), Valid: true},
+		Description:        pgtype.Text{String: description, Valid: description != ""},
+	})
+	if err != nil {
+		return TransactionSummary{}, ledgerError(err)
+	}
+	return summarizeTransaction(ctx, q, adjustmentID)
+}
+
+// checkCorrectable returns an error if the transaction does not exist or is a reversal.
+func checkCorrectable(ctx context.Context, q db.Querier, id int32) error {
+	t, err := q.GetTransaction(ctx, id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrTransactionNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if t.Type == db.TransactionTypeReversal {
+		return ErrCorrectReversal
+	}
+	return nil
+}
+
+// ledgerError translates errors raised by the ledger functions in the database to errors callers can check for.
+func ledgerError(err error) error {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return err
+	}
+	switch pgErr.Code {
+	case "23505", "55000": // unique_violation on transaction_reversal_uidx, or object_not_in_prerequisite_state from adjust_transaction
+		return ErrAlreadyReversed
+	case "55006": // object_in_use from reverse_transaction
+		return ErrHasAdjustments
+	case "P0002": // no_data_found
+		return ErrTransactionNotFound
+	case "22023": // invalid_parameter_value
+		return errors.Join(ErrAdjustmentInvalid, err)
+	}
+	return err
+}
+
+func summarizeTransaction(ctx context.Context, q db.Querier, id int32) (TransactionSummary, error) {
+	summaries, err := summarizeTransactions(ctx, q, []int32{id})
+	if err != nil {
+		return TransactionSummary{}, err
+	}
+	if len(summaries) == 0 {
+		return TransactionSummary{}, ErrTransactionNotFound
+	}
+	return summaries[0], nil
+}
+
+func summarizeTransactions(ctx context.Context, q db.Querier, ids []int32) ([]TransactionSummary, error) {
+	rows, err := q.ListTransactionSummaries(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	summaries := make([]TransactionSummary, len(rows))
+	for i, r := range rows {
+		summaries[i] = TransactionSummary{
+			ID:                    r.ID,
+			OccurredAt:            r.OccurredAt.Time,
+			Type:                  r.Type,
+			Description:           r.Description.String,
+			CustomerID:            r.CustomerID,
+			CustomerName:          r.CustomerName,
+			OriginalTransactionID: r.OriginalTransactionID.Int32,
+			AmountMicrocredits:    r.AmountMicrocredits,
+		}
+	}
+	return summaries, nil
+}
+
+func int4sToInt32s(in []pgtype.Int4) []int32 {
+	out := make([]int32, 0, len(in))
+	for _, v := range in {
+		if v.Valid {
+			out = append(out, v.Int32)
+		}
+	}
+	return out
+}
@@ -0,0 +1,230 @@
+package dbx_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/cloud-gov/billing/internal/db"
+	"github.com/cloud-gov/billing/internal/dbx"
+	. "github.com/cloud-gov/billing/internal/testutil"
+)
+
+// usageTestData returns a customer with 30 microcredits of usage in February 2025, and a price that values the same usage at 30 microcredits.
+func usageTestData() testData {
+	var (
+		customerName = "customer1"
+		orgID        = PgUUID()
+		meterName    = "meter-1"
+		kindID       = "kind-1"
+		resourceID   = "resource-1"
+		tz, _        = time.LoadLocation("America/New_York")
+		utc, _       = time.LoadLocation("")
+	)
+	return testData{
+		CustomerIDs: map[string]pgtype.UUID{},
+		Customers:   []db.Customer{{Name: customerName}},
+		CFOrgs: []CFOrg{
+			{CustomerName: customerName, CFOrg: db.CFOrg{ID: orgID}},
+		},
+		Meters:        []db.Meter{{Name: meterName}},
+		ResourceKinds: []db.ResourceKind{{Meter: meterName, NaturalID: kindID}},
+		Prices: []db.Price{
+			{
+				ID:                  1,
+				Meter:               meterName,
+				KindNaturalID:       kindID,
+				MicrocreditsPerUnit: 15,
+				UnitOfMeasure:       "hours",
+				Unit:                1,
+				ValidDuring: pgtype.Range[pgtype.Timestamptz]{
+					Lower:     PgTimestamptz(time.Date(2024, time.March, 1, 0, 0, 0, 0, tz)),
+					Upper:     PgTimestamptz(time.Date(2026, time.March, 1, 0, 0, 0, 0, tz)),
+					LowerType: pgtype.Inclusive,
+					UpperType: pgtype.Exclusive,
+					Valid:     true,
+				},
+			},
+		},
+		Readings: []db.Reading{
+			{ID: 1, CreatedAt: PgTimestamp(time.Date(2025, time.February, 3, 0, 0, 0, 0, utc))},
+			{ID: 2, CreatedAt: PgTimestamp(time.Date(2025, time.February, 4, 0, 0, 0, 0, utc))},
+		},
+		Resources: []db.Resource{
+			{Meter: meterName, NaturalID: resourceID, KindNaturalID: kindID, CFOrgID: orgID},
+		},
+		Measurements: []db.Measurement{
+			{Meter: meterName, ResourceNaturalID: resourceID, Value: 1, ReadingID: 1, AmountMicrocredits: PgInt8(10)},
+			{Meter: meterName, ResourceNaturalID: resourceID, Value: 1, ReadingID: 2, AmountMicrocredits: PgInt8(20)},
+		},
+	}
+}
+
+func TestDBCloseAndReverseMonth(t *testing.T) {
+	tz, _ := time.LoadLocation("America/New_York")
+	asOf := PgTimestamptz(time.Date(2025, time.March, 1, 0, 0, 0, 0, tz))
+	td := usageTestData()
+
+	conn, err := pgxpool.New(t.Context(), "")
+	if err != nil {
+		t.Fatal("creating database connection failed", err)
+	}
+	tx, q := newTxBeginner(t, conn, false)
+	createTestData(t, q, td)
+
+	// Preview the month close. Nothing is persisted.
+	before, err := q.ListTransactions(t.Context())
+	if err != nil {
+		t.Fatal("listing transactions:", err)
+	}
+	preview, err := dbx.PreviewCloseMonth(t.Context(), tx, q, asOf)
+	if err != nil {
+		t.Fatal("previewing month close:", err)
+	}
+	if len(preview.Pending) != 1 || len(preview.Posted) != 0 {
+		t.Fatalf("expected one pending transaction in the preview, got %+v", preview)
+	}
+	after, err := q.ListTransactions(t.Context())
+	if err != nil {
+		t.Fatal("listing transactions:", err)
+	}
+	if len(after) != len(before) {
+		t.Fatalf("expected the preview to post nothing, got %v transactions before and %v after", len(before), len(after))
+	}
+
+	// Close the month. The preview matches what is posted.
+	closed, err := dbx.CloseMonth(t.Context(), q, asOf)
+	if err != nil {
+		t.Fatal("closing month:", err)
+	}
+	if len(closed.Pending) != 1 || closed.Pending[0].AmountMicrocredits != 30 {
+		t.Fatalf("expected one pending transaction for 30 microcredits, got %+v", closed.Pending)
+	}
+	if p, c := preview.Pending[0], closed.Pending[0]; p.CustomerID != c.CustomerID || p.AmountMicrocredits != c.AmountMicrocredits {
+		t.Fatalf("expected preview %+v to match close %+v", p, c)
+	}
+
+	// Closing again posts nothing new.
+	again, err := dbx.CloseMonth(t.Context(), q, asOf)
+	if err != nil {
+		t.Fatal("closing month again:", err)
+	}
+	if len(again.Pending) != 0 || len(again.Posted) != 1 {
+		t.Fatalf("expected close to be idempotent, got %+v", again)
+	}
+
+	// Reversing undoes the posted transaction and its adjustments without modifying them. Adjustments are reversed first.
+	adjustment, err := dbx.AdjustTransaction(t.Context(), q, closed.Pending[0].ID, 5, "")
+	if err != nil {
+		t.Fatal("adjusting transaction:", err)
+	}
+	reversals, err := dbx.ReverseMonth(t.Context(), q, asOf)
+	if err != nil {
+		t.Fatal("reversing month:", err)
+	}
+	if len(reversals) != 2 {
+		t.Fatalf("expected two reversals, got %+v", reversals)
+	}
+	if reversals[0].Type != db.TransactionTypeReversal || reversals[0].OriginalTransactionID != adjustment.ID || reversals[0].AmountMicrocredits != 5 {
+		t.Fatalf("unexpected adjustment reversal %+v", reversals[0])
+	}
+	if reversals[1].Type != db.TransactionTypeReversal || reversals[1].OriginalTransactionID != closed.Pending[0].ID || reversals[1].AmountMicrocredits != 30 {
+		t.Fatalf("unexpected reversal %+v", reversals[1])
+	}
+	sums, err := q.SumEntries(t.Context())
+	if err != nil {
+		t.Fatal("summing entries:", err)
+	}
+	for _, s := range sums {
+		if v, _ := s.Int64Value(); v.Int64 != 0 {
+			t.Fatalf("expected balanced ledger, got sum %v", v.Int64)
+		}
+	}
+
+	// The month can be closed again after reversal. Measurements are repriced.
+	reclosed, err := dbx.CloseMonth(t.Context(), q, asOf)
+	if err != nil {
+		t.Fatal("closing month after reversal:", err)
+	}
+	if len(reclosed.Pending) != 1 || len(reclosed.Posted) != 0 || reclosed.Pending[0].AmountMicrocredits != 30 {
+		t.Fatalf("expected usage to be posted again after reversal, got %+v", reclosed)
+	}
+}
+
+func TestDBCorrectTransaction(t *testing.T) {
+	tz, _ := time.LoadLocation("America/New_York")
+	asOf := PgTimestamptz(time.Date(2025, time.March, 1, 0, 0, 0, 0, tz))
+
+	conn, err := pgxpool.New(t.Context(), "")
+	if err != nil {
+		t.Fatal("creating database connection failed", err)
+	}
+	q := newTx(t, conn, false)
+	createTestData(t, q, usageTestData())
+	closed, err := dbx.CloseMonth(t.Context(), q, asOf)
+	if err != nil {
+		t.Fatal("closing month:", err)
+	}
+	if len(closed.Pending) != 1 {
+		t.Fatalf("expected one usage transaction, got %+v", closed.Pending)
+	}
+	original := closed.Pending[0]
+
+	// Adjust
+	adjustment, err := dbx.AdjustTransaction(t.Context(), q, original.ID, -5, "")
+	if err != nil {
+		t.Fatal("adjusting transaction:", err)
+	}
+	if adjustment.Type != db.TransactionTypeAdjustment || adjustment.OriginalTransactionID != original.ID || adjustment.AmountMicrocredits != 5 {
+		t.Fatalf("unexpected adjustment %+v", adjustment)
+	}
+	_, err = dbx.AdjustTransaction(t.Context(), q, original.ID, 0, "")
+	if !errors.Is(err, dbx.ErrAdjustmentZero) {
+		t.Fatalf("expected ErrAdjustmentZero, got %v", err)
+	}
+
+	// Reverse. The adjustment must be reversed before the original.
+	_, err = dbx.ReverseTransaction(t.Context(), q, original.ID, "")
+	if !errors.Is(err, dbx.ErrHasAdjustments) {
+		t.Fatalf("expected ErrHasAdjustments, got %v", err)
+	}
+	if _, err = dbx.ReverseTransaction(t.Context(), q, adjustment.ID, ""); err != nil {
+		t.Fatal("reversing adjustment:", err)
+	}
+	reversal, err := dbx.ReverseTransaction(t.Context(), q, original.ID, "wrong price")
+	if err != nil {
+		t.Fatal("reversing transaction:", err)
+	}
+	if reversal.Type != db.TransactionTypeReversal || reversal.OriginalTransactionID != original.ID || reversal.Description != "wrong price" {
+		t.Fatalf("unexpected reversal %+v", reversal)
+	}
+	_, err = dbx.ReverseTransaction(t.Context(), q, original.ID, "")
+	if !errors.Is(err, dbx.ErrAlreadyReversed) {
+		t.Fatalf("expected ErrAlreadyReversed, got %v", err)
+	}
+	_, err = dbx.ReverseTransaction(t.Context(), q, reversal.ID, "")
+	if !errors.Is(err, dbx.ErrCorrectReversal) {
+		t.Fatalf("expected ErrCorrectReversal, got %v", err)
+	}
+
+	// Reversed transactions cannot be adjusted.
+	_, err = dbx.AdjustTransaction(t.Context(), q, original.ID, 5, "")
+	if !errors.Is(err, dbx.ErrAlreadyReversed) {
+		t.Fatalf("expected ErrAlreadyReversed, got %v", err)
+	}
+
+	// The original is unchanged and lists its corrections.
+	h, err := dbx.GetTransactionHistory(t.Context(), q, original.ID)
+	if err != nil {
+		t.Fatal("getting transaction history:", err)
+	}
+	if h.AmountMicrocredits != original.AmountMicrocredits {
+		t.Fatalf("expected original amount %v, got %v", original.AmountMicrocredits, h.AmountMicrocredits)
+	}
+	if len(h.Corrections) != 2 || h.Corrections[0].ID != adjustment.ID || h.Corrections[1].ID != reversal.ID {
+		t.Fatalf("expected adjustment and reversal as corrections, got %+v", h.Corrections)
+	}
+}
@@ -11,6 +11,7 @@ import (
 	"github.com/cloud-gov/billing/internal/db"
 	"github.com/cloud-gov/billing/internal/dbx"
 	. "github.com/cloud-gov/billing/internal/testutil"
+	"github.com/cloud-gov/billing/internal/usage/meter"
 	"github.com/google/go-cmp/cmp"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgtype"
@@ -113,6 +114,13 @@ func TestDBBoundsMonthPrev(t *testing.T) {
 //
 // Note that isolation is not perfect between tests, even when transactions are rolled back. For example, the sequence used to generate an auto-incrementing ID will not decrement when a transaction is rolled back; the next transaction will start from the incremented sequence. Avoid hardcoding generated IDs for this reason.
 func newTx(t *testing.T, conn *pgxpool.Pool, commit bool) dbx.Querier {
+	t.Helper()
+	_, q := newTxBeginner(t, conn, commit)
+	return q
+}
+
+// newTxBeginner is like [newTx], but also returns the transaction for functions that begin their own, like [dbx.PreviewCloseMonth]. Transactions begun on it are savepoints, so they can see the test data.
+func newTxBeginner(t *testing.T, conn *pgxpool.Pool, commit bool) (pgx.Tx, dbx.Querier) {
 	t.Helper()
 	// t.Context() can be cancelled before we have a chance to commit, so create a new context instead.
 	ctx := context.Background()
@@ -143,7 +151,7 @@ func newTx(t *testing.T, conn *pgxpool.Pool, commit bool) dbx.Querier {
 		})
 	}
 
-	return dbx.NewQuerier(db.New(conn)).WithTx(tx)
+	return tx, dbx.NewQuerier(db.New(conn)).WithTx(tx)
 }
 
 func TestDBUpdateMeasurementMicrocredits(t *testing.T) {
@@ -348,6 +356,90 @@ func TestDBUpdateMeasurementMicrocredits(t *testing.T) {
 	}
 }
 
+// TestDBUpdateMeasurementMicrocreditsAccrues checks that measurements of accruing kinds are priced for the time since the previous reading.
+func TestDBUpdateMeasurementMicrocreditsAccrues(t *testing.T) {
+	conn, err := pgxpool.New(t.Context(), "")
+	if err != nil {
+		t.Fatal("creating database connection failed", err)
+	}
+	q := newTx(t, conn, false)
+
+	var (
+		orgID      = PgUUID()
+		resourceID = meter.InstanceResourceID(PgUUID().String(), 0, meter.AppMemoryKind)
+		utc, _     = time.LoadLocation("")
+	)
+	// The cfapps meter and its kinds are created by migrations.
+	td := testData{
+		CFOrgs: []CFOrg{{CFOrg: db.CFOrg{ID: orgID}}},
+		Readings: []db.Reading{
+			{ID: 1, CreatedAt: PgTimestamp(time.Date(2031, time.January, 10, 0, 0, 0, 0, utc)), Periodic: true},
+			{ID: 2, CreatedAt: PgTimestamp(time.Date(2031, time.January, 10, 1, 0, 0, 0, utc)), Periodic: true},
+			// Two readings were missed.
+			{ID: 3, CreatedAt: PgTimestamp(time.Date(2031, time.January, 10, 4, 0, 0, 0, utc)), Periodic: true},
+			// A one-off reading took the place of the next periodic reading, and the meter failed in the one after it.
+			{ID: 4, CreatedAt: PgTimestamp(time.Date(2031, time.January, 10, 5, 10, 0, 0, utc))},
+			{ID: 5, CreatedAt: PgTimestamp(time.Date(2031, time.January, 10, 6, 0, 0, 0, utc)), Periodic: true},
+			{ID: 6, CreatedAt: PgTimestamp(time.Date(2031, time.January, 10, 7, 0, 0, 0, utc)), Periodic: true},
+		},
+		Resources: []db.Resource{
+			{Meter: meter.AppMeterName, NaturalID: resourceID, KindNaturalID: meter.AppMemoryKind, CFOrgID: orgID},
+		},
+		Measurements: []db.Measurement{
+			{Meter: meter.AppMeterName, ResourceNaturalID: resourceID, Value: 2048, ReadingID: 1},
+			{Meter: meter.AppMeterName, ResourceNaturalID: resourceID, Value: 2048, ReadingID: 2},
+			{Meter: meter.AppMeterName, ResourceNaturalID: resourceID, Value: 2048, ReadingID: 3},
+			{Meter: meter.AppMeterName, ResourceNaturalID: resourceID, Value: 2048, ReadingID: 6},
+		},
+	}
+	createTestData(t, q, td)
+	err = q.BulkCreateReadingMeterStatuses(t.Context(), db.BulkCreateReadingMeterStatusesParams{
+		ReadingID:        []int32{5, 6},
+		Meter:            []string{meter.AppMeterName, meter.AppMeterName},
+		StartedAt:        []pgtype.Timestamptz{PgTimestamptz(td.Readings[4].CreatedAt.Time), PgTimestamptz(td.Readings[5].CreatedAt.Time)},
+		DurationMs:       []int32{1000, 1000},
+		Succeeded:        []bool{false, true},
+		Error:            []string{"timed out", ""},
+		MeasurementCount: []int32{0, 1},
+	})
+	if err != nil {
+		t.Fatal("recording meter statuses:", err)
+	}
+	// 1000 microcredits per GB-hour.
+	_, err = dbx.PublishPrice(t.Context(), q, db.CreatePriceParams{
+		Meter:               meter.AppMeterName,
+		KindNaturalID:       meter.AppMemoryKind,
+		UnitOfMeasure:       "GB-hours",
+		MicrocreditsPerUnit: 1000,
+		Unit:                1024,
+		EffectiveDate:       pgtype.Date{Time: time.Date(2030, time.January, 1, 0, 0, 0, 0, utc), Valid: true},
+	})
+	if err != nil {
+		t.Fatal("publishing price:", err)
+	}
+
+	if _, err = q.UpdateMeasurementMicrocredits(t.Context(), PgTimestamptz(time.Date(2031, time.February, 2, 0, 0, 0, 0, utc))); err != nil {
+		t.Fatal("pricing measurements:", err)
+	}
+	ms, err := q.ListMeasurements(t.Context())
+	if err != nil {
+		t.Fatal("listing measurements:", err)
+	}
+	// The interval of the first reading depends on readings outside the test, so it is not checked.
+	// Reading 6 accrues from reading 3, the last in which the meter succeeded.
+	for id, want := range map[int32]int64{2: 2000, 3: 6000, 6: 6000} {
+		i := slices.IndexFunc(ms, func(m db.Measurement) bool {
+			return m.ReadingID == id && m.ResourceNaturalID == resourceID
+		})
+		if i < 0 {
+			t.Fatalf("measurement for reading %v not found", id)
+		}
+		if got := ms[i].AmountMicrocredits; !got.Valid || got.Int64 != want {
+			t.Errorf("expected measurement for reading %v to cost %v microcredits, got %v", id, want, got)
+		}
+	}
+}
+
 func measurementFromReadingID(m []db.Measurement, id int32) int {
 	return slices.IndexFunc(m, func(e db.Measurement) bool {
 		return e.ReadingID == id
@@ -752,7 +844,11 @@ func createTestData(t *testing.T, q db.Querier, td testData) {
 			}
 			v.CustomerID = customerID
 		}
-		_, err := q.CreateCFOrg(t.Context(), db.CreateCFOrgParams(v.CFOrg))
+		_, err := q.CreateCFOrg(t.Context(), db.CreateCFOrgParams{
+			ID:         v.ID,
+			Name:       v.Name,
+			CustomerID: v.CustomerID,
+		})
 		if err != nil {
 			t.Fatalf("creating CF org failed: %T %v", err, err)
 		}
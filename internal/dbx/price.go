@@ -0,0 +1,48 @@
+package dbx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/cloud-gov/billing/internal/db"
+)
+
+var (
+	ErrPriceNotFound = errors.New("price not found")
+	ErrPriceInUse    = errors.New("measurements taken after the effective date have already been priced; reverse the months they were posted in first")
+)
+
+// PublishPrice creates a price for a resource kind that takes effect at the start of arg.EffectiveDate in business time. The price previously in effect then, if any, is closed so the two do not overlap. The new price remains in effect until another is published.
+//
+// Measurements that have already been priced are not repriced, so PublishPrice returns [ErrPriceInUse] if the closed price was used for measurements taken on or after the effective date. If a price takes effect after the effective date, the new price would overlap it and the database rejects it with an exclusion violation.
+//
+// PublishPrice makes several changes to the database, so q should be scoped to a transaction.
+func PublishPrice(ctx context.Context, q db.Querier, arg db.CreatePriceParams) (db.Price, error) {
+	closed, err := q.ClosePrice(ctx, db.ClosePriceParams{
+		EffectiveDate: arg.EffectiveDate,
+		Meter:         arg.Meter,
+		KindNaturalID: arg.KindNaturalID,
+	})
+	switch {
+	case errors.Is(err, pgx.ErrNoRows):
+		// No price was in effect, or one takes effect at the same time. In the latter case, creating the price fails with an exclusion violation.
+	case err != nil:
+		return db.Price{}, fmt.Errorf("closing price: %w", err)
+	default:
+		n, err := q.CountPricedMeasurements(ctx, db.CountPricedMeasurementsParams{
+			PriceID:       pgtype.Int8{Int64: int64(closed.ID), Valid: true},
+			EffectiveDate: arg.EffectiveDate,
+		})
+		if err != nil {
+			return db.Price{}, fmt.Errorf("counting priced measurements: %w", err)
+		}
+		if n > 0 {
+			return db.Price{}, ErrPriceInUse
+		}
+	}
+	return q.CreatePrice(ctx, arg)
+}
@@ -0,0 +1,97 @@
+package dbx_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/cloud-gov/billing/internal/db"
+	"github.com/cloud-gov/billing/internal/dbx"
+	. "github.com/cloud-gov/billing/internal/testutil"
+)
+
+func TestDBPublishPrice(t *testing.T) {
+	conn, err := pgxpool.New(t.Context(), "")
+	if err != nil {
+		t.Fatal("creating database connection failed", err)
+	}
+	q := newTx(t, conn, false)
+	tz, _ := time.LoadLocation("America/New_York")
+	td := usageTestData()
+	td.Prices = nil
+	for i := range td.Measurements {
+		td.Measurements[i].AmountMicrocredits = pgtype.Int8{}
+	}
+	createTestData(t, q, td)
+	kind := td.ResourceKinds[0]
+
+	publish := func(effective time.Time, perUnit int64) (db.Price, error) {
+		t.Helper()
+		return dbx.PublishPrice(t.Context(), q, db.CreatePriceParams{
+			Meter:               kind.Meter,
+			KindNaturalID:       kind.NaturalID,
+			UnitOfMeasure:       "hours",
+			MicrocreditsPerUnit: perUnit,
+			Unit:                1,
+			EffectiveDate:       pgtype.Date{Time: effective, Valid: true},
+		})
+	}
+
+	first, err := publish(time.Date(2025, time.January, 1, 0, 0, 0, 0, time.UTC), 15)
+	if err != nil {
+		t.Fatal("publishing first price:", err)
+	}
+	// The first reading is on February 2 in business time and the second is on February 3.
+	second, err := publish(time.Date(2025, time.February, 3, 0, 0, 0, 0, time.UTC), 30)
+	if err != nil {
+		t.Fatal("publishing second price:", err)
+	}
+
+	first, err = q.GetPrice(t.Context(), first.ID)
+	if err != nil {
+		t.Fatal("getting first price:", err)
+	}
+	changeover := time.Date(2025, time.February, 3, 0, 0, 0, 0, tz)
+	if !first.ValidDuring.Upper.Time.Equal(changeover) {
+		t.Fatalf("expected first price to end at %v, got %v", changeover, first.ValidDuring.Upper.Time)
+	}
+	if !second.ValidDuring.Lower.Time.Equal(changeover) || second.ValidDuring.UpperType != pgtype.Unbounded {
+		t.Fatalf("expected second price to start at %v and not end, got %+v", changeover, second.ValidDuring)
+	}
+
+	// Each measurement is priced once, with the price valid when it was read.
+	updated, err := q.UpdateMeasurementMicrocredits(t.Context(), PgTimestamptz(time.Date(2025, time.March, 2, 0, 0, 0, 0, tz)))
+	if err != nil {
+		t.Fatal("pricing measurements:", err)
+	}
+	if updated.Int64 != 2 {
+		t.Fatalf("expected 2 measurements to be priced, got %v", updated.Int64)
+	}
+	ms, err := q.ListMeasurements(t.Context())
+	if err != nil {
+		t.Fatal("listing measurements:", err)
+	}
+	want := map[int32]int64{1: 15, 2: 30}
+	for _, m := range ms {
+		if w, ok := want[m.ReadingID]; ok && m.Meter == kind.Meter && m.AmountMicrocredits.Int64 != w {
+			t.Errorf("expected measurement for reading %v to cost %v microcredits, got %v", m.ReadingID, w, m.AmountMicrocredits.Int64)
+		}
+	}
+
+	// Prices cannot change for measurements that have been priced.
+	_, err = publish(time.Date(2025, time.February, 1, 0, 0, 0, 0, time.UTC), 20)
+	if !errors.Is(err, dbx.ErrPriceInUse) {
+		t.Fatalf("expected ErrPriceInUse, got %v", err)
+	}
+
+	// Prices for the same kind cannot overlap.
+	_, err = publish(time.Date(2025, time.February, 3, 0, 0, 0, 0, time.UTC), 20)
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) || pgErr.Code != "23P01" {
+		t.Fatalf("expected exclusion violation, got %v", err)
+	}
+}
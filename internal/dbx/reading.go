@@ -0,0 +1,120 @@
+package dbx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/cloud-gov/billing/internal/db"
+)
+
+// Provenances of readings. See the reading.provenance column.
+const (
+	ProvenanceMeasured      = "measured"
+	ProvenanceInterpolated  = "interpolated"
+	ProvenanceReconstructed = "reconstructed"
+)
+
+// UsageEventRetention is how long the intervals of the usage event meter are kept after they stop, so usage can be reconstructed by [BackfillReading]. It should match the retention of the meter.
+const UsageEventRetention = 31 * 24 * time.Hour
+
+var (
+	ErrBackfillNoAdjacent = errors.New("backfilling reading: no readings before and after the hour to estimate from")
+	ErrBackfillPriced     = errors.New("backfilling reading: the reading after the hour has been priced; backfilling would change its interval")
+)
+
+// Backfill is the result of [BackfillReading].
+type Backfill struct {
+	Hour          time.Time
+	ReadingID     int32
+	Provenance    string
+	Interpolated  int64
+	Reconstructed int64
+}
+
+// BackfillReading creates a reading for an hour in which none was taken. Measurements of accruing resource kinds, whose cost depends on the time between readings, are estimated: the memory of CF app processes is reconstructed from usage events if the usage event meter covers the hour, and other kinds are interpolated between the readings before and after the hour. Other kinds are not estimated, because their meters measure usage since their last measured reading, which includes the hour.
+//
+// It returns [ErrBackfillNoAdjacent] if there are no readings on both sides of the hour, [ErrBackfillPriced] if the following reading has been priced, and [pgx.ErrNoRows] if a reading already exists for the hour.
+//
+// BackfillReading makes several changes to the database, so q should be scoped to a transaction.
+func BackfillReading(ctx context.Context, q db.Querier, hour time.Time, now time.Time) (Backfill, error) {
+	hour = hour.UTC().Truncate(time.Hour)
+	b := Backfill{Hour: hour}
+
+	prev, err := q.GetReadingBefore(ctx, pgtype.Timestamptz{Time: hour, Valid: true})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return b, ErrBackfillNoAdjacent
+	}
+	if err != nil {
+		return b, fmt.Errorf("getting previous reading: %w", err)
+	}
+	next, err := q.GetReadingAfter(ctx, pgtype.Timestamptz{Time: hour.Add(time.Hour), Valid: true})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return b, ErrBackfillNoAdjacent
+	}
+	if err != nil {
+		return b, fmt.Errorf("getting next reading: %w", err)
+	}
+	// Adding a reading shortens the interval of the next one, which changes the cost of its accruing measurements.
+	priced, err := q.CountPricedReadingMeasurements(ctx, next.ID)
+	if err != nil {
+		return b, fmt.Errorf("counting priced measurements: %w", err)
+	}
+	if priced > 0 {
+		return b, ErrBackfillPriced
+	}
+
+	// Take the reading at the same minute of the hour as the previous one, so readings stay evenly spaced.
+	prevAt, nextAt := prev.CreatedAtUTC.Time, next.CreatedAtUTC.Time
+	at := hour.Add(prevAt.Sub(prevAt.Truncate(time.Hour)))
+
+	reading, err := q.CreateBackfillReading(ctx, db.CreateBackfillReadingParams{
+		CreatedAt:  UtilTimestamp(at),
+		Provenance: ProvenanceInterpolated,
+	})
+	if err != nil {
+		return b, fmt.Errorf("creating reading: %w", err)
+	}
+	b.ReadingID = reading.ID
+	b.Provenance = reading.Provenance
+	atTS := pgtype.Timestamptz{Time: at, Valid: true}
+
+	// Reconstruct first, so interpolation does not replace exact values with estimates.
+	if now.Sub(at) < UsageEventRetention {
+		ok, err := q.CanReconstructAppMemory(ctx, atTS)
+		if err != nil {
+			return b, fmt.Errorf("checking usage events: %w", err)
+		}
+		if ok.Valid && ok.Bool {
+			b.Reconstructed, err = q.ReconstructAppMemoryMeasurements(ctx, db.ReconstructAppMemoryMeasurementsParams{
+				ReadingID: reading.ID,
+				At:        atTS,
+			})
+			if err != nil {
+				return b, fmt.Errorf("reconstructing app memory: %w", err)
+			}
+		}
+	}
+	if b.Reconstructed > 0 {
+		b.Provenance = ProvenanceReconstructed
+		err = q.SetReadingProvenance(ctx, db.SetReadingProvenanceParams{ID: reading.ID, Provenance: b.Provenance})
+		if err != nil {
+			return b, fmt.Errorf("setting provenance: %w", err)
+		}
+	}
+
+	b.Interpolated, err = q.InterpolateMeasurements(ctx, db.InterpolateMeasurementsParams{
+		ReadingID:     reading.ID,
+		Fraction:      float64(at.Sub(prevAt)) / float64(nextAt.Sub(prevAt)),
+		PrevReadingID: prev.ID,
+		NextReadingID: next.ID,
+	})
+	if err != nil {
+		return b, fmt.Errorf("interpolating measurements: %w", err)
+	}
+	return b, nil
+}
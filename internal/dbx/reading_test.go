@@ -0,0 +1,153 @@
+package dbx_test
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/cloud-gov/billing/internal/db"
+	"github.com/cloud-gov/billing/internal/dbx"
+	. "github.com/cloud-gov/billing/internal/testutil"
+	"github.com/cloud-gov/billing/internal/usage/meter"
+)
+
+func TestDBBackfillReading(t *testing.T) {
+	conn, err := pgxpool.New(t.Context(), "")
+	if err != nil {
+		t.Fatal("creating database connection failed", err)
+	}
+	q := newTx(t, conn, false)
+
+	var (
+		orgID     = PgUUID()
+		appID     = meter.InstanceResourceID(PgUUID().String(), 0, meter.AppMemoryKind)
+		taskID    = PgUUID().String()
+		utc, _    = time.LoadLocation("")
+		day       = time.Date(2032, time.March, 10, 0, 0, 0, 0, utc)
+		hour      = func(h int) time.Time { return day.Add(time.Duration(h) * time.Hour) }
+		readingAt = func(h int) time.Time { return hour(h).Add(5 * time.Minute) }
+	)
+	// The cfapps and cftasks meters and their kinds are created by migrations.
+	td := testData{
+		CFOrgs: []CFOrg{{CFOrg: db.CFOrg{ID: orgID}}},
+		Readings: []db.Reading{
+			{ID: 9001, CreatedAt: PgTimestamp(readingAt(0))},
+			{ID: 9002, CreatedAt: PgTimestamp(readingAt(1))},
+			// Readings for hours 2 and 3 were missed.
+			{ID: 9003, CreatedAt: PgTimestamp(readingAt(4))},
+		},
+		Resources: []db.Resource{
+			{Meter: meter.AppMeterName, NaturalID: appID, KindNaturalID: meter.AppMemoryKind, CFOrgID: orgID},
+			{Meter: meter.TaskMeterName, NaturalID: taskID, KindNaturalID: meter.TaskMemoryKind, CFOrgID: orgID},
+		},
+		Measurements: []db.Measurement{
+			{Meter: meter.AppMeterName, ResourceNaturalID: appID, Value: 1024, ReadingID: 9001},
+			{Meter: meter.AppMeterName, ResourceNaturalID: appID, Value: 1024, ReadingID: 9002},
+			{Meter: meter.AppMeterName, ResourceNaturalID: appID, Value: 4096, ReadingID: 9003},
+			{Meter: meter.TaskMeterName, ResourceNaturalID: taskID, Value: 60, ReadingID: 9002},
+			{Meter: meter.TaskMeterName, ResourceNaturalID: taskID, Value: 180, ReadingID: 9003},
+		},
+	}
+	createTestData(t, q, td)
+
+	gaps, err := q.ListReadingGaps(t.Context(), db.ListReadingGapsParams{
+		Since: PgTimestamptz(hour(0)),
+		Until: PgTimestamptz(hour(5)),
+	})
+	if err != nil {
+		t.Fatal("listing gaps:", err)
+	}
+	if len(gaps) != 2 || !gaps[0].Time.Equal(hour(2)) || !gaps[1].Time.Equal(hour(3)) {
+		t.Fatalf("expected gaps at hours 2 and 3, got %v", gaps)
+	}
+
+	// Each backfilled reading is interpolated from the readings around it, including earlier backfilled ones.
+	want := map[int]int32{2: 3072, 3: 3584}
+	ids := map[int]int32{}
+	for _, h := range []int{2, 3} {
+		b, err := dbx.BackfillReading(t.Context(), q, gaps[h-2].Time, hour(5))
+		if err != nil {
+			t.Fatalf("backfilling hour %v: %v", h, err)
+		}
+		if b.Provenance != dbx.ProvenanceInterpolated || b.Interpolated != 1 {
+			t.Errorf("expected one interpolated measurement for hour %v, got %+v", h, b)
+		}
+		ids[h] = b.ReadingID
+	}
+
+	ms, err := q.ListMeasurements(t.Context())
+	if err != nil {
+		t.Fatal("listing measurements:", err)
+	}
+	for h, id := range ids {
+		i := slices.IndexFunc(ms, func(m db.Measurement) bool {
+			return m.ReadingID == id && m.ResourceNaturalID == appID
+		})
+		if i < 0 {
+			t.Fatalf("measurement for hour %v not found", h)
+		}
+		if ms[i].Value != want[h] {
+			t.Errorf("expected %v MB for hour %v, got %v", want[h], h, ms[i].Value)
+		}
+		// Task usage since the last measured reading is included in the next one, so it is not estimated.
+		if slices.ContainsFunc(ms, func(m db.Measurement) bool {
+			return m.ReadingID == id && m.ResourceNaturalID == taskID
+		}) {
+			t.Errorf("expected no task measurement for hour %v", h)
+		}
+	}
+
+	if _, err := dbx.BackfillReading(t.Context(), q, hour(5), hour(6)); err != dbx.ErrBackfillNoAdjacent {
+		t.Errorf("expected %v for an hour after the last reading, got %v", dbx.ErrBackfillNoAdjacent, err)
+	}
+}
+
+func TestDBGetLatestReadingTime(t *testing.T) {
+	conn, err := pgxpool.New(t.Context(), "")
+	if err != nil {
+		t.Fatal("creating database connection failed", err)
+	}
+	q := newTx(t, conn, false)
+
+	var (
+		utc, _ = time.LoadLocation("")
+		hour   = func(h int) time.Time { return time.Date(2040, time.May, 1, h, 0, 0, 0, utc) }
+	)
+	td := testData{
+		Readings: []db.Reading{
+			// Taken before statuses were recorded.
+			{ID: 9101, CreatedAt: PgTimestamp(hour(0)), Periodic: true},
+			{ID: 9102, CreatedAt: PgTimestamp(hour(1)), Periodic: true},
+			// A manual one-off reading of a single app.
+			{ID: 9103, CreatedAt: PgTimestamp(hour(2).Add(20 * time.Minute))},
+			{ID: 9104, CreatedAt: PgTimestamp(hour(3)), Periodic: true},
+		},
+	}
+	createTestData(t, q, td)
+	err = q.BulkCreateReadingMeterStatuses(t.Context(), db.BulkCreateReadingMeterStatusesParams{
+		ReadingID:        []int32{9102, 9104, 9104},
+		Meter:            []string{meter.AppMeterName, meter.TaskMeterName, meter.AppMeterName},
+		StartedAt:        []pgtype.Timestamptz{PgTimestamptz(hour(1)), PgTimestamptz(hour(3)), PgTimestamptz(hour(3))},
+		DurationMs:       []int32{1000, 1000, 1000},
+		Succeeded:        []bool{true, false, true},
+		Error:            []string{"", "timed out", ""},
+		MeasurementCount: []int32{0, 0, 0},
+	})
+	if err != nil {
+		t.Fatal("recording meter statuses:", err)
+	}
+
+	// The task meter failed in the latest reading and did not run in the one before it, so its usage since hour 0 has not been read.
+	for m, want := range map[string]time.Time{meter.TaskMeterName: hour(0), meter.AppMeterName: hour(3)} {
+		got, err := q.GetLatestReadingTime(t.Context(), m)
+		if err != nil {
+			t.Fatalf("getting latest reading time for %v: %v", m, err)
+		}
+		if !got.Time.Equal(want) {
+			t.Errorf("expected latest reading of %v at %v, got %v", m, want, got.Time)
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package dbx_test
+
+import (
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/cloud-gov/billing/internal/db"
+)
+
+func TestDBUnpricedResourceKinds(t *testing.T) {
+	conn, err := pgxpool.New(t.Context(), "")
+	if err != nil {
+		t.Fatal("creating database connection failed", err)
+	}
+
+	for _, priced := range []bool{true, false} {
+		q := newTx(t, conn, false)
+		td := usageTestData()
+		if !priced {
+			td.Prices = nil
+		}
+		createTestData(t, q, td)
+		kind := td.ResourceKinds[0]
+
+		rows, err := q.ListUnpricedResourceKinds(t.Context())
+		if err != nil {
+			t.Fatal("listing unpriced resource kinds:", err)
+		}
+		var found *db.ListUnpricedResourceKindsRow
+		for _, row := range rows {
+			if row.ResourceKind.Meter == kind.Meter && row.ResourceKind.NaturalID == kind.NaturalID {
+				found = &row
+			}
+		}
+		switch {
+		case priced && found != nil:
+			t.Fatalf("expected kind priced when measured not to be listed, got %+v", found)
+		case !priced && found == nil:
+			t.Fatal("expected kind without a price to be listed")
+		case !priced && found.MeasurementCount != 2:
+			t.Fatalf("expected 2 unpriced measurements, got %v", found.MeasurementCount)
+		}
+	}
+}
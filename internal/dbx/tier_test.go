@@ -0,0 +1,79 @@
+package dbx_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/cloud-gov/billing/internal/db"
+	"github.com/cloud-gov/billing/internal/dbx"
+	. "github.com/cloud-gov/billing/internal/testutil"
+)
+
+func TestDBPostTierGrants(t *testing.T) {
+	conn, err := pgxpool.New(t.Context(), "")
+	if err != nil {
+		t.Fatal("creating database connection failed", err)
+	}
+	q := newTx(t, conn, false)
+	td := usageTestData()
+	createTestData(t, q, td)
+	customerID := td.CustomerIDs["customer1"]
+
+	tier, err := q.CreateTier(t.Context(), db.CreateTierParams{Name: "Test", TierCredits: 5})
+	if err != nil {
+		t.Fatal("creating tier:", err)
+	}
+	if _, err = q.SetCustomerTier(t.Context(), db.SetCustomerTierParams{ID: customerID, TierID: PgInt4(tier.ID)}); err != nil {
+		t.Fatal("assigning tier:", err)
+	}
+
+	// Posting twice in the same month grants credits once.
+	asOf := PgTimestamptz(time.Date(2025, time.March, 1, 6, 0, 0, 0, time.UTC))
+	for range 2 {
+		if _, err = q.PostTierGrants(t.Context(), asOf); err != nil {
+			t.Fatal("posting tier grants:", err)
+		}
+	}
+	b, err := dbx.GetCustomerBalance(t.Context(), q, customerID)
+	if err != nil {
+		t.Fatal("getting balance:", err)
+	}
+	if b.RemainingMicrocredits != 5_000_000 {
+		t.Fatalf("expected 5 credits after one grant, got %v microcredits", b.RemainingMicrocredits)
+	}
+
+	// The next month receives its own grant.
+	if _, err = q.PostTierGrants(t.Context(), PgTimestamptz(time.Date(2025, time.April, 1, 6, 0, 0, 0, time.UTC))); err != nil {
+		t.Fatal("posting tier grants:", err)
+	}
+	if b, err = dbx.GetCustomerBalance(t.Context(), q, customerID); err != nil {
+		t.Fatal("getting balance:", err)
+	}
+	if b.RemainingMicrocredits != 10_000_000 {
+		t.Fatalf("expected 10 credits after two grants, got %v microcredits", b.RemainingMicrocredits)
+	}
+}
+
+func TestDBDeleteTier(t *testing.T) {
+	conn, err := pgxpool.New(t.Context(), "")
+	if err != nil {
+		t.Fatal("creating database connection failed", err)
+	}
+	q := newTx(t, conn, false)
+
+	tier, err := q.CreateTier(t.Context(), db.CreateTierParams{Name: "Test", TierCredits: 5})
+	if err != nil {
+		t.Fatal("creating tier:", err)
+	}
+	for _, want := range []int64{1, 0} {
+		n, err := q.DeleteTier(t.Context(), tier.ID)
+		if err != nil {
+			t.Fatal("deleting tier:", err)
+		}
+		if n != want {
+			t.Fatalf("expected %v tiers deleted, got %v", want, n)
+		}
+	}
+}
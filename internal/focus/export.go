@@ -0,0 +1,199 @@
+package focus
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	_ "time/tzdata" // Billing periods are months in business time, so the zone must be available in minimal containers.
+
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/cloud-gov/billing/internal/db"
+	"github.com/cloud-gov/billing/internal/usage/meter"
+)
+
+// Granularities of the charge periods of exported rows.
+const (
+	GranularityHour = "hour"
+	GranularityDay  = "day"
+)
+
+var ErrBadGranularity = errors.New("granularity must be one of hour, day")
+
+// businessTime is the time zone in which months are closed, so it bounds billing periods. See bounds_month_prev in the database.
+var businessTime = mustLoadLocation("America/New_York")
+
+func mustLoadLocation(name string) *time.Location {
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		panic(err)
+	}
+	return loc
+}
+
+// Service is how the resources of a meter are described in FOCUS.
+type Service struct {
+	Name, Category, ResourceType string
+}
+
+var meterServices = map[string]Service{
+	meter.AppMeterName:        {"Cloud Foundry Applications", "Compute", "Application"},
+	meter.UsageEventMeterName: {"Cloud Foundry Applications", "Compute", "Application"},
+	meter.TaskMeterName:       {"Cloud Foundry Tasks", "Compute", "Task"},
+	meter.ServiceMeterName:    {"Cloud Foundry Services", "Other", "Service Instance"},
+	meter.WorkshopMeterName:   {"Workshop", "Compute", "Namespace"},
+	meter.AWSCURMeterName:     {"Amazon Web Services", "Other", "AWS Resource"},
+}
+
+// MeterService returns the service of the named meter's resources.
+func MeterService(name string) Service {
+	if s, ok := meterServices[name]; ok {
+		return s
+	}
+	return Service{Provider, "Other", name}
+}
+
+// ExportParams selects the usage written by [Export].
+type ExportParams struct {
+	CustomerID pgtype.UUID
+	// CFOrgIDs limits the export to resources in those CF orgs. If it is nil, every resource of the customer is exported.
+	CFOrgIDs []pgtype.UUID
+	// Since and Until bound the readings whose usage is exported. They are widened to whole charge periods.
+	Since, Until time.Time
+	// Granularity is the length of each row's charge period: GranularityHour or GranularityDay.
+	Granularity  string
+	USDPerCredit float64
+}
+
+// Export writes a row for the usage of each of the customer's resources in each charge period to w. Usage is queried and written one day at a time, and w is flushed after each day, so exports of long ranges are streamed. It does not close w.
+func Export(ctx context.Context, q db.Querier, w Writer, params ExportParams) error {
+	var step time.Duration
+	switch params.Granularity {
+	case GranularityHour:
+		step = time.Hour
+	case GranularityDay:
+		step = 24 * time.Hour
+	default:
+		return ErrBadGranularity
+	}
+	// Hours and days in UTC are aligned with the zero time, so Truncate finds the start of their period.
+	since := params.Since.UTC().Truncate(step)
+	until := params.Until.UTC().Truncate(step)
+	if until.Before(params.Until) {
+		until = until.Add(step)
+	}
+
+	for start := since; start.Before(until); start = start.Add(24 * time.Hour) {
+		end := start.Add(24 * time.Hour)
+		if end.After(until) {
+			end = until
+		}
+		costs, err := q.ListResourceCosts(ctx, db.ListResourceCostsParams{
+			Granularity: params.Granularity,
+			CustomerID:  params.CustomerID,
+			CfOrgIds:    params.CFOrgIDs,
+			Since:       pgtype.Timestamptz{Time: start, Valid: true},
+			Until:       pgtype.Timestamptz{Time: end, Valid: true},
+		})
+		if err != nil {
+			return fmt.Errorf("listing resource costs: %w", err)
+		}
+		if len(costs) == 0 {
+			continue
+		}
+		rows := make([]Row, len(costs))
+		for i, c := range costs {
+			rows[i] = NewRow(c, params.CustomerID, step, params.USDPerCredit)
+		}
+		if err := w.Write(rows...); err != nil {
+			return err
+		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// NewRow converts the cost of a resource in a charge period of the given length to a FOCUS row. The billing period is the month containing the charge period, in business time.
+func NewRow(c db.ListResourceCostsRow, customerID pgtype.UUID, period time.Duration, usdPerCredit float64) Row {
+	start := c.ChargePeriodStart.Time.UTC()
+	local := start.In(businessTime)
+	billingStart := time.Date(local.Year(), local.Month(), 1, 0, 0, 0, 0, businessTime)
+	svc := MeterService(c.Meter)
+
+	billed := float64(c.BilledMicrocredits) / 1e6
+	effective := float64(c.EffectiveMicrocredits) / 1e6
+	consumedUnit := c.UnitOfMeasure
+	if c.Accrues && consumedUnit != "" {
+		consumedUnit += "-Hours"
+	}
+	quantity := c.Quantity
+
+	row := Row{
+		BilledCost:                    billed * usdPerCredit,
+		BillingAccountId:              customerID.String(),
+		BillingAccountName:            c.CustomerName,
+		BillingCurrency:               "USD",
+		BillingPeriodEnd:              billingStart.AddDate(0, 1, 0),
+		BillingPeriodStart:            billingStart,
+		ChargeCategory:                "Usage",
+		ChargeDescription:             c.KindName,
+		ChargeFrequency:               "Usage-Based",
+		ChargePeriodEnd:               start.Add(period),
+		ChargePeriodStart:             start,
+		ConsumedQuantity:              &quantity,
+		ConsumedUnit:                  consumedUnit,
+		ContractedCost:                effective * usdPerCredit,
+		EffectiveCost:                 effective * usdPerCredit,
+		InvoiceIssuerName:             Provider,
+		ListCost:                      effective * usdPerCredit,
+		PricingCurrency:               "Credits",
+		PricingCurrencyContractedCost: effective,
+		PricingCurrencyEffectiveCost:  effective,
+		PricingCurrencyListCost:       effective,
+		ProviderName:                  Provider,
+		PublisherName:                 Provider,
+		ResourceId:                    c.ResourceNaturalID,
+		ResourceName:                  resourceName(c.Path, c.ResourceNaturalID),
+		ResourceType:                  svc.ResourceType,
+		ServiceCategory:               svc.Category,
+		ServiceName:                   svc.Name,
+		SkuId:                         strings.TrimSuffix(c.Meter+"/"+c.KindNaturalID, "/"),
+		SubAccountId:                  c.CFOrgID.String(),
+		SubAccountName:                c.CFOrgName,
+		Tags:                          Tags(c.Path, c.Estimated),
+	}
+	// Usage of kinds without a price is listed without pricing columns. It costs nothing.
+	if c.PriceID != 0 {
+		pricingQuantity := c.Quantity / float64(c.Unit)
+		unitPrice := float64(c.MicrocreditsPerUnit) / 1e6
+		unitPriceUSD := unitPrice * usdPerCredit
+		row.PricingQuantity = &pricingQuantity
+		row.PricingUnit = consumedUnit
+		if c.Unit != 1 {
+			row.PricingUnit = strconv.FormatInt(c.Unit, 10) + " " + consumedUnit
+		}
+		row.ListUnitPrice = &unitPriceUSD
+		row.ContractedUnitPrice = &unitPriceUSD
+		row.PricingCurrencyListUnitPrice = &unitPrice
+		row.PricingCurrencyContractedUnitPrice = &unitPrice
+		row.SkuPriceId = strconv.Itoa(int(c.PriceID))
+	}
+	return row
+}
+
+// resourceName returns the value of the last label of a node path, e.g. web for apps.usage.cforg_sandbox.space_dev.app_web, or id if the resource has no node.
+func resourceName(path, id string) string {
+	if path == "" {
+		return id
+	}
+	label := path[strings.LastIndex(path, ".")+1:]
+	if _, value, ok := strings.Cut(label, "_"); ok {
+		return value
+	}
+	return label
+}
@@ -0,0 +1,307 @@
+// Package focus writes cost and usage data as FinOps Open Cost and Usage Specification (FOCUS) datasets, so agencies can analyze their cloud.gov costs alongside their bills from other providers.
+package focus
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/parquet-go/parquet-go"
+)
+
+// Formats in which datasets can be written.
+const (
+	FormatCSV     = "csv"
+	FormatParquet = "parquet"
+)
+
+var ErrBadFormat = errors.New("format must be one of csv, parquet")
+
+// Provider is the name of the provider, publisher, and invoice issuer of every row.
+const Provider = "cloud.gov"
+
+// Row is one row of a FOCUS 1.2 dataset, with the columns that can be derived from cloud.gov usage. Usage is priced in credits, a virtual currency, so costs are given in credits in the PricingCurrency columns and converted to dollars in the others. Nil quantities and prices are null.
+type Row struct {
+	BilledCost                         float64   `parquet:"BilledCost"`
+	BillingAccountId                   string    `parquet:"BillingAccountId"`
+	BillingAccountName                 string    `parquet:"BillingAccountName"`
+	BillingCurrency                    string    `parquet:"BillingCurrency"`
+	BillingPeriodEnd                   time.Time `parquet:"BillingPeriodEnd,timestamp(millisecond)"`
+	BillingPeriodStart                 time.Time `parquet:"BillingPeriodStart,timestamp(millisecond)"`
+	ChargeCategory                     string    `parquet:"ChargeCategory"`
+	ChargeClass                        string    `parquet:"ChargeClass,optional"`
+	ChargeDescription                  string    `parquet:"ChargeDescription"`
+	ChargeFrequency                    string    `parquet:"ChargeFrequency"`
+	ChargePeriodEnd                    time.Time `parquet:"ChargePeriodEnd,timestamp(millisecond)"`
+	ChargePeriodStart                  time.Time `parquet:"ChargePeriodStart,timestamp(millisecond)"`
+	ConsumedQuantity                   *float64  `parquet:"ConsumedQuantity,optional"`
+	ConsumedUnit                       string    `parquet:"ConsumedUnit,optional"`
+	ContractedCost                     float64   `parquet:"ContractedCost"`
+	ContractedUnitPrice                *float64  `parquet:"ContractedUnitPrice,optional"`
+	EffectiveCost                      float64   `parquet:"EffectiveCost"`
+	InvoiceIssuerName                  string    `parquet:"InvoiceIssuerName"`
+	ListCost                           float64   `parquet:"ListCost"`
+	ListUnitPrice                      *float64  `parquet:"ListUnitPrice,optional"`
+	PricingCurrency                    string    `parquet:"PricingCurrency"`
+	PricingCurrencyContractedCost      float64   `parquet:"PricingCurrencyContractedCost"`
+	PricingCurrencyContractedUnitPrice *float64  `parquet:"PricingCurrencyContractedUnitPrice,optional"`
+	PricingCurrencyEffectiveCost       float64   `parquet:"PricingCurrencyEffectiveCost"`
+	PricingCurrencyListCost            float64   `parquet:"PricingCurrencyListCost"`
+	PricingCurrencyListUnitPrice       *float64  `parquet:"PricingCurrencyListUnitPrice,optional"`
+	PricingQuantity                    *float64  `parquet:"PricingQuantity,optional"`
+	PricingUnit                        string    `parquet:"PricingUnit,optional"`
+	ProviderName                       string    `parquet:"ProviderName"`
+	PublisherName                      string    `parquet:"PublisherName"`
+	ResourceId                         string    `parquet:"ResourceId,optional"`
+	ResourceName                       string    `parquet:"ResourceName,optional"`
+	ResourceType                       string    `parquet:"ResourceType,optional"`
+	ServiceCategory                    string    `parquet:"ServiceCategory"`
+	ServiceName                        string    `parquet:"ServiceName"`
+	SkuId                              string    `parquet:"SkuId,optional"`
+	SkuPriceId                         string    `parquet:"SkuPriceId,optional"`
+	SubAccountId                       string    `parquet:"SubAccountId,optional"`
+	SubAccountName                     string    `parquet:"SubAccountName,optional"`
+	// Tags is a JSON object. See [Tags].
+	Tags string `parquet:"Tags,optional"`
+}
+
+// Header names the columns of a [Row], in the order they are written as CSV.
+var Header = []string{
+	"BilledCost",
+	"BillingAccountId",
+	"BillingAccountName",
+	"BillingCurrency",
+	"BillingPeriodEnd",
+	"BillingPeriodStart",
+	"ChargeCategory",
+	"ChargeClass",
+	"ChargeDescription",
+	"ChargeFrequency",
+	"ChargePeriodEnd",
+	"ChargePeriodStart",
+	"ConsumedQuantity",
+	"ConsumedUnit",
+	"ContractedCost",
+	"ContractedUnitPrice",
+	"EffectiveCost",
+	"InvoiceIssuerName",
+	"ListCost",
+	"ListUnitPrice",
+	"PricingCurrency",
+	"PricingCurrencyContractedCost",
+	"PricingCurrencyContractedUnitPrice",
+	"PricingCurrencyEffectiveCost",
+	"PricingCurrencyListCost",
+	"PricingCurrencyListUnitPrice",
+	"PricingQuantity",
+	"PricingUnit",
+	"ProviderName",
+	"PublisherName",
+	"ResourceId",
+	"ResourceName",
+	"ResourceType",
+	"ServiceCategory",
+	"ServiceName",
+	"SkuId",
+	"SkuPriceId",
+	"SubAccountId",
+	"SubAccountName",
+	"Tags",
+}
+
+// record returns the row's values in the order of [Header].
+func (r Row) record() []string {
+	return []string{
+		formatFloat(r.BilledCost),
+		r.BillingAccountId,
+		r.BillingAccountName,
+		r.BillingCurrency,
+		formatTime(r.BillingPeriodEnd),
+		formatTime(r.BillingPeriodStart),
+		r.ChargeCategory,
+		r.ChargeClass,
+		r.ChargeDescription,
+		r.ChargeFrequency,
+		formatTime(r.ChargePeriodEnd),
+		formatTime(r.ChargePeriodStart),
+		formatOptional(r.ConsumedQuantity),
+		r.ConsumedUnit,
+		formatFloat(r.ContractedCost),
+		formatOptional(r.ContractedUnitPrice),
+		formatFloat(r.EffectiveCost),
+		r.InvoiceIssuerName,
+		formatFloat(r.ListCost),
+		formatOptional(r.ListUnitPrice),
+		r.PricingCurrency,
+		formatFloat(r.PricingCurrencyContractedCost),
+		formatOptional(r.PricingCurrencyContractedUnitPrice),
+		formatFloat(r.PricingCurrencyEffectiveCost),
+		formatFloat(r.PricingCurrencyListCost),
+		formatOptional(r.PricingCurrencyListUnitPrice),
+		formatOptional(r.PricingQuantity),
+		r.PricingUnit,
+		r.ProviderName,
+		r.PublisherName,
+		r.ResourceId,
+		r.ResourceName,
+		r.ResourceType,
+		r.ServiceCategory,
+		r.ServiceName,
+		r.SkuId,
+		r.SkuPriceId,
+		r.SubAccountId,
+		r.SubAccountName,
+		r.Tags,
+	}
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+func formatOptional(f *float64) string {
+	if f == nil {
+		return ""
+	}
+	return formatFloat(*f)
+}
+
+// formatTime formats t as FOCUS requires: in UTC, to the second.
+func formatTime(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
+
+// pathTags maps the prefixes of resource node path labels, like cforg_ in cforg_sandbox, to the tags their values are given as.
+var pathTags = map[string]string{
+	"cforg": "cf_org",
+	"space": "cf_space",
+	"app":   "cf_app",
+	"proc":  "cf_process",
+	"task":  "cf_task",
+	"event": "cf_app",
+	"svc":   "cf_service_instance",
+	"ns":    "workshop_namespace",
+	"aws":   "aws_resource",
+}
+
+// Tags returns the tags of a resource as a JSON object. The labels of its node path become tags according to their prefixes, e.g. space_dev in apps.usage.cforg_sandbox.space_dev becomes "cf_space": "dev", and the path itself is tagged as "path". If estimated is true, the usage came from backfilled readings, and "estimated": "true" is included.
+func Tags(path string, estimated bool) string {
+	tags := map[string]string{}
+	if path != "" {
+		tags["path"] = path
+	}
+	for _, label := range strings.Split(path, ".") {
+		prefix, value, ok := strings.Cut(label, "_")
+		if !ok {
+			continue
+		}
+		key, ok := pathTags[prefix]
+		if !ok {
+			continue
+		}
+		if _, seen := tags[key]; !seen {
+			tags[key] = value
+		}
+	}
+	if estimated {
+		tags["estimated"] = "true"
+	}
+	// Marshaling a map of strings cannot fail.
+	b, _ := json.Marshal(tags)
+	return string(b)
+}
+
+// Writer writes rows of a FOCUS dataset. Rows are buffered; Flush writes them to the underlying writer, so large datasets can be streamed. Close flushes and finishes the dataset, but does not close the underlying writer.
+type Writer interface {
+	Write(rows ...Row) error
+	Flush() error
+	Close() error
+}
+
+// NewWriter returns a Writer for the given format.
+func NewWriter(w io.Writer, format string) (Writer, error) {
+	switch format {
+	case FormatCSV:
+		return NewCSVWriter(w), nil
+	case FormatParquet:
+		return NewParquetWriter(w), nil
+	}
+	return nil, ErrBadFormat
+}
+
+// ContentType returns the media type of datasets in the given format.
+func ContentType(format string) string {
+	if format == FormatParquet {
+		return "application/vnd.apache.parquet"
+	}
+	return "text/csv"
+}
+
+type csvWriter struct {
+	cw          *csv.Writer
+	wroteHeader bool
+}
+
+// NewCSVWriter returns a Writer that writes rows as CSV, after a header row of the column names.
+func NewCSVWriter(w io.Writer) Writer {
+	return &csvWriter{cw: csv.NewWriter(w)}
+}
+
+func (w *csvWriter) Write(rows ...Row) error {
+	if err := w.header(); err != nil {
+		return err
+	}
+	for _, r := range rows {
+		if err := w.cw.Write(r.record()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// header writes the header once, so a dataset without rows still has one.
+func (w *csvWriter) header() error {
+	if w.wroteHeader {
+		return nil
+	}
+	w.wroteHeader = true
+	return w.cw.Write(Header)
+}
+
+func (w *csvWriter) Flush() error {
+	w.cw.Flush()
+	return w.cw.Error()
+}
+
+func (w *csvWriter) Close() error {
+	if err := w.header(); err != nil {
+		return err
+	}
+	return w.Flush()
+}
+
+type parquetWriter struct {
+	pw *parquet.GenericWriter[Row]
+}
+
+// NewParquetWriter returns a Writer that writes rows as a Parquet file. Each flush writes a row group.
+func NewParquetWriter(w io.Writer) Writer {
+	return &parquetWriter{pw: parquet.NewGenericWriter[Row](w)}
+}
+
+func (w *parquetWriter) Write(rows ...Row) error {
+	_, err := w.pw.Write(rows)
+	return err
+}
+
+func (w *parquetWriter) Flush() error {
+	return w.pw.Flush()
+}
+
+func (w *parquetWriter) Close() error {
+	return w.pw.Close()
+}
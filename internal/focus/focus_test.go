@@ -0,0 +1,257 @@
+package focus
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/parquet-go/parquet-go"
+
+	"github.com/cloud-gov/billing/internal/db"
+	"github.com/cloud-gov/billing/internal/dbx"
+)
+
+func TestTags(t *testing.T) {
+	tests := []struct {
+		path      string
+		estimated bool
+		want      string
+	}{
+		{"", false, `{}`},
+		{"", true, `{"estimated":"true"}`},
+		{
+			"apps.usage.cforg_sandbox.space_dev.app_web.proc_web_web",
+			false,
+			`{"cf_app":"web","cf_org":"sandbox","cf_process":"web_web","cf_space":"dev","path":"apps.usage.cforg_sandbox.space_dev.app_web.proc_web_web"}`,
+		},
+		{
+			"workshop.usage.ns_team",
+			true,
+			`{"estimated":"true","path":"workshop.usage.ns_team","workshop_namespace":"team"}`,
+		},
+	}
+	for _, tt := range tests {
+		if got := Tags(tt.path, tt.estimated); got != tt.want {
+			t.Errorf("Tags(%q, %v): want %s, got %s", tt.path, tt.estimated, tt.want, got)
+		}
+	}
+}
+
+func testCost() db.ListResourceCostsRow {
+	return db.ListResourceCostsRow{
+		ChargePeriodStart:     pgtype.Timestamptz{Time: time.Date(2026, time.October, 1, 2, 0, 0, 0, time.UTC), Valid: true},
+		CustomerName:          "Agency",
+		CFOrgID:               dbx.UtilUUID("22222222-2222-2222-2222-222222222222"),
+		CFOrgName:             "sandbox",
+		Meter:                 "cfapps",
+		ResourceNaturalID:     "33333333-3333-3333-3333-333333333333",
+		KindNaturalID:         "",
+		KindName:              "cfapps",
+		Accrues:               true,
+		Path:                  "apps.usage.cforg_sandbox.space_dev.app_web",
+		PriceID:               7,
+		UnitOfMeasure:         "MB",
+		MicrocreditsPerUnit:   500_000,
+		Unit:                  1024,
+		Quantity:              2048,
+		BilledMicrocredits:    0,
+		EffectiveMicrocredits: 1_000_000,
+	}
+}
+
+func TestNewRow(t *testing.T) {
+	customerID := dbx.UtilUUID("11111111-1111-1111-1111-111111111111")
+	row := NewRow(testCost(), customerID, time.Hour, 50)
+
+	if row.BilledCost != 0 || row.EffectiveCost != 50 || row.PricingCurrencyEffectiveCost != 1 {
+		t.Errorf("unpriced usage should be estimated but not billed, got billed %v, effective %v (%v credits)", row.BilledCost, row.EffectiveCost, row.PricingCurrencyEffectiveCost)
+	}
+	// 02:00 UTC on October 1 is still September in business time.
+	if want := time.Date(2026, time.September, 1, 4, 0, 0, 0, time.UTC); !row.BillingPeriodStart.Equal(want) {
+		t.Errorf("BillingPeriodStart: want %v, got %v", want, row.BillingPeriodStart)
+	}
+	if want := time.Date(2026, time.October, 1, 3, 0, 0, 0, time.UTC); !row.ChargePeriodEnd.Equal(want) {
+		t.Errorf("ChargePeriodEnd: want %v, got %v", want, row.ChargePeriodEnd)
+	}
+	if row.PricingQuantity == nil || *row.PricingQuantity != 2 || row.PricingUnit != "1024 MB-Hours" {
+		t.Errorf("want pricing quantity 2 of 1024 MB-Hours, got %v of %q", row.PricingQuantity, row.PricingUnit)
+	}
+	if row.ListUnitPrice == nil || *row.ListUnitPrice != 25 {
+		t.Errorf("ListUnitPrice: want 25, got %v", row.ListUnitPrice)
+	}
+	want := map[string]string{
+		"BillingAccountId": customerID.String(),
+		"ConsumedUnit":     "MB-Hours",
+		"ResourceName":     "web",
+		"ServiceName":      "Cloud Foundry Applications",
+		"SkuId":            "cfapps",
+		"SkuPriceId":       "7",
+		"SubAccountName":   "sandbox",
+	}
+	got := map[string]string{
+		"BillingAccountId": row.BillingAccountId,
+		"ConsumedUnit":     row.ConsumedUnit,
+		"ResourceName":     row.ResourceName,
+		"ServiceName":      row.ServiceName,
+		"SkuId":            row.SkuId,
+		"SkuPriceId":       row.SkuPriceId,
+		"SubAccountName":   row.SubAccountName,
+	}
+	for k, v := range want {
+		if got[k] != v {
+			t.Errorf("%v: want %q, got %q", k, v, got[k])
+		}
+	}
+
+	unpriced := testCost()
+	unpriced.PriceID = 0
+	row = NewRow(unpriced, customerID, time.Hour, 50)
+	if row.PricingQuantity != nil || row.ListUnitPrice != nil || row.SkuPriceId != "" {
+		t.Errorf("usage without a price should have no pricing columns, got %+v", row)
+	}
+}
+
+func TestCSVWriter(t *testing.T) {
+	buf := &bytes.Buffer{}
+	w := NewCSVWriter(buf)
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	records, err := csv.NewReader(bytes.NewReader(buf.Bytes())).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 1 {
+		t.Fatalf("an empty dataset should have only a header, got %d records", len(records))
+	}
+
+	buf.Reset()
+	w = NewCSVWriter(buf)
+	row := NewRow(testCost(), dbx.UtilUUID("11111111-1111-1111-1111-111111111111"), time.Hour, 50)
+	row.PricingQuantity = nil
+	if err := w.Write(row); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	records, err = csv.NewReader(buf).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 2 {
+		t.Fatalf("expected a header and 1 row, got %d records", len(records))
+	}
+	got := map[string]string{}
+	for i, h := range records[0] {
+		got[h] = records[1][i]
+	}
+	want := map[string]string{
+		"EffectiveCost":     "50",
+		"ChargePeriodStart": "2026-10-01T02:00:00Z",
+		"PricingQuantity":   "",
+		"ConsumedQuantity":  "2048",
+	}
+	for k, v := range want {
+		if got[k] != v {
+			t.Errorf("%v: want %q, got %q", k, v, got[k])
+		}
+	}
+}
+
+func TestParquetWriter(t *testing.T) {
+	buf := &bytes.Buffer{}
+	w := NewParquetWriter(buf)
+	row := NewRow(testCost(), dbx.UtilUUID("11111111-1111-1111-1111-111111111111"), time.Hour, 50)
+	for range 2 {
+		if err := w.Write(row); err != nil {
+			t.Fatal(err)
+		}
+		if err := w.Flush(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := parquet.OpenFile(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(f.RowGroups()) != 2 {
+		t.Errorf("each flush should write a row group, got %d row groups", len(f.RowGroups()))
+	}
+	for _, name := range Header {
+		if _, ok := f.Schema().Lookup(name); !ok {
+			t.Errorf("schema is missing column %s", name)
+		}
+	}
+	rows, err := parquet.Read[Row](bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 2 || rows[0].Tags != row.Tags || !rows[0].ChargePeriodStart.Equal(row.ChargePeriodStart) {
+		t.Errorf("rows did not round trip, got %+v", rows)
+	}
+}
+
+// costQuerier returns the same cost for every hour it is asked for.
+type costQuerier struct {
+	db.Querier
+	calls []db.ListResourceCostsParams
+}
+
+func (q *costQuerier) ListResourceCosts(ctx context.Context, arg db.ListResourceCostsParams) ([]db.ListResourceCostsRow, error) {
+	q.calls = append(q.calls, arg)
+	c := testCost()
+	c.ChargePeriodStart = arg.Since
+	return []db.ListResourceCostsRow{c}, nil
+}
+
+func TestExport(t *testing.T) {
+	q := &costQuerier{}
+	buf := &bytes.Buffer{}
+	w := NewCSVWriter(buf)
+	orgIDs := []pgtype.UUID{dbx.UtilUUID("22222222-2222-2222-2222-222222222222")}
+	err := Export(context.Background(), q, w, ExportParams{
+		CFOrgIDs:    orgIDs,
+		Since:       time.Date(2026, time.October, 1, 12, 30, 0, 0, time.UTC),
+		Until:       time.Date(2026, time.October, 3, 6, 0, 0, 0, time.UTC),
+		Granularity: GranularityDay,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The range is widened to whole days and queried a day at a time.
+	if len(q.calls) != 3 {
+		t.Fatalf("want 3 queries, got %d", len(q.calls))
+	}
+	if want := time.Date(2026, time.October, 1, 0, 0, 0, 0, time.UTC); !q.calls[0].Since.Time.Equal(want) {
+		t.Errorf("first query: want since %v, got %v", want, q.calls[0].Since.Time)
+	}
+	if want := time.Date(2026, time.October, 4, 0, 0, 0, 0, time.UTC); !q.calls[2].Until.Time.Equal(want) {
+		t.Errorf("last query: want until %v, got %v", want, q.calls[2].Until.Time)
+	}
+	if len(q.calls[0].CfOrgIds) != 1 || q.calls[0].CfOrgIds[0] != orgIDs[0] {
+		t.Errorf("queries should be limited to the given orgs, got %v", q.calls[0].CfOrgIds)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	records, err := csv.NewReader(buf).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 4 {
+		t.Errorf("expected a header and 3 rows, got %d records", len(records))
+	}
+
+	err = Export(context.Background(), q, w, ExportParams{Granularity: "minute"})
+	if err != ErrBadGranularity {
+		t.Errorf("want ErrBadGranularity, got %v", err)
+	}
+}
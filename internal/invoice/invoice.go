@@ -0,0 +1,255 @@
+// Package invoice renders invoices as documents customers can attach to their interagency agreement billing.
+package invoice
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"time"
+	_ "time/tzdata" // Periods are shown in business time, so the zone must be available in minimal containers.
+
+	"github.com/go-pdf/fpdf"
+
+	"github.com/cloud-gov/billing/internal/dbx"
+)
+
+// businessTime is the time zone in which months are closed. See bounds_month_prev in the database.
+var businessTime = mustLoadLocation("America/New_York")
+
+func mustLoadLocation(name string) *time.Location {
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		panic(err)
+	}
+	return loc
+}
+
+// Number formats the invoice number as it is shown to customers, zero-padded so numbers sort as text.
+func Number(inv dbx.Invoice) string {
+	return fmt.Sprintf("%06d", inv.Number)
+}
+
+// Period returns the first and last days of the invoice's period in business time.
+func Period(inv dbx.Invoice) (first, last string) {
+	return inv.PeriodStart.In(businessTime).Format(time.DateOnly),
+		inv.PeriodEnd.In(businessTime).AddDate(0, 0, -1).Format(time.DateOnly)
+}
+
+// FormatCredits formats an amount of microcredits as credits with six decimal places, so no precision is lost.
+func FormatCredits(microcredits int64) string {
+	sign := ""
+	u := uint64(microcredits)
+	if microcredits < 0 {
+		sign = "-"
+		u = -u
+	}
+	return fmt.Sprintf("%s%d.%06d", sign, u/1e6, u%1e6)
+}
+
+// unitPrice describes the price of a line, e.g. "0.500000 per 1024 MB-hour".
+func unitPrice(l dbx.InvoiceLine) string {
+	return fmt.Sprintf("%s per %d %s", FormatCredits(l.MicrocreditsPerUnit), l.Unit, quantityUnit(l))
+}
+
+// quantityUnit is the unit of the line's quantity.
+func quantityUnit(l dbx.InvoiceLine) string {
+	if l.Accrues {
+		return l.UnitOfMeasure + "-hour"
+	}
+	return l.UnitOfMeasure
+}
+
+// estimated returns the line's estimated quantity for display, or an empty string if none of its quantity was estimated.
+func estimated(l dbx.InvoiceLine) string {
+	if n, err := strconv.ParseFloat(l.EstimatedQuantity, 64); err != nil || n == 0 {
+		return ""
+	}
+	return l.EstimatedQuantity
+}
+
+// csvHeader names the columns written by [WriteCSV].
+var csvHeader = []string{
+	"invoice_number", "customer_id", "customer_name", "iaa_number", "period_first_day", "period_last_day",
+	"line", "cf_org_id", "cf_org_name", "space", "meter", "kind_natural_id", "kind_name",
+	"quantity", "estimated_quantity", "quantity_unit", "unit_price_credits", "price_unit", "amount_credits",
+}
+
+// WriteCSV writes the invoice's lines as CSV, one row per line after a header. Each row repeats the invoice's details, so rows from several invoices can be combined.
+func WriteCSV(w io.Writer, inv dbx.Invoice) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(csvHeader); err != nil {
+		return err
+	}
+	first, last := Period(inv)
+	for _, l := range inv.Lines {
+		err := cw.Write([]string{
+			Number(inv),
+			inv.CustomerID.String(),
+			inv.CustomerName,
+			inv.IAANumber,
+			first,
+			last,
+			strconv.Itoa(int(l.Line)),
+			l.CFOrgID.String(),
+			l.CFOrgName,
+			l.Space,
+			l.Meter,
+			l.KindNaturalID,
+			l.KindName,
+			l.Quantity,
+			l.EstimatedQuantity,
+			quantityUnit(l),
+			FormatCredits(l.MicrocreditsPerUnit),
+			strconv.FormatInt(l.Unit, 10) + " " + quantityUnit(l),
+			FormatCredits(l.AmountMicrocredits),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// pdfColumns are the columns of the table of lines in [WritePDF], with their widths in millimeters. The widths add up to the width of a landscape letter page less its margins.
+var pdfColumns = []struct {
+	title string
+	width float64
+	align string
+}{
+	{"#", 10, "R"},
+	{"Org", 40, "L"},
+	{"Space", 30, "L"},
+	{"Resource", 45, "L"},
+	{"Quantity", 32, "R"},
+	{"Estimated", 25, "R"},
+	{"Unit price (credits)", 47, "R"},
+	{"Amount (credits)", 30, "R"},
+}
+
+// WritePDF writes the invoice as a PDF document with its summary and a table of its lines. Void invoices, whose transactions were reversed, are marked as such.
+func WritePDF(w io.Writer, inv dbx.Invoice) error {
+	pdf := fpdf.New("L", "mm", "Letter", "")
+	pdf.SetTitle("Invoice "+Number(inv), true)
+	pdf.SetAuthor("cloud.gov", true)
+	pdf.SetCreationDate(inv.CreatedAt)
+	pdf.SetModificationDate(inv.CreatedAt)
+	pdf.SetMargins(10, 10, 10)
+	pdf.SetAutoPageBreak(true, 15)
+	tr := pdf.UnicodeTranslatorFromDescriptor("")
+
+	header := func() {
+		for _, c := range pdfColumns {
+			pdf.CellFormat(c.width, 7, c.title, "1", 0, c.align, true, 0, "")
+		}
+		pdf.Ln(-1)
+	}
+	pdf.SetHeaderFunc(func() {
+		if pdf.PageNo() > 1 {
+			pdf.SetFont("Helvetica", "B", 9)
+			pdf.SetFillColor(230, 230, 230)
+			header()
+			pdf.SetFont("Helvetica", "", 9)
+		}
+	})
+	pdf.SetFooterFunc(func() {
+		pdf.SetY(-12)
+		pdf.SetFont("Helvetica", "", 8)
+		pdf.CellFormat(0, 5, fmt.Sprintf("Invoice %s, page %d of {nb}", Number(inv), pdf.PageNo()), "", 0, "C", false, 0, "")
+	})
+	pdf.AliasNbPages("")
+	pdf.AddPage()
+
+	pdf.SetFont("Helvetica", "B", 16)
+	title := "Invoice " + Number(inv)
+	if inv.Reversed {
+		title += " (VOID)"
+	}
+	pdf.CellFormat(0, 10, title, "", 1, "L", false, 0, "")
+	if inv.Reversed {
+		pdf.SetFont("Helvetica", "", 9)
+		pdf.MultiCell(0, 5, "The usage on this invoice was reversed. If it was posted again, it appears on a later invoice.", "", "L", false)
+	}
+
+	first, last := Period(inv)
+	details := [][2]string{
+		{"Customer", tr(inv.CustomerName)},
+		{"Customer ID", inv.CustomerID.String()},
+		{"Interagency agreement", tr(inv.IAANumber)},
+		{"Period", first + " to " + last},
+		{"Issued", inv.CreatedAt.In(businessTime).Format(time.DateOnly)},
+		{"Transaction", strconv.Itoa(int(inv.TransactionID))},
+	}
+	pdf.Ln(2)
+	for _, d := range details {
+		if d[1] == "" {
+			continue
+		}
+		pdf.SetFont("Helvetica", "B", 10)
+		pdf.CellFormat(50, 6, d[0], "", 0, "L", false, 0, "")
+		pdf.SetFont("Helvetica", "", 10)
+		pdf.CellFormat(0, 6, d[1], "", 1, "L", false, 0, "")
+	}
+
+	pdf.Ln(4)
+	summary := [][2]string{
+		{"Credit pool before", FormatCredits(inv.CreditPoolBeforeMicrocredits)},
+		{"Credits used", FormatCredits(inv.UsageMicrocredits)},
+		{"Credit pool after", FormatCredits(inv.CreditPoolAfterMicrocredits)},
+	}
+	for _, s := range summary {
+		pdf.SetFont("Helvetica", "B", 10)
+		pdf.CellFormat(50, 6, s[0], "", 0, "L", false, 0, "")
+		pdf.SetFont("Helvetica", "", 10)
+		pdf.CellFormat(40, 6, s[1], "", 1, "R", false, 0, "")
+	}
+
+	pdf.Ln(4)
+	pdf.SetFont("Helvetica", "B", 9)
+	pdf.SetFillColor(230, 230, 230)
+	header()
+	pdf.SetFont("Helvetica", "", 9)
+	for _, l := range inv.Lines {
+		cells := []string{
+			strconv.Itoa(int(l.Line)),
+			tr(l.CFOrgName),
+			tr(l.Space),
+			tr(l.KindName),
+			l.Quantity + " " + tr(quantityUnit(l)),
+			estimated(l),
+			tr(unitPrice(l)),
+			FormatCredits(l.AmountMicrocredits),
+		}
+		for i, c := range pdfColumns {
+			pdf.CellFormat(c.width, 6, fit(pdf, cells[i], c.width-2), "1", 0, c.align, false, 0, "")
+		}
+		pdf.Ln(-1)
+	}
+	pdf.SetFont("Helvetica", "B", 9)
+	total := 0.0
+	for _, c := range pdfColumns[:len(pdfColumns)-1] {
+		total += c.width
+	}
+	pdf.CellFormat(total, 7, "Total", "1", 0, "R", false, 0, "")
+	pdf.CellFormat(pdfColumns[len(pdfColumns)-1].width, 7, FormatCredits(inv.UsageMicrocredits), "1", 1, "R", false, 0, "")
+	if slices.ContainsFunc(inv.Lines, func(l dbx.InvoiceLine) bool { return estimated(l) != "" }) {
+		pdf.Ln(2)
+		pdf.SetFont("Helvetica", "", 8)
+		pdf.MultiCell(0, 4, "Estimated is the part of the quantity that was estimated for hours in which usage could not be read, from the readings before and after or from usage events. It is included in the quantity and amount.", "", "L", false)
+	}
+
+	return pdf.Output(w)
+}
+
+// fit truncates s so it fits in a cell of the given width with the current font. s must already be translated to the font's single-byte encoding.
+func fit(pdf *fpdf.Fpdf, s string, width float64) string {
+	if pdf.GetStringWidth(s) <= width {
+		return s
+	}
+	for len(s) > 0 && pdf.GetStringWidth(s+"...") > width {
+		s = s[:len(s)-1]
+	}
+	return s + "..."
+}
@@ -0,0 +1,131 @@
+package invoice_test
+
+import (
+	"bytes"
+	"encoding/csv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cloud-gov/billing/internal/dbx"
+	"github.com/cloud-gov/billing/internal/invoice"
+)
+
+func testInvoice() dbx.Invoice {
+	tz, _ := time.LoadLocation("America/New_York")
+	return dbx.Invoice{
+		Number:                       42,
+		TransactionID:                7,
+		CustomerID:                   dbx.UtilUUID("11111111-1111-1111-1111-111111111111"),
+		CustomerName:                 "Agency of Examples",
+		IAANumber:                    "IAA-2026-01",
+		PeriodStart:                  time.Date(2026, 9, 1, 0, 0, 0, 0, tz).UTC(),
+		PeriodEnd:                    time.Date(2026, 10, 1, 0, 0, 0, 0, tz).UTC(),
+		UsageMicrocredits:            1_500_000,
+		CreditPoolBeforeMicrocredits: 10_000_000,
+		CreditPoolAfterMicrocredits:  8_500_000,
+		CreatedAt:                    time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC),
+		Lines: []dbx.InvoiceLine{
+			{
+				Line:                1,
+				CFOrgID:             dbx.UtilUUID("22222222-2222-2222-2222-222222222222"),
+				CFOrgName:           "examples-org",
+				Space:               "prod",
+				Meter:               "cfapps",
+				KindNaturalID:       "memory",
+				KindName:            "App memory",
+				UnitOfMeasure:       "MB",
+				Accrues:             true,
+				MicrocreditsPerUnit: 1000,
+				Unit:                1024,
+				Quantity:            "1024000.0000",
+				EstimatedQuantity:   "2048.0000",
+				AmountMicrocredits:  1_000_000,
+			},
+			{
+				Line:                2,
+				CFOrgID:             dbx.UtilUUID("22222222-2222-2222-2222-222222222222"),
+				CFOrgName:           "examples-org",
+				Meter:               "cfservices",
+				KindNaturalID:       "plan-guid",
+				KindName:            strings.Repeat("A very long service plan name ", 5),
+				UnitOfMeasure:       "instance",
+				MicrocreditsPerUnit: 500_000,
+				Unit:                1,
+				Quantity:            "1.0000",
+				EstimatedQuantity:   "0.0000",
+				AmountMicrocredits:  500_000,
+			},
+		},
+	}
+}
+
+func TestFormatCredits(t *testing.T) {
+	cases := map[int64]string{
+		0:          "0.000000",
+		1:          "0.000001",
+		1_500_000:  "1.500000",
+		-2_000_001: "-2.000001",
+	}
+	for in, want := range cases {
+		if got := invoice.FormatCredits(in); got != want {
+			t.Errorf("FormatCredits(%d): want %q, got %q", in, want, got)
+		}
+	}
+}
+
+func TestPeriod(t *testing.T) {
+	first, last := invoice.Period(testInvoice())
+	if first != "2026-09-01" || last != "2026-09-30" {
+		t.Errorf("unexpected period %v to %v", first, last)
+	}
+}
+
+func TestWriteCSV(t *testing.T) {
+	buf := &bytes.Buffer{}
+	if err := invoice.WriteCSV(buf, testInvoice()); err != nil {
+		t.Fatal(err)
+	}
+	records, err := csv.NewReader(buf).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 3 {
+		t.Fatalf("expected a header and 2 lines, got %d records", len(records))
+	}
+	row := map[string]string{}
+	for i, h := range records[0] {
+		row[h] = records[1][i]
+	}
+	want := map[string]string{
+		"invoice_number":     "000042",
+		"period_last_day":    "2026-09-30",
+		"space":              "prod",
+		"quantity":           "1024000.0000",
+		"estimated_quantity": "2048.0000",
+		"quantity_unit":      "MB-hour",
+		"unit_price_credits": "0.001000",
+		"price_unit":         "1024 MB-hour",
+		"amount_credits":     "1.000000",
+	}
+	for k, v := range want {
+		if row[k] != v {
+			t.Errorf("%v: want %q, got %q", k, v, row[k])
+		}
+	}
+}
+
+func TestWritePDF(t *testing.T) {
+	for _, reversed := range []bool{false, true} {
+		inv := testInvoice()
+		inv.Reversed = reversed
+		inv.CustomerName = "Agência de Exemplos"
+		buf := &bytes.Buffer{}
+		if err := invoice.WritePDF(buf, inv); err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.HasPrefix(buf.Bytes(), []byte("%PDF-")) {
+			t.Errorf("output is not a PDF")
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/riverqueue/river"
+
+	"github.com/cloud-gov/billing/internal/db"
+	"github.com/cloud-gov/billing/internal/dbx"
+	"github.com/cloud-gov/billing/internal/notify"
+)
+
+const CheckBalancesKind = "check-balances"
+
+type CheckBalancesArgs struct {
+	// Periodic is true if the job was scheduled automatically, or false if it was requested manually.
+	Periodic bool
+	AsOf     pgtype.Timestamptz
+}
+
+func (CheckBalancesArgs) Kind() string {
+	return CheckBalancesKind
+}
+
+// CheckBalancesWorker records alerts for customers whose credits are running low and delivers them. Use [NewCheckBalancesWorker] to create an instance for registration with the River client.
+type CheckBalancesWorker struct {
+	river.WorkerDefaults[CheckBalancesArgs]
+	logger   *slog.Logger
+	conn     *pgxpool.Pool
+	querier  dbx.Querier
+	notifier notify.Multi
+}
+
+func (u *CheckBalancesWorker) InsertOpts() river.InsertOpts {
+	return river.InsertOpts{
+		UniqueOpts: river.UniqueOpts{
+			ByQueue: true,
+		},
+	}
+}
+
+// Work records alerts as of the AsOf arg, then delivers every alert that has not been delivered, including alerts left over from earlier runs. Alerts are recorded once per threshold and funding level, so Work is idempotent. Each notifier's deliveries are recorded, so if some notifiers fail, Work returns an error and River retries only them. Along with the embedded river.WorkerDefaults, Work fulfills River's Worker interface.
+func (u *CheckBalancesWorker) Work(ctx context.Context, job *river.Job[CheckBalancesArgs]) error {
+	tx, err := u.conn.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	u.logger.DebugContext(ctx, "check-balances job: recording alerts")
+	recorded, err := dbx.RecordAlerts(ctx, u.querier.WithTx(tx), job.Args.AsOf.Time)
+	if err != nil {
+		u.logger.Error("check-balances job: recording alerts", "err", err)
+		return err
+	}
+	if err = tx.Commit(ctx); err != nil {
+		return err
+	}
+	u.logger.InfoContext(ctx, "check-balances job: recorded alerts", "count", len(recorded))
+
+	pending, err := u.querier.ListUnnotifiedAlerts(ctx)
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for _, row := range pending {
+		succeeded, notifyErr := u.notifier.Notify(ctx, newNotifyAlert(row.Alert, row.CustomerName), row.DeliveredTo)
+		for _, name := range succeeded {
+			err := u.querier.CreateAlertDelivery(ctx, db.CreateAlertDeliveryParams{AlertID: row.Alert.ID, Notifier: name})
+			if err != nil {
+				notifyErr = errors.Join(notifyErr, err)
+			}
+		}
+		if notifyErr != nil {
+			u.logger.ErrorContext(ctx, "check-balances job: delivering alert", "alert_id", row.Alert.ID, "delivered_to", append(row.DeliveredTo, succeeded...), "err", notifyErr)
+			errs = append(errs, notifyErr)
+			continue
+		}
+		err = u.querier.MarkAlertNotified(ctx, db.MarkAlertNotifiedParams{
+			ID:         row.Alert.ID,
+			NotifiedAt: pgtype.Timestamptz{Time: time.Now(), Valid: true},
+		})
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	u.logger.InfoContext(ctx, "check-balances job: delivered alerts", "count", len(pending)-len(errs))
+	return errors.Join(errs...)
+}
+
+func newNotifyAlert(a db.Alert, customerName string) notify.Alert {
+	n := notify.Alert{
+		ID:                    a.ID,
+		CustomerID:            a.CustomerID.String(),
+		CustomerName:          customerName,
+		Kind:                  string(a.Kind),
+		PercentConsumed:       a.PercentConsumed,
+		FundedMicrocredits:    a.FundedMicrocredits,
+		RemainingMicrocredits: a.RemainingMicrocredits,
+		DailyBurnMicrocredits: a.DailyBurnMicrocredits,
+		CreatedAt:             a.CreatedAt.Time,
+	}
+	if a.ProjectedExhaustionDate.Valid {
+		n.ProjectedExhaustionDate = a.ProjectedExhaustionDate.Time.Format(time.DateOnly)
+	}
+	if a.FundedUntil.Valid {
+		n.FundedUntil = a.FundedUntil.Time.Format(time.DateOnly)
+	}
+	return n
+}
+
+// NewCheckBalancesWorker stores dependencies required for job execution and returns a new worker.
+func NewCheckBalancesWorker(l *slog.Logger, c *pgxpool.Pool, q dbx.Querier, n notify.Multi) *CheckBalancesWorker {
+	return &CheckBalancesWorker{
+		logger:   l,
+		conn:     c,
+		querier:  q,
+		notifier: n,
+	}
+}
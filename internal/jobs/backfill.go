@@ -0,0 +1,98 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/riverqueue/river"
+	"github.com/riverqueue/river/riverdriver/riverpgxv5"
+
+	"github.com/cloud-gov/billing/internal/db"
+	"github.com/cloud-gov/billing/internal/dbx"
+)
+
+const BackfillReadingsKind = "backfill-readings"
+
+type BackfillReadingsArgs struct {
+	// Since and Until bound the hours to backfill: hours in [Since, Until) without a reading are backfilled.
+	Since time.Time
+	Until time.Time
+}
+
+func (BackfillReadingsArgs) Kind() string {
+	return BackfillReadingsKind
+}
+
+// BackfillReadingsWorker creates readings for hours in which none were taken, estimating their measurements. Use [NewBackfillReadingsWorker] to create an instance for registration with the River client.
+type BackfillReadingsWorker struct {
+	river.WorkerDefaults[BackfillReadingsArgs]
+	logger  *slog.Logger
+	conn    *pgxpool.Pool
+	querier dbx.Querier
+}
+
+func (u *BackfillReadingsWorker) InsertOpts() river.InsertOpts {
+	return river.InsertOpts{
+		UniqueOpts: river.UniqueOpts{
+			ByArgs: true,
+		},
+	}
+}
+
+// Work backfills every gap in the job's range in a single transaction. See [dbx.BackfillReading]. Hours that cannot be backfilled, because there is no reading after them yet or the reading after them has been priced, are skipped and logged. Along with the embedded river.WorkerDefaults, Work fulfills River's Worker interface.
+func (u *BackfillReadingsWorker) Work(ctx context.Context, job *river.Job[BackfillReadingsArgs]) error {
+	tx, err := u.conn.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+	txquerier := u.querier.WithTx(tx)
+
+	gaps, err := txquerier.ListReadingGaps(ctx, db.ListReadingGapsParams{
+		Since: pgtype.Timestamptz{Time: job.Args.Since, Valid: true},
+		Until: pgtype.Timestamptz{Time: job.Args.Until, Valid: true},
+	})
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+	backfilled, skipped := 0, 0
+	for _, gap := range gaps {
+		b, err := dbx.BackfillReading(ctx, txquerier, gap.Time, now)
+		if errors.Is(err, dbx.ErrBackfillNoAdjacent) || errors.Is(err, dbx.ErrBackfillPriced) || errors.Is(err, pgx.ErrNoRows) {
+			u.logger.WarnContext(ctx, "backfill-readings job: skipped hour", "hour", gap.Time, "err", err)
+			skipped++
+			continue
+		}
+		if err != nil {
+			u.logger.Error("backfill-readings job: backfilling hour", "hour", gap.Time, "err", err)
+			return err
+		}
+		u.logger.InfoContext(ctx, "backfill-readings job: backfilled hour", "hour", b.Hour, "reading_id", b.ReadingID, "provenance", b.Provenance, "interpolated", b.Interpolated, "reconstructed", b.Reconstructed)
+		backfilled++
+	}
+
+	if _, err := river.JobCompleteTx[*riverpgxv5.Driver](ctx, tx, job); err != nil {
+		return err
+	}
+	if err = tx.Commit(ctx); err != nil {
+		return err
+	}
+	u.logger.InfoContext(ctx, "backfill-readings job: done", "gaps", len(gaps), "backfilled", backfilled, "skipped", skipped)
+	return nil
+}
+
+// NewBackfillReadingsWorker stores dependencies required for job execution and returns a new worker.
+func NewBackfillReadingsWorker(l *slog.Logger, c *pgxpool.Pool, q dbx.Querier) *BackfillReadingsWorker {
+	return &BackfillReadingsWorker{
+		logger:  l,
+		conn:    c,
+		querier: q,
+	}
+}
@@ -0,0 +1,72 @@
+package jobs
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/riverqueue/river"
+
+	"github.com/cloud-gov/billing/internal/cfsync"
+	"github.com/cloud-gov/billing/internal/dbx"
+)
+
+const SyncCFCatalogKind = "sync-cf-catalog"
+
+type SyncCFCatalogArgs struct {
+	// Periodic is true if the job was scheduled automatically, or false if it was requested manually.
+	Periodic bool
+}
+
+func (SyncCFCatalogArgs) Kind() string {
+	return SyncCFCatalogKind
+}
+
+// SyncCFCatalogWorker copies the Cloud Foundry service catalog into resource kinds. Use [NewSyncCFCatalogWorker] to create an instance for registration with the River client.
+type SyncCFCatalogWorker struct {
+	river.WorkerDefaults[SyncCFCatalogArgs]
+	logger  *slog.Logger
+	conn    *pgxpool.Pool
+	querier dbx.Querier
+	cf      cfsync.Catalog
+}
+
+func (u *SyncCFCatalogWorker) InsertOpts() river.InsertOpts {
+	return river.InsertOpts{
+		UniqueOpts: river.UniqueOpts{
+			ByQueue: true,
+		},
+	}
+}
+
+// Work syncs every service plan in a single transaction. See [cfsync.SyncServicePlans]. Along with the embedded river.WorkerDefaults, Work fulfills River's Worker interface.
+func (u *SyncCFCatalogWorker) Work(ctx context.Context, job *river.Job[SyncCFCatalogArgs]) error {
+	tx, err := u.conn.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	u.logger.DebugContext(ctx, "sync-cf-catalog job: syncing service plans")
+	res, err := cfsync.SyncServicePlans(ctx, u.logger, u.cf, u.querier.WithTx(tx), time.Now())
+	if err != nil {
+		u.logger.Error("sync-cf-catalog job: syncing service plans", "err", err)
+		return err
+	}
+	if err = tx.Commit(ctx); err != nil {
+		return err
+	}
+	u.logger.InfoContext(ctx, "sync-cf-catalog job: synced service plans", "synced", res.Synced, "deprecated", res.Deprecated)
+	return nil
+}
+
+// NewSyncCFCatalogWorker stores dependencies required for job execution and returns a new worker.
+func NewSyncCFCatalogWorker(l *slog.Logger, c *pgxpool.Pool, q dbx.Querier, cf cfsync.Catalog) *SyncCFCatalogWorker {
+	return &SyncCFCatalogWorker{
+		logger:  l,
+		conn:    c,
+		querier: q,
+		cf:      cf,
+	}
+}
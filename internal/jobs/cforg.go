@@ -0,0 +1,75 @@
+package jobs
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/riverqueue/river"
+
+	"github.com/cloud-gov/billing/internal/cfsync"
+	"github.com/cloud-gov/billing/internal/dbx"
+	"github.com/cloud-gov/billing/internal/usage/meter"
+)
+
+const SyncCFOrgsKind = "sync-cf-orgs"
+
+type SyncCFOrgsArgs struct {
+	// Periodic is true if the job was scheduled automatically, or false if it was requested manually.
+	Periodic bool
+}
+
+func (SyncCFOrgsArgs) Kind() string {
+	return SyncCFOrgsKind
+}
+
+// SyncCFOrgsWorker copies the names and metadata of Cloud Foundry organizations into the database. Use [NewSyncCFOrgsWorker] to create an instance for registration with the River client.
+type SyncCFOrgsWorker struct {
+	river.WorkerDefaults[SyncCFOrgsArgs]
+	logger        *slog.Logger
+	conn          *pgxpool.Pool
+	querier       dbx.Querier
+	cf            meter.Organizations
+	customerLabel string
+}
+
+func (u *SyncCFOrgsWorker) InsertOpts() river.InsertOpts {
+	return river.InsertOpts{
+		UniqueOpts: river.UniqueOpts{
+			ByQueue: true,
+		},
+	}
+}
+
+// Work syncs every org in a single transaction. See [cfsync.SyncOrgs]. Along with the embedded river.WorkerDefaults, Work fulfills River's Worker interface.
+func (u *SyncCFOrgsWorker) Work(ctx context.Context, job *river.Job[SyncCFOrgsArgs]) error {
+	tx, err := u.conn.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	u.logger.DebugContext(ctx, "sync-cf-orgs job: syncing orgs")
+	res, err := cfsync.SyncOrgs(ctx, u.logger, u.cf, u.querier.WithTx(tx), u.customerLabel, time.Now())
+	if err != nil {
+		u.logger.Error("sync-cf-orgs job: syncing orgs", "err", err)
+		return err
+	}
+	if err = tx.Commit(ctx); err != nil {
+		return err
+	}
+	u.logger.InfoContext(ctx, "sync-cf-orgs job: synced orgs", "synced", res.Synced, "assigned", res.Assigned)
+	return nil
+}
+
+// NewSyncCFOrgsWorker stores dependencies required for job execution and returns a new worker. If customerLabel is not empty, orgs are assigned to customers based on the label with that key.
+func NewSyncCFOrgsWorker(l *slog.Logger, c *pgxpool.Pool, q dbx.Querier, cf meter.Organizations, customerLabel string) *SyncCFOrgsWorker {
+	return &SyncCFOrgsWorker{
+		logger:        l,
+		conn:          c,
+		querier:       q,
+		cf:            cf,
+		customerLabel: customerLabel,
+	}
+}
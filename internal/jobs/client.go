@@ -6,7 +6,10 @@ import (
 	"runtime"
 	"time"
 
+	"github.com/cloud-gov/billing/internal/cfsync"
+	"github.com/cloud-gov/billing/internal/config"
 	"github.com/cloud-gov/billing/internal/dbx"
+	"github.com/cloud-gov/billing/internal/notify"
 	"github.com/cloud-gov/billing/internal/usage/reader"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgtype"
@@ -16,10 +19,17 @@ import (
 	"github.com/robfig/cron/v3"
 )
 
-// NewClient creates a new river client with periodic jobs scheduled.
-func NewClient(conn *pgxpool.Pool, logger *slog.Logger, q dbx.Querier, rdr *reader.Reader) (*river.Client[pgx.Tx], error) {
+// NewClient creates a new river client with periodic jobs scheduled. If customerLabel is not empty, CF orgs are assigned to customers based on the org label with that key.
+func NewClient(conn *pgxpool.Pool, logger *slog.Logger, q dbx.Querier, rdr *reader.Reader, n notify.Multi, cf cfsync.CF, customerLabel string) (*river.Client[pgx.Tx], error) {
 	workers := river.NewWorkers()
 	river.AddWorker(workers, NewMeasureUsageWorker(logger, conn, q, rdr))
+	river.AddWorker(workers, NewPostUsageWorker(logger, conn, q))
+	river.AddWorker(workers, NewPostIAAPopWorker(logger, conn, q))
+	river.AddWorker(workers, NewCheckBalancesWorker(logger, conn, q, n))
+	river.AddWorker(workers, NewPostTierGrantsWorker(logger, conn, q))
+	river.AddWorker(workers, NewSyncCFOrgsWorker(logger, conn, q, cf, customerLabel))
+	river.AddWorker(workers, NewSyncCFCatalogWorker(logger, conn, q, cf))
+	river.AddWorker(workers, NewBackfillReadingsWorker(logger, conn, q))
 
 	measureUsageSchedule, err := cron.ParseStandard("1 * * * *") // Read usage every hour, one minute after the hour.
 	if err != nil {
@@ -29,9 +39,29 @@ func NewClient(conn *pgxpool.Pool, logger *slog.Logger, q dbx.Querier, rdr *read
 	if err != nil {
 		return nil, fmt.Errorf("parsing postUsage cron spec: %w", err)
 	}
+	postIAAPopSchedule, err := cron.ParseStandard("1 5 * * *") // Start and end IAA periods of performance daily at 5:01am, soon after midnight in America/New_York.
+	if err != nil {
+		return nil, fmt.Errorf("parsing postIAAPop cron spec: %w", err)
+	}
+	postTierGrantsSchedule, err := cron.ParseStandard("1 5 1 * *") // Grant tier credits on the first of every month at 5:01am, soon after midnight in America/New_York.
+	if err != nil {
+		return nil, fmt.Errorf("parsing postTierGrants cron spec: %w", err)
+	}
+	syncCFOrgsSchedule, err := cron.ParseStandard("51 * * * *") // Sync CF orgs every hour, before usage is read, so new orgs have names in the next reading.
+	if err != nil {
+		return nil, fmt.Errorf("parsing syncCFOrgs cron spec: %w", err)
+	}
+	syncCFCatalogSchedule, err := cron.ParseStandard("49 * * * *") // Sync the CF service catalog every hour, so new plans can be priced before they are used.
+	if err != nil {
+		return nil, fmt.Errorf("parsing syncCFCatalog cron spec: %w", err)
+	}
+	checkBalancesSchedule, err := cron.ParseStandard("31 5 * * *") // Check balances daily at 5:31am, after IAA periods of performance are posted.
+	if err != nil {
+		return nil, fmt.Errorf("parsing checkBalances cron spec: %w", err)
+	}
 
 	return river.NewClient(riverpgxv5.New(conn), &river.Config{
-		JobTimeout: 10 * time.Minute,
+		JobTimeout: config.JobTimeout,
 		Logger:     logger,
 		Queues: map[string]river.QueueConfig{
 			river.QueueDefault: {MaxWorkers: runtime.GOMAXPROCS(0)}, // Run as many workers as we have CPU cores available.
@@ -56,6 +86,54 @@ func NewClient(conn *pgxpool.Pool, logger *slog.Logger, q dbx.Querier, rdr *read
 				},
 				nil,
 			),
+			river.NewPeriodicJob(
+				postIAAPopSchedule,
+				func() (river.JobArgs, *river.InsertOpts) {
+					return PostIAAPopArgs{
+						Periodic: true,
+						AsOf:     pgtype.Timestamptz{Time: time.Now().UTC(), Valid: true},
+					}, nil
+				},
+				nil,
+			),
+			river.NewPeriodicJob(
+				postTierGrantsSchedule,
+				func() (river.JobArgs, *river.InsertOpts) {
+					return PostTierGrantsArgs{
+						Periodic: true,
+						AsOf:     pgtype.Timestamptz{Time: time.Now().UTC(), Valid: true},
+					}, nil
+				},
+				nil,
+			),
+			river.NewPeriodicJob(
+				syncCFOrgsSchedule,
+				func() (river.JobArgs, *river.InsertOpts) {
+					return SyncCFOrgsArgs{
+						Periodic: true,
+					}, nil
+				},
+				&river.PeriodicJobOpts{RunOnStart: true},
+			),
+			river.NewPeriodicJob(
+				syncCFCatalogSchedule,
+				func() (river.JobArgs, *river.InsertOpts) {
+					return SyncCFCatalogArgs{
+						Periodic: true,
+					}, nil
+				},
+				&river.PeriodicJobOpts{RunOnStart: true},
+			),
+			river.NewPeriodicJob(
+				checkBalancesSchedule,
+				func() (river.JobArgs, *river.InsertOpts) {
+					return CheckBalancesArgs{
+						Periodic: true,
+						AsOf:     pgtype.Timestamptz{Time: time.Now().UTC(), Valid: true},
+					}, nil
+				},
+				nil,
+			),
 		},
 		Workers: workers,
 	})
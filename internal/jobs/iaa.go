@@ -0,0 +1,78 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/riverqueue/river"
+	"github.com/riverqueue/river/riverdriver/riverpgxv5"
+
+	"github.com/cloud-gov/billing/internal/dbx"
+)
+
+const PostIAAPopKind = "post-iaa-pop"
+
+type PostIAAPopArgs struct {
+	// Periodic is true if the job was scheduled automatically, or false if it was requested manually.
+	Periodic bool
+	AsOf     pgtype.Timestamptz
+}
+
+func (PostIAAPopArgs) Kind() string {
+	return PostIAAPopKind
+}
+
+// PostIAAPopWorker adds credits to customers' credit pools when the Period of Performance of an interagency agreement (IAA) starts, and expires unused credits when it ends. Use [NewPostIAAPopWorker] to create an instance for registration with the River client.
+type PostIAAPopWorker struct {
+	river.WorkerDefaults[PostIAAPopArgs]
+	logger  *slog.Logger
+	conn    *pgxpool.Pool
+	querier dbx.Querier
+}
+
+func (u *PostIAAPopWorker) InsertOpts() river.InsertOpts {
+	return river.InsertOpts{
+		UniqueOpts: river.UniqueOpts{
+			ByQueue: true,
+		},
+	}
+}
+
+// Work posts iaa_pop_start and iaa_pop_end transactions for agreements whose Period of Performance started or ended on or before the AsOf arg. Each agreement is started and ended once, so Work is idempotent. Along with the embedded river.WorkerDefaults, Work fulfills River's Worker interface.
+func (u *PostIAAPopWorker) Work(ctx context.Context, job *river.Job[PostIAAPopArgs]) error {
+	tx, err := u.conn.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+	txquerier := u.querier.WithTx(tx)
+
+	u.logger.DebugContext(ctx, "post-iaa-pop job: posting IAA period of performance transactions")
+	ids, err := txquerier.PostIAAPop(ctx, job.Args.AsOf)
+	if err != nil {
+		u.logger.Error("post-iaa-pop job: posting transactions", "err", err)
+		return err
+	}
+	u.logger.InfoContext(ctx, "post-iaa-pop job: posted transactions", "count", len(ids))
+
+	jobAfter, err := river.JobCompleteTx[*riverpgxv5.Driver](ctx, tx, job)
+	if err != nil {
+		return err
+	}
+	u.logger.Info(fmt.Sprintf("post-iaa-pop job: transitioned job from %q to %q", job.State, jobAfter.State))
+
+	err = tx.Commit(ctx)
+	return err
+}
+
+// NewPostIAAPopWorker stores dependencies required for job execution and returns a new worker.
+func NewPostIAAPopWorker(l *slog.Logger, c *pgxpool.Pool, q dbx.Querier) *PostIAAPopWorker {
+	return &PostIAAPopWorker{
+		logger:  l,
+		conn:    c,
+		querier: q,
+	}
+}
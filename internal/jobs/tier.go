@@ -0,0 +1,78 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/riverqueue/river"
+	"github.com/riverqueue/river/riverdriver/riverpgxv5"
+
+	"github.com/cloud-gov/billing/internal/dbx"
+)
+
+const PostTierGrantsKind = "post-tier-grants"
+
+type PostTierGrantsArgs struct {
+	// Periodic is true if the job was scheduled automatically, or false if it was requested manually.
+	Periodic bool
+	AsOf     pgtype.Timestamptz
+}
+
+func (PostTierGrantsArgs) Kind() string {
+	return PostTierGrantsKind
+}
+
+// PostTierGrantsWorker adds the credits included in each customer's tier to their credit pool once a month. Use [NewPostTierGrantsWorker] to create an instance for registration with the River client.
+type PostTierGrantsWorker struct {
+	river.WorkerDefaults[PostTierGrantsArgs]
+	logger  *slog.Logger
+	conn    *pgxpool.Pool
+	querier dbx.Querier
+}
+
+func (u *PostTierGrantsWorker) InsertOpts() river.InsertOpts {
+	return river.InsertOpts{
+		UniqueOpts: river.UniqueOpts{
+			ByQueue: true,
+		},
+	}
+}
+
+// Work posts a tier_grant transaction for each customer in a tier with included credits, for the business month containing the AsOf arg. Each customer receives one grant per month, so Work is idempotent. Along with the embedded river.WorkerDefaults, Work fulfills River's Worker interface.
+func (u *PostTierGrantsWorker) Work(ctx context.Context, job *river.Job[PostTierGrantsArgs]) error {
+	tx, err := u.conn.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+	txquerier := u.querier.WithTx(tx)
+
+	u.logger.DebugContext(ctx, "post-tier-grants job: posting tier grants")
+	ids, err := txquerier.PostTierGrants(ctx, job.Args.AsOf)
+	if err != nil {
+		u.logger.Error("post-tier-grants job: posting transactions", "err", err)
+		return err
+	}
+	u.logger.InfoContext(ctx, "post-tier-grants job: posted transactions", "count", len(ids))
+
+	jobAfter, err := river.JobCompleteTx[*riverpgxv5.Driver](ctx, tx, job)
+	if err != nil {
+		return err
+	}
+	u.logger.Info(fmt.Sprintf("post-tier-grants job: transitioned job from %q to %q", job.State, jobAfter.State))
+
+	err = tx.Commit(ctx)
+	return err
+}
+
+// NewPostTierGrantsWorker stores dependencies required for job execution and returns a new worker.
+func NewPostTierGrantsWorker(l *slog.Logger, c *pgxpool.Pool, q dbx.Querier) *PostTierGrantsWorker {
+	return &PostTierGrantsWorker{
+		logger:  l,
+		conn:    c,
+		querier: q,
+	}
+}
@@ -3,6 +3,7 @@ package jobs
 import (
 	"context"
 	"errors"
+	"expvar"
 	"fmt"
 	"log/slog"
 
@@ -51,7 +52,7 @@ func (u *MeasureUsageWorker) InsertOpts() river.InsertOpts {
 	}
 }
 
-// Work reads usage from all registered meters and persists the reading to the database if no reading exists for the current hour. It is idempotent if run multiple times within the same hour: For example, at 2:05 and 2:10, but not 2:55 and 1:05. Along with the embedded river.WorkerDefaults, Work fulfills River's Worker interface.
+// Work reads usage from all registered meters and persists the reading to the database if no reading exists for the current hour. If some meters fail, the reading is recorded with the measurements of the others; the job is retried only if all meters fail. It is idempotent if run multiple times within the same hour: For example, at 2:05 and 2:10, but not 2:55 and 1:05. Along with the embedded river.WorkerDefaults, Work fulfills River's Worker interface.
 //
 // Transactional job completion example: https://riverqueue.com/docs/transactional-job-completion
 func (u *MeasureUsageWorker) Work(ctx context.Context, job *river.Job[MeasureUsageArgs]) error {
@@ -72,12 +73,18 @@ func (u *MeasureUsageWorker) Work(ctx context.Context, job *river.Job[MeasureUsa
 	u.logger.DebugContext(ctx, "measure-usage job: reading usage information")
 	// TODO: This is an expensive operation. It can be avoided if we try inserting a Reading into the database with q.CreateUniqueReading before calling Read(), but as written, the Reading is only returned when all its meters have read usage. If we upsert the Reading earlier, we can mark the job Complete early.
 	reading, err := u.rdr.Read(ctx)
-	if err != nil {
+	if errors.Is(err, reader.ErrAllMetersFailed) {
+		// Nothing was measured, so retry the job rather than recording an empty reading.
 		return err
 	}
+	// Measurements from meters that succeeded are recorded even if others failed. Failed meters are recorded in the reading's statuses, and meters that measure usage since their last successful reading catch up on the next one.
+	for _, st := range reading.Failed() {
+		u.logger.WarnContext(ctx, "measure-usage job: meter failed", "meter", st.Meter, "duration", st.Duration, "err", st.Err)
+	}
 
 	u.logger.DebugContext(ctx, "measure-usage job: recording usage reading")
 	err = recorder.RecordReading(ctx, u.logger, txquerier, reading, job.Args.Periodic)
+	recorded := err == nil
 	if err != nil && !errors.Is(err, recorder.ErrReadingExists) {
 		// If err is ErrReadingExists, a Reading was already recorded for this hour. We can continue completing the job. Other errors are unexpected and are returned.
 		return err
@@ -89,7 +96,33 @@ func (u *MeasureUsageWorker) Work(ctx context.Context, job *river.Job[MeasureUsa
 	u.logger.Info(fmt.Sprintf("measure-usage job: transitioned job from %q to %q", job.State, jobAfter.State))
 
 	err = tx.Commit(ctx)
-	return err
+	if err != nil {
+		return err
+	}
+	if recorded {
+		publishAnomalies(reading)
+	}
+	return nil
+}
+
+// Metrics of the measurement anomalies in readings, keyed by meter. They are published with [expvar], so they are served by [expvar.Handler].
+var (
+	// AnomaliesLastReading holds the [recorder.AnomalySummary] of each meter in the last reading recorded by this process.
+	AnomaliesLastReading = expvar.NewMap("measurement_anomalies_last_reading")
+	// AnomaliesTotal counts the anomalies of each meter recorded by this process.
+	AnomaliesTotal = expvar.NewMap("measurement_anomalies_total")
+	// OrphanedValueTotal sums the values of measurements of each meter that could not be attributed to a CF org.
+	OrphanedValueTotal = expvar.NewMap("measurement_orphaned_value_total")
+)
+
+// publishAnomalies updates the anomaly metrics with the anomalies in a recorded reading.
+func publishAnomalies(reading reader.Reading) {
+	AnomaliesLastReading.Init()
+	for meter, s := range recorder.SummarizeAnomalies(reading) {
+		AnomaliesLastReading.Set(meter, expvar.Func(func() any { return s }))
+		AnomaliesTotal.Add(meter, int64(s.Anomalies))
+		OrphanedValueTotal.Add(meter, s.OrphanedValue)
+	}
 }
 
 // NewMeasureUsageWorker stores dependencies required for job execution and returns a new worker.
@@ -130,7 +163,7 @@ func (u *PostUsageWorker) InsertOpts() river.InsertOpts {
 	}
 }
 
-// Work updates measurements for the month before the AsOf arg with the microcredits they consumed, then creates transactions in accounts for each customer who consumed credits. It is idempotent if run multiple times with the same AsOf arg, as long as data for that month has not changed. Along with the embedded river.WorkerDefaults, Work fulfills River's Worker interface.
+// Work updates measurements for the month before the AsOf arg with the microcredits they consumed, then creates transactions in accounts for each customer who consumed credits, and an invoice for each transaction. It is idempotent if run multiple times with the same AsOf arg: customers whose usage was already posted for the month, and not reversed, are skipped. Along with the embedded river.WorkerDefaults, Work fulfills River's Worker interface.
 //
 // Transactional job completion example: https://riverqueue.com/docs/transactional-job-completion
 func (u *PostUsageWorker) Work(ctx context.Context, job *river.Job[PostUsageArgs]) error {
@@ -141,20 +174,22 @@ func (u *PostUsageWorker) Work(ctx context.Context, job *river.Job[PostUsageArgs
 	defer tx.Rollback(ctx)
 	txquerier := u.querier.WithTx(tx)
 
-	u.logger.DebugContext(ctx, "post-usage job: updating measurement microcredits")
-
-	_, err = txquerier.UpdateMeasurementMicrocredits(ctx, job.Args.AsOf)
+	u.logger.DebugContext(ctx, "post-usage job: closing month")
+	mc, err := dbx.CloseMonth(ctx, txquerier, job.Args.AsOf)
 	if err != nil {
-		u.logger.Error("post-usage job: updating measurement microcredits", "err", err)
+		u.logger.Error("post-usage job: closing month", "err", err)
 		return err
 	}
+	u.logger.InfoContext(ctx, "post-usage job: posted usage", "period_start", mc.PeriodStart, "period_end", mc.PeriodEnd, "posted", len(mc.Pending), "skipped", len(mc.Posted))
 
-	u.logger.Debug("post-usage job: posting usage")
-	_, err = txquerier.PostUsage(ctx, job.Args.AsOf)
+	// Invoices are created with the transactions they document, so every posted month has invoices.
+	u.logger.DebugContext(ctx, "post-usage job: creating invoices")
+	invoices, err := dbx.CreateInvoices(ctx, txquerier, job.Args.AsOf)
 	if err != nil {
-		u.logger.Error("post-usage job: posting usage", "err", err)
+		u.logger.Error("post-usage job: creating invoices", "err", err)
 		return err
 	}
+	u.logger.InfoContext(ctx, "post-usage job: created invoices", "invoices", len(invoices))
 
 	jobAfter, err := river.JobCompleteTx[*riverpgxv5.Driver](ctx, tx, job)
 	if err != nil {
@@ -0,0 +1,101 @@
+// Package notify delivers alerts about customers' credit balances to the people who act on them. All notifiers must implement [Notifier].
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"time"
+)
+
+// Alert describes a customer whose credits are running low.
+type Alert struct {
+	ID           int32  `json:"id"`
+	CustomerID   string `json:"customer_id"`
+	CustomerName string `json:"customer_name"`
+	// Kind is "consumed" if PercentConsumed of the customer's funded credits have been used, or "projected_exhaustion" if the customer is projected to run out of credits before FundedUntil.
+	Kind                    string    `json:"kind"`
+	PercentConsumed         int32     `json:"percent_consumed"`
+	FundedMicrocredits      int64     `json:"funded_microcredits"`
+	RemainingMicrocredits   int64     `json:"remaining_microcredits"`
+	DailyBurnMicrocredits   int64     `json:"daily_burn_microcredits"`
+	ProjectedExhaustionDate string    `json:"projected_exhaustion_date,omitempty"`
+	FundedUntil             string    `json:"funded_until,omitempty"`
+	CreatedAt               time.Time `json:"created_at"`
+}
+
+// Subject returns a one-line summary of the alert.
+func (a Alert) Subject() string {
+	if a.Kind == "projected_exhaustion" {
+		return fmt.Sprintf("%v is projected to run out of credits on %v", a.CustomerName, a.ProjectedExhaustionDate)
+	}
+	return fmt.Sprintf("%v has used %v%% of its credits", a.CustomerName, a.PercentConsumed)
+}
+
+// Body returns a plain text description of the alert.
+func (a Alert) Body() string {
+	exhaustion := a.ProjectedExhaustionDate
+	if exhaustion == "" {
+		exhaustion = "unknown (no recent usage)"
+	}
+	funded := a.FundedUntil
+	if funded == "" {
+		funded = "no active agreement"
+	}
+	return fmt.Sprintf(
+		"%v\n\nCustomer: %v (%v)\nFunded credits: %v\nRemaining credits: %v\nAverage daily usage: %v credits\nProjected exhaustion: %v\nFunded until: %v\n",
+		a.Subject(),
+		a.CustomerName, a.CustomerID,
+		credits(a.FundedMicrocredits),
+		credits(a.RemainingMicrocredits),
+		credits(a.DailyBurnMicrocredits),
+		exhaustion,
+		funded,
+	)
+}
+
+func credits(microcredits int64) string {
+	return fmt.Sprintf("%.3f", float64(microcredits)/1e6)
+}
+
+// Notifier delivers alerts.
+type Notifier interface {
+	// Name identifies the notifier in records of which notifiers have delivered an alert, e.g. email. It must not change between restarts.
+	Name() string
+	Notify(ctx context.Context, a Alert) error
+}
+
+// Multi delivers each alert with every notifier in the slice.
+type Multi []Notifier
+
+// Notify delivers the alert with every notifier whose name is not in delivered, even if some fail. It returns the names of the notifiers that succeeded and the errors of those that failed, so callers can retry only the failed ones.
+func (m Multi) Notify(ctx context.Context, a Alert, delivered []string) (succeeded []string, err error) {
+	var errs []error
+	for _, n := range m {
+		if slices.Contains(delivered, n.Name()) {
+			continue
+		}
+		if err := n.Notify(ctx, a); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		succeeded = append(succeeded, n.Name())
+	}
+	return succeeded, errors.Join(errs...)
+}
+
+// Log writes alerts to a logger. It is used when no other notifier is configured.
+type Log struct {
+	Logger *slog.Logger
+}
+
+func (Log) Name() string {
+	return "log"
+}
+
+func (l Log) Notify(ctx context.Context, a Alert) error {
+	l.Logger.WarnContext(ctx, "notify: "+a.Subject(), "alert_id", a.ID, "customer_id", a.CustomerID, "remaining_microcredits", a.RemainingMicrocredits)
+	return nil
+}
@@ -0,0 +1,50 @@
+package notify_test
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/cloud-gov/billing/internal/notify"
+)
+
+// fakeNotifier counts deliveries and fails if err is set.
+type fakeNotifier struct {
+	name  string
+	err   error
+	calls int
+}
+
+func (f *fakeNotifier) Name() string {
+	return f.name
+}
+
+func (f *fakeNotifier) Notify(context.Context, notify.Alert) error {
+	f.calls++
+	return f.err
+}
+
+func TestMultiNotify(t *testing.T) {
+	email := &fakeNotifier{name: "email"}
+	webhook := &fakeNotifier{name: "webhook", err: errors.New("unavailable")}
+	m := notify.Multi{email, webhook}
+
+	succeeded, err := m.Notify(t.Context(), testAlert(), nil)
+	if err == nil {
+		t.Fatal("expected the webhook's error")
+	}
+	if !slices.Equal(succeeded, []string{"email"}) {
+		t.Errorf("expected only email to succeed, got %v", succeeded)
+	}
+
+	// Retrying skips notifiers that already delivered the alert.
+	webhook.err = nil
+	succeeded, err = m.Notify(t.Context(), testAlert(), succeeded)
+	if err != nil {
+		t.Fatal("retrying:", err)
+	}
+	if !slices.Equal(succeeded, []string{"webhook"}) || email.calls != 1 || webhook.calls != 2 {
+		t.Errorf("expected only the webhook to be retried, got %v with %v email and %v webhook calls", succeeded, email.calls, webhook.calls)
+	}
+}
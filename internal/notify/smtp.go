@@ -0,0 +1,121 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"mime"
+	"net"
+	"net/smtp"
+	"strings"
+	"time"
+)
+
+// DefaultTimeout limits how long notifiers wait for a mail server or webhook when no other limit is set.
+const DefaultTimeout = 30 * time.Second
+
+// SMTP sends alerts by email.
+type SMTP struct {
+	// Addr is the host and port of the mail server.
+	Addr string
+	// Auth authenticates with the mail server. It may be nil if the server does not require authentication.
+	Auth smtp.Auth
+	From string
+	To   []string
+	// Timeout limits how long sending one alert may take. If zero, [DefaultTimeout] is used.
+	Timeout time.Duration
+}
+
+func (*SMTP) Name() string {
+	return "email"
+}
+
+// Notify sends the alert to every address in To. It gives up when ctx is done or Timeout elapses.
+func (s *SMTP) Notify(ctx context.Context, a Alert) error {
+	if err := s.send(ctx, s.message(a)); err != nil {
+		return fmt.Errorf("sending alert %v by email: %w", a.ID, err)
+	}
+	return nil
+}
+
+// send is like [smtp.SendMail], but dials with ctx and stops waiting for the server when ctx is done or Timeout elapses.
+func (s *SMTP) send(ctx context.Context, msg []byte) error {
+	timeout := s.Timeout
+	if timeout == 0 {
+		timeout = DefaultTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", s.Addr)
+	if err != nil {
+		return err
+	}
+	deadline, _ := ctx.Deadline()
+	if err := conn.SetDeadline(deadline); err != nil {
+		conn.Close()
+		return err
+	}
+	stop := context.AfterFunc(ctx, func() { conn.SetDeadline(time.Now()) })
+	defer stop()
+
+	host, _, _ := net.SplitHostPort(s.Addr)
+	c, err := smtp.NewClient(conn, host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer c.Close()
+	if ok, _ := c.Extension("STARTTLS"); ok {
+		if err := c.StartTLS(&tls.Config{ServerName: host}); err != nil {
+			return err
+		}
+	}
+	if s.Auth != nil {
+		if ok, _ := c.Extension("AUTH"); ok {
+			if err := c.Auth(s.Auth); err != nil {
+				return err
+			}
+		}
+	}
+	if err := c.Mail(s.From); err != nil {
+		return err
+	}
+	for _, to := range s.To {
+		if err := c.Rcpt(to); err != nil {
+			return err
+		}
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(msg); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
+
+func (s *SMTP) message(a Alert) []byte {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "From: %v\r\n", s.From)
+	fmt.Fprintf(&b, "To: %v\r\n", strings.Join(s.To, ", "))
+	fmt.Fprintf(&b, "Subject: %v\r\n", encodeHeader(a.Subject()))
+	fmt.Fprintf(&b, "Date: %v\r\n", a.CreatedAt.Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(strings.ReplaceAll(a.Body(), "\n", "\r\n"))
+	return b.Bytes()
+}
+
+// encodeHeader returns v as a header value. Line breaks, which would end the header, are replaced with spaces, and non-ASCII text is encoded as described in RFC 2047.
+func encodeHeader(v string) string {
+	v = strings.NewReplacer("\r\n", " ", "\r", " ", "\n", " ").Replace(v)
+	return mime.QEncoding.Encode("utf-8", v)
+}
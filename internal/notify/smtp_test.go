@@ -0,0 +1,162 @@
+package notify_test
+
+import (
+	"bufio"
+	"net"
+	"net/textproto"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cloud-gov/billing/internal/notify"
+)
+
+func testAlert() notify.Alert {
+	return notify.Alert{
+		ID:                      7,
+		CustomerID:              "6f1b5c1e-3f4e-4d8e-9a51-0b8f8d7f6a01",
+		CustomerName:            "Agency A",
+		Kind:                    "consumed",
+		PercentConsumed:         80,
+		FundedMicrocredits:      100_000_000,
+		RemainingMicrocredits:   20_000_000,
+		DailyBurnMicrocredits:   1_000_000,
+		ProjectedExhaustionDate: "2025-03-21",
+		FundedUntil:             "2025-09-30",
+		CreatedAt:               time.Date(2025, time.March, 1, 10, 0, 0, 0, time.UTC),
+	}
+}
+
+// smtpStandIn accepts one SMTP session on a local port and records the envelope and message it receives.
+type smtpStandIn struct {
+	addr string
+	from string
+	to   []string
+	data string
+	done chan struct{}
+}
+
+func newSMTPStandIn(t *testing.T) *smtpStandIn {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal("listening:", err)
+	}
+	t.Cleanup(func() { l.Close() })
+	s := &smtpStandIn{addr: l.Addr().String(), done: make(chan struct{})}
+	go func() {
+		defer close(s.done)
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		s.serve(textproto.NewConn(conn))
+	}()
+	return s
+}
+
+func (s *smtpStandIn) serve(c *textproto.Conn) {
+	c.PrintfLine("220 localhost stand-in")
+	for {
+		line, err := c.ReadLine()
+		if err != nil {
+			return
+		}
+		verb, arg, _ := strings.Cut(line, " ")
+		switch strings.ToUpper(verb) {
+		case "EHLO", "HELO":
+			c.PrintfLine("250 localhost")
+		case "MAIL":
+			s.from = strings.Trim(strings.TrimPrefix(arg, "FROM:"), "<>")
+			c.PrintfLine("250 OK")
+		case "RCPT":
+			s.to = append(s.to, strings.Trim(strings.TrimPrefix(arg, "TO:"), "<>"))
+			c.PrintfLine("250 OK")
+		case "DATA":
+			c.PrintfLine("354 go ahead")
+			b, _ := bufio.NewReader(c.DotReader()).ReadString(0)
+			s.data = b
+			c.PrintfLine("250 OK")
+		case "QUIT":
+			c.PrintfLine("221 bye")
+			return
+		default:
+			c.PrintfLine("250 OK")
+		}
+	}
+}
+
+func TestSMTPNotify(t *testing.T) {
+	srv := newSMTPStandIn(t)
+	n := &notify.SMTP{
+		Addr: srv.addr,
+		From: "billing@example.gov",
+		To:   []string{"ops@example.gov", "finance@example.gov"},
+	}
+	a := testAlert()
+	if err := n.Notify(t.Context(), a); err != nil {
+		t.Fatal("notifying:", err)
+	}
+	<-srv.done
+
+	if srv.from != n.From {
+		t.Errorf("expected sender %q, got %q", n.From, srv.from)
+	}
+	if strings.Join(srv.to, ",") != strings.Join(n.To, ",") {
+		t.Errorf("expected recipients %v, got %v", n.To, srv.to)
+	}
+	for _, want := range []string{
+		"Subject: Agency A has used 80% of its credits",
+		"Remaining credits: 20.000",
+		"Projected exhaustion: 2025-03-21",
+	} {
+		if !strings.Contains(srv.data, want) {
+			t.Errorf("expected message to contain %q, got:\n%v", want, srv.data)
+		}
+	}
+}
+
+func TestSMTPNotifyEncodesSubject(t *testing.T) {
+	srv := newSMTPStandIn(t)
+	n := &notify.SMTP{Addr: srv.addr, From: "billing@example.gov", To: []string{"ops@example.gov"}}
+	a := testAlert()
+	a.CustomerName = "Agência A\r\nBcc: attacker@example.com"
+	if err := n.Notify(t.Context(), a); err != nil {
+		t.Fatal("notifying:", err)
+	}
+	<-srv.done
+
+	header, _, _ := strings.Cut(srv.data, "\n\n")
+	if strings.Contains(header, "\nBcc:") {
+		t.Errorf("expected line breaks in the subject to be removed, got:\n%v", header)
+	}
+	if !strings.Contains(header, "Subject: =?utf-8?q?") {
+		t.Errorf("expected an encoded subject, got:\n%v", srv.data)
+	}
+}
+
+func TestSMTPNotifyTimeout(t *testing.T) {
+	// The server accepts the connection but never greets the client.
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal("listening:", err)
+	}
+	defer l.Close()
+	go func() {
+		conn, err := l.Accept()
+		if err == nil {
+			defer conn.Close()
+			time.Sleep(time.Second)
+		}
+	}()
+
+	n := &notify.SMTP{Addr: l.Addr().String(), From: "billing@example.gov", To: []string{"ops@example.gov"}, Timeout: 50 * time.Millisecond}
+	start := time.Now()
+	if err := n.Notify(t.Context(), testAlert()); err == nil {
+		t.Fatal("expected an error from a server that does not respond")
+	}
+	if d := time.Since(start); d > 500*time.Millisecond {
+		t.Errorf("expected Notify to give up after its timeout, took %v", d)
+	}
+}
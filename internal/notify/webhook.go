@@ -0,0 +1,50 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// defaultClient is used by webhooks that do not set a client. Unlike [http.DefaultClient], it does not wait forever for a response.
+var defaultClient = &http.Client{Timeout: DefaultTimeout}
+
+// Webhook posts alerts as JSON to a URL.
+type Webhook struct {
+	URL string
+	// Client sends the requests. If nil, a client with [DefaultTimeout] is used.
+	Client *http.Client
+}
+
+func (*Webhook) Name() string {
+	return "webhook"
+}
+
+// Notify posts the alert to the webhook URL. Any response status other than 2xx is an error.
+func (w *Webhook) Notify(ctx context.Context, a Alert) error {
+	body, err := json.Marshal(a)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	client := w.Client
+	if client == nil {
+		client = defaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("posting alert %v to webhook: %w", a.ID, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("posting alert %v to webhook: unexpected status %v", a.ID, resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,45 @@
+package notify_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/cloud-gov/billing/internal/notify"
+)
+
+func TestWebhookNotify(t *testing.T) {
+	var got notify.Alert
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if ct := r.Header.Get("Content-Type"); ct != "application/json" {
+			t.Errorf("expected JSON content type, got %q", ct)
+		}
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Errorf("decoding request: %v", err)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	want := testAlert()
+	n := &notify.Webhook{URL: srv.URL, Client: srv.Client()}
+	if err := n.Notify(t.Context(), want); err != nil {
+		t.Fatal("notifying:", err)
+	}
+	if got != want {
+		t.Fatalf("expected %+v, got %+v", want, got)
+	}
+}
+
+func TestWebhookNotifyErrorStatus(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	n := &notify.Webhook{URL: srv.URL, Client: srv.Client()}
+	if err := n.Notify(t.Context(), testAlert()); err == nil {
+		t.Fatal("expected an error for a 503 response")
+	}
+}
@@ -0,0 +1,186 @@
+package meter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"slices"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/cloud-gov/billing/internal/usage/node"
+	"github.com/cloud-gov/billing/internal/usage/reader"
+)
+
+// AWSCURMeterName is the name of [AWSCURMeter]. The natural IDs of its resource kinds are AWS product codes, like AmazonRDS.
+const AWSCURMeterName = "awscur"
+
+// DefaultCURDelay is how long AWS usually takes to include usage in a Cost and Usage Report.
+const DefaultCURDelay = 48 * time.Hour
+
+var ErrCostOverflow = errors.New("AWS CUR meter: cost is too large to record; the value was truncated")
+
+type AWSCURMeterDB interface {
+	TaskMeterDB
+}
+
+// AWSCURMeter reads the cost of AWS resources from Cost and Usage Reports (CUR). It is used for resources that brokers create in AWS on behalf of Cloud Foundry users, like RDS databases, S3 buckets, and Elasticsearch domains, whose costs we pass through to customers.
+//
+// Resources are assigned to customers by a tag whose value is the GUID of the CF org the resource belongs to. Brokers tag resources with the org GUID when creating them. Untagged resources are not measured. Resources are reported under their org's resource node, so their usage appears with the org's CF usage, and measurements of resources in orgs that are not in the database have [ErrOrgNotFound].
+//
+// Each reading measures the unblended cost, in millionths of a dollar, of line items whose usage started between the previous reading and this one. Because reports lag behind usage, the period is shifted back by a delay, e.g. two days. Line items that are added or changed after the delay, like month-end credits, are not measured.
+type AWSCURMeter struct {
+	logger *slog.Logger
+	source CURSource
+	dbq    AWSCURMeterDB
+	orgTag string
+	delay  time.Duration
+}
+
+// NewAWSCURMeter returns a meter that reads reports from source. orgTag is the key of the tag whose value is a CF org GUID, e.g. "Organization GUID".
+func NewAWSCURMeter(
+	logger *slog.Logger, source CURSource, dbq AWSCURMeterDB, orgTag string, delay time.Duration,
+) *AWSCURMeter {
+	return &AWSCURMeter{
+		logger: logger.WithGroup("AWSCURMeter"),
+		source: source,
+		dbq:    dbq,
+		// Tag columns are named after user-defined tags with a user: prefix.
+		orgTag: curColumnName("user:" + orgTag),
+		delay:  delay,
+	}
+}
+
+func (m *AWSCURMeter) Name() string {
+	return AWSCURMeterName
+}
+
+// AWSProductNodeID returns the natural ID of the node under which the resources of an AWS product in a CF org are reported.
+func AWSProductNodeID(orgGUID, productCode string) string {
+	return orgGUID + ":" + productCode
+}
+
+// awsResource is the cost of an AWS resource during a reading's period.
+type awsResource struct {
+	id           string
+	productCode  string
+	orgGUID      string
+	microdollars float64
+}
+
+// ReadUsage returns the cost of AWS resources whose usage started in the period covered by the reading.
+func (m *AWSCURMeter) ReadUsage(ctx context.Context) ([]reader.Measurement, []*node.Node, error) {
+	now := time.Now().UTC()
+	since, err := sinceLastReading(ctx, m.dbq, m.Name(), now)
+	if err != nil {
+		return nil, nil, fmt.Errorf("ReadUsage: %w", err)
+	}
+	from, to := since.Add(-m.delay), now.Add(-m.delay)
+
+	m.logger.DebugContext(ctx, "AWS CUR meter: listing report files")
+	names, err := m.source.ListCURFiles(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("ReadUsage: %w", err)
+	}
+
+	resources := map[string]*awsResource{}
+	untagged := 0
+	for _, name := range names {
+		if start, end, ok := curFilePeriod(name); ok && (!start.Before(to) || !end.After(from)) {
+			continue
+		}
+		m.logger.DebugContext(ctx, "AWS CUR meter: reading report file", "name", name)
+		f, err := m.source.OpenCURFile(ctx, name)
+		if err != nil {
+			return nil, nil, fmt.Errorf("ReadUsage: opening %v: %w", name, err)
+		}
+		err = readCURFile(name, f, func(item curLineItem) error {
+			if item.ResourceID == "" || item.UsageStart.Before(from) || !item.UsageStart.Before(to) {
+				return nil
+			}
+			org := item.Tags[m.orgTag]
+			// Tags can be set by anyone with access to the resource, so the value may not be a GUID.
+			var orgID pgtype.UUID
+			if err := orgID.Scan(org); err != nil || !orgID.Valid {
+				untagged++
+				return nil
+			}
+			r, ok := resources[item.ResourceID]
+			if !ok {
+				r = &awsResource{id: item.ResourceID, productCode: item.ProductCode, orgGUID: org}
+				resources[item.ResourceID] = r
+			}
+			r.microdollars += item.Cost * 1e6
+			return nil
+		})
+		f.Close()
+		if err != nil {
+			return nil, nil, fmt.Errorf("ReadUsage: reading %v: %w", name, err)
+		}
+	}
+	if untagged > 0 {
+		m.logger.WarnContext(ctx, "AWS CUR meter: skipped line items of resources without a CF org tag", "count", untagged, "tag", m.orgTag)
+	}
+
+	measurements := []reader.Measurement{}
+	nodes := []*node.Node{}
+
+	// Sort so nodes and measurements are returned in a stable order.
+	ids := make([]string, 0, len(resources))
+	for id := range resources {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+
+	for _, id := range ids {
+		r := resources[id]
+		customerID, cfOrgNode, found, err := orgNode(ctx, m.dbq, r.orgGUID)
+		if err != nil {
+			return nil, nil, fmt.Errorf("ReadUsage: %w", err)
+		}
+
+		msrmt := reader.Measurement{
+			CustomerID:            customerID,
+			OrgID:                 r.orgGUID,
+			Meter:                 m.Name(),
+			ResourceKindNaturalID: r.productCode,
+			ResourceNaturalID:     r.id,
+			Value:                 int(math.Round(r.microdollars)),
+		}
+		if !found {
+			msrmt.Errs = errors.Join(msrmt.Errs, ErrOrgNotFound)
+		}
+		// Measurement values are stored as 32-bit integers, or about $2,147 per resource per reading.
+		if msrmt.Value > math.MaxInt32 {
+			msrmt.Value = math.MaxInt32
+			msrmt.Errs = errors.Join(msrmt.Errs, ErrCostOverflow)
+		}
+
+		// Resources are grouped by product under their org, so AWS usage is reported with the org's CF usage.
+		productNode, err := node.New(
+			customerID,
+			AWSProductNodeID(r.orgGUID, r.productCode),
+			node.WithSlugAuto("aws", r.productCode),
+			node.WithPathByParent(cfOrgNode),
+		)
+		if err != nil {
+			return nil, nil, fmt.Errorf("ReadUsage: creating product node: %w", err)
+		}
+		n, err := node.New(
+			customerID,
+			r.id,
+			node.WithSlugAuto("aws", r.id),
+			node.WithPathByParent(productNode),
+		)
+		if err != nil {
+			return nil, nil, fmt.Errorf("ReadUsage: creating resource node: %w", err)
+		}
+		nodes = append(nodes, cfOrgNode, productNode, n)
+		measurements = append(measurements, msrmt)
+	}
+
+	return measurements, nodes, nil
+}
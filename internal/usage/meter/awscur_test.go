@@ -0,0 +1,268 @@
+package meter_test
+
+import (
+	"compress/gzip"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/parquet-go/parquet-go"
+
+	"github.com/cloud-gov/billing/internal/db"
+	"github.com/cloud-gov/billing/internal/usage/meter"
+)
+
+const (
+	curOrg   = "20000000-0000-0000-0000-000000000002"
+	curDelay = 48 * time.Hour
+)
+
+// curItem is a line item written to test report files.
+type curItem struct {
+	start    time.Time
+	product  string
+	resource string
+	cost     float64
+	org      string
+}
+
+// writeLegacyCSV writes a report in the legacy CUR format, with one column per tag. If gz is true, the file is compressed.
+func writeLegacyCSV(t *testing.T, path string, gz bool, items []curItem) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	var w *csv.Writer
+	if gz {
+		zw := gzip.NewWriter(f)
+		defer zw.Close()
+		w = csv.NewWriter(zw)
+	} else {
+		w = csv.NewWriter(f)
+	}
+	w.Write([]string{"identity/LineItemId", "lineItem/UsageStartDate", "lineItem/ProductCode", "lineItem/ResourceId", "lineItem/UnblendedCost", "resourceTags/user:Organization GUID"})
+	for i, it := range items {
+		w.Write([]string{strconv.Itoa(i), it.start.Format("2006-01-02T15:04Z"), it.product, it.resource, strconv.FormatFloat(it.cost, 'f', -1, 64), it.org})
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// writeCUR2CSV writes a report in the CUR 2.0 format, with all tags in one JSON column.
+func writeCUR2CSV(t *testing.T, path string, items []curItem) {
+	t.Helper()
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	w := csv.NewWriter(f)
+	w.Write([]string{"line_item_usage_start_date", "line_item_product_code", "line_item_resource_id", "line_item_unblended_cost", "resource_tags"})
+	for _, it := range items {
+		tags := map[string]string{"user_environment": "production"}
+		if it.org != "" {
+			tags["user_organization_guid"] = it.org
+		}
+		b, _ := json.Marshal(tags)
+		w.Write([]string{it.start.Format(time.RFC3339), it.product, it.resource, strconv.FormatFloat(it.cost, 'f', -1, 64), string(b)})
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// cur2Row is a line item in a CUR 2.0 Parquet report.
+type cur2Row struct {
+	LineItemUsageStartDate time.Time         `parquet:"line_item_usage_start_date,timestamp(millisecond)"`
+	LineItemProductCode    string            `parquet:"line_item_product_code"`
+	LineItemResourceID     string            `parquet:"line_item_resource_id"`
+	LineItemUnblendedCost  float64           `parquet:"line_item_unblended_cost"`
+	ResourceTags           map[string]string `parquet:"resource_tags"`
+}
+
+func writeCUR2Parquet(t *testing.T, path string, items []curItem) {
+	t.Helper()
+	rows := []cur2Row{}
+	for _, it := range items {
+		tags := map[string]string{}
+		if it.org != "" {
+			tags["user_organization_guid"] = it.org
+		}
+		rows = append(rows, cur2Row{it.start, it.product, it.resource, it.cost, tags})
+	}
+	if err := parquet.WriteFile(path, rows); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func readCURMeter(t *testing.T, dir string, since time.Time) map[string]int {
+	t.Helper()
+	dbq := &StubDbQ{
+		LatestReading: pgtype.Timestamptz{Time: since, Valid: true},
+		Org:           db.CFOrg{CustomerID: pgtype.UUID{Bytes: [16]byte{1}, Valid: true}, Name: pgtype.Text{String: "org-1", Valid: true}},
+	}
+	sut := meter.NewAWSCURMeter(slog.Default(), meter.DirCURSource{Dir: dir}, dbq, "Organization GUID", curDelay)
+	ms, nodes, err := sut.ReadUsage(t.Context())
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	// Each resource has a node under a node for its product, under its org's node.
+	if len(nodes) != 3*len(ms) {
+		t.Fatalf("expected 3 nodes per measurement, got %v measurements and %v nodes", len(ms), len(nodes))
+	}
+	got := map[string]int{}
+	for i, m := range ms {
+		if m.Meter != meter.AWSCURMeterName || m.OrgID != curOrg || m.Errs != nil {
+			t.Errorf("unexpected measurement %+v", m)
+		}
+		n := nodes[3*i+2]
+		if want := "apps.usage.cforg_org_1.aws_" + m.ResourceKindNaturalID + "."; n.ResourceNaturalID != m.ResourceNaturalID || !strings.HasPrefix(n.Path, want) {
+			t.Errorf("expected node of %v under %q, got %q", m.ResourceNaturalID, want, n.Path)
+		}
+		got[m.ResourceNaturalID] = m.Value
+	}
+	return got
+}
+
+func TestAWSCURMeter_Formats(t *testing.T) {
+	since := time.Now().UTC().Add(-time.Hour).Truncate(time.Minute)
+	in := since.Add(-curDelay).Add(10 * time.Minute)
+	items := []curItem{
+		{in, "AmazonRDS", "arn:aws:rds:us-gov-west-1:123:db:cg-aws-broker-1", 0.5, curOrg},
+		{in.Add(time.Minute), "AmazonRDS", "arn:aws:rds:us-gov-west-1:123:db:cg-aws-broker-1", 0.25, curOrg},
+		{in, "AmazonS3", "cg-bucket-1", 0.000001, curOrg},
+		// Line items without a resource or org, or outside the reading's period, are not measured.
+		{in, "AmazonEC2", "", 1, curOrg},
+		{in, "AmazonES", "arn:aws:es:us-gov-west-1:123:domain/untagged", 1, ""},
+		{in, "AmazonES", "arn:aws:es:us-gov-west-1:123:domain/bad-tag", 1, "not-a-guid"},
+		{since, "AmazonRDS", "arn:aws:rds:us-gov-west-1:123:db:too-new", 1, curOrg},
+		{in.Add(-time.Hour), "AmazonRDS", "arn:aws:rds:us-gov-west-1:123:db:too-old", 1, curOrg},
+	}
+	want := map[string]int{
+		"arn:aws:rds:us-gov-west-1:123:db:cg-aws-broker-1": 750000,
+		"cg-bucket-1": 1,
+	}
+
+	cases := map[string]func(t *testing.T, dir string){
+		"legacy CSV": func(t *testing.T, dir string) {
+			writeLegacyCSV(t, filepath.Join(dir, "report.csv"), false, items)
+		},
+		"legacy gzipped CSV": func(t *testing.T, dir string) {
+			writeLegacyCSV(t, filepath.Join(dir, "report", "report-00001.csv.gz"), true, items)
+		},
+		"CUR 2.0 CSV": func(t *testing.T, dir string) {
+			writeCUR2CSV(t, filepath.Join(dir, "report.csv"), items)
+		},
+		"CUR 2.0 Parquet": func(t *testing.T, dir string) {
+			writeCUR2Parquet(t, filepath.Join(dir, "report.parquet"), items)
+		},
+	}
+	for name, write := range cases {
+		t.Run(name, func(t *testing.T) {
+			dir := t.TempDir()
+			write(t, dir)
+			// Files that are not reports are ignored.
+			if err := os.WriteFile(filepath.Join(dir, "manifest.json"), []byte("{}"), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			if got := readCURMeter(t, dir, since); !reflect.DeepEqual(got, want) {
+				t.Errorf("expected %v, got %v", want, got)
+			}
+		})
+	}
+}
+
+func TestAWSCURMeter_BillingPeriods(t *testing.T) {
+	since := time.Now().UTC().Add(-time.Hour).Truncate(time.Minute)
+	in := since.Add(-curDelay).Add(10 * time.Minute)
+	dir := t.TempDir()
+	items := []curItem{{in, "AmazonS3", "cg-bucket-1", 1, curOrg}}
+
+	// Files for billing periods that do not overlap the reading are not read, even if they contain matching line items.
+	writeLegacyCSV(t, filepath.Join(dir, "20200101-20200201", "report.csv"), false, items)
+	if err := os.MkdirAll(filepath.Join(dir, "data", "BILLING_PERIOD=2020-01"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	writeCUR2CSV(t, filepath.Join(dir, "data", "BILLING_PERIOD=2020-01", "report.csv"), items)
+	if got := readCURMeter(t, dir, since); len(got) != 0 {
+		t.Errorf("expected no measurements, got %v", got)
+	}
+
+	period := filepath.Join(dir, "data", "BILLING_PERIOD="+in.Format("2006-01"))
+	if err := os.MkdirAll(period, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	writeCUR2CSV(t, filepath.Join(period, "report.csv"), items)
+	if got := readCURMeter(t, dir, since); got["cg-bucket-1"] != 1000000 {
+		t.Errorf("expected the current period to be measured, got %v", got)
+	}
+}
+
+func TestAWSCURMeter_Overflow(t *testing.T) {
+	since := time.Now().UTC().Add(-time.Hour).Truncate(time.Minute)
+	dir := t.TempDir()
+	writeCUR2CSV(t, filepath.Join(dir, "report.csv"), []curItem{
+		{since.Add(-curDelay), "AmazonRDS", "db-1", 5000, curOrg},
+	})
+	sut := meter.NewAWSCURMeter(slog.Default(), meter.DirCURSource{Dir: dir}, &StubDbQ{
+		LatestReading: pgtype.Timestamptz{Time: since, Valid: true},
+	}, "Organization GUID", curDelay)
+	ms, _, err := sut.ReadUsage(t.Context())
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	if len(ms) != 1 || !errors.Is(ms[0].Errs, meter.ErrCostOverflow) {
+		t.Errorf("expected a measurement with %v, got %+v", meter.ErrCostOverflow, ms)
+	}
+}
+
+func TestAWSCURMeter_UnknownOrg(t *testing.T) {
+	since := time.Now().UTC().Add(-time.Hour).Truncate(time.Minute)
+	dir := t.TempDir()
+	writeCUR2CSV(t, filepath.Join(dir, "report.csv"), []curItem{
+		{since.Add(-curDelay), "AmazonRDS", "db-1", 1, curOrg},
+	})
+	sut := meter.NewAWSCURMeter(slog.Default(), meter.DirCURSource{Dir: dir}, &StubDbQ{
+		LatestReading: pgtype.Timestamptz{Time: since, Valid: true},
+		OrgError:      pgx.ErrNoRows,
+	}, "Organization GUID", curDelay)
+	ms, nodes, err := sut.ReadUsage(t.Context())
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	if len(ms) != 1 || ms[0].OrgID != curOrg || !errors.Is(ms[0].Errs, meter.ErrOrgNotFound) {
+		t.Errorf("expected a measurement with %v, got %+v", meter.ErrOrgNotFound, ms)
+	}
+	if len(nodes) == 0 || nodes[0].ResourceNaturalID != curOrg {
+		t.Errorf("expected nodes under the org's node, got %+v", nodes)
+	}
+}
+
+func TestAWSCURMeter_BadFile(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "report.parquet"), []byte("not parquet"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	sut := meter.NewAWSCURMeter(slog.Default(), meter.DirCURSource{Dir: dir}, &StubDbQ{}, "Organization GUID", curDelay)
+	if _, _, err := sut.ReadUsage(t.Context()); err == nil {
+		t.Error("expected an error reading an invalid report file")
+	}
+}
@@ -0,0 +1,394 @@
+package meter
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/parquet-go/parquet-go"
+)
+
+// CURSource lists and opens AWS Cost and Usage Report (CUR) files. Files may be CSV, gzipped CSV, or Parquet, and may be in the legacy CUR format or the CUR 2.0 format of AWS Data Exports.
+//
+// The source should only contain the latest version of each report, e.g. by configuring the report to overwrite previous versions. Otherwise, line items in older versions are counted more than once.
+type CURSource interface {
+	// ListCURFiles returns the names of all report files in the source.
+	ListCURFiles(ctx context.Context) ([]string, error)
+	OpenCURFile(ctx context.Context, name string) (CURFile, error)
+}
+
+// CURFile is a report file opened from a [CURSource]. Parquet files are read with ReadAt, so the whole file does not need to be downloaded at once.
+type CURFile interface {
+	io.Reader
+	io.ReaderAt
+	io.Closer
+	Size() int64
+}
+
+var ErrCURFormat = errors.New("unsupported CUR file format")
+
+// DirCURSource reads report files from a local directory and its subdirectories.
+type DirCURSource struct {
+	Dir string
+}
+
+func (s DirCURSource) ListCURFiles(ctx context.Context) ([]string, error) {
+	names := []string{}
+	err := filepath.WalkDir(s.Dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() && isCURFile(path) {
+			rel, err := filepath.Rel(s.Dir, path)
+			if err != nil {
+				return err
+			}
+			names = append(names, filepath.ToSlash(rel))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("listing CUR files in %v: %w", s.Dir, err)
+	}
+	return names, nil
+}
+
+func (s DirCURSource) OpenCURFile(ctx context.Context, name string) (CURFile, error) {
+	f, err := os.Open(filepath.Join(s.Dir, filepath.FromSlash(name)))
+	if err != nil {
+		return nil, err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return &sizedFile{File: f, size: info.Size()}, nil
+}
+
+type sizedFile struct {
+	*os.File
+	size int64
+}
+
+func (f *sizedFile) Size() int64 { return f.size }
+
+// S3CURSource reads report files from an S3 bucket, or from any S3-compatible object store, like MinIO.
+type S3CURSource struct {
+	client *minio.Client
+	bucket string
+	prefix string
+}
+
+// NewS3CURSource returns a source that reads report files in bucket whose keys start with prefix.
+func NewS3CURSource(client *minio.Client, bucket, prefix string) *S3CURSource {
+	return &S3CURSource{client: client, bucket: bucket, prefix: prefix}
+}
+
+func (s *S3CURSource) ListCURFiles(ctx context.Context) ([]string, error) {
+	names := []string{}
+	for obj := range s.client.ListObjects(ctx, s.bucket, minio.ListObjectsOptions{Prefix: s.prefix, Recursive: true}) {
+		if obj.Err != nil {
+			return nil, fmt.Errorf("listing CUR files in bucket %v: %w", s.bucket, obj.Err)
+		}
+		if isCURFile(obj.Key) {
+			names = append(names, obj.Key)
+		}
+	}
+	return names, nil
+}
+
+func (s *S3CURSource) OpenCURFile(ctx context.Context, name string) (CURFile, error) {
+	obj, err := s.client.GetObject(ctx, s.bucket, name, minio.GetObjectOptions{})
+	if err != nil {
+		return nil, err
+	}
+	info, err := obj.Stat()
+	if err != nil {
+		obj.Close()
+		return nil, err
+	}
+	return &sizedObject{Object: obj, size: info.Size}, nil
+}
+
+type sizedObject struct {
+	*minio.Object
+	size int64
+}
+
+func (o *sizedObject) Size() int64 { return o.size }
+
+func isCURFile(name string) bool {
+	return strings.HasSuffix(name, ".csv") || strings.HasSuffix(name, ".csv.gz") || strings.HasSuffix(name, ".parquet")
+}
+
+var (
+	// CUR 2.0 exports are partitioned by billing period, e.g. data/BILLING_PERIOD=2025-01/.
+	curPeriodExpr = regexp.MustCompile(`BILLING_PERIOD=(\d{4}-\d{2})`)
+	// Legacy reports are stored under the billing period's date range, e.g. 20250101-20250201/.
+	curRangeExpr = regexp.MustCompile(`(\d{8})-(\d{8})`)
+)
+
+// curFilePeriod returns the billing period of a report file, if its name contains one.
+func curFilePeriod(name string) (start, end time.Time, ok bool) {
+	if m := curPeriodExpr.FindStringSubmatch(name); m != nil {
+		start, err := time.Parse("2006-01", m[1])
+		if err == nil {
+			return start, start.AddDate(0, 1, 0), true
+		}
+	}
+	if m := curRangeExpr.FindStringSubmatch(name); m != nil {
+		start, err1 := time.Parse("20060102", m[1])
+		end, err2 := time.Parse("20060102", m[2])
+		if err1 == nil && err2 == nil {
+			return start, end, true
+		}
+	}
+	return time.Time{}, time.Time{}, false
+}
+
+// curLineItem is the subset of a CUR line item used by [AWSCURMeter].
+type curLineItem struct {
+	UsageStart  time.Time
+	ProductCode string
+	ResourceID  string
+	// Cost is the unblended cost in USD.
+	Cost float64
+	// Tags are the resource's tags, keyed by normalized column name, e.g. user_organization_guid.
+	Tags map[string]string
+}
+
+// Normalized names of the CUR columns used by [AWSCURMeter].
+const (
+	curColUsageStart  = "line_item_usage_start_date"
+	curColProductCode = "line_item_product_code"
+	curColResourceID  = "line_item_resource_id"
+	curColCost        = "line_item_unblended_cost"
+	curColTags        = "resource_tags"
+)
+
+// curColumnName normalizes a CUR column name so the legacy and CUR 2.0 formats can be read the same way. For example, lineItem/UsageStartDate and line_item_usage_start_date both become line_item_usage_start_date, and resourceTags/user:Organization GUID becomes resource_tags_user_organization_guid.
+func curColumnName(s string) string {
+	b := strings.Builder{}
+	// prev is the previous rune of s; written is the previous rune written to b.
+	prev, written := '_', '_'
+	for _, r := range s {
+		out := r
+		switch {
+		case unicode.IsUpper(r):
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) {
+				b.WriteRune('_')
+			}
+			out = unicode.ToLower(r)
+		case unicode.IsLower(r), unicode.IsDigit(r):
+		default:
+			out = '_'
+		}
+		prev = r
+		if out == '_' && written == '_' {
+			continue
+		}
+		b.WriteRune(out)
+		written = out
+	}
+	return strings.TrimSuffix(b.String(), "_")
+}
+
+// readCURFile calls fn for each line item in the report file.
+func readCURFile(name string, f CURFile, fn func(curLineItem) error) error {
+	switch {
+	case strings.HasSuffix(name, ".parquet"):
+		return readCURParquet(f, fn)
+	case strings.HasSuffix(name, ".csv.gz"):
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			return err
+		}
+		defer gz.Close()
+		return readCURCSV(gz, fn)
+	case strings.HasSuffix(name, ".csv"):
+		return readCURCSV(f, fn)
+	default:
+		return fmt.Errorf("%w: %v", ErrCURFormat, name)
+	}
+}
+
+func readCURCSV(r io.Reader, fn func(curLineItem) error) error {
+	cr := csv.NewReader(r)
+	cr.ReuseRecord = true
+	header, err := cr.Read()
+	if err != nil {
+		return fmt.Errorf("reading CSV header: %w", err)
+	}
+	cols := make([]string, len(header))
+	for i, h := range header {
+		cols[i] = curColumnName(h)
+	}
+
+	fields := map[string]string{}
+	for {
+		record, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("reading CSV: %w", err)
+		}
+		clear(fields)
+		for i, v := range record {
+			fields[cols[i]] = v
+		}
+		// CUR 2.0 stores all tags in one column as a JSON object.
+		if tags := fields[curColTags]; tags != "" {
+			m := map[string]string{}
+			if err := json.Unmarshal([]byte(tags), &m); err != nil {
+				return fmt.Errorf("reading resource tags: %w", err)
+			}
+			for k, v := range m {
+				fields[curColTags+"_"+curColumnName(k)] = v
+			}
+		}
+		item, err := curLineItemFromFields(fields)
+		if err != nil {
+			return err
+		}
+		if err := fn(item); err != nil {
+			return err
+		}
+	}
+}
+
+func readCURParquet(f CURFile, fn func(curLineItem) error) error {
+	pf, err := parquet.OpenFile(f, f.Size())
+	if err != nil {
+		return fmt.Errorf("opening Parquet file: %w", err)
+	}
+	schema := pf.Schema()
+	paths := schema.Columns()
+	cols := make([]string, len(paths))
+	timestamps := make([]*time.Duration, len(paths))
+	tagKey, tagValue := -1, -1
+	for i, path := range paths {
+		if len(path) > 1 && curColumnName(path[0]) == curColTags {
+			// CUR 2.0 stores all tags in one column as a map, whose keys and values are stored as repeated leaf columns.
+			switch path[len(path)-1] {
+			case "key":
+				tagKey = i
+			case "value":
+				tagValue = i
+			}
+			continue
+		}
+		cols[i] = curColumnName(strings.Join(path, "_"))
+		if leaf, ok := schema.Lookup(path...); ok {
+			if lt := leaf.Node.Type().LogicalType(); lt != nil && lt.Timestamp != nil {
+				unit := time.Millisecond
+				switch {
+				case lt.Timestamp.Unit.Micros != nil:
+					unit = time.Microsecond
+				case lt.Timestamp.Unit.Nanos != nil:
+					unit = time.Nanosecond
+				}
+				timestamps[i] = &unit
+			}
+		}
+	}
+
+	fields := map[string]string{}
+	rows := make([]parquet.Row, 128)
+	for _, rg := range pf.RowGroups() {
+		rr := rg.Rows()
+		for {
+			n, readErr := rr.ReadRows(rows)
+			for _, row := range rows[:n] {
+				clear(fields)
+				keys, values := []string{}, []string{}
+				for _, v := range row {
+					c := v.Column()
+					switch {
+					case v.IsNull():
+					case c == tagKey:
+						keys = append(keys, string(v.ByteArray()))
+					case c == tagValue:
+						values = append(values, string(v.ByteArray()))
+					case timestamps[c] != nil:
+						fields[cols[c]] = time.Unix(0, v.Int64()*int64(*timestamps[c])).UTC().Format(time.RFC3339)
+					case v.Kind() == parquet.ByteArray:
+						fields[cols[c]] = string(v.ByteArray())
+					case v.Kind() == parquet.Double:
+						fields[cols[c]] = strconv.FormatFloat(v.Double(), 'f', -1, 64)
+					case v.Kind() == parquet.Float:
+						fields[cols[c]] = strconv.FormatFloat(float64(v.Float()), 'f', -1, 32)
+					}
+				}
+				for i := range min(len(keys), len(values)) {
+					fields[curColTags+"_"+curColumnName(keys[i])] = values[i]
+				}
+				item, err := curLineItemFromFields(fields)
+				if err != nil {
+					rr.Close()
+					return err
+				}
+				if err := fn(item); err != nil {
+					rr.Close()
+					return err
+				}
+			}
+			if errors.Is(readErr, io.EOF) {
+				break
+			}
+			if readErr != nil {
+				rr.Close()
+				return fmt.Errorf("reading Parquet rows: %w", readErr)
+			}
+		}
+		if err := rr.Close(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func curLineItemFromFields(fields map[string]string) (curLineItem, error) {
+	item := curLineItem{
+		ProductCode: fields[curColProductCode],
+		ResourceID:  fields[curColResourceID],
+		Tags:        map[string]string{},
+	}
+	var err error
+	if s := fields[curColUsageStart]; s != "" {
+		item.UsageStart, err = time.Parse(time.RFC3339, s)
+		if err != nil {
+			// Legacy CSV reports omit seconds, e.g. 2025-01-01T00:00Z.
+			item.UsageStart, err = time.Parse("2006-01-02T15:04Z07:00", s)
+		}
+		if err != nil {
+			return item, fmt.Errorf("parsing usage start date %q: %w", s, err)
+		}
+	}
+	if s := fields[curColCost]; s != "" {
+		item.Cost, err = strconv.ParseFloat(s, 64)
+		if err != nil {
+			return item, fmt.Errorf("parsing cost %q: %w", s, err)
+		}
+	}
+	for k, v := range fields {
+		if tag, ok := strings.CutPrefix(k, curColTags+"_"); ok && v != "" {
+			item.Tags[tag] = v
+		}
+	}
+	return item, nil
+}
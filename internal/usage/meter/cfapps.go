@@ -6,6 +6,8 @@ import (
 	"fmt"
 	"log/slog"
 	"slices"
+	"strconv"
+	"strings"
 
 	"github.com/cloudfoundry/go-cfclient/v3/client"
 	"github.com/cloudfoundry/go-cfclient/v3/resource"
@@ -20,11 +22,55 @@ import (
 
 const appStateStarted = "STARTED"
 
+// AppMeterName is the name of [CFAppMeter].
+const AppMeterName = "cfapps"
+
+// Resource kinds measured by [CFAppMeter]. Each instance of a process of a started app has one resource of each kind, identified by [InstanceResourceID]. All of them accrue: they are priced per unit-hour for the time between readings.
+const (
+	// AppMemoryKind is memory allocated to a process instance, in MB.
+	AppMemoryKind = "memory"
+	// AppDiskKind is disk allocated to a process instance, in MB.
+	AppDiskKind = "disk"
+	// AppLogRateKind is the log rate limit of a process instance, in KiB per second. Processes with unlimited log rates are not measured.
+	AppLogRateKind = "log-rate"
+)
+
+// InstanceID returns the natural ID of the instance of the process with the given index.
+func InstanceID(processGUID string, index int) string {
+	return processGUID + ":" + strconv.Itoa(index)
+}
+
+// InstanceResourceID returns the natural ID of the resource of the given kind that belongs to the instance of the process with the given index.
+func InstanceResourceID(processGUID string, index int, kind string) string {
+	return InstanceID(processGUID, index) + ":" + kind
+}
+
+type kindValue struct {
+	kind  string
+	value int
+}
+
+// instanceValues returns the value of each kind of resource measured for each instance of the process. Every instance is allocated the same quotas.
+func instanceValues(p *resource.Process) []kindValue {
+	v := []kindValue{
+		{AppMemoryKind, p.MemoryInMB},
+		{AppDiskKind, p.DiskInMB},
+	}
+	// -1 means the log rate is unlimited, so there is no quota to measure.
+	if p.LogRateLimitInBytesPerSecond >= 0 {
+		v = append(v, kindValue{AppLogRateKind, p.LogRateLimitInBytesPerSecond / 1024})
+	}
+	return v
+}
+
 var (
 	ErrAppNotFound   = errors.New("CF processes meter: application not found")
 	ErrSpaceNotFound = errors.New("CF processes meter: space not found")
 )
 
+// ErrOrgNotFound is added to the errors of a measurement whose CF org is not in the database, for instance because orgs have not been synced since it was created. The usage is recorded, but is not attributed to a customer.
+var ErrOrgNotFound = errors.New("meter: CF org not found")
+
 type AppMeterDB interface {
 	GetCFOrg(ctx context.Context, id pgtype.UUID) (db.CFOrg, error)
 }
@@ -46,7 +92,7 @@ func NewCFAppMeter(
 }
 
 func (m *CFAppMeter) Name() string {
-	return "cfapps"
+	return AppMeterName
 }
 
 func (m *CFAppMeter) ReadUsage(ctx context.Context) ([]reader.Measurement, []*node.Node, error) {
@@ -66,26 +112,31 @@ func (m *CFAppMeter) ReadUsage(ctx context.Context) ([]reader.Measurement, []*no
 	measurements := []reader.Measurement{}
 	nodes := []*node.Node{}
 
-	// Aggregate process usage info by app.
-	m.logger.DebugContext(ctx, "app meter: aggregating process usage")
-	appUsage := map[string]int{}
+	// Group processes by app. Sort them so nodes and measurements are returned in a stable order.
+	procsByApp := map[string][]*resource.Process{}
 	for _, proc := range procs {
-		usage := proc.Instances * proc.MemoryInMB
-		appUsage[proc.Relationships.App.Data.GUID] += usage
+		guid := proc.Relationships.App.Data.GUID
+		procsByApp[guid] = append(procsByApp[guid], proc)
+	}
+	for _, ps := range procsByApp {
+		slices.SortFunc(ps, func(a, b *resource.Process) int {
+			if a.Type != b.Type {
+				return strings.Compare(a.Type, b.Type)
+			}
+			return strings.Compare(a.GUID, b.GUID)
+		})
 	}
 
-	m.logger.DebugContext(ctx, "app meter: aggregating app usage")
+	m.logger.DebugContext(ctx, "app meter: measuring processes")
 	for _, app := range apps {
 		if app.State != appStateStarted {
 			// Only STARTED apps consume resources. Skip the rest.
 			continue
 		}
 
-		msrmt := reader.Measurement{
-			Meter:             m.Name(),
-			ResourceNaturalID: app.GUID,
-			Value:             appUsage[app.GUID], // In MB. TODO: make sure units align.
-		}
+		// Fields common to all of the app's measurements are set on msrmt, which is copied for each process and kind below.
+		var msrmt reader.Measurement
+		var appNode *node.Node
 
 		spaceGUID := app.Relationships.Space.Data.GUID
 
@@ -94,7 +145,7 @@ func (m *CFAppMeter) ReadUsage(ctx context.Context) ([]reader.Measurement, []*no
 		})
 		if sidx < 0 {
 			msrmt.Errs = errors.Join(msrmt.Errs, ErrSpaceNotFound)
-			appNode, err := node.New(
+			appNode, err = node.New(
 				nil,
 				app.GUID,
 				node.WithSlugAuto("app", app.Name),
@@ -106,39 +157,15 @@ func (m *CFAppMeter) ReadUsage(ctx context.Context) ([]reader.Measurement, []*no
 
 			nodes = append(nodes, []*node.Node{appNode}...)
 		} else {
-			cfOrgGUIDString := spaces[sidx].Relationships.Organization.Data.GUID
-			cfOrgGUID := dbx.UtilUUID(cfOrgGUIDString)
-
-			org, err := m.dbq.GetCFOrg(ctx, cfOrgGUID)
-			if err != nil && !errors.Is(err, pgx.ErrNoRows) {
-				return nil, nil, fmt.Errorf("ReadUsage: getting org: %w", err)
-			}
-
-			customerID := org.CustomerID
-			msrmt.CustomerID = customerID
-			msrmt.OrgID = cfOrgGUIDString
-
-			cfOrgNode, err := node.New(
-				customerID,
-				cfOrgGUIDString,
-				node.WithSlugAuto("cforg", org.Name.String),
-				node.WithPathAuto("apps.usage"),
-			)
-			if err != nil {
-				return nil, nil, fmt.Errorf("ReadUsage: creating org node: %w", err)
-			}
-
-			spaceNode, err := node.New(
-				customerID,
-				spaceGUID,
-				node.WithSlugAuto("space", spaces[sidx].Name),
-				node.WithPathByParent(cfOrgNode),
-			)
+			space := spaces[sidx]
+			customerID, cfOrgNode, spaceNode, err := spaceNodes(ctx, m.dbq, space.Relationships.Organization.Data.GUID, space.GUID, space.Name)
 			if err != nil {
-				return nil, nil, fmt.Errorf("ReadUsage: creating space node: %w", err)
+				return nil, nil, fmt.Errorf("ReadUsage: %w", err)
 			}
+			msrmt.CustomerID = customerID
+			msrmt.OrgID = space.Relationships.Organization.Data.GUID
 
-			appNode, err := node.New(
+			appNode, err = node.New(
 				customerID,
 				app.GUID,
 				node.WithSlugAuto("app", app.Name),
@@ -151,8 +178,98 @@ func (m *CFAppMeter) ReadUsage(ctx context.Context) ([]reader.Measurement, []*no
 			nodes = append(nodes, []*node.Node{cfOrgNode, spaceNode, appNode}...)
 		}
 
-		measurements = append(measurements, msrmt)
+		for _, proc := range procsByApp[app.GUID] {
+			// Each process is a child of the app node, each instance is a child of the process node, and each kind is a child of the instance node, so usage can be reported per app, process, instance, or kind.
+			procNode, err := node.New(
+				appNode.CustomerID,
+				proc.GUID,
+				node.WithSlugAuto("proc", app.Name, proc.Type),
+				node.WithPathByParent(appNode),
+			)
+			if err != nil {
+				return nil, nil, fmt.Errorf("ReadUsage: creating process node: %w", err)
+			}
+			nodes = append(nodes, procNode)
+
+			// Instances are identified by their index, which CF assigns from 0 to the desired number of instances.
+			values := instanceValues(proc)
+			for i := range proc.Instances {
+				index := strconv.Itoa(i)
+				instanceNode, err := node.New(
+					appNode.CustomerID,
+					InstanceID(proc.GUID, i),
+					node.WithSlugAuto("instance", app.Name, proc.Type, index),
+					node.WithPathByParent(procNode),
+				)
+				if err != nil {
+					return nil, nil, fmt.Errorf("ReadUsage: creating instance node: %w", err)
+				}
+				nodes = append(nodes, instanceNode)
+
+				for _, v := range values {
+					resourceID := InstanceResourceID(proc.GUID, i, v.kind)
+					kindNode, err := node.New(
+						appNode.CustomerID,
+						resourceID,
+						node.WithSlugAuto("instance", app.Name, proc.Type, index, v.kind),
+						node.WithPathByParent(instanceNode),
+					)
+					if err != nil {
+						return nil, nil, fmt.Errorf("ReadUsage: creating %v node: %w", v.kind, err)
+					}
+					nodes = append(nodes, kindNode)
+
+					km := msrmt
+					km.Meter = m.Name()
+					km.ResourceKindNaturalID = v.kind
+					km.ResourceNaturalID = resourceID
+					km.Instance = i
+					km.Value = v.value
+					measurements = append(measurements, km)
+				}
+			}
+		}
 	}
 
 	return measurements, nodes, nil
 }
+
+// orgNode returns the node of a CF org, under which the usage of the org's resources is reported, and the ID of the customer that owns the org. If the org is not in the database, found is false and the node has no customer.
+func orgNode(ctx context.Context, dbq AppMeterDB, cfOrgGUIDString string) (customerID pgtype.UUID, n *node.Node, found bool, err error) {
+	org, err := dbq.GetCFOrg(ctx, dbx.UtilUUID(cfOrgGUIDString))
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return customerID, nil, false, fmt.Errorf("getting org: %w", err)
+	}
+	found = err == nil
+	customerID = org.CustomerID
+
+	n, err = node.New(
+		customerID,
+		cfOrgGUIDString,
+		node.WithSlugAuto("cforg", org.Name.String),
+		node.WithPathAuto("apps.usage"),
+	)
+	if err != nil {
+		return customerID, nil, false, fmt.Errorf("creating org node: %w", err)
+	}
+	return customerID, n, found, nil
+}
+
+// spaceNodes returns nodes for a CF space and the org that contains it, and the ID of the customer that owns the org. The nodes have no customer if the org is not in the database.
+func spaceNodes(ctx context.Context, dbq AppMeterDB, cfOrgGUIDString, spaceGUID, spaceName string) (customerID pgtype.UUID, orgN, spaceNode *node.Node, err error) {
+	customerID, orgN, _, err = orgNode(ctx, dbq, cfOrgGUIDString)
+	if err != nil {
+		return customerID, nil, nil, err
+	}
+
+	spaceNode, err = node.New(
+		customerID,
+		spaceGUID,
+		node.WithSlugAuto("space", spaceName),
+		node.WithPathByParent(orgN),
+	)
+	if err != nil {
+		return customerID, nil, nil, fmt.Errorf("creating space node: %w", err)
+	}
+	return customerID, orgN, spaceNode, nil
+}
@@ -3,6 +3,7 @@ package meter_test
 import (
 	"errors"
 	"log/slog"
+	"maps"
 	"reflect"
 	"testing"
 
@@ -17,8 +18,12 @@ const (
 	appStateStopped = "STOPPED"
 )
 
-func mkProc(appGUID string, instances, mb int) *resource.Process {
+// mkProc returns a process of the given type whose GUID is the app GUID followed by the type.
+func mkProc(appGUID, procType string, instances, mb int) *resource.Process {
 	return &resource.Process{
+		Resource: resource.Resource{
+			GUID: appGUID + "-" + procType,
+		},
 		Relationships: resource.ProcessRelationships{
 			App: resource.ToOneRelationship{
 				Data: &resource.Relationship{
@@ -26,11 +31,34 @@ func mkProc(appGUID string, instances, mb int) *resource.Process {
 				},
 			},
 		},
+		Type:       procType,
 		Instances:  instances,
 		MemoryInMB: mb,
 	}
 }
 
+// mkProcQuotas returns a process with disk and log rate limits as well as memory.
+func mkProcQuotas(appGUID, procType string, instances, mb, diskMB, logBytesPerSecond int) *resource.Process {
+	p := mkProc(appGUID, procType, instances, mb)
+	p.DiskInMB = diskMB
+	p.LogRateLimitInBytesPerSecond = logBytesPerSecond
+	return p
+}
+
+// procValues returns the expected measurement values of each kind for each instance of a process created with [mkProc], keyed by resource natural ID. A negative logRate means no log rate measurement is expected.
+func procValues(appGUID, procType string, instances, memory, disk, logRate int) map[string]int {
+	guid := appGUID + "-" + procType
+	out := map[string]int{}
+	for i := range instances {
+		out[meter.InstanceResourceID(guid, i, meter.AppMemoryKind)] = memory
+		out[meter.InstanceResourceID(guid, i, meter.AppDiskKind)] = disk
+		if logRate >= 0 {
+			out[meter.InstanceResourceID(guid, i, meter.AppLogRateKind)] = logRate
+		}
+	}
+	return out
+}
+
 func mkApp(guid, spaceGUID, state string) *resource.App {
 	return &resource.App{
 		Resource: resource.Resource{
@@ -94,7 +122,6 @@ func TestCFAppMeter_ReadUsage(t *testing.T) {
 
 	hugeInstances := 1024
 	hugeMemory := 128 * 1024 // 128GB
-	hugeValue := 134_217_728 // instances * memory
 
 	tests := []struct {
 		name               string
@@ -103,7 +130,7 @@ func TestCFAppMeter_ReadUsage(t *testing.T) {
 		spaces             []*resource.Space
 		procErr            error
 		appErr             error
-		want               map[string]int // expected aggregated usage by app GUID
+		want               map[string]int // expected usage by resource natural ID
 		wantMeasurementErr map[string]error
 		wantErr            bool
 	}{
@@ -127,69 +154,88 @@ func TestCFAppMeter_ReadUsage(t *testing.T) {
 			name:   "one app, no processes",
 			apps:   []*resource.App{mkApp(app1, sp, appStateStarted)},
 			spaces: []*resource.Space{mkSpace(sp, org)},
-			want:   map[string]int{app1: 0},
+			want:   map[string]int{},
 		},
 		{
-			name: "aggregate multiple procs",
+			name: "one measurement per instance",
 			procs: []*resource.Process{
-				mkProc(app1, 2, 512), // 1024
-				mkProc(app1, 1, 256), // +256 = 1280
+				mkProc(app1, "web", 2, 512),
+				mkProc(app1, "worker", 1, 256),
 			},
 			apps:   []*resource.App{mkApp(app1, sp, appStateStarted)},
 			spaces: []*resource.Space{mkSpace(sp, org)},
-			want:   map[string]int{app1: 1280},
+			want:   merge(procValues(app1, "web", 2, 512, 0, 0), procValues(app1, "worker", 1, 256, 0, 0)),
 		},
 		{
 			name: "process for unknown app is ignored",
 			procs: []*resource.Process{
-				mkProc("orphan‑app", 1, 512),
+				mkProc("orphan‑app", "web", 1, 512),
 			},
 			apps:   []*resource.App{mkApp(app1, sp, appStateStarted)},
 			spaces: []*resource.Space{mkSpace(sp, org)},
-			want:   map[string]int{app1: 0},
+			want:   map[string]int{},
 		},
 		{
 			name: "stopped app is skipped",
 			procs: []*resource.Process{
-				mkProc(app1, 1, 128),
-				mkProc(app2, 2, 128),
+				mkProc(app1, "web", 1, 128),
+				mkProc(app2, "web", 2, 128),
 			},
 			apps: []*resource.App{
 				mkApp(app1, sp, appStateStarted),
 				mkApp(app2, sp, appStateStopped), // skipped
 			},
 			spaces: []*resource.Space{mkSpace(sp, org)},
-			want:   map[string]int{app1: 128},
+			want:   procValues(app1, "web", 1, 128, 0, 0),
 		},
 		{
 			name: "missing space error is collected",
 			procs: []*resource.Process{
-				mkProc(app1, 1, 128),
+				mkProc(app1, "web", 1, 128),
 			},
 			apps: []*resource.App{
 				mkApp(app1, "non‑existent‑space", appStateStarted),
 			},
 			spaces:             []*resource.Space{mkSpace(sp, org)},
-			want:               map[string]int{app1: 128},
-			wantMeasurementErr: map[string]error{app1: meter.ErrSpaceNotFound},
+			want:               procValues(app1, "web", 1, 128, 0, 0),
+			wantMeasurementErr: map[string]error{meter.InstanceResourceID(app1+"-web", 0, meter.AppMemoryKind): meter.ErrSpaceNotFound},
 		},
 		{
 			name: "space present but org missing",
 			procs: []*resource.Process{
-				mkProc(app1, 1, 128),
+				mkProc(app1, "web", 1, 128),
 			},
 			apps:   []*resource.App{mkApp(app1, sp, appStateStarted)},
 			spaces: []*resource.Space{mkSpace(sp, "")}, // empty org GUID
-			want:   map[string]int{app1: 128},
+			want:   procValues(app1, "web", 1, 128, 0, 0),
+		},
+		{
+			name: "disk and log rate",
+			procs: []*resource.Process{
+				mkProcQuotas(app1, "web", 2, 512, 1024, 16*1024), // 1024 MB disk, 16 KiB/s per instance
+			},
+			apps:   []*resource.App{mkApp(app1, sp, appStateStarted)},
+			spaces: []*resource.Space{mkSpace(sp, org)},
+			want:   procValues(app1, "web", 2, 512, 1024, 16),
+		},
+		{
+			name: "unlimited log rate is not measured",
+			procs: []*resource.Process{
+				mkProcQuotas(app1, "web", 1, 256, 512, 8*1024),
+				mkProcQuotas(app1, "worker", 1, 256, 512, -1),
+			},
+			apps:   []*resource.App{mkApp(app1, sp, appStateStarted)},
+			spaces: []*resource.Space{mkSpace(sp, org)},
+			want:   merge(procValues(app1, "web", 1, 256, 512, 8), procValues(app1, "worker", 1, 256, 512, -1)),
 		},
 		{
 			name: "large numbers",
 			procs: []*resource.Process{
-				mkProc(app1, hugeInstances, hugeMemory),
+				mkProc(app1, "web", hugeInstances, hugeMemory),
 			},
 			apps:   []*resource.App{mkApp(app1, sp, appStateStarted)},
 			spaces: []*resource.Space{mkSpace(sp, org)},
-			want:   map[string]int{app1: hugeValue},
+			want:   procValues(app1, "web", hugeInstances, hugeMemory, 0, 0),
 		},
 	}
 
@@ -236,3 +282,59 @@ func TestCFAppMeter_ReadUsage(t *testing.T) {
 		})
 	}
 }
+
+func merge(ms ...map[string]int) map[string]int {
+	out := map[string]int{}
+	for _, m := range ms {
+		maps.Copy(out, m)
+	}
+	return out
+}
+
+func TestCFAppMeter_ProcessNodes(t *testing.T) {
+	const (
+		app   = "app-1"
+		space = "space-1"
+		org   = "10000000-0000-0000-0000-000000000001"
+	)
+	a := mkApp(app, space, appStateStarted)
+	a.Name = "my-app"
+	sut := meter.NewCFAppMeter(
+		slog.Default(),
+		&MockAppMeterCfProvider{
+			Apps:      []*resource.App{a},
+			Spaces:    []*resource.Space{mkSpace(space, org)},
+			Processes: []*resource.Process{mkProc(app, "worker", 1, 128), mkProc(app, "web", 2, 128)},
+		},
+		&StubDbQ{},
+	)
+
+	ms, nodes, err := sut.ReadUsage(t.Context())
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	for _, m := range ms {
+		if want := meter.InstanceResourceID(app+"-web", 1, m.ResourceKindNaturalID); m.ResourceNaturalID == want && m.Instance != 1 {
+			t.Errorf("expected %v to be measured as instance 1, got %d", want, m.Instance)
+		}
+	}
+	paths := map[string]string{}
+	for _, n := range nodes {
+		paths[n.ResourceNaturalID] = n.Path
+	}
+	appPath := paths[app]
+	for _, procType := range []string{"web", "worker"} {
+		procPath := paths[app+"-"+procType]
+		if want := appPath + ".proc_my_app_" + procType; procPath != want {
+			t.Errorf("expected %v process node at %q, got %q", procType, want, procPath)
+		}
+		instancePath := paths[meter.InstanceID(app+"-"+procType, 0)]
+		if want := procPath + ".instance_my_app_" + procType + "_0"; instancePath != want {
+			t.Errorf("expected %v instance node at %q, got %q", procType, want, instancePath)
+		}
+		memPath := paths[meter.InstanceResourceID(app+"-"+procType, 0, meter.AppMemoryKind)]
+		if want := instancePath + ".instance_my_app_" + procType + "_0_memory"; memPath != want {
+			t.Errorf("expected %v memory node at %q, got %q", procType, want, memPath)
+		}
+	}
+}
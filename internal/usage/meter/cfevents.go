@@ -0,0 +1,352 @@
+package meter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/cloudfoundry/go-cfclient/v3/client"
+	"github.com/cloudfoundry/go-cfclient/v3/resource"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/cloud-gov/billing/internal/db"
+	"github.com/cloud-gov/billing/internal/dbx"
+	"github.com/cloud-gov/billing/internal/usage/node"
+	"github.com/cloud-gov/billing/internal/usage/reader"
+)
+
+// UsageEventMeterName is the name of [CFUsageEventMeter].
+const UsageEventMeterName = "cfusageevents"
+
+// UsageEventMemoryKind is the resource kind of app processes and tasks measured by [CFUsageEventMeter], in MB-minutes. Services are measured in instance-minutes, and their kinds are service plan GUIDs.
+const UsageEventMemoryKind = "memory"
+
+// Sources of usage events. Each has its own checkpoint.
+const (
+	usageEventSourceApp     = "app"
+	usageEventSourceService = "service"
+)
+
+// Usage event states, as reported by the CF API. Other states, like BUILDPACK_SET, do not change usage.
+const (
+	appUsageStarted     = "STARTED"
+	appUsageStopped     = "STOPPED"
+	appUsageTaskStarted = "TASK_STARTED"
+	appUsageTaskStopped = "TASK_STOPPED"
+	serviceUsageCreated = "CREATED"
+	serviceUsageUpdated = "UPDATED"
+	serviceUsageDeleted = "DELETED"
+)
+
+// UsageEventResourceID returns the natural ID of the resource measured by [CFUsageEventMeter] for a CF process, task, or service instance. It differs from the IDs used by the snapshot meters so the two can measure the same resources side by side.
+func UsageEventResourceID(guid string) string {
+	return guid + ":" + UsageEventMeterName
+}
+
+type UsageEventMeterDB interface {
+	TaskMeterDB
+	GetUsageEventCheckpoint(ctx context.Context, arg db.GetUsageEventCheckpointParams) (db.UsageEventCheckpoint, error)
+	UpsertUsageEventCheckpoint(ctx context.Context, arg db.UpsertUsageEventCheckpointParams) error
+	OpenUsageEventInterval(ctx context.Context, arg db.OpenUsageEventIntervalParams) error
+	CloseUsageEventIntervals(ctx context.Context, arg db.CloseUsageEventIntervalsParams) error
+	ListUsageEventIntervals(ctx context.Context, arg db.ListUsageEventIntervalsParams) ([]db.UsageEventInterval, error)
+	DeleteUsageEventIntervals(ctx context.Context, arg db.DeleteUsageEventIntervalsParams) (int64, error)
+}
+
+// CFUsageEventMeter reads usage from Cloud Foundry app and service usage events. It is an alternative to the snapshot meters, [CFAppMeter] and [CFServiceMeter], which miss resources that start and stop between readings.
+//
+// Each reading, the meter processes the events since its checkpoint, turning start and stop events into intervals during which a resource had a constant value. It then measures how long each interval overlapped the time since the previous reading. Intervals and checkpoints are stored in the database, so processing is resumed where it left off. Processing is idempotent, so if a reading fails, its events are processed again by the next one.
+//
+// The meter only knows about resources that started after the oldest event CF has retained. Resources that have run longer than that are not measured until they are restarted or updated.
+type CFUsageEventMeter struct {
+	logger *slog.Logger
+	client UsageEventMeterCfProvider
+	dbq    UsageEventMeterDB
+}
+
+func NewCFUsageEventMeter(
+	logger *slog.Logger, client UsageEventMeterCfProvider, dbq UsageEventMeterDB,
+) *CFUsageEventMeter {
+	return &CFUsageEventMeter{
+		logger: logger.WithGroup("CFUsageEventMeter"),
+		client: client,
+		dbq:    dbq,
+	}
+}
+
+func (m *CFUsageEventMeter) Name() string {
+	return UsageEventMeterName
+}
+
+// ReadUsage processes new usage events and returns the usage of apps, tasks, and services since the previous reading.
+func (m *CFUsageEventMeter) ReadUsage(ctx context.Context) ([]reader.Measurement, []*node.Node, error) {
+	now := time.Now().UTC()
+	since, err := sinceLastReading(ctx, m.dbq, m.Name(), now)
+	if err != nil {
+		return nil, nil, fmt.Errorf("ReadUsage: %w", err)
+	}
+
+	if err := m.processAppEvents(ctx); err != nil {
+		return nil, nil, fmt.Errorf("ReadUsage: %w", err)
+	}
+	if err := m.processServiceEvents(ctx); err != nil {
+		return nil, nil, fmt.Errorf("ReadUsage: %w", err)
+	}
+
+	m.logger.DebugContext(ctx, "usage event meter: measuring intervals", "since", since)
+	intervals, err := m.dbq.ListUsageEventIntervals(ctx, db.ListUsageEventIntervalsParams{
+		Meter: m.Name(),
+		Since: pgtype.Timestamptz{Time: since, Valid: true},
+		Until: pgtype.Timestamptz{Time: now, Valid: true},
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("ReadUsage: listing intervals: %w", err)
+	}
+
+	measurements := []reader.Measurement{}
+	nodes := []*node.Node{}
+
+	// Intervals are ordered by resource, so consecutive intervals of the same resource are summed into one measurement. If a service instance changed plans, the measurement has the latest plan.
+	var valueSeconds int64
+	for i, iv := range intervals {
+		valueSeconds += int64(iv.Value) * intervalSeconds(iv, since, now)
+		if i+1 < len(intervals) && intervals[i+1].ResourceNaturalID == iv.ResourceNaturalID {
+			continue
+		}
+		value := int(valueSeconds / 60)
+		valueSeconds = 0
+		if value <= 0 {
+			continue
+		}
+
+		msrmt := reader.Measurement{
+			Meter:                 m.Name(),
+			ResourceKindNaturalID: iv.KindNaturalID,
+			ResourceNaturalID:     iv.ResourceNaturalID,
+			Value:                 value,
+		}
+
+		var resourceNode *node.Node
+		if !iv.CFOrgID.Valid || iv.SpaceGuid == "" {
+			msrmt.Errs = errors.Join(msrmt.Errs, ErrSpaceNotFound)
+			resourceNode, err = node.New(
+				nil,
+				iv.ResourceNaturalID,
+				node.WithSlugAuto("event", iv.Name),
+				node.WithPathAuto("orphan"),
+			)
+		} else {
+			orgGUID := iv.CFOrgID.String()
+			customerID, cfOrgNode, spaceNode, err := spaceNodes(ctx, m.dbq, orgGUID, iv.SpaceGuid, iv.SpaceName)
+			if err != nil {
+				return nil, nil, fmt.Errorf("ReadUsage: %w", err)
+			}
+			msrmt.CustomerID = customerID
+			msrmt.OrgID = orgGUID
+			nodes = append(nodes, cfOrgNode, spaceNode)
+
+			resourceNode, err = node.New(
+				customerID,
+				iv.ResourceNaturalID,
+				node.WithSlugAuto("event", iv.Name),
+				node.WithPathByParent(spaceNode),
+			)
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("ReadUsage: creating resource node: %w", err)
+		}
+		nodes = append(nodes, resourceNode)
+		measurements = append(measurements, msrmt)
+	}
+
+	// Intervals that stopped before the previous reading cannot be included in later readings, but are kept for a while so hours without readings can be reconstructed. See [dbx.BackfillReading].
+	deleted, err := m.dbq.DeleteUsageEventIntervals(ctx, db.DeleteUsageEventIntervalsParams{
+		Meter:  m.Name(),
+		Before: pgtype.Timestamptz{Time: since.Add(-dbx.UsageEventRetention), Valid: true},
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("ReadUsage: deleting old intervals: %w", err)
+	}
+	m.logger.DebugContext(ctx, "usage event meter: deleted old intervals", "count", deleted)
+
+	return measurements, nodes, nil
+}
+
+// intervalSeconds returns the number of seconds the interval overlapped the period [since, now).
+func intervalSeconds(iv db.UsageEventInterval, since, now time.Time) int64 {
+	start, end := iv.StartedAt.Time, now
+	if iv.StoppedAt.Valid && iv.StoppedAt.Time.Before(end) {
+		end = iv.StoppedAt.Time
+	}
+	if start.Before(since) {
+		start = since
+	}
+	if !end.After(start) {
+		return 0
+	}
+	return int64(end.Sub(start) / time.Second)
+}
+
+// checkpoint returns the GUID of the last event processed from source, or the empty string if none have been.
+func (m *CFUsageEventMeter) checkpoint(ctx context.Context, source string) (string, error) {
+	cp, err := m.dbq.GetUsageEventCheckpoint(ctx, db.GetUsageEventCheckpointParams{
+		Meter:  m.Name(),
+		Source: source,
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("getting %v event checkpoint: %w", source, err)
+	}
+	return cp.LastEventGuid, nil
+}
+
+func (m *CFUsageEventMeter) saveCheckpoint(ctx context.Context, source string, last resource.Resource) error {
+	err := m.dbq.UpsertUsageEventCheckpoint(ctx, db.UpsertUsageEventCheckpointParams{
+		Meter:         m.Name(),
+		Source:        source,
+		LastEventGuid: last.GUID,
+		LastEventAt:   pgtype.Timestamptz{Time: last.CreatedAt, Valid: true},
+	})
+	if err != nil {
+		return fmt.Errorf("saving %v event checkpoint: %w", source, err)
+	}
+	return nil
+}
+
+func (m *CFUsageEventMeter) processAppEvents(ctx context.Context) error {
+	after, err := m.checkpoint(ctx, usageEventSourceApp)
+	if err != nil {
+		return err
+	}
+	m.logger.DebugContext(ctx, "usage event meter: listing app usage events", "after", after)
+	opts := client.NewAppUsageOptions()
+	opts.AfterGUID = after
+	events, err := m.client.AppUsageEventsList(ctx, opts)
+	if err != nil {
+		return fmt.Errorf("listing app usage events: %w", err)
+	}
+	if len(events) == 0 {
+		return nil
+	}
+
+	for _, ev := range events {
+		iv := db.OpenUsageEventIntervalParams{
+			Meter:         m.Name(),
+			KindNaturalID: UsageEventMemoryKind,
+			StartedAt:     pgtype.Timestamptz{Time: ev.CreatedAt, Valid: true},
+			CFOrgID:       dbx.UtilUUID(ev.Organization.GUID),
+			SpaceGuid:     ev.Space.GUID,
+			SpaceName:     ev.Space.Name,
+		}
+		switch ev.State.Current {
+		case appUsageStarted:
+			// A started event is also sent when a started process is scaled, so it replaces the process's open interval.
+			iv.ResourceNaturalID = UsageEventResourceID(ev.Process.GUID)
+			iv.Value = int32(ev.InstanceCount.Current * ev.MemoryInMbPerInstance.Current)
+			iv.Instances = int32(ev.InstanceCount.Current)
+			iv.Name = ev.App.Name + " " + ev.Process.Type
+			err = m.restart(ctx, iv)
+		case appUsageTaskStarted:
+			iv.ResourceNaturalID = UsageEventResourceID(ev.Task.GUID)
+			iv.Value = int32(ev.MemoryInMbPerInstance.Current)
+			iv.Instances = 1
+			iv.Name = ev.App.Name + " " + ev.Task.Name
+			err = m.restart(ctx, iv)
+		case appUsageStopped:
+			err = m.stop(ctx, UsageEventResourceID(ev.Process.GUID), ev.CreatedAt)
+		case appUsageTaskStopped:
+			err = m.stop(ctx, UsageEventResourceID(ev.Task.GUID), ev.CreatedAt)
+		}
+		if err != nil {
+			return fmt.Errorf("processing app usage event %v: %w", ev.GUID, err)
+		}
+	}
+	return m.saveCheckpoint(ctx, usageEventSourceApp, events[len(events)-1].Resource)
+}
+
+func (m *CFUsageEventMeter) processServiceEvents(ctx context.Context) error {
+	after, err := m.checkpoint(ctx, usageEventSourceService)
+	if err != nil {
+		return err
+	}
+	m.logger.DebugContext(ctx, "usage event meter: listing service usage events", "after", after)
+	opts := client.NewServiceUsageOptions()
+	opts.AfterGUID = after
+	// Ignore user-provided services, which we do not bill for.
+	opts.ServiceInstanceTypes.EqualTo("managed_service_instance")
+	events, err := m.client.ServiceUsageEventsList(ctx, opts)
+	if err != nil {
+		return fmt.Errorf("listing service usage events: %w", err)
+	}
+	if len(events) == 0 {
+		return nil
+	}
+
+	for _, ev := range events {
+		instanceID := UsageEventResourceID(deref(ev.ServiceInstance.GUID))
+		switch deref(ev.State) {
+		case serviceUsageCreated, serviceUsageUpdated:
+			// An updated event may change the plan, so it replaces the instance's open interval.
+			err = m.restart(ctx, db.OpenUsageEventIntervalParams{
+				Meter:             m.Name(),
+				ResourceNaturalID: instanceID,
+				KindNaturalID:     deref(ev.ServicePlan.GUID),
+				Value:             1,
+				Instances:         1,
+				StartedAt:         pgtype.Timestamptz{Time: ev.CreatedAt, Valid: true},
+				CFOrgID:           dbx.UtilUUID(deref(ev.Organization.GUID)),
+				SpaceGuid:         deref(ev.Space.GUID),
+				SpaceName:         deref(ev.Space.Name),
+				Name:              deref(ev.ServiceInstance.Name),
+			})
+		case serviceUsageDeleted:
+			err = m.stop(ctx, instanceID, ev.CreatedAt)
+		}
+		if err != nil {
+			return fmt.Errorf("processing service usage event %v: %w", ev.GUID, err)
+		}
+	}
+	return m.saveCheckpoint(ctx, usageEventSourceService, events[len(events)-1].Resource)
+}
+
+// restart opens iv and stops the resource's earlier open interval, if any. If the event was already processed, iv is left as it is.
+func (m *CFUsageEventMeter) restart(ctx context.Context, iv db.OpenUsageEventIntervalParams) error {
+	if err := m.dbq.OpenUsageEventInterval(ctx, iv); err != nil {
+		return fmt.Errorf("opening interval: %w", err)
+	}
+	err := m.dbq.CloseUsageEventIntervals(ctx, db.CloseUsageEventIntervalsParams{
+		Meter:             m.Name(),
+		ResourceNaturalID: iv.ResourceNaturalID,
+		StoppedAt:         iv.StartedAt,
+		ExceptStartedAt:   iv.StartedAt,
+	})
+	if err != nil {
+		return fmt.Errorf("closing interval: %w", err)
+	}
+	return nil
+}
+
+// stop stops the resource's open interval, if any.
+func (m *CFUsageEventMeter) stop(ctx context.Context, resourceID string, at time.Time) error {
+	err := m.dbq.CloseUsageEventIntervals(ctx, db.CloseUsageEventIntervalsParams{
+		Meter:             m.Name(),
+		ResourceNaturalID: resourceID,
+		StoppedAt:         pgtype.Timestamptz{Time: at, Valid: true},
+	})
+	if err != nil {
+		return fmt.Errorf("closing interval: %w", err)
+	}
+	return nil
+}
+
+func deref(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
@@ -0,0 +1,255 @@
+package meter_test
+
+import (
+	"context"
+	"log/slog"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cloudfoundry/go-cfclient/v3/resource"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/cloud-gov/billing/internal/db"
+	"github.com/cloud-gov/billing/internal/usage/meter"
+)
+
+// memUsageEventDB stores usage event checkpoints and intervals in memory, mimicking the queries used by [meter.CFUsageEventMeter].
+type memUsageEventDB struct {
+	StubDbQ
+	checkpoints map[string]db.UsageEventCheckpoint
+	intervals   []db.UsageEventInterval
+}
+
+func newMemUsageEventDB(latest time.Time) *memUsageEventDB {
+	return &memUsageEventDB{
+		StubDbQ:     StubDbQ{LatestReading: pgtype.Timestamptz{Time: latest, Valid: true}},
+		checkpoints: map[string]db.UsageEventCheckpoint{},
+	}
+}
+
+func (d *memUsageEventDB) GetUsageEventCheckpoint(_ context.Context, arg db.GetUsageEventCheckpointParams) (db.UsageEventCheckpoint, error) {
+	cp, ok := d.checkpoints[arg.Meter+"/"+arg.Source]
+	if !ok {
+		return cp, pgx.ErrNoRows
+	}
+	return cp, nil
+}
+
+func (d *memUsageEventDB) UpsertUsageEventCheckpoint(_ context.Context, arg db.UpsertUsageEventCheckpointParams) error {
+	d.checkpoints[arg.Meter+"/"+arg.Source] = db.UsageEventCheckpoint{
+		Meter:         arg.Meter,
+		Source:        arg.Source,
+		LastEventGuid: arg.LastEventGuid,
+		LastEventAt:   arg.LastEventAt,
+	}
+	return nil
+}
+
+func (d *memUsageEventDB) OpenUsageEventInterval(_ context.Context, arg db.OpenUsageEventIntervalParams) error {
+	if slices.ContainsFunc(d.intervals, func(iv db.UsageEventInterval) bool {
+		return iv.Meter == arg.Meter && iv.ResourceNaturalID == arg.ResourceNaturalID && iv.StartedAt.Time.Equal(arg.StartedAt.Time)
+	}) {
+		return nil
+	}
+	d.intervals = append(d.intervals, db.UsageEventInterval{
+		Meter:             arg.Meter,
+		ResourceNaturalID: arg.ResourceNaturalID,
+		KindNaturalID:     arg.KindNaturalID,
+		Value:             arg.Value,
+		Instances:         arg.Instances,
+		StartedAt:         arg.StartedAt,
+		CFOrgID:           arg.CFOrgID,
+		SpaceGuid:         arg.SpaceGuid,
+		SpaceName:         arg.SpaceName,
+		Name:              arg.Name,
+	})
+	return nil
+}
+
+func (d *memUsageEventDB) CloseUsageEventIntervals(_ context.Context, arg db.CloseUsageEventIntervalsParams) error {
+	for i, iv := range d.intervals {
+		if iv.Meter != arg.Meter || iv.ResourceNaturalID != arg.ResourceNaturalID || iv.StoppedAt.Valid {
+			continue
+		}
+		if iv.StartedAt.Time.After(arg.StoppedAt.Time) || (arg.ExceptStartedAt.Valid && iv.StartedAt.Time.Equal(arg.ExceptStartedAt.Time)) {
+			continue
+		}
+		d.intervals[i].StoppedAt = arg.StoppedAt
+	}
+	return nil
+}
+
+func (d *memUsageEventDB) ListUsageEventIntervals(_ context.Context, arg db.ListUsageEventIntervalsParams) ([]db.UsageEventInterval, error) {
+	out := []db.UsageEventInterval{}
+	for _, iv := range d.intervals {
+		if iv.Meter == arg.Meter && iv.StartedAt.Time.Before(arg.Until.Time) && (!iv.StoppedAt.Valid || iv.StoppedAt.Time.After(arg.Since.Time)) {
+			out = append(out, iv)
+		}
+	}
+	slices.SortFunc(out, func(a, b db.UsageEventInterval) int {
+		if c := strings.Compare(a.ResourceNaturalID, b.ResourceNaturalID); c != 0 {
+			return c
+		}
+		return a.StartedAt.Time.Compare(b.StartedAt.Time)
+	})
+	return out, nil
+}
+
+func (d *memUsageEventDB) DeleteUsageEventIntervals(_ context.Context, arg db.DeleteUsageEventIntervalsParams) (int64, error) {
+	before := len(d.intervals)
+	d.intervals = slices.DeleteFunc(d.intervals, func(iv db.UsageEventInterval) bool {
+		return iv.Meter == arg.Meter && iv.StoppedAt.Valid && !iv.StoppedAt.Time.After(arg.Before.Time)
+	})
+	return int64(before - len(d.intervals)), nil
+}
+
+const (
+	eventOrg   = "10000000-0000-0000-0000-000000000001"
+	eventSpace = "space-1"
+)
+
+func mkAppEvent(guid, state, procGUID string, instances, mb int, at time.Time) *resource.AppUsage {
+	e := &resource.AppUsage{
+		Resource:     resource.Resource{GUID: guid, CreatedAt: at},
+		App:          resource.AppUsageGUIDName{GUID: "app-1", Name: "my-app"},
+		Process:      resource.AppUsageGUIDType{GUID: procGUID, Type: "web"},
+		Space:        resource.AppUsageGUIDName{GUID: eventSpace, Name: "dev"},
+		Organization: resource.Relationship{GUID: eventOrg},
+	}
+	e.State.Current = state
+	e.InstanceCount.Current = instances
+	e.MemoryInMbPerInstance.Current = mb
+	return e
+}
+
+func mkServiceEvent(guid, state, instanceGUID, planGUID string, at time.Time) *resource.ServiceUsage {
+	org, space, spaceName, name := eventOrg, eventSpace, "dev", "my-db"
+	return &resource.ServiceUsage{
+		Resource:        resource.Resource{GUID: guid, CreatedAt: at},
+		State:           &state,
+		Organization:    resource.NullableRelationship{GUID: &org},
+		Space:           resource.ServiceUsageGUIDName{GUID: &space, Name: &spaceName},
+		ServiceInstance: resource.ServiceUsageGUIDNameType{GUID: &instanceGUID, Name: &name},
+		ServicePlan:     resource.ServiceUsageGUIDName{GUID: &planGUID},
+	}
+}
+
+func TestCFUsageEventMeter_ReadUsage(t *testing.T) {
+	// The previous reading was an hour ago. Events happened since then, so finished intervals are measured exactly.
+	since := time.Now().UTC().Add(-time.Hour).Truncate(time.Second)
+	at := func(minutes int) time.Time {
+		return since.Add(time.Duration(minutes) * time.Minute)
+	}
+	proc := meter.UsageEventResourceID("proc-1")
+	short := meter.UsageEventResourceID("proc-2")
+	instance := meter.UsageEventResourceID("si-1")
+
+	cf := &MockUsageEventMeterCfProvider{
+		AppEvents: []*resource.AppUsage{
+			mkAppEvent("a1", "STARTED", "proc-1", 1, 512, at(-120)),
+			mkAppEvent("a2", "BUILDPACK_SET", "proc-1", 1, 512, at(-100)),
+			// Scaled up halfway through the window.
+			mkAppEvent("a3", "STARTED", "proc-1", 2, 512, at(30)),
+			// Ran for 10 minutes between readings, which snapshots would miss.
+			mkAppEvent("a4", "STARTED", "proc-2", 1, 1024, at(10)),
+			mkAppEvent("a5", "STOPPED", "proc-2", 1, 1024, at(20)),
+		},
+		ServiceEvents: []*resource.ServiceUsage{
+			mkServiceEvent("s1", "CREATED", "si-1", "plan-1", at(15)),
+			mkServiceEvent("s2", "DELETED", "si-1", "plan-1", at(45)),
+		},
+	}
+	dbq := newMemUsageEventDB(since)
+	sut := meter.NewCFUsageEventMeter(slog.Default(), cf, dbq)
+
+	ms, nodes, err := sut.ReadUsage(t.Context())
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	got := map[string]int{}
+	kinds := map[string]string{}
+	for _, m := range ms {
+		if m.Meter != meter.UsageEventMeterName {
+			t.Errorf("unexpected meter %q", m.Meter)
+		}
+		if m.OrgID != eventOrg || m.Errs != nil {
+			t.Errorf("expected org %v and no errors for %v, got %v and %v", eventOrg, m.ResourceNaturalID, m.OrgID, m.Errs)
+		}
+		got[m.ResourceNaturalID] = m.Value
+		kinds[m.ResourceNaturalID] = m.ResourceKindNaturalID
+	}
+
+	// proc-1 is still running, so its value depends on how long the test took. Check the other resources exactly.
+	if v := got[proc]; v < 30*512+30*1024 || v > 30*512+31*1024 {
+		t.Errorf("expected about %v MB-minutes for the scaled process, got %v", 30*512+30*1024, v)
+	}
+	delete(got, proc)
+	want := map[string]int{short: 10 * 1024, instance: 30}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected values %v, got %v", want, got)
+	}
+	if kinds[short] != meter.UsageEventMemoryKind || kinds[instance] != "plan-1" {
+		t.Errorf("unexpected kinds %v", kinds)
+	}
+
+	paths := map[string]string{}
+	for _, n := range nodes {
+		paths[n.ResourceNaturalID] = n.Path
+	}
+	if want := paths[eventSpace] + ".event_my_app_web"; paths[short] != want {
+		t.Errorf("expected process node at %q, got %q", want, paths[short])
+	}
+
+	// The checkpoint is the last event processed, and intervals that can no longer be measured are deleted.
+	if cp := dbq.checkpoints[meter.UsageEventMeterName+"/app"]; cp.LastEventGuid != "a5" {
+		t.Errorf("expected app checkpoint a5, got %q", cp.LastEventGuid)
+	}
+	if cp := dbq.checkpoints[meter.UsageEventMeterName+"/service"]; cp.LastEventGuid != "s2" {
+		t.Errorf("expected service checkpoint s2, got %q", cp.LastEventGuid)
+	}
+	if n := len(dbq.intervals); n != 4 {
+		t.Errorf("expected 4 intervals, got %v", n)
+	}
+}
+
+func TestCFUsageEventMeter_Checkpoint(t *testing.T) {
+	since := time.Now().UTC().Add(-time.Hour).Truncate(time.Second)
+	at := func(minutes int) time.Time {
+		return since.Add(time.Duration(minutes) * time.Minute)
+	}
+	id := meter.UsageEventResourceID("proc-1")
+	cf := &MockUsageEventMeterCfProvider{
+		AppEvents: []*resource.AppUsage{
+			mkAppEvent("a1", "STARTED", "proc-1", 1, 100, at(10)),
+		},
+	}
+	dbq := newMemUsageEventDB(since)
+	sut := meter.NewCFUsageEventMeter(slog.Default(), cf, dbq)
+	if _, _, err := sut.ReadUsage(t.Context()); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	// New events are processed after the checkpoint. Events that were already processed are not processed again, and replaying them would not change the intervals.
+	cf.AppEvents = append(cf.AppEvents, mkAppEvent("a2", "STOPPED", "proc-1", 1, 100, at(40)))
+	ms, _, err := sut.ReadUsage(t.Context())
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	if len(ms) != 1 || ms[0].ResourceNaturalID != id || ms[0].Value != 3000 {
+		t.Errorf("expected 3000 MB-minutes for %v, got %+v", id, ms)
+	}
+
+	delete(dbq.checkpoints, meter.UsageEventMeterName+"/app")
+	ms, _, err = sut.ReadUsage(t.Context())
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	if len(ms) != 1 || ms[0].Value != 3000 {
+		t.Errorf("expected replayed events to give the same usage, got %+v", ms)
+	}
+}
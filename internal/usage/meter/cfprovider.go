@@ -12,6 +12,16 @@ type AppMeterCfProvider interface {
 	Processes
 }
 
+type TaskMeterCfProvider interface {
+	Apps
+	Tasks
+}
+
+type UsageEventMeterCfProvider interface {
+	AppUsageEvents
+	ServiceUsageEvents
+}
+
 type ServiceMeterCfProvider interface {
 	Spaces
 	ServiceInstances
@@ -24,6 +34,15 @@ type Apps interface {
 type Processes interface {
 	ProcessesList(context.Context, *client.ProcessListOptions) ([]*resource.Process, error)
 }
+type Tasks interface {
+	TasksList(context.Context, *client.TaskListOptions) ([]*resource.Task, error)
+}
+type AppUsageEvents interface {
+	AppUsageEventsList(context.Context, *client.AppUsageListOptions) ([]*resource.AppUsage, error)
+}
+type ServiceUsageEvents interface {
+	ServiceUsageEventsList(context.Context, *client.ServiceUsageListOptions) ([]*resource.ServiceUsage, error)
+}
 
 type Spaces interface {
 	SpacesList(context.Context, *client.SpaceListOptions) ([]*resource.Space, error)
@@ -31,10 +50,17 @@ type Spaces interface {
 type ServiceInstances interface {
 	ServiceInstancesList(context.Context, *client.ServiceInstanceListOptions) ([]*resource.ServiceInstance, error)
 }
+type Organizations interface {
+	OrgsList(context.Context, *client.OrganizationListOptions) ([]*resource.Organization, error)
+}
 type ServicePlans interface {
 	ServicePlansOfferingsList(context.Context, *client.ServicePlanListOptions) ([]*resource.ServicePlan, []*resource.ServiceOffering, error)
 }
 
+type ServiceBrokers interface {
+	ServiceBrokersList(context.Context, *client.ServiceBrokerListOptions) ([]*resource.ServiceBroker, error)
+}
+
 type CFAdapter struct {
 	*client.Client
 }
@@ -47,6 +73,10 @@ func (c *CFAdapter) ProcessesList(ctx context.Context, opts *client.ProcessListO
 	return c.Processes.ListAll(ctx, opts)
 }
 
+func (c *CFAdapter) TasksList(ctx context.Context, opts *client.TaskListOptions) ([]*resource.Task, error) {
+	return c.Tasks.ListAll(ctx, opts)
+}
+
 func (c *CFAdapter) SpacesList(ctx context.Context, opts *client.SpaceListOptions) ([]*resource.Space, error) {
 	return c.Spaces.ListAll(ctx, opts)
 }
@@ -58,3 +88,19 @@ func (c *CFAdapter) ServiceInstancesList(ctx context.Context, opts *client.Servi
 func (c *CFAdapter) ServicePlansOfferingsList(ctx context.Context, opts *client.ServicePlanListOptions) ([]*resource.ServicePlan, []*resource.ServiceOffering, error) {
 	return c.ServicePlans.ListIncludeServiceOfferingAll(ctx, opts)
 }
+
+func (c *CFAdapter) OrgsList(ctx context.Context, opts *client.OrganizationListOptions) ([]*resource.Organization, error) {
+	return c.Organizations.ListAll(ctx, opts)
+}
+
+func (c *CFAdapter) ServiceBrokersList(ctx context.Context, opts *client.ServiceBrokerListOptions) ([]*resource.ServiceBroker, error) {
+	return c.ServiceBrokers.ListAll(ctx, opts)
+}
+
+func (c *CFAdapter) AppUsageEventsList(ctx context.Context, opts *client.AppUsageListOptions) ([]*resource.AppUsage, error) {
+	return c.AppUsageEvents.ListAll(ctx, opts)
+}
+
+func (c *CFAdapter) ServiceUsageEventsList(ctx context.Context, opts *client.ServiceUsageListOptions) ([]*resource.ServiceUsage, error) {
+	return c.ServiceUsageEvents.ListAll(ctx, opts)
+}
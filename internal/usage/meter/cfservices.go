@@ -15,6 +15,9 @@ import (
 	"github.com/cloud-gov/billing/internal/usage/reader"
 )
 
+// ServiceMeterName is the name of [CFServiceMeter]. The natural IDs of its resource kinds are service plan GUIDs.
+const ServiceMeterName = "cfservices"
+
 type ServiceMeterDB interface {
 	GetCFOrg(ctx context.Context, id pgtype.UUID) (db.CFOrg, error)
 }
@@ -37,7 +40,7 @@ func NewCFServiceMeter(
 }
 
 func (m *CFServiceMeter) Name() string {
-	return "cfservices"
+	return ServiceMeterName
 }
 
 // ReadUsage returns the point-in-time usage of services in Cloud Foundry.
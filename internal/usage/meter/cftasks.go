@@ -0,0 +1,212 @@
+package meter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/cloudfoundry/go-cfclient/v3/client"
+	"github.com/cloudfoundry/go-cfclient/v3/resource"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/cloud-gov/billing/internal/usage/node"
+	"github.com/cloud-gov/billing/internal/usage/reader"
+)
+
+// TaskMeterName is the name of [CFTaskMeter].
+const TaskMeterName = "cftasks"
+
+// TaskMemoryKind is the resource kind measured by [CFTaskMeter]: the memory-minutes a task used since the previous reading. Each task has one resource of this kind, identified by the task GUID.
+const TaskMemoryKind = "memory"
+
+// Task states, as reported by the CF API.
+const (
+	taskStateRunning   = "RUNNING"
+	taskStateCanceling = "CANCELING"
+	taskStateSucceeded = "SUCCEEDED"
+	taskStateFailed    = "FAILED"
+)
+
+// defaultWindow is how far back meters that measure usage since the previous reading look when there are no previous readings. Readings are usually hourly.
+const defaultWindow = time.Hour
+
+type TaskMeterDB interface {
+	AppMeterDB
+	GetLatestReadingTime(ctx context.Context, meter string) (pgtype.Timestamptz, error)
+}
+
+// CFTaskMeter reads usage from Cloud Foundry tasks, like those started with `cf run-task`. Tasks do not run as app processes, so [CFAppMeter] does not measure them.
+//
+// Unlike the allocation measured by [CFAppMeter], task usage is measured as memory-minutes used between the previous reading and this one, so short tasks that start and finish between readings are still billed. A task is considered to run from its creation until it succeeds or fails.
+type CFTaskMeter struct {
+	logger *slog.Logger
+	client TaskMeterCfProvider
+	dbq    TaskMeterDB
+}
+
+func NewCFTaskMeter(
+	logger *slog.Logger, client TaskMeterCfProvider, dbq TaskMeterDB,
+) *CFTaskMeter {
+	return &CFTaskMeter{
+		logger: logger.WithGroup("CFTaskMeter"),
+		client: client,
+		dbq:    dbq,
+	}
+}
+
+func (m *CFTaskMeter) Name() string {
+	return TaskMeterName
+}
+
+// ReadUsage returns the memory-minutes used by Cloud Foundry tasks since the previous reading.
+func (m *CFTaskMeter) ReadUsage(ctx context.Context) ([]reader.Measurement, []*node.Node, error) {
+	now := time.Now().UTC()
+	since, err := sinceLastReading(ctx, m.dbq, m.Name(), now)
+	if err != nil {
+		return nil, nil, fmt.Errorf("ReadUsage: %w", err)
+	}
+
+	m.logger.DebugContext(ctx, "task meter: listing tasks", "since", since)
+	// Running tasks may have started long ago, so list all of them. Finished tasks only used resources since the last reading if they finished after it.
+	activeOpts := client.NewTaskListOptions()
+	activeOpts.States.EqualTo(taskStateRunning, taskStateCanceling)
+	active, err := m.client.TasksList(ctx, activeOpts)
+	if err != nil {
+		return nil, nil, fmt.Errorf("ReadUsage: listing active tasks: %w", err)
+	}
+	doneOpts := client.NewTaskListOptions()
+	doneOpts.States.EqualTo(taskStateSucceeded, taskStateFailed)
+	doneOpts.UpdatedAts.AfterOrEqualTo(since)
+	done, err := m.client.TasksList(ctx, doneOpts)
+	if err != nil {
+		return nil, nil, fmt.Errorf("ReadUsage: listing finished tasks: %w", err)
+	}
+
+	// A task may finish between the two calls, so de-duplicate by GUID. Sort so nodes and measurements are returned in a stable order.
+	tasks := slices.Concat(active, done)
+	slices.SortFunc(tasks, func(a, b *resource.Task) int {
+		return strings.Compare(a.GUID, b.GUID)
+	})
+	tasks = slices.CompactFunc(tasks, func(a, b *resource.Task) bool {
+		return a.GUID == b.GUID
+	})
+
+	m.logger.DebugContext(ctx, "task meter: listing apps")
+	apps, spaces, err := m.client.AppsListWithSpaces(ctx, client.NewAppListOptions())
+	if err != nil {
+		return nil, nil, fmt.Errorf("ReadUsage: listing apps w/ spaces: %w", err)
+	}
+
+	measurements := []reader.Measurement{}
+	nodes := []*node.Node{}
+
+	m.logger.DebugContext(ctx, "task meter: measuring tasks")
+	for _, task := range tasks {
+		value := taskMemoryMinutes(task, since, now)
+		if value <= 0 {
+			continue
+		}
+
+		msrmt := reader.Measurement{
+			Meter:                 m.Name(),
+			ResourceKindNaturalID: TaskMemoryKind,
+			ResourceNaturalID:     task.GUID,
+			Value:                 value,
+		}
+		var parent *node.Node
+		appName := ""
+
+		appGUID := task.Relationships.App.Data.GUID
+		aidx := slices.IndexFunc(apps, func(a *resource.App) bool {
+			return a.GUID == appGUID
+		})
+		if aidx < 0 {
+			msrmt.Errs = errors.Join(msrmt.Errs, ErrAppNotFound)
+		} else {
+			appName = apps[aidx].Name
+			spaceGUID := apps[aidx].Relationships.Space.Data.GUID
+			sidx := slices.IndexFunc(spaces, func(s *resource.Space) bool {
+				return s.GUID == spaceGUID
+			})
+			if sidx < 0 {
+				msrmt.Errs = errors.Join(msrmt.Errs, ErrSpaceNotFound)
+			} else {
+				space := spaces[sidx]
+				customerID, cfOrgNode, spaceNode, err := spaceNodes(ctx, m.dbq, space.Relationships.Organization.Data.GUID, space.GUID, space.Name)
+				if err != nil {
+					return nil, nil, fmt.Errorf("ReadUsage: %w", err)
+				}
+				msrmt.CustomerID = customerID
+				msrmt.OrgID = space.Relationships.Organization.Data.GUID
+				nodes = append(nodes, cfOrgNode, spaceNode)
+				parent = spaceNode
+			}
+		}
+
+		var taskNode *node.Node
+		if parent == nil {
+			taskNode, err = node.New(
+				nil,
+				task.GUID,
+				node.WithSlugAuto("task", appName, task.Name),
+				node.WithPathAuto("orphan"),
+			)
+		} else {
+			// Tasks belong to apps, but are children of the space node so their usage is not mixed up with the app's processes.
+			taskNode, err = node.New(
+				parent.CustomerID,
+				task.GUID,
+				node.WithSlugAuto("task", appName, task.Name),
+				node.WithPathByParent(parent),
+			)
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("ReadUsage: creating task node: %w", err)
+		}
+		nodes = append(nodes, taskNode)
+		measurements = append(measurements, msrmt)
+	}
+
+	return measurements, nodes, nil
+}
+
+// taskMemoryMinutes returns the MB-minutes of memory the task used between since and now. Tasks that have not started running yet, or that ran outside the window, used none.
+func taskMemoryMinutes(t *resource.Task, since, now time.Time) int {
+	var end time.Time
+	switch t.State {
+	case taskStateRunning, taskStateCanceling:
+		end = now
+	case taskStateSucceeded, taskStateFailed:
+		end = t.UpdatedAt
+	default:
+		return 0
+	}
+	start := t.CreatedAt
+	if start.Before(since) {
+		start = since
+	}
+	if end.After(now) {
+		end = now
+	}
+	if !end.After(start) {
+		return 0
+	}
+	return int(int64(t.MemoryInMB) * int64(end.Sub(start)/time.Second) / 60)
+}
+
+// sinceLastReading returns the time of the latest reading in which the meter succeeded, or [defaultWindow] before now if there are no such readings. Usage since then is measured by the meter's next successful reading, so readings in which it failed and manual one-off readings do not cut the window short.
+func sinceLastReading(ctx context.Context, dbq TaskMeterDB, meter string, now time.Time) (time.Time, error) {
+	latest, err := dbq.GetLatestReadingTime(ctx, meter)
+	if errors.Is(err, pgx.ErrNoRows) || (err == nil && !latest.Valid) {
+		return now.Add(-defaultWindow), nil
+	}
+	if err != nil {
+		return time.Time{}, fmt.Errorf("getting latest reading time: %w", err)
+	}
+	return latest.Time, nil
+}
@@ -0,0 +1,195 @@
+package meter_test
+
+import (
+	"errors"
+	"log/slog"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/cloudfoundry/go-cfclient/v3/resource"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/cloud-gov/billing/internal/usage/meter"
+)
+
+func mkTask(guid, appGUID, state string, mb int, created, updated time.Time) *resource.Task {
+	return &resource.Task{
+		Resource: resource.Resource{
+			GUID:      guid,
+			CreatedAt: created,
+			UpdatedAt: updated,
+		},
+		Relationships: resource.AppRelationship{
+			App: resource.ToOneRelationship{
+				Data: &resource.Relationship{
+					GUID: appGUID,
+				},
+			},
+		},
+		Name:       guid + "-name",
+		State:      state,
+		MemoryInMB: mb,
+	}
+}
+
+func TestCFTaskMeter_ReadUsage(t *testing.T) {
+	const (
+		app   = "app-1"
+		space = "space-1"
+		org   = "10000000-0000-0000-0000-000000000001"
+	)
+	// The previous reading was two hours ago. Finished tasks are measured exactly; running tasks depend on the current time.
+	since := time.Now().UTC().Add(-2 * time.Hour).Truncate(time.Second)
+	at := func(minutes int) time.Time {
+		return since.Add(time.Duration(minutes) * time.Minute)
+	}
+	latest := pgtype.Timestamptz{Time: since, Valid: true}
+
+	tests := []struct {
+		name    string
+		latest  pgtype.Timestamptz
+		tasks   []*resource.Task
+		apps    []*resource.App
+		want    map[string]int
+		wantErr map[string]error
+	}{
+		{
+			name:   "task finished since last reading",
+			latest: latest,
+			tasks: []*resource.Task{
+				mkTask("t1", app, "SUCCEEDED", 512, at(30), at(40)),
+			},
+			apps: []*resource.App{mkApp(app, space, appStateStopped)},
+			want: map[string]int{"t1": 5120},
+		},
+		{
+			name:   "only usage since last reading is measured",
+			latest: latest,
+			tasks: []*resource.Task{
+				mkTask("t1", app, "FAILED", 256, at(-60), at(20)),
+			},
+			apps: []*resource.App{mkApp(app, space, appStateStarted)},
+			want: map[string]int{"t1": 5120},
+		},
+		{
+			name:   "tasks finished before last reading and pending tasks are skipped",
+			latest: latest,
+			tasks: []*resource.Task{
+				mkTask("t1", app, "SUCCEEDED", 512, at(-60), at(-10)),
+				mkTask("t2", app, "PENDING", 512, at(110), at(110)),
+			},
+			apps: []*resource.App{mkApp(app, space, appStateStarted)},
+			want: map[string]int{},
+		},
+		{
+			name: "without readings, measure the last hour",
+			tasks: []*resource.Task{
+				mkTask("t1", app, "SUCCEEDED", 100, at(70), at(100)),
+				mkTask("t2", app, "SUCCEEDED", 100, at(30), at(50)), // Finished more than an hour ago.
+			},
+			apps: []*resource.App{mkApp(app, space, appStateStarted)},
+			want: map[string]int{"t1": 3000},
+		},
+		{
+			name:   "task for unknown app",
+			latest: latest,
+			tasks: []*resource.Task{
+				mkTask("t1", "gone", "SUCCEEDED", 512, at(30), at(40)),
+			},
+			apps:    []*resource.App{mkApp(app, space, appStateStarted)},
+			want:    map[string]int{"t1": 5120},
+			wantErr: map[string]error{"t1": meter.ErrAppNotFound},
+		},
+		{
+			name:   "app in unknown space",
+			latest: latest,
+			tasks: []*resource.Task{
+				mkTask("t1", app, "SUCCEEDED", 512, at(30), at(40)),
+			},
+			apps:    []*resource.App{mkApp(app, "gone", appStateStarted)},
+			want:    map[string]int{"t1": 5120},
+			wantErr: map[string]error{"t1": meter.ErrSpaceNotFound},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			sut := meter.NewCFTaskMeter(
+				slog.Default(),
+				&MockTaskMeterCfProvider{
+					Apps:   tc.apps,
+					Spaces: []*resource.Space{mkSpace(space, org)},
+					Tasks:  tc.tasks,
+				},
+				&StubDbQ{LatestReading: tc.latest},
+			)
+
+			ms, _, err := sut.ReadUsage(t.Context())
+			if err != nil {
+				t.Fatal("unexpected error:", err)
+			}
+
+			got := map[string]int{}
+			for _, m := range ms {
+				got[m.ResourceNaturalID] = m.Value
+				if m.Meter != meter.TaskMeterName || m.ResourceKindNaturalID != meter.TaskMemoryKind {
+					t.Errorf("unexpected meter %q and kind %q", m.Meter, m.ResourceKindNaturalID)
+				}
+				if want := tc.wantErr[m.ResourceNaturalID]; !errors.Is(m.Errs, want) || (want == nil && m.Errs != nil) {
+					t.Errorf("expected error %v for %v, got %v", want, m.ResourceNaturalID, m.Errs)
+				}
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("expected values %v, got %v", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestCFTaskMeter_RunningTask(t *testing.T) {
+	const (
+		app   = "app-1"
+		space = "space-1"
+		org   = "10000000-0000-0000-0000-000000000001"
+	)
+	since := time.Now().UTC().Add(-2 * time.Hour)
+	a := mkApp(app, space, appStateStarted)
+	a.Name = "my-app"
+	// Running and canceling tasks are measured until now.
+	running := mkTask("t1", app, "RUNNING", 1024, since.Add(time.Hour), since.Add(time.Hour))
+	canceling := mkTask("t2", app, "CANCELING", 1024, since.Add(-time.Hour), since.Add(-time.Hour))
+	sut := meter.NewCFTaskMeter(
+		slog.Default(),
+		&MockTaskMeterCfProvider{
+			Apps:   []*resource.App{a},
+			Spaces: []*resource.Space{mkSpace(space, org)},
+			Tasks:  []*resource.Task{running, canceling},
+		},
+		&StubDbQ{LatestReading: pgtype.Timestamptz{Time: since, Valid: true}},
+	)
+
+	ms, nodes, err := sut.ReadUsage(t.Context())
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	want := map[string]int{"t1": 60 * 1024, "t2": 120 * 1024}
+	if len(ms) != len(want) {
+		t.Fatalf("expected %v measurements, got %v", len(want), len(ms))
+	}
+	for _, m := range ms {
+		// Allow a minute for the time the test takes to run.
+		if d := m.Value - want[m.ResourceNaturalID]; d < 0 || d > 1024 {
+			t.Errorf("expected about %v MB-minutes for %v, got %v", want[m.ResourceNaturalID], m.ResourceNaturalID, m.Value)
+		}
+	}
+
+	paths := map[string]string{}
+	for _, n := range nodes {
+		paths[n.ResourceNaturalID] = n.Path
+	}
+	if want := paths[space] + ".task_my_app_t1_name"; paths["t1"] != want {
+		t.Errorf("expected task node at %q, got %q", want, paths["t1"])
+	}
+}
@@ -2,10 +2,12 @@ package meter_test
 
 import (
 	"context"
+	"slices"
 
 	"github.com/cloudfoundry/go-cfclient/v3/client"
 	"github.com/cloudfoundry/go-cfclient/v3/resource"
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgtype"
 
 	"github.com/cloud-gov/billing/internal/db"
@@ -20,6 +22,8 @@ func newUUID() string {
 type StubDbQ struct {
 	Org      db.CFOrg
 	OrgError error
+	// LatestReading is returned by GetLatestReadingTime. If it is not valid, [pgx.ErrNoRows] is returned instead.
+	LatestReading pgtype.Timestamptz
 }
 
 func (d *StubDbQ) GetCFOrg(ctx context.Context, id pgtype.UUID) (o db.CFOrg, e error) {
@@ -29,6 +33,13 @@ func (d *StubDbQ) GetCFOrg(ctx context.Context, id pgtype.UUID) (o db.CFOrg, e e
 	return o, e
 }
 
+func (d *StubDbQ) GetLatestReadingTime(ctx context.Context, meter string) (pgtype.Timestamptz, error) {
+	if !d.LatestReading.Valid {
+		return d.LatestReading, pgx.ErrNoRows
+	}
+	return d.LatestReading, nil
+}
+
 // MockAppMeterCfProvider is an in-memory implementation of [meter.AppMeterCfProvider].
 type MockAppMeterCfProvider struct {
 	Apps      []*resource.App
@@ -38,6 +49,13 @@ type MockAppMeterCfProvider struct {
 	ProcErr   error
 }
 
+// MockTaskMeterCfProvider is an in-memory implementation of [meter.TaskMeterCfProvider].
+type MockTaskMeterCfProvider struct {
+	Apps   []*resource.App
+	Spaces []*resource.Space
+	Tasks  []*resource.Task
+}
+
 // MockServiceMeterCfProvider is an in-memory implementation of [meter.ServiceMeterCfProvider].
 type MockServiceMeterCfProvider struct {
 	Spaces    []*resource.Space
@@ -73,3 +91,36 @@ func (p *MockServiceMeterCfProvider) ServiceInstancesList(_ context.Context, _ *
 func (p *MockServiceMeterCfProvider) ServicePlansOfferingsList(_ context.Context, _ *client.ServicePlanListOptions) ([]*resource.ServicePlan, []*resource.ServiceOffering, error) {
 	return p.Plans, p.Offerings, nil
 }
+
+func (p *MockTaskMeterCfProvider) AppsListWithSpaces(_ context.Context, _ *client.AppListOptions) ([]*resource.App, []*resource.Space, error) {
+	return p.Apps, p.Spaces, nil
+}
+
+// TasksList returns the tasks in the states requested by opts. Other filters are ignored.
+func (p *MockTaskMeterCfProvider) TasksList(_ context.Context, opts *client.TaskListOptions) ([]*resource.Task, error) {
+	out := []*resource.Task{}
+	for _, t := range p.Tasks {
+		if len(opts.States.Values) == 0 || slices.Contains(opts.States.Values, t.State) {
+			out = append(out, t)
+		}
+	}
+	return out, nil
+}
+
+// MockUsageEventMeterCfProvider is an in-memory implementation of [meter.UsageEventMeterCfProvider].
+type MockUsageEventMeterCfProvider struct {
+	AppEvents     []*resource.AppUsage
+	ServiceEvents []*resource.ServiceUsage
+}
+
+// AppUsageEventsList returns the events after opts.AfterGUID, or all events if it is empty.
+func (p *MockUsageEventMeterCfProvider) AppUsageEventsList(_ context.Context, opts *client.AppUsageListOptions) ([]*resource.AppUsage, error) {
+	i := slices.IndexFunc(p.AppEvents, func(e *resource.AppUsage) bool { return e.GUID == opts.AfterGUID })
+	return p.AppEvents[i+1:], nil
+}
+
+// ServiceUsageEventsList returns the events after opts.AfterGUID, or all events if it is empty.
+func (p *MockUsageEventMeterCfProvider) ServiceUsageEventsList(_ context.Context, opts *client.ServiceUsageListOptions) ([]*resource.ServiceUsage, error) {
+	i := slices.IndexFunc(p.ServiceEvents, func(e *resource.ServiceUsage) bool { return e.GUID == opts.AfterGUID })
+	return p.ServiceEvents[i+1:], nil
+}
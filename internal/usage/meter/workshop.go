@@ -0,0 +1,241 @@
+package meter
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	corev1 "k8s.io/api/core/v1"
+	apiresource "k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/cloud-gov/billing/internal/usage/node"
+	"github.com/cloud-gov/billing/internal/usage/reader"
+)
+
+// WorkshopMeterName is the name of [WorkshopMeter].
+const WorkshopMeterName = "workshop"
+
+// Resource kinds measured by [WorkshopMeter]. Each namespace has one resource of each kind, identified by [NamespaceResourceID]. All of them accrue: they are priced per unit-hour for the time between readings.
+const (
+	// WorkshopCPUKind is CPU requested by a namespace's pods, in millicores.
+	WorkshopCPUKind = "cpu"
+	// WorkshopMemoryKind is memory requested by a namespace's pods, in MB.
+	WorkshopMemoryKind = "memory"
+	// WorkshopStorageKind is storage provisioned for a namespace's persistent volume claims, in MB.
+	WorkshopStorageKind = "storage"
+)
+
+// DefaultWorkshopOrgLabel is the default label whose value is the GUID of the CF org that owns a namespace.
+const DefaultWorkshopOrgLabel = "billing.cloud.gov/cf-org-guid"
+
+// workshopPageSize is the number of objects requested from the Kubernetes API at a time.
+const workshopPageSize = 500
+
+// NamespaceResourceID returns the natural ID of the resource of the given kind that belongs to the namespace.
+func NamespaceResourceID(namespaceUID, kind string) string {
+	return namespaceUID + ":" + kind
+}
+
+// WorkshopMeter reads usage from namespaces in a Kubernetes cluster of the Workshop platform. It measures the CPU and memory requested by running pods and the storage of bound persistent volume claims, summed per namespace.
+//
+// Namespaces are assigned to customers by a label whose value is the GUID of a CF org, like resources in AWS. Usage is attributed to customers through CF orgs, so customers of both platforms are billed the same way, and namespaces are reported under their org's resource node. Unlabeled namespaces, like kube-system, are not measured, and measurements of namespaces whose org is not in the database have [ErrOrgNotFound].
+type WorkshopMeter struct {
+	logger   *slog.Logger
+	client   kubernetes.Interface
+	dbq      AppMeterDB
+	orgLabel string
+}
+
+func NewWorkshopMeter(
+	logger *slog.Logger, client kubernetes.Interface, dbq AppMeterDB, orgLabel string,
+) *WorkshopMeter {
+	return &WorkshopMeter{
+		logger:   logger.WithGroup("WorkshopMeter"),
+		client:   client,
+		dbq:      dbq,
+		orgLabel: orgLabel,
+	}
+}
+
+func (m *WorkshopMeter) Name() string {
+	return WorkshopMeterName
+}
+
+// namespaceUsage is the usage of a namespace at the time of a reading.
+type namespaceUsage struct {
+	cpu, memory, storage apiresource.Quantity
+}
+
+// ReadUsage returns the resources requested in each labeled namespace.
+func (m *WorkshopMeter) ReadUsage(ctx context.Context) ([]reader.Measurement, []*node.Node, error) {
+	m.logger.DebugContext(ctx, "workshop meter: listing namespaces")
+	namespaces := []corev1.Namespace{}
+	err := listAll(ctx, metav1.ListOptions{LabelSelector: m.orgLabel}, func(ctx context.Context, opts metav1.ListOptions) (string, error) {
+		l, err := m.client.CoreV1().Namespaces().List(ctx, opts)
+		if err != nil {
+			return "", err
+		}
+		namespaces = append(namespaces, l.Items...)
+		return l.Continue, nil
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("ReadUsage: listing namespaces: %w", err)
+	}
+
+	usage := map[string]*namespaceUsage{}
+	for _, ns := range namespaces {
+		usage[ns.Name] = &namespaceUsage{}
+	}
+
+	m.logger.DebugContext(ctx, "workshop meter: listing pods")
+	// Finished pods no longer hold their requested resources.
+	podOpts := metav1.ListOptions{FieldSelector: "status.phase!=Succeeded,status.phase!=Failed"}
+	err = listAll(ctx, podOpts, func(ctx context.Context, opts metav1.ListOptions) (string, error) {
+		l, err := m.client.CoreV1().Pods(metav1.NamespaceAll).List(ctx, opts)
+		if err != nil {
+			return "", err
+		}
+		for _, pod := range l.Items {
+			u, ok := usage[pod.Namespace]
+			if !ok || pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+				continue
+			}
+			u.cpu.Add(podRequest(&pod, corev1.ResourceCPU))
+			u.memory.Add(podRequest(&pod, corev1.ResourceMemory))
+		}
+		return l.Continue, nil
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("ReadUsage: listing pods: %w", err)
+	}
+
+	m.logger.DebugContext(ctx, "workshop meter: listing persistent volume claims")
+	err = listAll(ctx, metav1.ListOptions{}, func(ctx context.Context, opts metav1.ListOptions) (string, error) {
+		l, err := m.client.CoreV1().PersistentVolumeClaims(metav1.NamespaceAll).List(ctx, opts)
+		if err != nil {
+			return "", err
+		}
+		for _, pvc := range l.Items {
+			u, ok := usage[pvc.Namespace]
+			// Unbound claims have no volume yet, and the capacity of a bound volume may be larger than requested.
+			if !ok || pvc.Status.Phase != corev1.ClaimBound {
+				continue
+			}
+			if q, ok := pvc.Status.Capacity[corev1.ResourceStorage]; ok {
+				u.storage.Add(q)
+			}
+		}
+		return l.Continue, nil
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("ReadUsage: listing persistent volume claims: %w", err)
+	}
+
+	measurements := []reader.Measurement{}
+	nodes := []*node.Node{}
+
+	// Sort so nodes and measurements are returned in a stable order.
+	slices.SortFunc(namespaces, func(a, b corev1.Namespace) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+
+	m.logger.DebugContext(ctx, "workshop meter: measuring namespaces")
+	for _, ns := range namespaces {
+		orgGUID := ns.Labels[m.orgLabel]
+		// Labels can be set by anyone who can edit the namespace, so the value may not be a GUID.
+		var orgID pgtype.UUID
+		if err := orgID.Scan(orgGUID); err != nil || !orgID.Valid {
+			m.logger.WarnContext(ctx, "workshop meter: skipped namespace with invalid CF org label", "namespace", ns.Name, "label", m.orgLabel, "value", orgGUID)
+			continue
+		}
+		customerID, cfOrgNode, found, err := orgNode(ctx, m.dbq, orgGUID)
+		if err != nil {
+			return nil, nil, fmt.Errorf("ReadUsage: %w", err)
+		}
+		var errs error
+		if !found {
+			errs = ErrOrgNotFound
+		}
+
+		nsNode, err := node.New(
+			customerID,
+			string(ns.UID),
+			node.WithSlugAuto("ns", ns.Name),
+			node.WithPathByParent(cfOrgNode),
+		)
+		if err != nil {
+			return nil, nil, fmt.Errorf("ReadUsage: creating namespace node: %w", err)
+		}
+		nodes = append(nodes, cfOrgNode, nsNode)
+
+		u := usage[ns.Name]
+		values := []kindValue{
+			{WorkshopCPUKind, int(u.cpu.MilliValue())},
+			{WorkshopMemoryKind, int(u.memory.Value() / (1 << 20))},
+			{WorkshopStorageKind, int(u.storage.Value() / (1 << 20))},
+		}
+		for _, v := range values {
+			resourceID := NamespaceResourceID(string(ns.UID), v.kind)
+			kindNode, err := node.New(
+				customerID,
+				resourceID,
+				node.WithSlugAuto("ns", ns.Name, v.kind),
+				node.WithPathByParent(nsNode),
+			)
+			if err != nil {
+				return nil, nil, fmt.Errorf("ReadUsage: creating %v node: %w", v.kind, err)
+			}
+			nodes = append(nodes, kindNode)
+			measurements = append(measurements, reader.Measurement{
+				CustomerID:            customerID,
+				OrgID:                 orgGUID,
+				Meter:                 m.Name(),
+				ResourceKindNaturalID: v.kind,
+				ResourceNaturalID:     resourceID,
+				Value:                 v.value,
+				Errs:                  errs,
+			})
+		}
+	}
+
+	return measurements, nodes, nil
+}
+
+// podRequest returns the amount of the resource the pod requests, the way the Kubernetes scheduler computes it: the larger of the sum of its containers' requests and the largest request of any init container, plus the pod's overhead.
+func podRequest(pod *corev1.Pod, name corev1.ResourceName) apiresource.Quantity {
+	total := apiresource.Quantity{}
+	for _, c := range pod.Spec.Containers {
+		if q, ok := c.Resources.Requests[name]; ok {
+			total.Add(q)
+		}
+	}
+	for _, c := range pod.Spec.InitContainers {
+		if q, ok := c.Resources.Requests[name]; ok && q.Cmp(total) > 0 {
+			total = q.DeepCopy()
+		}
+	}
+	if q, ok := pod.Spec.Overhead[name]; ok {
+		total.Add(q)
+	}
+	return total
+}
+
+// listAll calls list with opts until the Kubernetes API returns no continue token. list returns the continue token of the page it listed.
+func listAll(ctx context.Context, opts metav1.ListOptions, list func(context.Context, metav1.ListOptions) (string, error)) error {
+	opts.Limit = workshopPageSize
+	for {
+		next, err := list(ctx, opts)
+		if err != nil {
+			return err
+		}
+		if next == "" {
+			return nil
+		}
+		opts.Continue = next
+	}
+}
@@ -0,0 +1,165 @@
+package meter_test
+
+import (
+	"errors"
+	"log/slog"
+	"reflect"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/cloud-gov/billing/internal/db"
+	"github.com/cloud-gov/billing/internal/usage/meter"
+)
+
+const workshopOrg = "30000000-0000-0000-0000-000000000003"
+
+func mkNamespace(name, org string) *corev1.Namespace {
+	ns := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: name, UID: types.UID(name + "-uid")}}
+	if org != "" {
+		ns.Labels = map[string]string{meter.DefaultWorkshopOrgLabel: org}
+	}
+	return ns
+}
+
+func mkPod(namespace, name string, phase corev1.PodPhase, cpu, memory string) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name},
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{{
+				Name: "main",
+				Resources: corev1.ResourceRequirements{Requests: corev1.ResourceList{
+					corev1.ResourceCPU:    resource.MustParse(cpu),
+					corev1.ResourceMemory: resource.MustParse(memory),
+				}},
+			}},
+		},
+		Status: corev1.PodStatus{Phase: phase},
+	}
+}
+
+func mkPVC(namespace, name string, phase corev1.PersistentVolumeClaimPhase, capacity string) *corev1.PersistentVolumeClaim {
+	pvc := &corev1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name},
+		Status:     corev1.PersistentVolumeClaimStatus{Phase: phase},
+	}
+	if capacity != "" {
+		pvc.Status.Capacity = corev1.ResourceList{corev1.ResourceStorage: resource.MustParse(capacity)}
+	}
+	return pvc
+}
+
+func TestWorkshopMeter_ReadUsage(t *testing.T) {
+	// A pod with a sidecar, an init container that requests more CPU than the others combined, and overhead.
+	sidecar := mkPod("team-a", "web-1", corev1.PodRunning, "250m", "256Mi")
+	sidecar.Spec.Containers = append(sidecar.Spec.Containers, corev1.Container{
+		Name: "sidecar",
+		Resources: corev1.ResourceRequirements{Requests: corev1.ResourceList{
+			corev1.ResourceMemory: resource.MustParse("64Mi"),
+		}},
+	})
+	sidecar.Spec.InitContainers = []corev1.Container{{
+		Name: "migrate",
+		Resources: corev1.ResourceRequirements{Requests: corev1.ResourceList{
+			corev1.ResourceCPU: resource.MustParse("1"),
+		}},
+	}}
+	sidecar.Spec.Overhead = corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("10m")}
+
+	objects := []runtime.Object{
+		mkNamespace("team-a", workshopOrg),
+		mkNamespace("team-b", workshopOrg),
+		mkNamespace("kube-system", ""),
+		mkNamespace("bad-label", "not-a-guid"),
+		sidecar,
+		mkPod("team-a", "web-2", corev1.PodPending, "500m", "1Gi"),
+		// Finished pods and pods in unmeasured namespaces are not measured.
+		mkPod("team-a", "job-1", corev1.PodSucceeded, "4", "8Gi"),
+		mkPod("kube-system", "dns", corev1.PodRunning, "100m", "70Mi"),
+		mkPod("bad-label", "web", corev1.PodRunning, "100m", "70Mi"),
+		mkPVC("team-a", "data", corev1.ClaimBound, "10Gi"),
+		mkPVC("team-a", "pending", corev1.ClaimPending, ""),
+		mkPVC("team-b", "data", corev1.ClaimBound, "512Mi"),
+	}
+	client := fake.NewClientset(objects...)
+	customer := pgtype.UUID{Bytes: [16]byte{3}, Valid: true}
+	dbq := &StubDbQ{Org: db.CFOrg{CustomerID: customer, Name: pgtype.Text{String: "org-3", Valid: true}}}
+	sut := meter.NewWorkshopMeter(slog.Default(), client, dbq, meter.DefaultWorkshopOrgLabel)
+
+	ms, nodes, err := sut.ReadUsage(t.Context())
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	got := map[string]int{}
+	for _, m := range ms {
+		if m.Meter != meter.WorkshopMeterName || m.OrgID != workshopOrg || m.CustomerID != customer || m.Errs != nil {
+			t.Errorf("unexpected measurement %+v", m)
+		}
+		got[m.ResourceNaturalID] = m.Value
+	}
+	want := map[string]int{
+		meter.NamespaceResourceID("team-a-uid", meter.WorkshopCPUKind):     1510,
+		meter.NamespaceResourceID("team-a-uid", meter.WorkshopMemoryKind):  1344,
+		meter.NamespaceResourceID("team-a-uid", meter.WorkshopStorageKind): 10240,
+		meter.NamespaceResourceID("team-b-uid", meter.WorkshopCPUKind):     0,
+		meter.NamespaceResourceID("team-b-uid", meter.WorkshopMemoryKind):  0,
+		meter.NamespaceResourceID("team-b-uid", meter.WorkshopStorageKind): 512,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected values %v, got %v", want, got)
+	}
+
+	paths := map[string]string{}
+	for _, n := range nodes {
+		if n.CustomerID != customer {
+			t.Errorf("expected node %v to belong to customer %v, got %v", n.Slug, customer, n.CustomerID)
+		}
+		paths[n.ResourceNaturalID] = n.Path
+	}
+	if p := paths["team-a-uid"]; p != "apps.usage.cforg_org_3.ns_team_a" {
+		t.Errorf("unexpected namespace node path %q", p)
+	}
+	if p := paths[meter.NamespaceResourceID("team-a-uid", meter.WorkshopCPUKind)]; p != "apps.usage.cforg_org_3.ns_team_a.ns_team_a_cpu" {
+		t.Errorf("unexpected kind node path %q", p)
+	}
+}
+
+func TestWorkshopMeter_Unlabeled(t *testing.T) {
+	client := fake.NewClientset(
+		mkNamespace("default", ""),
+		mkPod("default", "web", corev1.PodRunning, "1", "1Gi"),
+	)
+	sut := meter.NewWorkshopMeter(slog.Default(), client, &StubDbQ{}, meter.DefaultWorkshopOrgLabel)
+	ms, nodes, err := sut.ReadUsage(t.Context())
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	if len(ms) != 0 || len(nodes) != 0 {
+		t.Errorf("expected no measurements or nodes, got %v and %v", ms, nodes)
+	}
+}
+
+func TestWorkshopMeter_UnknownOrg(t *testing.T) {
+	client := fake.NewClientset(mkNamespace("team-a", workshopOrg))
+	sut := meter.NewWorkshopMeter(slog.Default(), client, &StubDbQ{OrgError: pgx.ErrNoRows}, meter.DefaultWorkshopOrgLabel)
+	ms, _, err := sut.ReadUsage(t.Context())
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	if len(ms) != 3 {
+		t.Fatalf("expected a measurement of each kind, got %+v", ms)
+	}
+	for _, m := range ms {
+		if m.OrgID != workshopOrg || !errors.Is(m.Errs, meter.ErrOrgNotFound) {
+			t.Errorf("expected a measurement with %v, got %+v", meter.ErrOrgNotFound, m)
+		}
+	}
+}
@@ -4,6 +4,8 @@ package reader
 import (
 	"context"
 	"errors"
+	"fmt"
+	"sync"
 	"time"
 
 	"github.com/cloud-gov/billing/internal/usage/node"
@@ -15,6 +17,35 @@ type Reading struct {
 	Time         time.Time
 	Measurements []Measurement
 	Nodes        []*node.Node
+	// Statuses contains the result of each meter, in the order the meters were registered. Measurements and Nodes only include those from meters that succeeded.
+	Statuses []MeterStatus
+}
+
+// MeterStatus is the result of reading usage from one meter.
+type MeterStatus struct {
+	Meter    string
+	Started  time.Time
+	Duration time.Duration
+	// Err is the error returned by the meter, or [context.DeadlineExceeded] if it timed out. It is nil if the meter succeeded.
+	Err error
+	// Measurements is the number of measurements the meter returned.
+	Measurements int
+}
+
+// Succeeded returns true if the meter did not return an error.
+func (s MeterStatus) Succeeded() bool {
+	return s.Err == nil
+}
+
+// Failed returns the statuses of meters that did not succeed.
+func (r Reading) Failed() []MeterStatus {
+	failed := []MeterStatus{}
+	for _, s := range r.Statuses {
+		if !s.Succeeded() {
+			failed = append(failed, s)
+		}
+	}
+	return failed
 }
 
 // Measurement is a single point-in-time snapshot of the utilization of a billable resource. Measurement only includes information gleaned directly from the target system -- not the database.
@@ -27,7 +58,9 @@ type Measurement struct {
 	ResourceKindNaturalID string
 	// ResourceNaturalID is the "natural" ID of the billable Resource being measured. The ID is maintained by the external system. For example, the service instance GUID of a Cloud Foundry service instance, or the process ID of a Cloud Foundry process.
 	ResourceNaturalID string
-	Value             int
+	// Instance is the zero-based index of the instance measured, for resources that run as several identical instances, like the processes of Cloud Foundry apps. Each instance is measured separately and has its own ResourceNaturalID. It is 0 for other resources.
+	Instance int
+	Value    int
 	// Errs contains any errors that occurred while gathering information about this particular measurement. It exists so we can preserve as much data as possible about the measurement. For instance, if we record a resource but fail to get its corresponding organization, a Measurement should be returned with a blank OrgID field and an Errs field including the error. Use [errors.Join] to add new errors.
 	Errs error
 }
@@ -38,34 +71,132 @@ type Meter interface {
 	Name() string
 }
 
+// DefaultTimeout is how long each meter may take to read usage, unless configured otherwise with [WithTimeout] or [WithMeterTimeout].
+const DefaultTimeout = 5 * time.Minute
+
+// ErrAllMetersFailed is returned by [Reader.Read] if no meter succeeded.
+var ErrAllMetersFailed = errors.New("reading usage: all meters failed")
+
 // Reader reads usage information from all configured meters and returns it in aggregate.
 type Reader struct {
-	meters []Meter
+	meters   []Meter
+	timeout  time.Duration
+	timeouts map[string]time.Duration
+}
+
+type Opt func(*Reader)
+
+// WithTimeout sets how long each meter may take to read usage.
+func WithTimeout(d time.Duration) Opt {
+	return func(r *Reader) {
+		r.timeout = d
+	}
+}
+
+// WithMeterTimeout sets how long the named meter may take to read usage, overriding [WithTimeout].
+func WithMeterTimeout(meter string, d time.Duration) Opt {
+	return func(r *Reader) {
+		r.timeouts[meter] = d
+	}
 }
 
-func New(meters []Meter) *Reader {
-	return &Reader{
-		meters: meters,
+func New(meters []Meter, opts ...Opt) *Reader {
+	rdr := &Reader{
+		meters:   meters,
+		timeout:  DefaultTimeout,
+		timeouts: map[string]time.Duration{},
+	}
+	for _, o := range opts {
+		o(rdr)
 	}
+	return rdr
+}
+
+// meterResult is the output of one meter.
+type meterResult struct {
+	measurements []Measurement
+	nodes        []*node.Node
+	status       MeterStatus
 }
 
-// Read calls ReadUsage on all registered Meters and returns the result in aggregate.
+// Read calls ReadUsage on all registered Meters concurrently and returns the result in aggregate. Each meter has its own deadline, so a slow or failing meter does not prevent others from being read.
+//
+// The measurements and nodes of meters that fail are discarded, because they may be incomplete. Failures are reported in [Reading.Statuses] and the returned error, which joins the errors of all failed meters. If at least one meter succeeded, the Reading contains its results and should still be recorded. If all meters failed, the error also wraps [ErrAllMetersFailed].
 func (rdr *Reader) Read(ctx context.Context) (Reading, error) {
 	reading := Reading{
 		Time:         time.Now().UTC(),
 		Nodes:        make([]*node.Node, 0),
 		Measurements: make([]Measurement, 0),
+		Statuses:     make([]MeterStatus, 0, len(rdr.meters)),
 	}
-	var reterr error
 
-	for _, p := range rdr.meters {
-		meas, nodes, err := p.ReadUsage(ctx)
-		if err != nil {
-			reterr = errors.Join(reterr, err)
+	results := make([]meterResult, len(rdr.meters))
+	wg := sync.WaitGroup{}
+	for i, m := range rdr.meters {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = rdr.readMeter(ctx, m)
+		}()
+	}
+	wg.Wait()
+
+	var reterr error
+	succeeded := 0
+	for _, res := range results {
+		reading.Statuses = append(reading.Statuses, res.status)
+		if !res.status.Succeeded() {
+			reterr = errors.Join(reterr, fmt.Errorf("meter %v: %w", res.status.Meter, res.status.Err))
+			continue
 		}
-		reading.Measurements = append(reading.Measurements, meas...)
-		reading.Nodes = append(reading.Nodes, nodes...)
+		succeeded++
+		reading.Measurements = append(reading.Measurements, res.measurements...)
+		reading.Nodes = append(reading.Nodes, res.nodes...)
+	}
+	if succeeded == 0 && len(rdr.meters) > 0 {
+		reterr = errors.Join(ErrAllMetersFailed, reterr)
 	}
 
 	return reading, reterr
 }
+
+// readMeter reads usage from one meter, with the meter's deadline. If the meter does not return by its deadline, it is abandoned and its results are discarded.
+func (rdr *Reader) readMeter(ctx context.Context, m Meter) meterResult {
+	timeout, ok := rdr.timeouts[m.Name()]
+	if !ok {
+		timeout = rdr.timeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	status := MeterStatus{Meter: m.Name(), Started: time.Now().UTC()}
+	done := make(chan meterResult, 1)
+	go func() {
+		res := meterResult{}
+		defer func() {
+			// A panicking meter should not take down other meters or the worker.
+			if r := recover(); r != nil {
+				res = meterResult{}
+				res.status.Err = fmt.Errorf("meter panicked: %v", r)
+			}
+			done <- res
+		}()
+		res.measurements, res.nodes, res.status.Err = m.ReadUsage(ctx)
+	}()
+
+	select {
+	case res := <-done:
+		status.Duration = time.Since(status.Started)
+		status.Err = res.status.Err
+		if status.Err != nil {
+			return meterResult{status: status}
+		}
+		status.Measurements = len(res.measurements)
+		res.status = status
+		return res
+	case <-ctx.Done():
+		status.Duration = time.Since(status.Started)
+		status.Err = ctx.Err()
+		return meterResult{status: status}
+	}
+}
@@ -0,0 +1,105 @@
+package reader_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/cloud-gov/billing/internal/usage/node"
+	"github.com/cloud-gov/billing/internal/usage/reader"
+)
+
+var ErrMeter = errors.New("meter failed")
+
+// stubMeter returns one measurement after delay, or err. If block is true, it ignores its context and never returns.
+type stubMeter struct {
+	name  string
+	delay time.Duration
+	err   error
+	block bool
+	panic bool
+}
+
+func (m stubMeter) Name() string {
+	return m.name
+}
+
+func (m stubMeter) ReadUsage(ctx context.Context) ([]reader.Measurement, []*node.Node, error) {
+	if m.panic {
+		panic("meter panicked")
+	}
+	if m.block {
+		select {}
+	}
+	select {
+	case <-time.After(m.delay):
+	case <-ctx.Done():
+		return nil, nil, ctx.Err()
+	}
+	ms := []reader.Measurement{{Meter: m.name, ResourceNaturalID: m.name + "-1", Value: 1}}
+	return ms, nil, m.err
+}
+
+func TestRead(t *testing.T) {
+	rdr := reader.New([]reader.Meter{
+		stubMeter{name: "ok", delay: 10 * time.Millisecond},
+		stubMeter{name: "err", err: ErrMeter},
+		stubMeter{name: "slow", delay: time.Hour},
+		stubMeter{name: "stuck", block: true},
+		stubMeter{name: "panic", panic: true},
+		stubMeter{name: "patient", delay: 50 * time.Millisecond},
+	}, reader.WithTimeout(20*time.Millisecond), reader.WithMeterTimeout("patient", time.Second))
+
+	start := time.Now()
+	reading, err := rdr.Read(t.Context())
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("expected meters to be read concurrently with deadlines, took %v", elapsed)
+	}
+
+	if !errors.Is(err, ErrMeter) || !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected the errors of failed meters, got %v", err)
+	}
+	if errors.Is(err, reader.ErrAllMetersFailed) {
+		t.Errorf("expected %v not to be returned when some meters succeeded", reader.ErrAllMetersFailed)
+	}
+
+	// Measurements of failed meters are discarded, even if they returned some.
+	got := []string{}
+	for _, m := range reading.Measurements {
+		got = append(got, m.Meter)
+	}
+	if len(got) != 2 || got[0] != "ok" || got[1] != "patient" {
+		t.Errorf("expected measurements from ok and patient, got %v", got)
+	}
+
+	wantSucceeded := map[string]bool{"ok": true, "err": false, "slow": false, "stuck": false, "panic": false, "patient": true}
+	if len(reading.Statuses) != len(wantSucceeded) {
+		t.Fatalf("expected %v statuses, got %v", len(wantSucceeded), len(reading.Statuses))
+	}
+	for _, st := range reading.Statuses {
+		if st.Succeeded() != wantSucceeded[st.Meter] {
+			t.Errorf("expected meter %v succeeded=%v, got error %v", st.Meter, wantSucceeded[st.Meter], st.Err)
+		}
+		if st.Started.IsZero() {
+			t.Errorf("expected start time for meter %v", st.Meter)
+		}
+		if st.Succeeded() && st.Measurements != 1 {
+			t.Errorf("expected meter %v to report 1 measurement, got %v", st.Meter, st.Measurements)
+		}
+	}
+	if n := len(reading.Failed()); n != 4 {
+		t.Errorf("expected 4 failed meters, got %v", n)
+	}
+}
+
+func TestRead_AllFailed(t *testing.T) {
+	rdr := reader.New([]reader.Meter{stubMeter{name: "err", err: ErrMeter}})
+	reading, err := rdr.Read(t.Context())
+	if !errors.Is(err, reader.ErrAllMetersFailed) || !errors.Is(err, ErrMeter) {
+		t.Errorf("expected %v wrapping %v, got %v", reader.ErrAllMetersFailed, ErrMeter, err)
+	}
+	if len(reading.Measurements) != 0 || len(reading.Statuses) != 1 {
+		t.Errorf("expected no measurements and one status, got %+v", reading)
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
+	"strings"
 
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgtype"
@@ -15,7 +16,10 @@ import (
 	"github.com/cloud-gov/billing/internal/usage/reader"
 )
 
-var ErrReadingExists = errors.New("a reading already exists for the hour of created_at")
+var (
+	ErrReadingExists = errors.New("a reading already exists for the hour of created_at")
+	ErrNoOrg         = errors.New("measurement has no CF org, so it cannot be recorded")
+)
 
 // RecordReading saves a reading to the database. It returns [ErrReadingExists] if a Reading already exists for the same hour of r.Time.
 func RecordReading(ctx context.Context, logger *slog.Logger, q db.Querier, r reader.Reading, periodic bool) error {
@@ -39,6 +43,8 @@ func RecordReading(ctx context.Context, logger *slog.Logger, q db.Querier, r rea
 	dbResources := db.BulkCreateResourcesParams{}
 	dbResourceNodes := db.BulkCreateResourceNodesParams{}
 	dbMeasurements := db.BulkCreateMeasurementParams{}
+	dbStatuses := db.BulkCreateReadingMeterStatusesParams{}
+	dbAnomalies := db.BulkCreateMeasurementAnomaliesParams{}
 
 	discard := 0
 
@@ -49,6 +55,26 @@ func RecordReading(ctx context.Context, logger *slog.Logger, q db.Querier, r rea
 			continue
 		}
 
+		if m.Errs != nil || m.OrgID == "" {
+			errs := m.Errs
+			if m.OrgID == "" && errs == nil {
+				errs = ErrNoOrg
+			}
+			dbAnomalies.ReadingID = append(dbAnomalies.ReadingID, dbReading.ID)
+			dbAnomalies.Meter = append(dbAnomalies.Meter, m.Meter)
+			dbAnomalies.ResourceNaturalID = append(dbAnomalies.ResourceNaturalID, m.ResourceNaturalID)
+			dbAnomalies.KindNaturalID = append(dbAnomalies.KindNaturalID, m.ResourceKindNaturalID)
+			dbAnomalies.CFOrgID = append(dbAnomalies.CFOrgID, dbx.UtilUUID(m.OrgID))
+			dbAnomalies.CustomerID = append(dbAnomalies.CustomerID, m.CustomerID)
+			dbAnomalies.Value = append(dbAnomalies.Value, int32(m.Value))
+			dbAnomalies.Errors = append(dbAnomalies.Errors, strings.Join(errorMessages(errs), "\n"))
+			dbAnomalies.Recorded = append(dbAnomalies.Recorded, m.OrgID != "")
+		}
+		if m.OrgID == "" {
+			// Resources must belong to a CF org, so orphaned usage is only recorded as an anomaly.
+			continue
+		}
+
 		// We may insert thousands of rows at a time. We only want to insert if a row does not already exist. COPY does not support ON CONFLICT, running an INSERT in a loop is inefficient, and sqlc does not support variable-length INSERTs. As a workaround we write INSERT queries that accept arrays, with one array per column where appropriate.
 		dbMeters = append(dbMeters, m.Meter)
 		dbCFOrgs = append(dbCFOrgs, dbx.UtilUUID(m.OrgID))
@@ -68,6 +94,22 @@ func RecordReading(ctx context.Context, logger *slog.Logger, q db.Querier, r rea
 		logger.Warn(fmt.Sprintf("discarded %v empty measurements; a meter is returning empty data", discard))
 	}
 
+	for _, st := range r.Statuses {
+		// Statuses refer to meters, which may not exist yet if the meter failed.
+		dbMeters = append(dbMeters, st.Meter)
+		dbStatuses.ReadingID = append(dbStatuses.ReadingID, dbReading.ID)
+		dbStatuses.Meter = append(dbStatuses.Meter, st.Meter)
+		dbStatuses.StartedAt = append(dbStatuses.StartedAt, pgtype.Timestamptz{Time: st.Started, Valid: true})
+		dbStatuses.DurationMs = append(dbStatuses.DurationMs, int32(st.Duration.Milliseconds()))
+		dbStatuses.Succeeded = append(dbStatuses.Succeeded, st.Succeeded())
+		errText := ""
+		if st.Err != nil {
+			errText = st.Err.Error()
+		}
+		dbStatuses.Error = append(dbStatuses.Error, errText)
+		dbStatuses.MeasurementCount = append(dbStatuses.MeasurementCount, int32(st.Measurements))
+	}
+
 	for _, n := range r.Nodes {
 		dbResourceNodes.Slug = append(dbResourceNodes.Slug, n.Slug)
 		dbResourceNodes.Path = append(dbResourceNodes.Path, n.Path)
@@ -107,5 +149,59 @@ func RecordReading(ctx context.Context, logger *slog.Logger, q db.Querier, r rea
 		return err
 	}
 	logger.Debug("created measurements")
-	return err
+	logger.Debug("creating measurement anomalies in database")
+	err = q.BulkCreateMeasurementAnomalies(ctx, dbAnomalies)
+	if err != nil {
+		return err
+	}
+	logger.Debug("creating meter statuses in database")
+	err = q.BulkCreateReadingMeterStatuses(ctx, dbStatuses)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// errorMessages returns the messages of err, or of each error joined in err with [errors.Join]. Newlines in messages are replaced, because they separate messages in the database.
+func errorMessages(err error) []string {
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		msgs := []string{}
+		for _, e := range joined.Unwrap() {
+			msgs = append(msgs, errorMessages(e)...)
+		}
+		return msgs
+	}
+	return []string{strings.ReplaceAll(err.Error(), "\n", " ")}
+}
+
+// AnomalySummary counts the anomalies of one meter in a reading.
+type AnomalySummary struct {
+	Anomalies int `json:"anomalies"`
+	// Value is the sum of the values of measurements with anomalies.
+	Value int64 `json:"value"`
+	// Orphaned counts measurements without a CF org, whose usage cannot be billed.
+	Orphaned      int   `json:"orphaned"`
+	OrphanedValue int64 `json:"orphaned_value"`
+}
+
+// SummarizeAnomalies counts the anomalies in r per meter, as [RecordReading] records them.
+func SummarizeAnomalies(r reader.Reading) map[string]AnomalySummary {
+	out := map[string]AnomalySummary{}
+	for _, m := range r.Measurements {
+		if m.Meter == "" && m.ResourceNaturalID == "" {
+			continue
+		}
+		if m.Errs == nil && m.OrgID != "" {
+			continue
+		}
+		s := out[m.Meter]
+		s.Anomalies++
+		s.Value += int64(m.Value)
+		if m.OrgID == "" {
+			s.Orphaned++
+			s.OrphanedValue += int64(m.Value)
+		}
+		out[m.Meter] = s
+	}
+	return out
 }
@@ -19,7 +19,7 @@ import (
 
 // stubQuerier records the arguments it receives.  If errOn matches the method name being called it returns an error so the test can verify the error-handling path. Only the methods that RecordReading uses are implemented.
 type stubQuerier struct {
-	errOn string // one of: CreateReading, BulkCreateMeters, BulkCreateCFOrgs, BulkCreateResourceKinds, BulkCreateResources, BulkCreateMeasurement
+	errOn string // one of: CreateReading, BulkCreateMeters, BulkCreateCFOrgs, BulkCreateResourceKinds, BulkCreateResources, BulkCreateMeasurement, BulkCreateMeasurementAnomalies, BulkCreateReadingMeterStatuses
 
 	createReadingTS pgtype.Timestamp
 	bulkMeters      []string
@@ -28,6 +28,8 @@ type stubQuerier struct {
 	bulkResources   db.BulkCreateResourcesParams
 	bulkMs          db.BulkCreateMeasurementParams
 	bulkRNodes      db.BulkCreateResourceNodesParams
+	bulkStatuses    db.BulkCreateReadingMeterStatusesParams
+	bulkAnomalies   db.BulkCreateMeasurementAnomaliesParams
 }
 
 var ErrExpected = errors.New("this error was expected")
@@ -164,7 +166,7 @@ func (s *stubQuerier) DeleteResourceKind(_ context.Context, arg db.DeleteResourc
 	panic("unimplemented")
 }
 
-func (s *stubQuerier) DeleteTier(_ context.Context, id int32) error {
+func (s *stubQuerier) DeleteTier(_ context.Context, id int32) (int64, error) {
 	panic("unimplemented")
 }
 
@@ -284,7 +286,7 @@ func (s *stubQuerier) UpdateCFOrg(_ context.Context, arg db.UpdateCFOrgParams) e
 	panic("unimplemented")
 }
 
-func (s *stubQuerier) UpdateCustomer(_ context.Context, arg db.UpdateCustomerParams) error {
+func (s *stubQuerier) UpdateCustomer(_ context.Context, arg db.UpdateCustomerParams) (db.Customer, error) {
 	panic("unimplemented")
 }
 
@@ -296,7 +298,7 @@ func (s *stubQuerier) UpdateResource(_ context.Context, arg db.UpdateResourcePar
 	panic("unimplemented")
 }
 
-func (s *stubQuerier) UpdateTier(_ context.Context, arg db.UpdateTierParams) error {
+func (s *stubQuerier) UpdateTier(_ context.Context, arg db.UpdateTierParams) (db.Tier, error) {
 	panic("unimplemented")
 }
 
@@ -304,6 +306,278 @@ func (s *stubQuerier) UpsertResource(_ context.Context, arg db.UpsertResourcePar
 	panic("unimplemented")
 }
 
+func (s *stubQuerier) ListTransactionSummaries(_ context.Context, ids []int32) ([]db.ListTransactionSummariesRow, error) {
+	panic("unimplemented")
+}
+
+func (s *stubQuerier) ListUsagePostIDs(_ context.Context, periodEnd pgtype.Timestamptz) ([]int32, error) {
+	panic("unimplemented")
+}
+
+func (s *stubQuerier) ReverseUsage(_ context.Context, asOf pgtype.Timestamptz) ([]pgtype.Int4, error) {
+	panic("unimplemented")
+}
+
+func (s *stubQuerier) AdjustTransaction(_ context.Context, arg db.AdjustTransactionParams) (int32, error) {
+	panic("unimplemented")
+}
+
+func (s *stubQuerier) ListTransactionCorrections(_ context.Context, originalTransactionID pgtype.Int4) ([]db.Transaction, error) {
+	panic("unimplemented")
+}
+
+func (s *stubQuerier) ReverseTransaction(_ context.Context, arg db.ReverseTransactionParams) (int32, error) {
+	panic("unimplemented")
+}
+
+func (s *stubQuerier) CreateIAA(_ context.Context, arg db.CreateIAAParams) (db.IAA, error) {
+	panic("unimplemented")
+}
+
+func (s *stubQuerier) GetIAA(_ context.Context, id int32) (db.IAA, error) {
+	panic("unimplemented")
+}
+
+func (s *stubQuerier) GetIAAForUpdate(_ context.Context, id int32) (db.IAA, error) {
+	panic("unimplemented")
+}
+
+func (s *stubQuerier) ListIAAs(_ context.Context, customerID pgtype.UUID) ([]db.IAA, error) {
+	panic("unimplemented")
+}
+
+func (s *stubQuerier) PostIAAPop(_ context.Context, asOf pgtype.Timestamptz) ([]pgtype.Int4, error) {
+	panic("unimplemented")
+}
+
+func (s *stubQuerier) UpdateIAA(_ context.Context, arg db.UpdateIAAParams) (db.IAA, error) {
+	panic("unimplemented")
+}
+
+func (s *stubQuerier) GetCustomerBalances(_ context.Context, customerID pgtype.UUID) ([]db.GetCustomerBalancesRow, error) {
+	panic("unimplemented")
+}
+
+func (s *stubQuerier) ListCustomerStatement(_ context.Context, arg db.ListCustomerStatementParams) ([]db.ListCustomerStatementRow, error) {
+	panic("unimplemented")
+}
+
+func (s *stubQuerier) ListCustomersByCFOrgIDs(_ context.Context, cfOrgIds []pgtype.UUID) ([]db.Customer, error) {
+	panic("unimplemented")
+}
+
+func (s *stubQuerier) CreateAlert(_ context.Context, arg db.CreateAlertParams) (db.Alert, error) {
+	panic("unimplemented")
+}
+
+func (s *stubQuerier) CreateAlertThresholds(_ context.Context, arg db.CreateAlertThresholdsParams) error {
+	panic("unimplemented")
+}
+
+func (s *stubQuerier) DeleteAlertThresholds(_ context.Context, customerID pgtype.UUID) error {
+	panic("unimplemented")
+}
+
+func (s *stubQuerier) ListAlertThresholds(_ context.Context, customerID pgtype.UUID) ([]db.AlertThreshold, error) {
+	panic("unimplemented")
+}
+
+func (s *stubQuerier) ListAlerts(_ context.Context, arg db.ListAlertsParams) ([]db.ListAlertsRow, error) {
+	panic("unimplemented")
+}
+
+func (s *stubQuerier) ListCustomerFunding(_ context.Context, arg db.ListCustomerFundingParams) ([]db.ListCustomerFundingRow, error) {
+	panic("unimplemented")
+}
+
+func (s *stubQuerier) ListUnnotifiedAlerts(_ context.Context) ([]db.ListUnnotifiedAlertsRow, error) {
+	panic("unimplemented")
+}
+
+func (s *stubQuerier) MarkAlertNotified(_ context.Context, arg db.MarkAlertNotifiedParams) error {
+	panic("unimplemented")
+}
+
+func (s *stubQuerier) PostTierGrants(_ context.Context, asOf pgtype.Timestamptz) ([]pgtype.Int4, error) {
+	panic("unimplemented")
+}
+
+func (s *stubQuerier) SetCustomerTier(_ context.Context, arg db.SetCustomerTierParams) (db.Customer, error) {
+	panic("unimplemented")
+}
+
+func (s *stubQuerier) ListCFOrgsByCustomer(_ context.Context, customerID pgtype.UUID) ([]db.CFOrg, error) {
+	panic("unimplemented")
+}
+
+func (s *stubQuerier) ListOrphanCFOrgs(_ context.Context) ([]db.ListOrphanCFOrgsRow, error) {
+	panic("unimplemented")
+}
+
+func (s *stubQuerier) SetCFOrgCustomer(_ context.Context, arg db.SetCFOrgCustomerParams) (db.CFOrg, error) {
+	panic("unimplemented")
+}
+
+func (s *stubQuerier) SyncCFOrg(_ context.Context, arg db.SyncCFOrgParams) (db.CFOrg, error) {
+	panic("unimplemented")
+}
+
+func (s *stubQuerier) ClosePrice(_ context.Context, arg db.ClosePriceParams) (db.Price, error) {
+	panic("unimplemented")
+}
+
+func (s *stubQuerier) CountPricedMeasurements(_ context.Context, arg db.CountPricedMeasurementsParams) (int64, error) {
+	panic("unimplemented")
+}
+
+func (s *stubQuerier) CreatePrice(_ context.Context, arg db.CreatePriceParams) (db.Price, error) {
+	panic("unimplemented")
+}
+
+func (s *stubQuerier) GetPrice(_ context.Context, id int32) (db.Price, error) {
+	panic("unimplemented")
+}
+
+func (s *stubQuerier) ListPrices(_ context.Context, arg db.ListPricesParams) ([]db.Price, error) {
+	panic("unimplemented")
+}
+
+func (s *stubQuerier) DeprecateResourceKinds(_ context.Context, arg db.DeprecateResourceKindsParams) ([]db.ResourceKind, error) {
+	panic("unimplemented")
+}
+
+func (s *stubQuerier) ListUnpricedResourceKinds(_ context.Context) ([]db.ListUnpricedResourceKindsRow, error) {
+	panic("unimplemented")
+}
+
+func (s *stubQuerier) SyncResourceKind(_ context.Context, arg db.SyncResourceKindParams) (db.ResourceKind, error) {
+	panic("unimplemented")
+}
+
+func (s *stubQuerier) GetLatestReadingTime(_ context.Context, meter string) (pgtype.Timestamptz, error) {
+	panic("unimplemented")
+}
+
+func (s *stubQuerier) CloseUsageEventIntervals(_ context.Context, arg db.CloseUsageEventIntervalsParams) error {
+	panic("unimplemented")
+}
+
+func (s *stubQuerier) DeleteUsageEventIntervals(_ context.Context, arg db.DeleteUsageEventIntervalsParams) (int64, error) {
+	panic("unimplemented")
+}
+
+func (s *stubQuerier) GetUsageEventCheckpoint(_ context.Context, arg db.GetUsageEventCheckpointParams) (db.UsageEventCheckpoint, error) {
+	panic("unimplemented")
+}
+
+func (s *stubQuerier) ListUsageEventIntervals(_ context.Context, arg db.ListUsageEventIntervalsParams) ([]db.UsageEventInterval, error) {
+	panic("unimplemented")
+}
+
+func (s *stubQuerier) OpenUsageEventInterval(_ context.Context, arg db.OpenUsageEventIntervalParams) error {
+	panic("unimplemented")
+}
+
+func (s *stubQuerier) UpsertUsageEventCheckpoint(_ context.Context, arg db.UpsertUsageEventCheckpointParams) error {
+	panic("unimplemented")
+}
+
+func (s *stubQuerier) BulkCreateReadingMeterStatuses(_ context.Context, arg db.BulkCreateReadingMeterStatusesParams) error {
+	if s.errOn == "BulkCreateReadingMeterStatuses" {
+		return ErrExpected
+	}
+	s.bulkStatuses = arg
+	return nil
+}
+
+func (s *stubQuerier) ListReadingMeterStatuses(_ context.Context, readingID int32) ([]db.ReadingMeterStatus, error) {
+	panic("unimplemented")
+}
+
+func (s *stubQuerier) CanReconstructAppMemory(_ context.Context, at pgtype.Timestamptz) (pgtype.Bool, error) {
+	panic("unimplemented")
+}
+
+func (s *stubQuerier) CountPricedReadingMeasurements(_ context.Context, readingID int32) (int64, error) {
+	panic("unimplemented")
+}
+
+func (s *stubQuerier) CreateBackfillReading(_ context.Context, arg db.CreateBackfillReadingParams) (db.Reading, error) {
+	panic("unimplemented")
+}
+
+func (s *stubQuerier) GetReadingAfter(_ context.Context, after pgtype.Timestamptz) (db.Reading, error) {
+	panic("unimplemented")
+}
+
+func (s *stubQuerier) GetReadingBefore(_ context.Context, before pgtype.Timestamptz) (db.Reading, error) {
+	panic("unimplemented")
+}
+
+func (s *stubQuerier) InterpolateMeasurements(_ context.Context, arg db.InterpolateMeasurementsParams) (int64, error) {
+	panic("unimplemented")
+}
+
+func (s *stubQuerier) ListReadingGaps(_ context.Context, arg db.ListReadingGapsParams) ([]pgtype.Timestamptz, error) {
+	panic("unimplemented")
+}
+
+func (s *stubQuerier) ReconstructAppMemoryMeasurements(_ context.Context, arg db.ReconstructAppMemoryMeasurementsParams) (int64, error) {
+	panic("unimplemented")
+}
+
+func (s *stubQuerier) SetReadingProvenance(_ context.Context, arg db.SetReadingProvenanceParams) error {
+	panic("unimplemented")
+}
+
+func (s *stubQuerier) BulkCreateMeasurementAnomalies(_ context.Context, arg db.BulkCreateMeasurementAnomaliesParams) error {
+	if s.errOn == "BulkCreateMeasurementAnomalies" {
+		return ErrExpected
+	}
+	s.bulkAnomalies = arg
+	return nil
+}
+
+func (s *stubQuerier) ListMeasurementAnomalies(_ context.Context, arg db.ListMeasurementAnomaliesParams) ([]db.MeasurementAnomaly, error) {
+	panic("unimplemented")
+}
+
+func (s *stubQuerier) SummarizeMeasurementAnomalies(_ context.Context, arg db.SummarizeMeasurementAnomaliesParams) ([]db.SummarizeMeasurementAnomaliesRow, error) {
+	panic("unimplemented")
+}
+
+func (s *stubQuerier) CreateInvoice(_ context.Context, transactionID int32) (int32, error) {
+	panic("unimplemented")
+}
+
+func (s *stubQuerier) GetInvoice(_ context.Context, id int32) (db.GetInvoiceRow, error) {
+	panic("unimplemented")
+}
+
+func (s *stubQuerier) GetInvoiceByNumber(_ context.Context, number int32) (db.GetInvoiceByNumberRow, error) {
+	panic("unimplemented")
+}
+
+func (s *stubQuerier) ListInvoiceLines(_ context.Context, invoiceID int32) ([]db.InvoiceLine, error) {
+	panic("unimplemented")
+}
+
+func (s *stubQuerier) ListInvoices(_ context.Context, arg db.ListInvoicesParams) ([]db.ListInvoicesRow, error) {
+	panic("unimplemented")
+}
+
+func (s *stubQuerier) ListUninvoicedUsagePostIDs(_ context.Context, periodEnd pgtype.Timestamptz) ([]int32, error) {
+	panic("unimplemented")
+}
+
+func (s *stubQuerier) ListResourceCosts(_ context.Context, arg db.ListResourceCostsParams) ([]db.ListResourceCostsRow, error) {
+	panic("unimplemented")
+}
+
+func (s *stubQuerier) CreateAlertDelivery(_ context.Context, arg db.CreateAlertDeliveryParams) error {
+	panic("unimplemented")
+}
+
 type WantedErr int64
 
 const (
@@ -430,6 +704,30 @@ func TestRecordReading(t *testing.T) {
 			NotWanted,
 			1,
 		},
+		{
+			"meter statuses", // statuses add their meters, including failed meters without measurements
+			reader.Reading{
+				Time:         time.Now(),
+				Measurements: []reader.Measurement{goodM},
+				Statuses: []reader.MeterStatus{
+					{Meter: "cpu", Started: time.Now(), Measurements: 1},
+					{Meter: "gpu", Started: time.Now(), Err: ErrExpected},
+				},
+			},
+			"",
+			NotWanted,
+			3,
+		},
+		{
+			"error on BulkCreateReadingMeterStatuses",
+			reader.Reading{
+				Time:     time.Now(),
+				Statuses: []reader.MeterStatus{{Meter: "cpu", Started: time.Now()}},
+			},
+			"BulkCreateReadingMeterStatuses",
+			ErrWanted,
+			1,
+		},
 		{
 			"error on BulkCreateResources",
 			reader.Reading{
@@ -466,6 +764,14 @@ func TestRecordReading(t *testing.T) {
 			if got := len(stub.bulkMeters); got != tc.wantMeters {
 				t.Fatalf("meters: want %d, got %d", tc.wantMeters, got)
 			}
+			if got, want := len(stub.bulkStatuses.Meter), len(tc.reading.Statuses); tc.wantErr == NotWanted && got != want {
+				t.Fatalf("statuses: want %d, got %d", want, got)
+			}
+			for i, st := range tc.reading.Statuses {
+				if tc.wantErr == NotWanted && stub.bulkStatuses.Succeeded[i] != (st.Err == nil) {
+					t.Errorf("status %v: expected succeeded=%v", st.Meter, st.Err == nil)
+				}
+			}
 			// spot-check one other slice so we know they stayed in-sync
 			if len(stub.bulkMeters) != tc.wantMeters {
 				t.Fatalf("expected %v meters passed to database, got %v", len(stub.bulkMeters), tc.wantMeters)
@@ -473,3 +779,53 @@ func TestRecordReading(t *testing.T) {
 		})
 	}
 }
+
+func TestRecordReadingAnomalies(t *testing.T) {
+	goodM := reader.Measurement{
+		Meter:                 "cpu",
+		OrgID:                 uuid.NewString(),
+		ResourceNaturalID:     "inst-1",
+		ResourceKindNaturalID: "kind-1",
+		Value:                 10,
+	}
+	errM := goodM
+	errM.ResourceNaturalID = "inst-2"
+	errM.Errs = errors.Join(errors.New("space not found"), errors.New("line one\nline two"))
+	orphanM := goodM
+	orphanM.ResourceNaturalID = "inst-3"
+	orphanM.OrgID = ""
+	orphanM.Value = 7
+
+	reading := reader.Reading{
+		Time:         time.Now(),
+		Measurements: []reader.Measurement{goodM, errM, orphanM},
+	}
+	stub := &stubQuerier{}
+	nullLogger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	if err := recorder.RecordReading(t.Context(), nullLogger, stub, reading, false); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if got := len(stub.bulkMs.ResourceNaturalID); got != 2 {
+		t.Errorf("measurements: want 2, got %d", got)
+	}
+	if got := len(stub.bulkOrgs); got != 2 {
+		t.Errorf("orgs: want 2, got %d", got)
+	}
+	a := stub.bulkAnomalies
+	if len(a.ResourceNaturalID) != 2 {
+		t.Fatalf("anomalies: want 2, got %d", len(a.ResourceNaturalID))
+	}
+	if a.ResourceNaturalID[0] != "inst-2" || !a.Recorded[0] || a.Errors[0] != "space not found\nline one line two" {
+		t.Errorf("unexpected anomaly for measurement with errors: %v %v %q", a.ResourceNaturalID[0], a.Recorded[0], a.Errors[0])
+	}
+	if a.ResourceNaturalID[1] != "inst-3" || a.Recorded[1] || a.CFOrgID[1].Valid || a.Errors[1] != recorder.ErrNoOrg.Error() {
+		t.Errorf("unexpected anomaly for orphaned measurement: %v %v %q", a.ResourceNaturalID[1], a.Recorded[1], a.Errors[1])
+	}
+
+	summary := recorder.SummarizeAnomalies(reading)
+	want := recorder.AnomalySummary{Anomalies: 2, Value: 17, Orphaned: 1, OrphanedValue: 7}
+	if summary["cpu"] != want {
+		t.Errorf("summary: want %+v, got %+v", want, summary["cpu"])
+	}
+}
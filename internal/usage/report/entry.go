@@ -0,0 +1,184 @@
+package report
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/cloud-gov/billing/internal/db"
+)
+
+var (
+	appReg = regexp.MustCompile(`^app_[^\.]+(\..+)?$`) // Apps and the resources under them.
+	svcReg = regexp.MustCompile(`^svc_[^\.]+$`)
+)
+
+func isApp(s pgtype.Text) bool {
+	return appReg.MatchString(s.String)
+}
+
+func isService(s pgtype.Text) bool {
+	return svcReg.MatchString(s.String)
+}
+
+// Entry is a node of a Report or BasicReport tree, flattened for output.
+type Entry struct {
+	Kind     string   `json:"kind"`
+	Name     string   `json:"name"`
+	UCredits int      `json:"microcredits"`
+	Children []*Entry `json:"children,omitempty"`
+
+	// Ancestors are the names of the entry's ancestors below the root, outermost first.
+	Ancestors []string `json:"-"`
+}
+
+// describe returns the kind, name, and microcredits of a report node.
+func describe(link ReportLinker) (kind, name string, uCredits int) {
+	switch n := link.(type) {
+	case *Report:
+		return "report", "", n.UCreditSum
+	case *ReportNode:
+		return n.Kind, n.Slug, n.UCreditSum
+	case *ReportLeaf:
+		return n.Kind, n.Slug, n.UCreditUse
+	case *BasicReport:
+		return "report", "", n.UCreditsUtilized
+	case *BasicReportOrg:
+		return Org.String(), n.Name, n.UCreditsUtilized
+	case *BasicReportSpace:
+		return Space.String(), n.Space, n.UCreditsUtilized
+	}
+	return fmt.Sprintf("%T", link), "", 0
+}
+
+// Flatten converts a report tree to entries.
+func Flatten(link ReportLinker) *Entry {
+	return flatten(link, nil)
+}
+
+func flatten(link ReportLinker, ancestors []string) *Entry {
+	kind, name, uCredits := describe(link)
+	e := &Entry{Kind: kind, Name: name, UCredits: uCredits, Ancestors: ancestors}
+	childAncestors := ancestors
+	if name != "" {
+		childAncestors = append(append([]string{}, ancestors...), name)
+	}
+	for _, c := range link.getChildren() {
+		e.Children = append(e.Children, flatten(c, childAncestors))
+	}
+	return e
+}
+
+// Walk calls fn for e and each of its descendants, depth first, with their depth below e.
+func Walk(e *Entry, depth int, fn func(e *Entry, depth int) error) error {
+	if err := fn(e, depth); err != nil {
+		return err
+	}
+	for _, c := range e.Children {
+		if err := Walk(c, depth+1, fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Build rolls the rows returned by GetUsageByPath up into a tree: orgs, generalized spaces, specific spaces, then resources. If basic is true, the tree has only orgs and generalized spaces. Rows that fit nowhere in the tree are returned as skipped, so callers can log them.
+func Build(rows []db.GetUsageByPathRow, basic bool) (root ReportLinker, skipped []db.GetUsageByPathRow, err error) {
+	// GetUsageByPath sorts rollup totals after the rows they total, so reversing puts parents before their children.
+	nodes := slices.Clone(rows)
+	slices.Reverse(nodes)
+	var report ReportWriter
+	if basic {
+		// The basic report has no nodes below generalized spaces.
+		nodes = slices.DeleteFunc(nodes, func(n db.GetUsageByPathRow) bool { return n.L3.Valid })
+		r := NewBasicReporter().(*BasicReport)
+		report, root = r, r
+	} else {
+		r := NewReporter()
+		report, root = r, r
+	}
+	var link ReportLinker
+	for i, n := range nodes {
+		uCreds, err := n.TotalMicrocredits.Int64Value()
+		if err != nil {
+			return nil, nil, err
+		}
+		uCredsInt := int(uCreds.Int64)
+
+		if i == 0 {
+			switch r := root.(type) {
+			case *Report:
+				r.UCreditSum = uCredsInt
+			case *BasicReport:
+				r.UCreditsUtilized = uCredsInt
+			}
+			continue
+		}
+
+		p := nodes[i-1]
+
+		// org
+		if n.L1.Valid && !n.L2.Valid {
+			// org is always linked to root
+			link, err = report.SetNode(root, uCredsInt, Org, n.L1.String, "")
+			if err != nil {
+				return nil, nil, err
+			}
+			continue
+		}
+
+		// space generalized
+		if n.L2.Valid && !n.L3.Valid {
+			if p.L4.Valid { // go back from leaf > space/s > space/g > org
+				link = link.getParent().getParent().getParent()
+			} else if p.L3.Valid { // go back from space/s > space/g > org
+				link = link.getParent().getParent()
+			} else if p.L2.Valid { // go space/g > org
+				link = link.getParent()
+			}
+			link, err = report.SetNode(link, uCredsInt, Space, n.L2.String, "")
+			if err != nil {
+				return nil, nil, err
+			}
+			continue
+		}
+
+		// space specific
+		if n.L3.Valid && !n.L4.Valid {
+			if p.L4.Valid { // go back from leaf > space/s > space/g
+				link = link.getParent().getParent()
+			} else if p.L3.Valid { // go space/s > space/g
+				link = link.getParent()
+			}
+			link, err = report.SetNode(link, uCredsInt, Space, n.L3.String, "")
+			if err != nil {
+				return nil, nil, err
+			}
+			continue
+		}
+
+		if n.L4.Valid {
+			if p.L4.Valid { // go from leaf > space/s
+				link = link.getParent()
+			}
+
+			var k Kind
+			if isApp(n.L4) {
+				k = CfApp
+			} else if isService(n.L4) {
+				k = CfSvc
+			}
+
+			link, err = report.SetNode(link, uCredsInt, k, n.L4.String, "")
+			if err != nil {
+				return nil, nil, err
+			}
+			continue
+		}
+
+		skipped = append(skipped, n)
+	}
+	return root, skipped, nil
+}
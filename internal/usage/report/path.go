@@ -0,0 +1,62 @@
+package report
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Prefix is the path of the node under which all app and service usage is recorded. Queries built by [Path] only match nodes below it.
+const Prefix = "apps.usage"
+
+// maxLQueryLen limits the length of lqueries, so callers cannot submit patterns that are expensive to match.
+const maxLQueryLen = 256
+
+var ErrInvalidLQuery = errors.New("lquery must be a sequence of .-prefixed levels, each a label pattern like cforg_name% or a star like * or *{1,}, at most 256 characters long")
+
+var (
+	// lqueryLevel matches one level of an lquery: a star with an optional quantifier, or alternative labels with optional modifiers (@ case-insensitive, * prefix, % word prefix), optionally negated and quantified. It is the lquery grammar of Postgres's ltree extension.
+	lqueryLevel = regexp.MustCompile(`^(\*|!?[A-Za-z0-9_-]+[@*%]*(\|[A-Za-z0-9_-]+[@*%]*)*)(\{\d*(,\d*)?\})?$`)
+	// nonWordReg matches the characters replaced with underscores in node slugs. See node.WithSlugAuto.
+	nonWordReg = regexp.MustCompile(`[^\w]`)
+)
+
+// ValidateLQuery returns [ErrInvalidLQuery] unless lq is a valid lquery relative to [Prefix], e.g. .cforg_sandbox%.*. Each level must begin with a dot, so the levels of Prefix cannot be changed, and every query only matches nodes below it.
+func ValidateLQuery(lq string) error {
+	if len(lq) > maxLQueryLen || !strings.HasPrefix(lq, ".") {
+		return ErrInvalidLQuery
+	}
+	for _, level := range strings.Split(lq[1:], ".") {
+		if !lqueryLevel.MatchString(level) {
+			return ErrInvalidLQuery
+		}
+	}
+	return nil
+}
+
+// Path returns the lquery that selects usage below [Prefix]. If lq is given, it is validated with [ValidateLQuery] and supersedes org and space. Otherwise, org and space select organizations and spaces whose names begin with them, and every resource below the selected spaces is matched. They are sanitized like node slugs, so they cannot add patterns to the query.
+func Path(org, space, lq string) (string, error) {
+	if lq != "" {
+		if err := ValidateLQuery(lq); err != nil {
+			return "", err
+		}
+		return Prefix + lq, nil
+	}
+	org = nonWordReg.ReplaceAllString(org, "_")
+	space = nonWordReg.ReplaceAllString(space, "_")
+
+	nodeQuery := strings.Builder{}
+	nodeQuery.WriteString(Prefix)
+
+	// L1
+	fmt.Fprintf(&nodeQuery, ".cforg_%v%%", org)
+
+	// L2/3
+	fmt.Fprintf(&nodeQuery, ".space_%v%%", space)
+
+	// Resources Leaves
+	nodeQuery.WriteString(".*{1,}")
+
+	return nodeQuery.String(), nil
+}
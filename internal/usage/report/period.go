@@ -0,0 +1,64 @@
+package report
+
+import (
+	"errors"
+	"time"
+)
+
+// Periods are the periods GetUsageByPath groups usage by. Federal fiscal years begin on October 1 of the preceding calendar year, e.g. FY2026 began on 2025-10-01.
+var Periods = []string{"day", "week", "month", "quarter", "year", "fiscal_year"}
+
+var (
+	ErrBadPeriod   = errors.New("period must be one of day, week, month, quarter, year, or fiscal_year")
+	ErrBadTime     = errors.New("times must be RFC 3339 timestamps, like 2026-10-01T00:00:00Z, or dates, like 2026-10-01")
+	ErrBadTimeZone = errors.New("time zones must be IANA time zone names, like America/Chicago")
+)
+
+// LoadLocation returns the time zone with the given IANA name. Unlike [time.LoadLocation], it rejects "Local" and the empty string, which Postgres does not recognize.
+func LoadLocation(name string) (*time.Location, error) {
+	if name == "" || name == "Local" {
+		return nil, ErrBadTimeZone
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, ErrBadTimeZone
+	}
+	return loc, nil
+}
+
+// PeriodStart returns the start of the period containing t in loc, moved n periods forward or back. Weeks begin on Monday. It matches the period_start function in Postgres.
+func PeriodStart(t time.Time, period string, loc *time.Location, n int) (time.Time, error) {
+	y, m, d := t.In(loc).Date()
+	switch period {
+	case "day":
+		return time.Date(y, m, d+n, 0, 0, 0, 0, loc), nil
+	case "week":
+		// Weeks start on Monday.
+		offset := (int(time.Date(y, m, d, 0, 0, 0, 0, loc).Weekday()) + 6) % 7
+		return time.Date(y, m, d-offset+7*n, 0, 0, 0, 0, loc), nil
+	case "month":
+		return time.Date(y, m+time.Month(n), 1, 0, 0, 0, 0, loc), nil
+	case "quarter":
+		return time.Date(y, m-(m-1)%3+time.Month(3*n), 1, 0, 0, 0, 0, loc), nil
+	case "year":
+		return time.Date(y+n, time.January, 1, 0, 0, 0, 0, loc), nil
+	case "fiscal_year":
+		if m >= time.October {
+			y++
+		}
+		return time.Date(y-1+n, time.October, 1, 0, 0, 0, 0, loc), nil
+	}
+	return time.Time{}, ErrBadPeriod
+}
+
+// ParseTime parses s as an RFC 3339 timestamp, or as a date, which is midnight at the start of that day in loc.
+func ParseTime(s string, loc *time.Location) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	t, err := time.ParseInLocation(time.DateOnly, s, loc)
+	if err != nil {
+		return time.Time{}, ErrBadTime
+	}
+	return t, nil
+}
@@ -0,0 +1,83 @@
+package report
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPeriodStart(t *testing.T) {
+	chicago, err := time.LoadLocation("America/Chicago")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 03:00 UTC on October 1 is still September 30 in Chicago.
+	ts := time.Date(2026, time.October, 1, 3, 0, 0, 0, time.UTC)
+	cases := []struct {
+		period string
+		loc    *time.Location
+		n      int
+		want   time.Time
+	}{
+		{"day", time.UTC, 0, time.Date(2026, time.October, 1, 0, 0, 0, 0, time.UTC)},
+		{"day", chicago, 0, time.Date(2026, time.September, 30, 0, 0, 0, 0, chicago)},
+		{"week", time.UTC, -1, time.Date(2026, time.September, 21, 0, 0, 0, 0, time.UTC)},
+		{"month", chicago, 1, time.Date(2026, time.October, 1, 0, 0, 0, 0, chicago)},
+		{"quarter", time.UTC, -1, time.Date(2026, time.July, 1, 0, 0, 0, 0, time.UTC)},
+		{"year", time.UTC, 1, time.Date(2027, time.January, 1, 0, 0, 0, 0, time.UTC)},
+		{"fiscal_year", time.UTC, 0, time.Date(2026, time.October, 1, 0, 0, 0, 0, time.UTC)},
+		{"fiscal_year", chicago, 0, time.Date(2025, time.October, 1, 0, 0, 0, 0, chicago)},
+		{"fiscal_year", chicago, -1, time.Date(2024, time.October, 1, 0, 0, 0, 0, chicago)},
+	}
+	for _, tc := range cases {
+		got, err := PeriodStart(ts, tc.period, tc.loc, tc.n)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !got.Equal(tc.want) {
+			t.Errorf("%v in %v, %d: want %v, got %v", tc.period, tc.loc, tc.n, tc.want, got)
+		}
+	}
+	if _, err := PeriodStart(ts, "decade", time.UTC, 0); err != ErrBadPeriod {
+		t.Errorf("expected ErrBadPeriod, got %v", err)
+	}
+}
+
+func TestLoadLocation(t *testing.T) {
+	if loc, err := LoadLocation("America/Chicago"); err != nil || loc.String() != "America/Chicago" {
+		t.Errorf("expected America/Chicago, got %v, %v", loc, err)
+	}
+	for _, name := range []string{"", "Local", "Mars/Olympus_Mons"} {
+		if _, err := LoadLocation(name); err != ErrBadTimeZone {
+			t.Errorf("%q: expected ErrBadTimeZone, got %v", name, err)
+		}
+	}
+}
+
+func TestParseTime(t *testing.T) {
+	chicago, err := time.LoadLocation("America/Chicago")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cases := []struct {
+		s    string
+		want time.Time
+	}{
+		{"2025-10-01", time.Date(2025, time.October, 1, 5, 0, 0, 0, time.UTC)},
+		{"2025-10-01T00:00:00Z", time.Date(2025, time.October, 1, 0, 0, 0, 0, time.UTC)},
+		{"2025-10-01T00:00:00-04:00", time.Date(2025, time.October, 1, 4, 0, 0, 0, time.UTC)},
+	}
+	for _, tc := range cases {
+		got, err := ParseTime(tc.s, chicago)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !got.Equal(tc.want) {
+			t.Errorf("%v: want %v, got %v", tc.s, tc.want, got)
+		}
+	}
+	for _, s := range []string{"", "10/01/2025", "2025-10-01 00:00"} {
+		if _, err := ParseTime(s, chicago); err != ErrBadTime {
+			t.Errorf("%q: expected ErrBadTime, got %v", s, err)
+		}
+	}
+}
@@ -1,4 +1,5 @@
-package main
+// Package report rolls usage up into a tree of orgs, spaces, and resources. It is shared by cmd/usage and the usage API.
+package report
 
 import (
 	"regexp"
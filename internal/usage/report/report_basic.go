@@ -1,10 +1,11 @@
-package main
+package report
 
 import "fmt"
 
 type (
 	BasicReport struct {
 		Orgs []*BasicReportOrg
+		BasicReportLine
 		BasicReportLinker
 	}
 	BasicReportOrg struct {
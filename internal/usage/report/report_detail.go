@@ -1,4 +1,4 @@
-package main
+package report
 
 import "fmt"
 
@@ -18,6 +18,7 @@ type (
 	}
 	ReportLeaf struct {
 		Kind       string
+		Slug       string
 		UCreditUse int
 		ReportLink
 	}
@@ -30,11 +31,7 @@ type (
 )
 
 func (n *ReportNode) getChildren() []ReportLinker {
-	if len(n.Meters) > 0 {
-		return sliceToReportLinkers(n.Meters)
-	} else {
-		return sliceToReportLinkers(n.Nodes)
-	}
+	return append(sliceToReportLinkers(n.Nodes), sliceToReportLinkers(n.Meters)...)
 }
 
 func (n *ReportNode) addChild(link ReportLinker) {
@@ -66,7 +63,7 @@ func (r *Report) SetNode(link ReportLinker, uCredits int, kind any, name, path s
 	var rp rootParenter
 
 	if kk, ok := kind.(Kind); ok && kk.isMeter() { // this could also be implicit by excluding a name & path?
-		rp = &ReportLeaf{Kind: kk.meterName(), UCreditUse: uCredits}
+		rp = &ReportLeaf{Kind: kk.meterName(), Slug: name, UCreditUse: uCredits}
 	} else if ok {
 		rp = &ReportNode{Kind: kk.String(), UCreditSum: uCredits, Slug: name, Path: path}
 	} else if ks, ok := kind.(string); ok {
@@ -0,0 +1,123 @@
+package report
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/cloud-gov/billing/internal/db"
+)
+
+func TestValidateLQuery(t *testing.T) {
+	valid := []string{
+		".cforg_sandbox%.*",
+		".cforg_%.space_dev%.*{1,}",
+		".*",
+		".cforg_a|cforg_b.space_*@.!app_x.*{0,2}",
+	}
+	for _, lq := range valid {
+		if err := ValidateLQuery(lq); err != nil {
+			t.Errorf("%q: want valid, got %v", lq, err)
+		}
+	}
+	invalid := []string{
+		"",
+		"cforg_sandbox",               // Must be relative to the prefix.
+		"|aws.*",                      // Cannot add alternatives to the prefix's last label.
+		".cforg_x.",                   // Empty level.
+		".cforg_x'; drop table x; --", // Not lquery syntax.
+		".cforg_x.*{1,}{2}",
+		"." + string(make([]byte, maxLQueryLen)),
+	}
+	for _, lq := range invalid {
+		if err := ValidateLQuery(lq); err != ErrInvalidLQuery {
+			t.Errorf("%q: want ErrInvalidLQuery, got %v", lq, err)
+		}
+	}
+}
+
+func TestPath(t *testing.T) {
+	cases := []struct {
+		org, space, lq, want string
+	}{
+		{"", "", "", "apps.usage.cforg_%.space_%.*{1,}"},
+		{"sandbox", "dev", "", "apps.usage.cforg_sandbox%.space_dev%.*{1,}"},
+		// Names are sanitized like slugs, so they cannot inject patterns.
+		{"my-org", "a|b.*", "", "apps.usage.cforg_my_org%.space_a_b__%.*{1,}"},
+		{"ignored", "", ".cforg_x%.*", "apps.usage.cforg_x%.*"},
+	}
+	for _, tc := range cases {
+		got, err := Path(tc.org, tc.space, tc.lq)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tc.want {
+			t.Errorf("Path(%q, %q, %q): want %q, got %q", tc.org, tc.space, tc.lq, tc.want, got)
+		}
+	}
+	if _, err := Path("", "", "*"); err != ErrInvalidLQuery {
+		t.Errorf("want ErrInvalidLQuery, got %v", err)
+	}
+}
+
+func usageRow(uCredits int64, levels ...string) db.GetUsageByPathRow {
+	row := db.GetUsageByPathRow{TotalMicrocredits: pgtype.Numeric{Int: big.NewInt(uCredits), Valid: true}}
+	dsts := []*pgtype.Text{&row.L1, &row.L2, &row.L3, &row.L4}
+	for i, l := range levels {
+		*dsts[i] = pgtype.Text{String: l, Valid: true}
+	}
+	return row
+}
+
+// testRows returns usage rows in the order GetUsageByPath returns them: rollup totals after the rows they total.
+func testRows() []db.GetUsageByPathRow {
+	return []db.GetUsageByPathRow{
+		usageRow(1_000_000, "cforg_agency", "space_web", "space_web_prod", "svc_db"),
+		usageRow(2_000_000, "cforg_agency", "space_web", "space_web_prod", "app_frontend"),
+		usageRow(3_000_000, "cforg_agency", "space_web", "space_web_prod"),
+		usageRow(3_000_000, "cforg_agency", "space_web"),
+		usageRow(3_000_000, "cforg_agency"),
+		usageRow(3_000_000),
+	}
+}
+
+func TestBuild(t *testing.T) {
+	root, skipped, err := Build(testRows(), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(skipped) != 0 {
+		t.Errorf("unexpected skipped rows %+v", skipped)
+	}
+	tree := Flatten(root)
+	if tree.UCredits != 3_000_000 || len(tree.Children) != 1 {
+		t.Fatalf("unexpected tree %+v", tree)
+	}
+	org := tree.Children[0]
+	if org.Kind != Org.String() || org.Name != "cforg_agency" {
+		t.Errorf("unexpected org %+v", org)
+	}
+	leaves := org.Children[0].Children[0].Children
+	if len(leaves) != 2 || leaves[0].Name != "app_frontend" || leaves[0].Kind != CfApp.String() || leaves[1].Kind != CfSvc.String() {
+		t.Errorf("unexpected leaves %+v", leaves)
+	}
+	if want := []string{"cforg_agency", "space_web", "space_web_prod"}; len(leaves[0].Ancestors) != 3 || leaves[0].Ancestors[2] != want[2] {
+		t.Errorf("want ancestors %v, got %v", want, leaves[0].Ancestors)
+	}
+}
+
+func TestBuildBasic(t *testing.T) {
+	root, _, err := Build(testRows(), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tree := Flatten(root)
+	if len(tree.Children) != 1 || len(tree.Children[0].Children) != 1 {
+		t.Fatalf("unexpected tree %+v", tree)
+	}
+	space := tree.Children[0].Children[0]
+	if space.Kind != Space.String() || space.Name != "space_web" || len(space.Children) != 0 || space.UCredits != 3_000_000 {
+		t.Errorf("unexpected space %+v", space)
+	}
+}
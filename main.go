@@ -6,6 +6,8 @@ import (
 	"fmt"
 	"io"
 	"log/slog"
+	"net"
+	"net/smtp"
 	"os"
 	"os/signal"
 
@@ -13,6 +15,10 @@ import (
 	cfconfig "github.com/cloudfoundry/go-cfclient/v3/config"
 	"github.com/coreos/go-oidc/v3/oidc"
 	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/clientcmd"
 
 	"github.com/cloud-gov/billing/internal/api"
 	"github.com/cloud-gov/billing/internal/config"
@@ -20,6 +26,7 @@ import (
 	"github.com/cloud-gov/billing/internal/dbx"
 	"github.com/cloud-gov/billing/internal/jobs"
 	"github.com/cloud-gov/billing/internal/migrate"
+	"github.com/cloud-gov/billing/internal/notify"
 	"github.com/cloud-gov/billing/internal/server"
 	"github.com/cloud-gov/billing/internal/usage/meter"
 	"github.com/cloud-gov/billing/internal/usage/reader"
@@ -29,12 +36,14 @@ import (
 var BuildVersion string = "devel"
 
 var (
+	ErrAWSCURSource     = errors.New("creating AWS Cost and Usage Report source")
 	ErrBadConfig        = errors.New("reading config from environment")
 	ErrCFClient         = errors.New("creating Cloud Foundry client")
 	ErrCFConfig         = errors.New("parsing Cloud Foundry connection configuration")
 	ErrCrontab          = errors.New("parsing crontab for periodic job execution")
 	ErrDBConn           = errors.New("connecting to database")
 	ErrDBMigration      = errors.New("migrating the database")
+	ErrKubeClient       = errors.New("creating Kubernetes client")
 	ErrOIDCProvider     = errors.New("discovering OIDC provider")
 	ErrRiverClientNew   = errors.New("creating River client")
 	ErrRiverClientStart = errors.New("starting River client")
@@ -44,6 +53,30 @@ func fmtErr(outer, inner error) error {
 	return fmt.Errorf("%w: %w", outer, inner)
 }
 
+// newCURSource returns the configured source of AWS Cost and Usage Reports, or nil if none is configured.
+func newCURSource(c config.Config) (meter.CURSource, error) {
+	if c.AWSCURDir != "" {
+		return meter.DirCURSource{Dir: c.AWSCURDir}, nil
+	}
+	if c.AWSCURS3Bucket == "" {
+		return nil, nil
+	}
+	// Use static credentials if provided, otherwise fall back to the instance's IAM role.
+	creds := credentials.NewIAM("")
+	if c.AWSCURS3AccessKeyID != "" {
+		creds = credentials.NewStaticV4(c.AWSCURS3AccessKeyID, c.AWSCURS3SecretAccessKey, "")
+	}
+	client, err := minio.New(c.AWSCURS3Endpoint, &minio.Options{
+		Creds:  creds,
+		Secure: !c.AWSCURS3Insecure,
+		Region: c.AWSCURS3Region,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return meter.NewS3CURSource(client, c.AWSCURS3Bucket, c.AWSCURS3Prefix), nil
+}
+
 // run sets up dependencies, migrates the database to the latest
 // migration, calls route registration, and starts the server. It is separate
 // from main so it can return errors conventionally and main can handle them
@@ -94,8 +127,34 @@ func run(ctx context.Context, out io.Writer) error {
 	meters := []reader.Meter{
 		meter.NewCFServiceMeter(logger, mClient, q),
 		meter.NewCFAppMeter(logger, mClient, q),
+		meter.NewCFTaskMeter(logger, mClient, q),
+	}
+	if c.UsageEventMeter {
+		meters = append(meters, meter.NewCFUsageEventMeter(logger, mClient, q))
+	}
+	if c.WorkshopMeter {
+		kubeConfig, err := clientcmd.BuildConfigFromFlags("", c.WorkshopKubeconfig)
+		if err != nil {
+			return fmtErr(ErrKubeClient, err)
+		}
+		kubeClient, err := kubernetes.NewForConfig(kubeConfig)
+		if err != nil {
+			return fmtErr(ErrKubeClient, err)
+		}
+		meters = append(meters, meter.NewWorkshopMeter(logger, kubeClient, q, c.WorkshopOrgLabel))
 	}
-	rdr := reader.New(meters)
+	curSource, err := newCURSource(c)
+	if err != nil {
+		return fmtErr(ErrAWSCURSource, err)
+	}
+	if curSource != nil {
+		meters = append(meters, meter.NewAWSCURMeter(logger, curSource, q, c.AWSCUROrgTag, c.AWSCURDelay))
+	}
+	rdrOpts := []reader.Opt{reader.WithTimeout(c.MeterTimeout)}
+	for name, d := range c.MeterTimeouts {
+		rdrOpts = append(rdrOpts, reader.WithMeterTimeout(name, d))
+	}
+	rdr := reader.New(meters, rdrOpts...)
 
 	logger.Debug("run: initializing OIDC provider for JWT verification")
 	oidcProvider, err := oidc.NewProvider(ctx, c.Issuer)
@@ -103,9 +162,11 @@ func run(ctx context.Context, out io.Writer) error {
 		return fmtErr(ErrOIDCProvider, err)
 	}
 	verifier := oidcProvider.Verifier(&oidc.Config{ClientID: c.CFClientId}) // todo check alg
+	// Customers' tokens are issued to other clients, like the cf CLI, so their audience is not checked. The customer-facing routes check their scope instead.
+	userVerifier := oidcProvider.Verifier(&oidc.Config{SkipClientIDCheck: true})
 
 	logger.Debug("run: initializing River workers and client")
-	riverc, err := jobs.NewClient(conn, logger, q, rdr)
+	riverc, err := jobs.NewClient(conn, logger, q, rdr, newNotifier(c, logger), mClient, c.CFOrgCustomerLabel)
 	if err != nil {
 		return fmtErr(ErrRiverClientNew, err)
 	}
@@ -116,11 +177,31 @@ func run(ctx context.Context, out io.Writer) error {
 	}
 
 	logger.Debug("run: starting web server")
-	srv := server.New(c.Host, c.Port, api.Routes(logger, cfclient, q, riverc, verifier, c), logger)
+	srv := server.New(c.Host, c.Port, api.Routes(logger, cfclient, conn, q, riverc, verifier, userVerifier, c), logger)
 	srv.ListenAndServe(ctx)
 	return nil
 }
 
+// newNotifier returns notifiers that deliver balance alerts by every method configured in c. If none are configured, alerts are logged.
+func newNotifier(c config.Config, logger *slog.Logger) notify.Multi {
+	var m notify.Multi
+	if c.AlertSMTPAddr != "" {
+		var auth smtp.Auth
+		if c.AlertSMTPUsername != "" {
+			host, _, _ := net.SplitHostPort(c.AlertSMTPAddr)
+			auth = smtp.PlainAuth("", c.AlertSMTPUsername, c.AlertSMTPPassword, host)
+		}
+		m = append(m, &notify.SMTP{Addr: c.AlertSMTPAddr, Auth: auth, From: c.AlertSMTPFrom, To: c.AlertSMTPTo})
+	}
+	if c.AlertWebhookURL != "" {
+		m = append(m, &notify.Webhook{URL: c.AlertWebhookURL})
+	}
+	if len(m) == 0 {
+		return notify.Multi{notify.Log{Logger: logger}}
+	}
+	return m
+}
+
 func main() {
 	ctx := context.Background()
 	err := run(ctx, os.Stdout)
@@ -0,0 +1,397 @@
+alter type transaction_type add value if not exists 'adjustment';
+
+comment on type transaction_type is 'TransactionType explains why the transaction was made. Each means:
+  - iaa_pop_start: The IAA Period of Performance started.
+  - iaa_pop_end: The IAA Period of Performance ended.
+  - usage_post: Customer usage of was posted, i.e. their account balance was updated to reflect their usage.
+  - reversal: A previous transaction was undone by posting offsetting entries. original_transaction_id refers to the reversed transaction.
+  - adjustment: The amount of a previous transaction was corrected by posting the difference to the same accounts. original_transaction_id refers to the adjusted transaction.
+';
+
+-- A transaction may be adjusted many times, but reversed only once. The index is created here rather than in 010 because an enum value cannot be used in the transaction that adds it.
+create unique index transaction_reversal_uidx
+on transaction (original_transaction_id)
+where type = 'reversal';
+comment on index transaction_reversal_uidx is 'A transaction can be reversed at most once.';
+
+create index transaction_original_transaction_id_idx
+on transaction (original_transaction_id);
+
+create or replace function adjust_transaction(
+  p_transaction_id int,
+  p_amount_microcredits bigint,
+  p_occurred_at timestamptz default now(),
+  p_description text default null
+)
+returns int
+language plpgsql
+as $$
+declare
+  v_id int;
+  v_amounts int;
+begin
+  if p_amount_microcredits = 0 then
+    raise exception
+      using
+        errcode = '22023', -- invalid_parameter_value
+        message = 'ledger error: adjustment amount must not be zero';
+  end if;
+
+  -- An adjustment changes every entry by the same amount, so it only makes sense when every entry has the same amount.
+  select count(distinct e.amount_microcredits) into v_amounts
+  from entry as e
+  where e.transaction_id = p_transaction_id;
+
+  if v_amounts > 1 then
+    raise exception
+      using
+        errcode = '22023', -- invalid_parameter_value
+        message = format('ledger error: transaction %s cannot be adjusted because its entries have different amounts', p_transaction_id);
+  end if;
+
+  insert into transaction as txn (customer_id, occurred_at, description, type, original_transaction_id)
+  select
+    t.customer_id,
+    p_occurred_at,
+    coalesce(p_description, format('Adjustment of transaction %s', t.id)),
+    'adjustment',
+    t.id
+  from transaction as t
+  where t.id = p_transaction_id
+    and t.type <> 'reversal'
+  returning txn.id into v_id;
+
+  if v_id is null then
+    raise exception
+      using
+        errcode = 'P0002', -- no_data_found
+        message = format('ledger error: transaction %s does not exist or is a reversal', p_transaction_id);
+  end if;
+
+  -- A positive amount moves more credits in the same direction as the original; a negative amount moves credits back.
+  insert into entry (transaction_id, account_id, direction, amount_microcredits)
+  select
+    v_id,
+    e.account_id,
+    e.direction * sign(p_amount_microcredits)::int,
+    abs(p_amount_microcredits)
+  from entry as e
+  where e.transaction_id = p_transaction_id;
+
+  return v_id;
+end $$;
+
+comment on function adjust_transaction is 'adjust_transaction posts a transaction that changes the amount of p_transaction_id by p_amount_microcredits and returns its ID. The original transaction is not modified.';
+
+-- Same as post_usage from 010, but adjustments no longer count as reversals.
+create or replace function post_usage (
+	as_of timestamptz default now()
+)
+returns table(
+	transaction_id integer
+)
+language plpgsql
+as $$
+declare
+	ps timestamptz;
+	pe timestamptz;
+begin
+	select period_start, period_end into ps, pe from bounds_month_prev(as_of);
+
+	return query
+	-- Step 1: Calculate total credits for measurements in period
+	with measurement_totals as (
+		select c.id as customer_id, sum(m.amount_microcredits) as total_amount_microcredits
+		from reading as rd
+		join measurement as m
+		on rd.id = m.reading_id
+		join resource as r
+		on m.meter = r.meter and m.resource_natural_id = r.natural_id
+		join cf_org as o
+		on r.cf_org_id = o.id
+		join customer as c
+		on o.customer_id = c.id
+		where ps <= rd.created_at_utc
+		and rd.created_at_utc < pe
+		and m.amount_microcredits is not null
+		and not exists (
+			select 1
+			from transaction as posted
+			where posted.customer_id = c.id
+			and posted.type = 'usage_post'
+			and posted.occurred_at = pe
+			and not exists (
+				select 1
+				from transaction as rev
+				where rev.original_transaction_id = posted.id
+				and rev.type = 'reversal'
+			)
+		)
+		group by c.id
+	),
+	-- Step 2: Create a transaction row for each customer with nonzero usage
+	ins_tx as (
+		insert into transaction as txn(customer_id, occurred_at, description, type)
+		select
+			mt.customer_id,
+			pe,
+			format('Monthly usage %s--%s', to_char(ps, 'YYYY-MM-DD'), to_char(pe, 'YYYY-MM-DD')),
+			'usage_post'
+		from measurement_totals as mt
+		where mt.total_amount_microcredits <> 0
+		returning txn.id, txn.customer_id
+	),
+	-- Step 3: Insert two entries for each transaction with credits calculated earlier
+	ins_entries as (
+		insert into entry as e(transaction_id, account_id, direction, amount_microcredits)
+		select
+			it.id,
+			ac.account_id,
+			ac.normal,
+			mt.total_amount_microcredits
+		from ins_tx as it
+		join measurement_totals as mt
+		on it.customer_id = mt.customer_id
+		join lateral (
+			select a.id, at.normal
+			from account as a
+			join account_type as at
+			on a.type = at.id
+			where a.customer_id = it.customer_id
+			and (at.name = 'credit_pool' or at.name = 'credits_used')
+			limit 2
+		) as ac(account_id, normal) on true
+		returning e.transaction_id, e.account_id, e.direction, e.amount_microcredits
+	)
+	-- Step 4: Return transaction IDs created by the function
+	select distinct e.transaction_id
+	from ins_entries as e;
+end $$;
+
+create or replace function reverse_usage (
+	as_of timestamptz default now()
+)
+returns table(
+	transaction_id integer
+)
+language plpgsql
+as $$
+declare
+	ps timestamptz;
+	pe timestamptz;
+begin
+	select period_start, period_end into ps, pe from bounds_month_prev(as_of);
+
+	-- Clear calculated amounts so the month is repriced if it is posted again, e.g. after a price correction.
+	update measurement as m
+	set
+		amount_microcredits = null,
+		price_id = null
+	from reading as rd
+	where rd.id = m.reading_id
+	and ps <= rd.created_at_utc
+	and rd.created_at_utc < pe;
+
+	return query
+	select reverse_transaction(
+		t.id,
+		now(),
+		format('Reversal of monthly usage %s--%s', to_char(ps, 'YYYY-MM-DD'), to_char(pe, 'YYYY-MM-DD'))
+	)
+	from transaction as t
+	where (
+		-- Usage posted for the period, and adjustments to it.
+		(t.type = 'usage_post' and t.occurred_at = pe)
+		or (
+			t.type = 'adjustment'
+			and exists (
+				select 1
+				from transaction as orig
+				where orig.id = t.original_transaction_id
+				and orig.type = 'usage_post'
+				and orig.occurred_at = pe
+			)
+		)
+	)
+	and not exists (
+		select 1
+		from transaction as rev
+		where rev.original_transaction_id = t.id
+		and rev.type = 'reversal'
+	)
+	order by t.id;
+end $$;
+
+comment on function reverse_usage is 'reverse_usage reverses all usage posted for the month preceding as_of, including adjustments to it, and returns the IDs of the reversal transactions. This function must be run in a transaction.';
+
+---- create above / drop below ----
+
+drop function if exists adjust_transaction;
+
+-- Restore post_usage and reverse_usage from 010.
+create or replace function post_usage (
+	as_of timestamptz default now()
+)
+returns table(
+	transaction_id integer
+)
+language plpgsql
+as $$
+declare
+	ps timestamptz;
+	pe timestamptz;
+begin
+	select period_start, period_end into ps, pe from bounds_month_prev(as_of);
+
+	return query
+	-- Step 1: Calculate total credits for measurements in period
+	with measurement_totals as (
+		select c.id as customer_id, sum(m.amount_microcredits) as total_amount_microcredits
+		from reading as rd
+		join measurement as m
+		on rd.id = m.reading_id
+		join resource as r
+		on m.meter = r.meter and m.resource_natural_id = r.natural_id
+		join cf_org as o
+		on r.cf_org_id = o.id
+		join customer as c
+		on o.customer_id = c.id
+		where ps <= rd.created_at_utc
+		and rd.created_at_utc < pe
+		and m.amount_microcredits is not null
+		and not exists (
+			select 1
+			from transaction as posted
+			where posted.customer_id = c.id
+			and posted.type = 'usage_post'
+			and posted.occurred_at = pe
+			and not exists (
+				select 1
+				from transaction as rev
+				where rev.original_transaction_id = posted.id
+			)
+		)
+		group by c.id
+	),
+	-- Step 2: Create a transaction row for each customer with nonzero usage
+	ins_tx as (
+		insert into transaction as txn(customer_id, occurred_at, description, type)
+		select
+			mt.customer_id,
+			pe,
+			format('Monthly usage %s--%s', to_char(ps, 'YYYY-MM-DD'), to_char(pe, 'YYYY-MM-DD')),
+			'usage_post'
+		from measurement_totals as mt
+		where mt.total_amount_microcredits <> 0
+		returning txn.id, txn.customer_id
+	),
+	-- Step 3: Insert two entries for each transaction with credits calculated earlier
+	ins_entries as (
+		insert into entry as e(transaction_id, account_id, direction, amount_microcredits)
+		select
+			it.id,
+			ac.account_id,
+			ac.normal,
+			mt.total_amount_microcredits
+		from ins_tx as it
+		join measurement_totals as mt
+		on it.customer_id = mt.customer_id
+		join lateral (
+			select a.id, at.normal
+			from account as a
+			join account_type as at
+			on a.type = at.id
+			where a.customer_id = it.customer_id
+			and (at.name = 'credit_pool' or at.name = 'credits_used')
+			limit 2
+		) as ac(account_id, normal) on true
+		returning e.transaction_id, e.account_id, e.direction, e.amount_microcredits
+	)
+	-- Step 4: Return transaction IDs created by the function
+	select distinct e.transaction_id
+	from ins_entries as e;
+end $$;
+
+create or replace function reverse_usage (
+	as_of timestamptz default now()
+)
+returns table(
+	transaction_id integer
+)
+language plpgsql
+as $$
+declare
+	ps timestamptz;
+	pe timestamptz;
+begin
+	select period_start, period_end into ps, pe from bounds_month_prev(as_of);
+
+	-- Clear calculated amounts so the month is repriced if it is posted again, e.g. after a price correction.
+	update measurement as m
+	set
+		amount_microcredits = null,
+		price_id = null
+	from reading as rd
+	where rd.id = m.reading_id
+	and ps <= rd.created_at_utc
+	and rd.created_at_utc < pe;
+
+	return query
+	select reverse_transaction(
+		t.id,
+		now(),
+		format('Reversal of monthly usage %s--%s', to_char(ps, 'YYYY-MM-DD'), to_char(pe, 'YYYY-MM-DD'))
+	)
+	from transaction as t
+	where t.type = 'usage_post'
+	and t.occurred_at = pe
+	and not exists (
+		select 1
+		from transaction as rev
+		where rev.original_transaction_id = t.id
+	)
+	order by t.id;
+end $$;
+
+comment on function reverse_usage is 'reverse_usage reverses all usage posted for the month preceding as_of and returns the IDs of the reversal transactions. This function must be run in a transaction.';
+
+drop index if exists transaction_original_transaction_id_idx;
+drop index if exists transaction_reversal_uidx;
+
+-- Remove adjustments and any reversals of them before removing the enum value.
+delete from entry as e
+using transaction as t
+where e.transaction_id = t.id
+and (
+  t.type = 'adjustment'
+  or t.original_transaction_id in (select id from transaction where type = 'adjustment')
+);
+
+delete from transaction
+where original_transaction_id in (select id from transaction where type = 'adjustment');
+
+delete from transaction
+where type = 'adjustment';
+
+-- Postgres cannot remove a value from an enum; recreate the type without it.
+alter type transaction_type rename to transaction_type_old;
+
+create type transaction_type as enum (
+  'iaa_pop_start',
+  'iaa_pop_end',
+  'usage_post',
+  'reversal'
+);
+
+alter table transaction
+alter column type type transaction_type
+using type::text::transaction_type;
+
+drop type transaction_type_old;
+
+comment on type transaction_type is 'TransactionType explains why the transaction was made. Each means:
+  - iaa_pop_start: The IAA Period of Performance started.
+  - iaa_pop_end: The IAA Period of Performance ended.
+  - usage_post: Customer usage of was posted, i.e. their account balance was updated to reflect their usage.
+  - reversal: A previous transaction was undone by posting offsetting entries. original_transaction_id refers to the reversed transaction.
+';
@@ -0,0 +1,185 @@
+create table iaa (
+  id serial primary key,
+  customer_id uuid not null references customer (id),
+  number text not null default '',
+  amount_microcredits bigint not null,
+  rolled_over_microcredits bigint not null default 0,
+  pop_start date not null,
+  pop_end date not null,
+  rollover boolean not null default false,
+  start_transaction_id int references transaction (id),
+  end_transaction_id int references transaction (id),
+  ended_at timestamptz,
+  created_at timestamptz not null default now(),
+
+  constraint iaa_amount_check check (amount_microcredits > 0),
+  constraint iaa_rolled_over_check check (rolled_over_microcredits >= 0),
+  constraint iaa_pop_check check (pop_start <= pop_end)
+);
+
+create index iaa_customer_id_idx on iaa (customer_id);
+
+comment on table iaa is 'IAA is an interagency agreement through which a customer purchases credits. Credits are added to the customer''s credit pool when the Period of Performance (PoP) starts and unused credits expire when it ends.';
+comment on column iaa.number is 'Number is the agreement number assigned outside the billing service, e.g. in G-Invoicing.';
+comment on column iaa.amount_microcredits is 'AmountMicrocredits is the number of microcredits purchased through the agreement.';
+comment on column iaa.rolled_over_microcredits is 'RolledOverMicrocredits is the number of unused microcredits carried over from a previous agreement instead of expiring. They expire when this agreement ends.';
+comment on column iaa.pop_start is 'PopStart is the first day of the Period of Performance in business time (America/New_York).';
+comment on column iaa.pop_end is 'PopEnd is the last day of the Period of Performance in business time (America/New_York), inclusive.';
+comment on column iaa.rollover is 'Rollover is true if unused credits should be carried over to the customer''s next agreement when this agreement ends, rather than expiring.';
+comment on column iaa.start_transaction_id is 'StartTransactionID is the iaa_pop_start transaction that added the purchased credits to the customer''s credit pool. It is null until the PoP starts.';
+comment on column iaa.end_transaction_id is 'EndTransactionID is the iaa_pop_end transaction that expired unused credits. It is null until the PoP ends, and remains null if no credits expired.';
+comment on column iaa.ended_at is 'EndedAt is when the end of the PoP was processed. It is null until then.';
+
+create or replace function post_credit_pool_transaction(
+  p_customer_id uuid,
+  p_amount_microcredits bigint,
+  p_occurred_at timestamptz,
+  p_description text,
+  p_type transaction_type
+)
+returns int
+language plpgsql
+as $$
+declare
+  v_id int;
+begin
+  insert into transaction as txn (customer_id, occurred_at, description, type)
+  values (p_customer_id, p_occurred_at, p_description, p_type)
+  returning txn.id into v_id;
+
+  -- Credits added to the pool are owed to the customer as services, so the pool is debited and liabilities are credited. Removing credits does the opposite.
+  insert into entry (transaction_id, account_id, direction, amount_microcredits)
+  select
+    v_id,
+    a.id,
+    case at.name when 'credit_pool' then 1 else -1 end * sign(p_amount_microcredits)::int,
+    abs(p_amount_microcredits)
+  from account as a
+  join account_type as at
+  on a.type = at.id
+  where a.customer_id = p_customer_id
+  and at.name in ('credit_pool', 'liabilities');
+
+  return v_id;
+end $$;
+
+comment on function post_credit_pool_transaction is 'post_credit_pool_transaction posts a transaction that adds p_amount_microcredits to the customer''s credit pool, or removes them if negative, and returns its ID.';
+
+create or replace function post_iaa_pop(
+  as_of timestamptz default now()
+)
+returns table (
+  transaction_id integer
+)
+language plpgsql
+as $$
+declare
+  tz constant text := 'America/New_York';
+  today date := (as_of at time zone tz)::date;
+  agreement iaa%rowtype;
+  v_id int;
+  v_pool bigint;
+  v_reserved bigint;
+  v_unused bigint;
+  v_successor int;
+begin
+  -- Step 1: Add credits for agreements whose PoP has started.
+  for agreement in
+    select *
+    from iaa as i
+    where i.start_transaction_id is null
+    and i.pop_start <= today
+    order by i.pop_start, i.id
+    for update
+  loop
+    v_id := post_credit_pool_transaction(
+      agreement.customer_id,
+      agreement.amount_microcredits,
+      agreement.pop_start::timestamp at time zone tz,
+      format('IAA %s period of performance started', coalesce(nullif(agreement.number, ''), '#' || agreement.id)),
+      'iaa_pop_start'
+    );
+    update iaa set start_transaction_id = v_id where id = agreement.id;
+    transaction_id := v_id;
+    return next;
+  end loop;
+
+  -- Step 2: Expire or roll over unused credits for agreements whose PoP has ended. Credits are assumed to be used in the order their agreements end, so credits in the pool belong to the agreements that end last.
+  for agreement in
+    select *
+    from iaa as i
+    where i.start_transaction_id is not null
+    and i.ended_at is null
+    and i.pop_end < today
+    order by i.pop_end, i.id
+    for update
+  loop
+    select coalesce(sum(e.amount_microcredits * e.direction), 0) into v_pool
+    from entry as e
+    join account as a
+    on e.account_id = a.id
+    join account_type as at
+    on a.type = at.id
+    where a.customer_id = agreement.customer_id
+    and at.name = 'credit_pool';
+
+    select coalesce(sum(
+      case when i.start_transaction_id is not null then i.amount_microcredits else 0 end
+      + i.rolled_over_microcredits
+    ), 0) into v_reserved
+    from iaa as i
+    where i.customer_id = agreement.customer_id
+    and i.id <> agreement.id
+    and i.ended_at is null;
+
+    v_unused := greatest(0, least(
+      agreement.amount_microcredits + agreement.rolled_over_microcredits,
+      v_pool - v_reserved
+    ));
+
+    if agreement.rollover and v_unused > 0 then
+      select i.id into v_successor
+      from iaa as i
+      where i.customer_id = agreement.customer_id
+      and i.id <> agreement.id
+      and i.ended_at is null
+      and i.pop_end > agreement.pop_end
+      order by i.pop_start, i.id
+      limit 1;
+
+      if v_successor is not null then
+        update iaa
+        set rolled_over_microcredits = rolled_over_microcredits + v_unused
+        where id = v_successor;
+        v_unused := 0;
+      end if;
+    end if;
+
+    v_id := null;
+    if v_unused > 0 then
+      v_id := post_credit_pool_transaction(
+        agreement.customer_id,
+        -v_unused,
+        (agreement.pop_end + 1)::timestamp at time zone tz,
+        format('IAA %s period of performance ended', coalesce(nullif(agreement.number, ''), '#' || agreement.id)),
+        'iaa_pop_end'
+      );
+      transaction_id := v_id;
+      return next;
+    end if;
+
+    update iaa
+    set
+      end_transaction_id = v_id,
+      ended_at = now()
+    where id = agreement.id;
+  end loop;
+end $$;
+
+comment on function post_iaa_pop is 'post_iaa_pop adds credits for agreements whose Period of Performance started on or before the business day containing as_of, and expires unused credits for agreements whose PoP ended before it. It returns the IDs of the transactions it created. This function must be run in a transaction.';
+
+---- create above / drop below ----
+
+drop function if exists post_iaa_pop;
+drop function if exists post_credit_pool_transaction;
+drop table if exists iaa;
@@ -0,0 +1,46 @@
+create table alert_threshold (
+  customer_id uuid not null references customer (id),
+  percent_consumed int not null,
+
+  primary key (customer_id, percent_consumed),
+  constraint alert_threshold_percent_check check (percent_consumed between 1 and 100)
+);
+
+comment on table alert_threshold is 'AlertThreshold is a percentage of a customer''s funded credits that, once consumed, causes an alert to be sent. Customers without thresholds use the defaults in the application.';
+comment on column alert_threshold.percent_consumed is 'PercentConsumed is the percentage of funded credits that must be consumed for the alert to be sent.';
+
+create type alert_kind as enum ('consumed', 'projected_exhaustion');
+
+create table alert (
+  id serial primary key,
+  customer_id uuid not null references customer (id),
+  kind alert_kind not null,
+  percent_consumed int not null default 0,
+  funded_microcredits bigint not null,
+  remaining_microcredits bigint not null,
+  daily_burn_microcredits bigint not null,
+  projected_exhaustion_date date,
+  funded_until date,
+  created_at timestamptz not null default now(),
+  notified_at timestamptz,
+
+  constraint alert_uq unique (customer_id, kind, percent_consumed, funded_microcredits)
+);
+
+create index alert_notified_at_idx on alert (created_at) where notified_at is null;
+
+comment on table alert is 'Alert records that a customer''s credits are running low. Each alert is recorded once per threshold and funding level, so alerts are sent again after a customer purchases more credits.';
+comment on column alert.kind is 'Kind is consumed if a percent_consumed threshold was reached, or projected_exhaustion if the customer''s credits are projected to run out before the end of their latest period of performance.';
+comment on column alert.percent_consumed is 'PercentConsumed is the threshold that was reached, for alerts of kind consumed. It is 0 for other kinds.';
+comment on column alert.funded_microcredits is 'FundedMicrocredits is the number of credits provided by the customer''s active agreements when the alert was recorded.';
+comment on column alert.remaining_microcredits is 'RemainingMicrocredits is the customer''s credit pool balance less usage that has been measured but not yet posted.';
+comment on column alert.daily_burn_microcredits is 'DailyBurnMicrocredits is the customer''s average daily usage over the recent window used for projection.';
+comment on column alert.projected_exhaustion_date is 'ProjectedExhaustionDate is the date in business time (America/New_York) on which remaining credits are projected to run out at the daily burn rate. It is null if the customer has no recent usage.';
+comment on column alert.funded_until is 'FundedUntil is the last day of the latest period of performance of the customer''s active agreements.';
+comment on column alert.notified_at is 'NotifiedAt is when the alert was delivered. It is null until then.';
+
+---- create above / drop below ----
+
+drop table if exists alert;
+drop type if exists alert_kind;
+drop table if exists alert_threshold;
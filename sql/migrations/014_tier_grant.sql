@@ -0,0 +1,157 @@
+alter type transaction_type add value if not exists 'tier_grant';
+
+comment on type transaction_type is 'TransactionType explains why the transaction was made. Each means:
+  - iaa_pop_start: The IAA Period of Performance started.
+  - iaa_pop_end: The IAA Period of Performance ended.
+  - usage_post: Customer usage of was posted, i.e. their account balance was updated to reflect their usage.
+  - reversal: A previous transaction was undone by posting offsetting entries. original_transaction_id refers to the reversed transaction.
+  - adjustment: The amount of a previous transaction was corrected by posting the difference to the same accounts. original_transaction_id refers to the adjusted transaction.
+  - tier_grant: The credits included in the customer''s tier were added to their credit pool for the month.
+';
+
+alter table tier
+add constraint tier_credits_check check (tier_credits >= 0);
+
+comment on column tier.tier_credits is 'TierCredits is the number of whole credits granted to each customer in the tier at the start of every month.';
+
+create or replace function post_tier_grants(
+  as_of timestamptz default now()
+)
+returns table (
+  transaction_id integer
+)
+language plpgsql
+as $$
+declare
+  tz constant text := 'America/New_York';
+  month_start timestamptz := date_trunc('month', as_of at time zone tz) at time zone tz;
+  cust record;
+  v_id int;
+begin
+  for cust in
+    select c.id, t.name as tier_name, t.tier_credits
+    from customer as c
+    join tier as t
+    on c.tier_id = t.id
+    where t.tier_credits > 0
+    and not exists (
+      select 1
+      from transaction as granted
+      where granted.customer_id = c.id
+      and granted.type = 'tier_grant'
+      and granted.occurred_at = month_start
+      and not exists (
+        select 1
+        from transaction as rev
+        where rev.original_transaction_id = granted.id
+        and rev.type = 'reversal'
+      )
+    )
+    order by c.id
+    for update of c
+  loop
+    v_id := post_credit_pool_transaction(
+      cust.id,
+      cust.tier_credits * 1000000,
+      month_start,
+      format('%s tier credits for %s', cust.tier_name, to_char(month_start at time zone tz, 'YYYY-MM')),
+      'tier_grant'
+    );
+    transaction_id := v_id;
+    return next;
+  end loop;
+end $$;
+
+comment on function post_tier_grants is 'post_tier_grants adds the credits included in each customer''s tier to their credit pool for the business month containing as_of, and returns the IDs of the transactions it created. Customers who have already received the month''s grant are skipped unless it was reversed. This function must be run in a transaction.';
+
+---- create above / drop below ----
+
+drop function if exists post_tier_grants;
+
+comment on column tier.tier_credits is null;
+
+alter table tier
+drop constraint if exists tier_credits_check;
+
+-- Remove grants and any corrections of them before removing the enum value.
+delete from entry as e
+using transaction as t
+where e.transaction_id = t.id
+and (
+  t.type = 'tier_grant'
+  or t.original_transaction_id in (select id from transaction where type = 'tier_grant')
+);
+
+delete from transaction
+where original_transaction_id in (select id from transaction where type = 'tier_grant');
+
+delete from transaction
+where type = 'tier_grant';
+
+-- Postgres cannot remove a value from an enum; recreate the type without it. The partial index and the function that depend on the type must be recreated with it.
+drop index if exists transaction_reversal_uidx;
+drop function if exists post_credit_pool_transaction;
+
+alter type transaction_type rename to transaction_type_old;
+
+create type transaction_type as enum (
+  'iaa_pop_start',
+  'iaa_pop_end',
+  'usage_post',
+  'reversal',
+  'adjustment'
+);
+
+alter table transaction
+alter column type type transaction_type
+using type::text::transaction_type;
+
+drop type transaction_type_old;
+
+create unique index transaction_reversal_uidx
+on transaction (original_transaction_id)
+where type = 'reversal';
+comment on index transaction_reversal_uidx is 'A transaction can be reversed at most once.';
+
+create or replace function post_credit_pool_transaction(
+  p_customer_id uuid,
+  p_amount_microcredits bigint,
+  p_occurred_at timestamptz,
+  p_description text,
+  p_type transaction_type
+)
+returns int
+language plpgsql
+as $$
+declare
+  v_id int;
+begin
+  insert into transaction as txn (customer_id, occurred_at, description, type)
+  values (p_customer_id, p_occurred_at, p_description, p_type)
+  returning txn.id into v_id;
+
+  -- Credits added to the pool are owed to the customer as services, so the pool is debited and liabilities are credited. Removing credits does the opposite.
+  insert into entry (transaction_id, account_id, direction, amount_microcredits)
+  select
+    v_id,
+    a.id,
+    case at.name when 'credit_pool' then 1 else -1 end * sign(p_amount_microcredits)::int,
+    abs(p_amount_microcredits)
+  from account as a
+  join account_type as at
+  on a.type = at.id
+  where a.customer_id = p_customer_id
+  and at.name in ('credit_pool', 'liabilities');
+
+  return v_id;
+end $$;
+
+comment on function post_credit_pool_transaction is 'post_credit_pool_transaction posts a transaction that adds p_amount_microcredits to the customer''s credit pool, or removes them if negative, and returns its ID.';
+
+comment on type transaction_type is 'TransactionType explains why the transaction was made. Each means:
+  - iaa_pop_start: The IAA Period of Performance started.
+  - iaa_pop_end: The IAA Period of Performance ended.
+  - usage_post: Customer usage of was posted, i.e. their account balance was updated to reflect their usage.
+  - reversal: A previous transaction was undone by posting offsetting entries. original_transaction_id refers to the reversed transaction.
+  - adjustment: The amount of a previous transaction was corrected by posting the difference to the same accounts. original_transaction_id refers to the adjusted transaction.
+';
@@ -0,0 +1,21 @@
+alter table cf_org
+add column suspended boolean not null default false,
+add column labels jsonb not null default '{}',
+add column annotations jsonb not null default '{}',
+add column synced_at timestamptz;
+
+comment on column cf_org.name is 'Name is the name of the organization in Cloud Foundry. It is null until the organization is first synced.';
+comment on column cf_org.suspended is 'Suspended is true if the organization is suspended in Cloud Foundry.';
+comment on column cf_org.labels is 'Labels are the organization''s Cloud Foundry metadata labels, as a JSON object.';
+comment on column cf_org.annotations is 'Annotations are the organization''s Cloud Foundry metadata annotations, as a JSON object.';
+comment on column cf_org.synced_at is 'SyncedAt is when the organization was last synced from Cloud Foundry. It is null if it has never been synced, e.g. because it was deleted before the first sync.';
+
+---- create above / drop below ----
+
+comment on column cf_org.name is null;
+
+alter table cf_org
+drop column if exists synced_at,
+drop column if exists annotations,
+drop column if exists labels,
+drop column if exists suspended;
@@ -0,0 +1,131 @@
+-- Prices for a resource kind must not overlap, or measurements would be priced more than once. Existing overlapping prices must be corrected before this migration can run.
+create extension if not exists btree_gist;
+
+alter table price
+add constraint price_valid_during_excl exclude using gist (
+	meter with =,
+	kind_natural_id with =,
+	valid_during with &&
+),
+add constraint price_valid_during_check check (not isempty(valid_during));
+
+-- Prices created with explicit IDs did not advance the sequence. Advance it so prices can be created with generated IDs.
+select setval(pg_get_serial_sequence('price', 'id'), coalesce(max(id), 0) + 1, false) from price;
+
+comment on column price.valid_during is 'ValidDuring is the period during which the price applies to readings. Periods for the same resource kind do not overlap. An unbounded upper bound means the price applies until a new price is published.';
+
+CREATE OR REPLACE FUNCTION update_measurement_microcredits(
+	as_of timestamptz DEFAULT now()
+)
+RETURNS bigint
+LANGUAGE plpgsql
+AS $$
+DECLARE
+	ps timestamptz;
+	pe timestamptz;
+	updated bigint;
+BEGIN
+	SELECT period_start, period_end INTO ps, pe FROM bounds_month_prev(as_of);
+
+	WITH measurement_amounts AS (
+		SELECT
+			r.meter AS meter,
+			r.natural_id AS resource_natural_id,
+			rd.id AS reading_id,
+			sum(p.microcredits_per_unit * m.value / p.unit) AS amount_microcredits,
+			p.id AS price_id
+		FROM reading rd
+		JOIN measurement AS m
+		ON rd.id = m.reading_id
+		JOIN resource AS r
+		ON m.meter = r.meter AND m.resource_natural_id = r.natural_id
+		JOIN price AS p
+		ON r.meter = p.meter AND r.kind_natural_id = p.kind_natural_id
+		-- Select the price that was valid when the reading was taken. The exclusion constraint on price ensures there is at most one.
+		AND p.valid_during @> rd.created_at_utc
+		WHERE ps <= rd.created_at_utc
+		AND rd.created_at_utc < pe
+		AND m.amount_microcredits IS NULL
+		GROUP BY
+			r.meter,
+			r.natural_id,
+			rd.id,
+			p.id
+	),
+	update_measurements AS (
+		UPDATE measurement AS m
+		SET
+			amount_microcredits = ma.amount_microcredits,
+			price_id = ma.price_id
+		FROM measurement_amounts AS ma
+		WHERE
+			m.meter = ma.meter AND
+			m.resource_natural_id = ma.resource_natural_id AND
+			m.reading_id = ma.reading_id
+		RETURNING 1
+	)
+	SELECT count(*) INTO updated FROM update_measurements;
+	RETURN updated;
+END $$;
+
+---- create above / drop below ----
+
+CREATE OR REPLACE FUNCTION update_measurement_microcredits(
+	as_of timestamptz DEFAULT now()
+)
+RETURNS bigint
+LANGUAGE plpgsql
+AS $$
+DECLARE
+	ps timestamptz;
+	pe timestamptz;
+	updated bigint;
+BEGIN
+	SELECT period_start, period_end INTO ps, pe FROM bounds_month_prev(as_of);
+
+	WITH measurement_amounts AS (
+		SELECT
+			r.meter AS meter,
+			r.natural_id AS resource_natural_id,
+			rd.id AS reading_id,
+			sum(p.microcredits_per_unit * m.value / p.unit) AS amount_microcredits,
+			p.id AS price_id
+		FROM reading rd
+		JOIN measurement AS m
+		ON rd.id = m.reading_id
+		JOIN resource AS r
+		ON m.meter = r.meter AND m.resource_natural_id = r.natural_id
+		JOIN price AS p
+		ON r.meter = p.meter AND r.kind_natural_id = p.kind_natural_id
+		WHERE ps <= rd.created_at_utc
+		AND rd.created_at_utc < pe
+		AND m.amount_microcredits IS NULL
+		GROUP BY
+			r.meter,
+			r.natural_id,
+			rd.id,
+			p.id
+	),
+	update_measurements AS (
+		UPDATE measurement AS m
+		SET
+			amount_microcredits = ma.amount_microcredits,
+			price_id = ma.price_id
+		FROM measurement_amounts AS ma
+		WHERE
+			m.meter = ma.meter AND
+			m.resource_natural_id = ma.resource_natural_id AND
+			m.reading_id = ma.reading_id
+		RETURNING 1
+	)
+	SELECT count(*) INTO updated FROM update_measurements;
+	RETURN updated;
+END $$;
+
+comment on column price.valid_during is null;
+
+alter table price
+drop constraint if exists price_valid_during_check,
+drop constraint if exists price_valid_during_excl;
+
+drop extension if exists btree_gist;
@@ -0,0 +1,21 @@
+alter table resource_kind
+add column broker text,
+add column active boolean not null default true,
+add column deprecated_at timestamptz,
+add column synced_at timestamptz;
+
+comment on column resource_kind.name is 'Name is a human-readable name for the kind. For service plans, it is the name of the service offering followed by the name of the plan.';
+comment on column resource_kind.broker is 'Broker is the name of the service broker that provides the kind, if it is a service plan.';
+comment on column resource_kind.active is 'Active is false if new resources of this kind cannot be created, e.g. because the service plan is not available in Cloud Foundry.';
+comment on column resource_kind.deprecated_at is 'DeprecatedAt is when the kind was first found to be missing from the catalog of the system it is read from. It is null if the kind is still in the catalog. Existing resources of a deprecated kind may still be measured.';
+comment on column resource_kind.synced_at is 'SyncedAt is when the kind was last synced from the catalog of the system it is read from. It is null if the kind has never been synced.';
+
+---- create above / drop below ----
+
+comment on column resource_kind.name is null;
+
+alter table resource_kind
+drop column if exists synced_at,
+drop column if exists deprecated_at,
+drop column if exists active,
+drop column if exists broker;
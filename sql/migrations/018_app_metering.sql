@@ -0,0 +1,156 @@
+alter table resource_kind
+add column unit_of_measure text,
+add column accrues boolean not null default false;
+
+comment on column resource_kind.unit_of_measure is 'UnitOfMeasure is the unit of the values of measurements of this kind, e.g. MB.';
+comment on column resource_kind.accrues is 'Accrues is true if measurements of this kind are a level of allocation, like MB of memory, that accrues cost over time. When priced, they are multiplied by the hours since the previous reading, so prices for the kind are per unit-hour. Other measurements are priced once per reading.';
+
+-- Kinds measured by the cfapps meter. Each app has one resource of each kind.
+insert into meter (name) values ('cfapps') on conflict do nothing;
+
+insert into resource_kind (meter, natural_id, name, unit_of_measure, accrues)
+values
+	('cfapps', 'memory', 'App memory', 'MB', true),
+	('cfapps', 'disk', 'App disk', 'MB', true),
+	('cfapps', 'log-rate', 'App log rate limit', 'KiB/s', true)
+on conflict (meter, natural_id) do update
+set
+	name = excluded.name,
+	unit_of_measure = excluded.unit_of_measure,
+	accrues = excluded.accrues;
+
+CREATE OR REPLACE FUNCTION update_measurement_microcredits(
+	as_of timestamptz DEFAULT now()
+)
+RETURNS bigint
+LANGUAGE plpgsql
+AS $$
+DECLARE
+	ps timestamptz;
+	pe timestamptz;
+	updated bigint;
+BEGIN
+	SELECT period_start, period_end INTO ps, pe FROM bounds_month_prev(as_of);
+
+	WITH reading_intervals AS (
+		-- The interval of a reading is the time since the previous reading, or one hour for the first reading. Readings are usually hourly, but may be late or missing.
+		SELECT
+			id,
+			created_at_utc,
+			coalesce(
+				extract(epoch FROM created_at_utc - lag(created_at_utc) OVER (ORDER BY created_at_utc)),
+				3600
+			) AS interval_seconds
+		FROM reading
+		WHERE created_at_utc < pe
+	),
+	measurement_amounts AS (
+		SELECT
+			r.meter AS meter,
+			r.natural_id AS resource_natural_id,
+			rd.id AS reading_id,
+			sum(
+				CASE
+					-- Accruing kinds are priced per unit-hour.
+					WHEN k.accrues THEN floor(p.microcredits_per_unit * m.value * rd.interval_seconds / (p.unit * 3600))
+					ELSE p.microcredits_per_unit * m.value / p.unit
+				END
+			) AS amount_microcredits,
+			p.id AS price_id
+		FROM reading_intervals rd
+		JOIN measurement AS m
+		ON rd.id = m.reading_id
+		JOIN resource AS r
+		ON m.meter = r.meter AND m.resource_natural_id = r.natural_id
+		JOIN resource_kind AS k
+		ON r.meter = k.meter AND r.kind_natural_id = k.natural_id
+		JOIN price AS p
+		ON r.meter = p.meter AND r.kind_natural_id = p.kind_natural_id
+		-- Select the price that was valid when the reading was taken. The exclusion constraint on price ensures there is at most one.
+		AND p.valid_during @> rd.created_at_utc
+		WHERE ps <= rd.created_at_utc
+		AND rd.created_at_utc < pe
+		AND m.amount_microcredits IS NULL
+		GROUP BY
+			r.meter,
+			r.natural_id,
+			rd.id,
+			p.id
+	),
+	update_measurements AS (
+		UPDATE measurement AS m
+		SET
+			amount_microcredits = ma.amount_microcredits,
+			price_id = ma.price_id
+		FROM measurement_amounts AS ma
+		WHERE
+			m.meter = ma.meter AND
+			m.resource_natural_id = ma.resource_natural_id AND
+			m.reading_id = ma.reading_id
+		RETURNING 1
+	)
+	SELECT count(*) INTO updated FROM update_measurements;
+	RETURN updated;
+END $$;
+
+---- create above / drop below ----
+
+CREATE OR REPLACE FUNCTION update_measurement_microcredits(
+	as_of timestamptz DEFAULT now()
+)
+RETURNS bigint
+LANGUAGE plpgsql
+AS $$
+DECLARE
+	ps timestamptz;
+	pe timestamptz;
+	updated bigint;
+BEGIN
+	SELECT period_start, period_end INTO ps, pe FROM bounds_month_prev(as_of);
+
+	WITH measurement_amounts AS (
+		SELECT
+			r.meter AS meter,
+			r.natural_id AS resource_natural_id,
+			rd.id AS reading_id,
+			sum(p.microcredits_per_unit * m.value / p.unit) AS amount_microcredits,
+			p.id AS price_id
+		FROM reading rd
+		JOIN measurement AS m
+		ON rd.id = m.reading_id
+		JOIN resource AS r
+		ON m.meter = r.meter AND m.resource_natural_id = r.natural_id
+		JOIN price AS p
+		ON r.meter = p.meter AND r.kind_natural_id = p.kind_natural_id
+		-- Select the price that was valid when the reading was taken. The exclusion constraint on price ensures there is at most one.
+		AND p.valid_during @> rd.created_at_utc
+		WHERE ps <= rd.created_at_utc
+		AND rd.created_at_utc < pe
+		AND m.amount_microcredits IS NULL
+		GROUP BY
+			r.meter,
+			r.natural_id,
+			rd.id,
+			p.id
+	),
+	update_measurements AS (
+		UPDATE measurement AS m
+		SET
+			amount_microcredits = ma.amount_microcredits,
+			price_id = ma.price_id
+		FROM measurement_amounts AS ma
+		WHERE
+			m.meter = ma.meter AND
+			m.resource_natural_id = ma.resource_natural_id AND
+			m.reading_id = ma.reading_id
+		RETURNING 1
+	)
+	SELECT count(*) INTO updated FROM update_measurements;
+	RETURN updated;
+END $$;
+
+-- Kinds are not deleted because resources and prices may refer to them.
+
+alter table resource_kind
+drop column if exists accrues,
+drop column if exists unit_of_measure;
@@ -0,0 +1,15 @@
+-- Kinds measured by the cftasks meter. Each task has one resource. Its measurements are the memory-minutes the task used since the previous reading, so they are priced once per reading rather than accruing.
+insert into meter (name) values ('cftasks') on conflict do nothing;
+
+insert into resource_kind (meter, natural_id, name, unit_of_measure, accrues)
+values
+	('cftasks', 'memory', 'Task memory', 'MB-minutes', false)
+on conflict (meter, natural_id) do update
+set
+	name = excluded.name,
+	unit_of_measure = excluded.unit_of_measure,
+	accrues = excluded.accrues;
+
+---- create above / drop below ----
+
+-- The meter and kind are not deleted because resources and prices may refer to them.
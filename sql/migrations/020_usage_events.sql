@@ -0,0 +1,49 @@
+-- The cfusageevents meter measures apps and services from CF usage events. App and task memory is measured in MB-minutes since the previous reading; services are measured in instance-minutes of each plan.
+insert into meter (name) values ('cfusageevents') on conflict do nothing;
+
+insert into resource_kind (meter, natural_id, name, unit_of_measure, accrues)
+values
+	('cfusageevents', 'memory', 'App memory (usage events)', 'MB-minutes', false)
+on conflict (meter, natural_id) do update
+set
+	name = excluded.name,
+	unit_of_measure = excluded.unit_of_measure,
+	accrues = excluded.accrues;
+
+create table usage_event_checkpoint (
+	meter text not null references meter (name),
+	source text not null,
+	last_event_guid text not null,
+	last_event_at timestamptz not null,
+	updated_at timestamptz not null default now(),
+	primary key (meter, source)
+);
+
+comment on table usage_event_checkpoint is 'UsageEventCheckpoint records the last usage event a meter processed from each source, e.g. CF app usage events, so the next reading only requests newer events.';
+comment on column usage_event_checkpoint.source is 'Source is the stream of events the checkpoint is for, e.g. app or service.';
+
+create table usage_event_interval (
+	meter text not null references meter (name),
+	resource_natural_id text not null,
+	kind_natural_id text not null,
+	value int not null,
+	started_at timestamptz not null,
+	stopped_at timestamptz,
+	cf_org_id uuid,
+	space_guid text not null,
+	space_name text not null,
+	name text not null,
+	primary key (meter, resource_natural_id, started_at),
+	constraint usage_event_interval_check check (stopped_at is null or stopped_at >= started_at)
+);
+
+create index usage_event_interval_open_idx on usage_event_interval (meter, resource_natural_id) where stopped_at is null;
+
+comment on table usage_event_interval is 'UsageEventInterval is a period during which a resource had a constant value, e.g. memory allocated to a process, as derived from usage events. A resource has a new interval each time its value changes. Intervals are kept until no reading can include them.';
+comment on column usage_event_interval.stopped_at is 'StoppedAt is when the resource stopped or its value changed. It is null if the interval is open.';
+comment on column usage_event_interval.name is 'Name is a human-readable name for the resource, used to create its resource node.';
+
+---- create above / drop below ----
+
+drop table if exists usage_event_interval;
+drop table if exists usage_event_checkpoint;
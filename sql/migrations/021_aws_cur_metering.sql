@@ -0,0 +1,17 @@
+-- Kinds measured by the awscur meter. Kinds are AWS product codes. Measurements are the cost, in millionths of a dollar, of a resource's usage since the previous reading, so they are priced once per reading rather than accruing. Kinds for other products are created when first measured.
+insert into meter (name) values ('awscur') on conflict do nothing;
+
+insert into resource_kind (meter, natural_id, name, unit_of_measure, accrues)
+values
+	('awscur', 'AmazonRDS', 'Amazon RDS', 'microdollars', false),
+	('awscur', 'AmazonS3', 'Amazon S3', 'microdollars', false),
+	('awscur', 'AmazonES', 'Amazon OpenSearch Service', 'microdollars', false)
+on conflict (meter, natural_id) do update
+set
+	name = excluded.name,
+	unit_of_measure = excluded.unit_of_measure,
+	accrues = excluded.accrues;
+
+---- create above / drop below ----
+
+-- The meter and kinds are not deleted because resources and prices may refer to them.
@@ -0,0 +1,17 @@
+-- Kinds measured by the workshop meter. Each Kubernetes namespace has one resource of each kind.
+insert into meter (name) values ('workshop') on conflict do nothing;
+
+insert into resource_kind (meter, natural_id, name, unit_of_measure, accrues)
+values
+	('workshop', 'cpu', 'Workshop CPU', 'millicores', true),
+	('workshop', 'memory', 'Workshop memory', 'MB', true),
+	('workshop', 'storage', 'Workshop storage', 'MB', true)
+on conflict (meter, natural_id) do update
+set
+	name = excluded.name,
+	unit_of_measure = excluded.unit_of_measure,
+	accrues = excluded.accrues;
+
+---- create above / drop below ----
+
+-- The meter and kinds are not deleted because resources and prices may refer to them.
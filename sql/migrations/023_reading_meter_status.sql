@@ -0,0 +1,20 @@
+create table reading_meter_status (
+	reading_id int not null references reading (id) on delete cascade,
+	meter text not null references meter (name),
+	started_at timestamptz not null,
+	duration_ms int not null,
+	succeeded boolean not null,
+	error text,
+	measurement_count int not null default 0,
+	primary key (reading_id, meter)
+);
+
+comment on table reading_meter_status is 'ReadingMeterStatus is the result of one meter during a reading. Meters are read concurrently and independently, so a reading may include measurements from some meters and not others. Readings taken before statuses were recorded have none.';
+comment on column reading_meter_status.succeeded is 'Succeeded is false if the meter returned an error or timed out. Measurements from meters that did not succeed are not recorded.';
+comment on column reading_meter_status.error is 'Error is the error returned by the meter, if it did not succeed.';
+
+create index reading_meter_status_meter_idx on reading_meter_status (meter, succeeded);
+
+---- create above / drop below ----
+
+drop table if exists reading_meter_status;
@@ -0,0 +1,10 @@
+alter table reading
+add column provenance text not null default 'measured'
+	check (provenance in ('measured', 'interpolated', 'reconstructed'));
+
+comment on column reading.provenance is 'Provenance is how the reading''s measurements were obtained. Measured readings were read from meters. Interpolated and reconstructed readings were backfilled for hours in which no reading was taken: interpolated measurements are estimated from the readings before and after, and reconstructed measurements are derived from usage events. Usage in backfilled readings is estimated and should be disclosed as such.';
+
+---- create above / drop below ----
+
+alter table reading
+drop column if exists provenance;
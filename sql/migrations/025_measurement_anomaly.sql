@@ -0,0 +1,24 @@
+create table measurement_anomaly (
+	reading_id int not null references reading (id) on delete cascade,
+	meter text not null,
+	resource_natural_id text not null,
+	kind_natural_id text not null,
+	cf_org_id uuid,
+	customer_id uuid,
+	value int not null,
+	errors text[] not null,
+	recorded boolean not null,
+	primary key (reading_id, meter, resource_natural_id)
+);
+
+comment on table measurement_anomaly is 'MeasurementAnomaly is a problem a meter found while measuring a resource, like an app whose space could not be found. Meters return as much of the measurement as they can, so anomalies are kept alongside measurements to show how much usage may be misattributed.';
+comment on column measurement_anomaly.meter is 'Meter and ResourceNaturalID identify the resource. If Recorded is true, they refer to a row in resource and, with ReadingID, a row in measurement. Otherwise the resource could not be recorded.';
+comment on column measurement_anomaly.cf_org_id is 'CFOrgID is the CF org the meter attributed the usage to. It is null if the meter could not determine the org, i.e. the usage is orphaned.';
+comment on column measurement_anomaly.errors is 'Errors are the messages of the errors the meter returned for the measurement.';
+comment on column measurement_anomaly.recorded is 'Recorded is true if the measurement was recorded. Measurements without a CF org cannot be recorded, so they are only recorded as anomalies.';
+
+create index measurement_anomaly_meter_idx on measurement_anomaly (meter, reading_id);
+
+---- create above / drop below ----
+
+drop table if exists measurement_anomaly;
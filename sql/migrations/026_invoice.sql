@@ -0,0 +1,230 @@
+create table invoice (
+	id serial primary key,
+	number int not null unique,
+	transaction_id int not null unique references transaction (id),
+	customer_id uuid not null references customer (id),
+	customer_name text not null,
+	iaa_number text,
+	period_start timestamptz not null,
+	period_end timestamptz not null,
+	usage_microcredits bigint not null,
+	credit_pool_before_microcredits bigint not null,
+	credit_pool_after_microcredits bigint not null,
+	created_at timestamptz not null default now()
+);
+
+create index invoice_customer_id_idx on invoice (customer_id, period_end);
+
+comment on table invoice is 'Invoice documents the usage a customer was charged for in a closed month. One invoice is created for each usage_post transaction, and invoices are never modified: if the transaction is reversed and the month is closed again, the new transaction gets a new invoice.';
+comment on column invoice.number is 'Number identifies the invoice to customers. Invoices are numbered sequentially without gaps, in the order they are created.';
+comment on column invoice.transaction_id is 'TransactionID is the usage_post transaction the invoice documents.';
+comment on column invoice.customer_name is 'CustomerName is the name of the customer when the invoice was created.';
+comment on column invoice.iaa_number is 'IAANumber is the number of the customer''s interagency agreement whose Period of Performance included the last day of the period, if any.';
+comment on column invoice.usage_microcredits is 'UsageMicrocredits is the amount of the transaction: the credits drawn from the customer''s credit pool for the period. It is the sum of the amounts of the invoice''s lines.';
+comment on column invoice.credit_pool_before_microcredits is 'CreditPoolBeforeMicrocredits is the balance of the customer''s credit_pool account before the transaction, following the conventions of GetCustomerBalances.';
+comment on column invoice.credit_pool_after_microcredits is 'CreditPoolAfterMicrocredits is the balance of the customer''s credit_pool account after the transaction.';
+
+create table invoice_line (
+	invoice_id int not null references invoice (id),
+	line int not null,
+	cf_org_id uuid not null,
+	cf_org_name text not null,
+	space text not null,
+	meter text not null,
+	kind_natural_id text not null,
+	kind_name text not null,
+	price_id int not null references price (id),
+	unit_of_measure text not null,
+	accrues boolean not null,
+	microcredits_per_unit bigint not null,
+	unit bigint not null,
+	quantity numeric(20, 4) not null,
+	amount_microcredits bigint not null,
+	primary key (invoice_id, line)
+);
+
+comment on table invoice_line is 'InvoiceLine is the usage of one resource kind in one CF org and space, at one price, on an invoice.';
+comment on column invoice_line.cf_org_name is 'CFOrgName is the name of the CF org when the invoice was created, or its ID if the org has not been synced.';
+comment on column invoice_line.space is 'Space is the slug of the CF space of the resources, as recorded in their resource nodes. It is empty for resources that do not belong to a space, like AWS resources.';
+comment on column invoice_line.accrues is 'Accrues is true if the kind accrues cost over time, so Quantity is in unit-hours.';
+comment on column invoice_line.microcredits_per_unit is 'MicrocreditsPerUnit and Unit are the unit price of the line: MicrocreditsPerUnit microcredits per Unit units of measure, or unit-hours if the kind accrues.';
+comment on column invoice_line.quantity is 'Quantity is the total measured value, in units of measure, or unit-hours if the kind accrues.';
+
+create or replace function assert_invoice_immutable()
+returns trigger
+language plpgsql
+as $$
+begin
+	raise exception
+		using
+			errcode = '55000', -- object_not_in_prerequisite_state
+			message = format('invoice error: %s cannot be modified; invoices are immutable', tg_table_name);
+end;
+$$;
+
+create trigger invoice_immutable
+before update or delete on invoice
+for each row execute function assert_invoice_immutable();
+
+create trigger invoice_line_immutable
+before update or delete on invoice_line
+for each row execute function assert_invoice_immutable();
+
+create or replace function create_invoice(
+	p_transaction_id int
+)
+returns int
+language plpgsql
+as $$
+declare
+	v_id int;
+	v_number int;
+	ps timestamptz;
+	pe timestamptz;
+begin
+	-- Serialize invoice creation so numbers are sequential without gaps. A sequence would leave gaps when transactions roll back.
+	lock table invoice in share row exclusive mode;
+
+	select coalesce(max(number), 0) + 1 into v_number from invoice;
+
+	-- post_usage records the end of the period as the time the transaction occurred, so the period is the month preceding it.
+	select b.period_start, b.period_end into ps, pe
+	from transaction as t, bounds_month_prev(t.occurred_at) as b
+	where t.id = p_transaction_id
+	and t.type = 'usage_post';
+
+	if pe is null then
+		raise exception
+			using
+				errcode = 'P0002', -- no_data_found
+				message = format('invoice error: transaction %s does not exist or is not a usage_post', p_transaction_id);
+	end if;
+
+	with balances as (
+		select
+			t.id,
+			coalesce(sum(e.amount_microcredits * e.direction) filter (where prior.id = p_transaction_id), 0)::bigint as change,
+			coalesce(sum(e.amount_microcredits * e.direction), 0)::bigint as balance
+		from transaction as t
+		join transaction as prior
+		on prior.customer_id = t.customer_id
+		and (prior.occurred_at, prior.id) <= (t.occurred_at, t.id)
+		join entry as e
+		on e.transaction_id = prior.id
+		join account as a
+		on e.account_id = a.id
+		join account_type as at
+		on a.type = at.id
+		where t.id = p_transaction_id
+		and at.name = 'credit_pool'
+		group by t.id
+	),
+	usage_amount as (
+		select coalesce(sum(e.amount_microcredits) filter (where e.direction = 1), 0)::bigint as amount
+		from entry as e
+		where e.transaction_id = p_transaction_id
+	)
+	insert into invoice as inv (
+		number, transaction_id, customer_id, customer_name, iaa_number, period_start, period_end,
+		usage_microcredits, credit_pool_before_microcredits, credit_pool_after_microcredits
+	)
+	select
+		v_number,
+		t.id,
+		t.customer_id,
+		c.name,
+		(
+			select i.number
+			from iaa as i
+			where i.customer_id = t.customer_id
+			and (pe at time zone 'America/New_York')::date - 1 between i.pop_start and i.pop_end
+			order by i.pop_start desc, i.id desc
+			limit 1
+		),
+		ps,
+		pe,
+		u.amount,
+		coalesce(b.balance - b.change, 0),
+		coalesce(b.balance, 0)
+	from transaction as t
+	join customer as c
+	on t.customer_id = c.id
+	cross join usage_amount as u
+	left join balances as b
+	on b.id = t.id
+	where t.id = p_transaction_id
+	returning inv.id into v_id;
+
+	-- Lines cover the same measurements as post_usage: priced measurements of the customer's resources in readings taken during the period.
+	insert into invoice_line (
+		invoice_id, line, cf_org_id, cf_org_name, space, meter, kind_natural_id, kind_name, price_id,
+		unit_of_measure, accrues, microcredits_per_unit, unit, quantity, amount_microcredits
+	)
+	select
+		v_id,
+		row_number() over (order by l.cf_org_name, l.cf_org_id, l.space, l.meter, l.kind_natural_id, l.price_id),
+		l.*
+	from (
+		select
+			o.id as cf_org_id,
+			coalesce(o.name, o.id::text) as cf_org_name,
+			coalesce(substring(rn.path::text from '(?:^|\.)space_([^.]+)'), '') as space,
+			r.meter,
+			r.kind_natural_id,
+			coalesce(k.name, nullif(r.kind_natural_id, ''), r.meter) as kind_name,
+			p.id as price_id,
+			p.unit_of_measure,
+			k.accrues,
+			p.microcredits_per_unit,
+			p.unit,
+			sum(
+				case
+					when k.accrues then m.value * rd.interval_seconds / 3600
+					else m.value
+				end
+			)::numeric(20, 4) as quantity,
+			sum(m.amount_microcredits)::bigint as amount_microcredits
+		from (
+			-- Intervals are calculated as in update_measurement_microcredits.
+			select
+				id,
+				created_at_utc,
+				coalesce(
+					extract(epoch from created_at_utc - lag(created_at_utc) over (order by created_at_utc)),
+					3600
+				) as interval_seconds
+			from reading
+			where created_at_utc < pe
+		) as rd
+		join measurement as m
+		on rd.id = m.reading_id
+		join resource as r
+		on m.meter = r.meter and m.resource_natural_id = r.natural_id
+		join resource_kind as k
+		on r.meter = k.meter and r.kind_natural_id = k.natural_id
+		join price as p
+		on m.price_id = p.id
+		join cf_org as o
+		on r.cf_org_id = o.id
+		join transaction as t
+		on o.customer_id = t.customer_id
+		left join resource_node as rn
+		on rn.customer_id = t.customer_id and rn.resource_natural_id = r.natural_id
+		where t.id = p_transaction_id
+		and ps <= rd.created_at_utc
+		and rd.created_at_utc < pe
+		and m.amount_microcredits is not null
+		group by o.id, space, r.meter, r.kind_natural_id, k.name, k.accrues, p.id
+	) as l;
+
+	return v_id;
+end $$;
+
+comment on function create_invoice is 'create_invoice creates the invoice for a usage_post transaction and returns its ID. It fails if the transaction already has an invoice.';
+
+---- create above / drop below ----
+
+drop function if exists create_invoice;
+drop table if exists invoice_line;
+drop table if exists invoice;
+drop function if exists assert_invoice_immutable;
@@ -0,0 +1,53 @@
+create or replace function is_time_zone(tz text)
+returns boolean
+language plpgsql immutable
+as $$
+begin
+	perform timestamptz '2000-01-01 00:00:00+00' at time zone tz;
+	return true;
+exception
+	when invalid_parameter_value then
+		return false;
+end $$;
+
+comment on function is_time_zone is 'IsTimeZone returns true if tz is a time zone name Postgres recognizes, like America/Chicago.';
+
+alter table customer
+add column time_zone text not null default 'America/New_York'
+	constraint customer_time_zone_valid check (is_time_zone(time_zone));
+
+comment on column customer.time_zone is 'TimeZone is the IANA time zone in which the customer''s usage is reported, e.g. America/Chicago. Usage periods, like days and fiscal years, begin at midnight in this zone. Months are still closed and invoiced in business time; see bounds_month_prev.';
+
+-- Federal fiscal years begin on October 1 of the preceding calendar year, e.g. FY2026 began on 2025-10-01.
+create or replace function period_start(
+	p_period text,
+	p_ts timestamptz,
+	p_tz text default 'America/New_York',
+	p_offset int default 0
+)
+returns timestamptz
+language sql immutable
+as $$
+	select (
+		case p_period
+			when 'day' then date_trunc('day', local) + make_interval(days => p_offset)
+			when 'week' then date_trunc('week', local) + make_interval(weeks => p_offset)
+			when 'month' then date_trunc('month', local) + make_interval(months => p_offset)
+			when 'quarter' then date_trunc('quarter', local) + make_interval(months => 3 * p_offset)
+			when 'year' then date_trunc('year', local) + make_interval(years => p_offset)
+			when 'fiscal_year' then date_trunc('year', local + interval '3 months') - interval '3 months' + make_interval(years => p_offset)
+		end
+	) at time zone p_tz
+	from (select p_ts at time zone p_tz as local) as l;
+$$;
+
+comment on function period_start is 'PeriodStart returns the start of the period (day, week, month, quarter, year, or fiscal_year) containing p_ts in time zone p_tz, moved p_offset periods forward or back. Weeks begin on Monday and fiscal years on October 1. It returns null for other periods.';
+
+---- create above / drop below ----
+
+drop function if exists period_start;
+
+alter table customer
+drop column if exists time_zone;
+
+drop function if exists is_time_zone;
@@ -0,0 +1,201 @@
+-- reading_succeeded is true if the meter's usage was read completely in the reading, so the next reading of the meter accrues usage from it.
+create or replace function reading_succeeded(p_reading reading, p_meter text)
+returns boolean
+language sql stable
+as $$
+	select
+		case
+			when exists (select 1 from reading_meter_status as s where s.reading_id = p_reading.id)
+				then exists (
+					select 1
+					from reading_meter_status as s
+					where
+						s.reading_id = p_reading.id
+						and s.meter = p_meter
+						and s.succeeded
+				)
+			-- Readings taken before statuses were recorded read every meter, unless they were one-off readings requested manually. Backfilled readings estimate every meter's usage for their hour.
+			else p_reading.periodic or p_reading.provenance <> 'measured'
+		end;
+$$;
+
+comment on function reading_succeeded is 'ReadingSucceeded is true if the meter succeeded in the reading. Readings with statuses succeeded for the meter if its status says so. Readings without statuses succeeded for every meter if they were periodic or backfilled; manual one-off readings only measure a single resource, so they succeeded for none.';
+
+-- The previous reading is looked up at most this far before p_since, so pricing a month does not scan the whole reading history. Longer gaps are backfilled (see ListReadingGaps), so a meter that has not succeeded in this long is treated as if it was never read.
+create or replace function reading_intervals(p_since timestamptz, p_until timestamptz)
+returns table (
+	reading_id int,
+	meter text,
+	created_at_utc timestamptz,
+	provenance text,
+	interval_seconds numeric
+)
+language sql stable
+as $$
+	select
+		rd.id,
+		rm.meter,
+		rd.created_at_utc,
+		rd.provenance,
+		coalesce(extract(epoch from rd.created_at_utc - prev.created_at_utc), 3600)
+	from reading as rd
+	cross join lateral (
+		select distinct m.meter
+		from measurement as m
+		where m.reading_id = rd.id
+	) as rm
+	left join lateral (
+		select p.created_at_utc
+		from reading as p
+		where
+			p.created_at_utc < rd.created_at_utc
+			and p.created_at_utc >= p_since - interval '7 days'
+			and reading_succeeded(p, rm.meter)
+		order by p.created_at_utc desc
+		limit 1
+	) as prev on true
+	where
+		p_since <= rd.created_at_utc
+		and rd.created_at_utc < p_until;
+$$;
+
+comment on function reading_intervals is 'ReadingIntervals returns, for each meter measured in each reading taken in [p_since, p_until), the seconds since the previous reading in which the meter succeeded, or one hour if there was none in the 7 days before p_since. Measurements of accruing kinds are multiplied by their interval, so usage is not lost when a meter fails or a one-off reading is taken between periodic readings.';
+
+CREATE OR REPLACE FUNCTION update_measurement_microcredits(
+	as_of timestamptz DEFAULT now()
+)
+RETURNS bigint
+LANGUAGE plpgsql
+AS $$
+DECLARE
+	ps timestamptz;
+	pe timestamptz;
+	updated bigint;
+BEGIN
+	SELECT period_start, period_end INTO ps, pe FROM bounds_month_prev(as_of);
+
+	WITH measurement_amounts AS (
+		SELECT
+			r.meter AS meter,
+			r.natural_id AS resource_natural_id,
+			rd.reading_id AS reading_id,
+			sum(
+				CASE
+					-- Accruing kinds are priced per unit-hour.
+					WHEN k.accrues THEN floor(p.microcredits_per_unit * m.value * rd.interval_seconds / (p.unit * 3600))
+					ELSE p.microcredits_per_unit * m.value / p.unit
+				END
+			) AS amount_microcredits,
+			p.id AS price_id
+		FROM reading_intervals(ps, pe) rd
+		JOIN measurement AS m
+		ON rd.reading_id = m.reading_id AND rd.meter = m.meter
+		JOIN resource AS r
+		ON m.meter = r.meter AND m.resource_natural_id = r.natural_id
+		JOIN resource_kind AS k
+		ON r.meter = k.meter AND r.kind_natural_id = k.natural_id
+		JOIN price AS p
+		ON r.meter = p.meter AND r.kind_natural_id = p.kind_natural_id
+		-- Select the price that was valid when the reading was taken. The exclusion constraint on price ensures there is at most one.
+		AND p.valid_during @> rd.created_at_utc
+		WHERE m.amount_microcredits IS NULL
+		GROUP BY
+			r.meter,
+			r.natural_id,
+			rd.reading_id,
+			p.id
+	),
+	update_measurements AS (
+		UPDATE measurement AS m
+		SET
+			amount_microcredits = ma.amount_microcredits,
+			price_id = ma.price_id
+		FROM measurement_amounts AS ma
+		WHERE
+			m.meter = ma.meter AND
+			m.resource_natural_id = ma.resource_natural_id AND
+			m.reading_id = ma.reading_id
+		RETURNING 1
+	)
+	SELECT count(*) INTO updated FROM update_measurements;
+	RETURN updated;
+END $$;
+
+---- create above / drop below ----
+
+CREATE OR REPLACE FUNCTION update_measurement_microcredits(
+	as_of timestamptz DEFAULT now()
+)
+RETURNS bigint
+LANGUAGE plpgsql
+AS $$
+DECLARE
+	ps timestamptz;
+	pe timestamptz;
+	updated bigint;
+BEGIN
+	SELECT period_start, period_end INTO ps, pe FROM bounds_month_prev(as_of);
+
+	WITH reading_intervals AS (
+		-- The interval of a reading is the time since the previous reading, or one hour for the first reading. Readings are usually hourly, but may be late or missing.
+		SELECT
+			id,
+			created_at_utc,
+			coalesce(
+				extract(epoch FROM created_at_utc - lag(created_at_utc) OVER (ORDER BY created_at_utc)),
+				3600
+			) AS interval_seconds
+		FROM reading
+		WHERE created_at_utc < pe
+	),
+	measurement_amounts AS (
+		SELECT
+			r.meter AS meter,
+			r.natural_id AS resource_natural_id,
+			rd.id AS reading_id,
+			sum(
+				CASE
+					-- Accruing kinds are priced per unit-hour.
+					WHEN k.accrues THEN floor(p.microcredits_per_unit * m.value * rd.interval_seconds / (p.unit * 3600))
+					ELSE p.microcredits_per_unit * m.value / p.unit
+				END
+			) AS amount_microcredits,
+			p.id AS price_id
+		FROM reading_intervals rd
+		JOIN measurement AS m
+		ON rd.id = m.reading_id
+		JOIN resource AS r
+		ON m.meter = r.meter AND m.resource_natural_id = r.natural_id
+		JOIN resource_kind AS k
+		ON r.meter = k.meter AND r.kind_natural_id = k.natural_id
+		JOIN price AS p
+		ON r.meter = p.meter AND r.kind_natural_id = p.kind_natural_id
+		-- Select the price that was valid when the reading was taken. The exclusion constraint on price ensures there is at most one.
+		AND p.valid_during @> rd.created_at_utc
+		WHERE ps <= rd.created_at_utc
+		AND rd.created_at_utc < pe
+		AND m.amount_microcredits IS NULL
+		GROUP BY
+			r.meter,
+			r.natural_id,
+			rd.id,
+			p.id
+	),
+	update_measurements AS (
+		UPDATE measurement AS m
+		SET
+			amount_microcredits = ma.amount_microcredits,
+			price_id = ma.price_id
+		FROM measurement_amounts AS ma
+		WHERE
+			m.meter = ma.meter AND
+			m.resource_natural_id = ma.resource_natural_id AND
+			m.reading_id = ma.reading_id
+		RETURNING 1
+	)
+	SELECT count(*) INTO updated FROM update_measurements;
+	RETURN updated;
+END $$;
+
+drop function if exists reading_intervals;
+drop function if exists reading_succeeded;
@@ -0,0 +1,14 @@
+create table alert_delivery (
+  alert_id int not null references alert (id) on delete cascade,
+  notifier text not null,
+  delivered_at timestamptz not null default now(),
+
+  primary key (alert_id, notifier)
+);
+
+comment on table alert_delivery is 'AlertDelivery records that a notifier, like email or a webhook, delivered an alert. When some notifiers fail, only they are retried, so recipients of the others do not receive the alert again. The alert is marked notified once every notifier has delivered it.';
+comment on column alert_delivery.notifier is 'Notifier is the name of the notifier, e.g. email or webhook.';
+
+---- create above / drop below ----
+
+drop table if exists alert_delivery;
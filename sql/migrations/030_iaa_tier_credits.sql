@@ -0,0 +1,240 @@
+create or replace function post_iaa_pop(
+  as_of timestamptz default now()
+)
+returns table (
+  transaction_id integer
+)
+language plpgsql
+as $$
+declare
+  tz constant text := 'America/New_York';
+  today date := (as_of at time zone tz)::date;
+  agreement iaa%rowtype;
+  v_id int;
+  v_pool bigint;
+  v_reserved bigint;
+  v_unused bigint;
+  v_successor int;
+begin
+  -- Step 1: Add credits for agreements whose PoP has started.
+  for agreement in
+    select *
+    from iaa as i
+    where i.start_transaction_id is null
+    and i.pop_start <= today
+    order by i.pop_start, i.id
+    for update
+  loop
+    v_id := post_credit_pool_transaction(
+      agreement.customer_id,
+      agreement.amount_microcredits,
+      agreement.pop_start::timestamp at time zone tz,
+      format('IAA %s period of performance started', coalesce(nullif(agreement.number, ''), '#' || agreement.id)),
+      'iaa_pop_start'
+    );
+    update iaa set start_transaction_id = v_id where id = agreement.id;
+    transaction_id := v_id;
+    return next;
+  end loop;
+
+  -- Step 2: Expire or roll over unused credits for agreements whose PoP has ended. Credits are assumed to be used in the order their agreements end, so credits in the pool belong to the agreements that end last. Usage is charged against purchased credits before tier credits.
+  for agreement in
+    select *
+    from iaa as i
+    where i.start_transaction_id is not null
+    and i.ended_at is null
+    and i.pop_end < today
+    order by i.pop_end, i.id
+    for update
+  loop
+    select coalesce(sum(e.amount_microcredits * e.direction), 0) into v_pool
+    from entry as e
+    join account as a
+    on e.account_id = a.id
+    join account_type as at
+    on a.type = at.id
+    where a.customer_id = agreement.customer_id
+    and at.name = 'credit_pool'
+    -- Tier credits are not purchased through an agreement, so they neither expire nor roll over with one. Corrections of tier grants are excluded with them.
+    and not exists (
+      select 1
+      from transaction as t
+      left join transaction as orig
+      on t.original_transaction_id = orig.id
+      where t.id = e.transaction_id
+      and 'tier_grant' in (t.type, orig.type)
+    );
+
+    select coalesce(sum(
+      case when i.start_transaction_id is not null then i.amount_microcredits else 0 end
+      + i.rolled_over_microcredits
+    ), 0) into v_reserved
+    from iaa as i
+    where i.customer_id = agreement.customer_id
+    and i.id <> agreement.id
+    and i.ended_at is null;
+
+    v_unused := greatest(0, least(
+      agreement.amount_microcredits + agreement.rolled_over_microcredits,
+      v_pool - v_reserved
+    ));
+
+    if agreement.rollover and v_unused > 0 then
+      select i.id into v_successor
+      from iaa as i
+      where i.customer_id = agreement.customer_id
+      and i.id <> agreement.id
+      and i.ended_at is null
+      and i.pop_end > agreement.pop_end
+      order by i.pop_start, i.id
+      limit 1;
+
+      if v_successor is not null then
+        update iaa
+        set rolled_over_microcredits = rolled_over_microcredits + v_unused
+        where id = v_successor;
+        v_unused := 0;
+      end if;
+    end if;
+
+    v_id := null;
+    if v_unused > 0 then
+      v_id := post_credit_pool_transaction(
+        agreement.customer_id,
+        -v_unused,
+        (agreement.pop_end + 1)::timestamp at time zone tz,
+        format('IAA %s period of performance ended', coalesce(nullif(agreement.number, ''), '#' || agreement.id)),
+        'iaa_pop_end'
+      );
+      transaction_id := v_id;
+      return next;
+    end if;
+
+    update iaa
+    set
+      end_transaction_id = v_id,
+      ended_at = now()
+    where id = agreement.id;
+  end loop;
+end $$;
+
+comment on function post_iaa_pop is 'post_iaa_pop adds credits for agreements whose Period of Performance started on or before the business day containing as_of, and expires unused credits for agreements whose PoP ended before it. It returns the IDs of the transactions it created. This function must be run in a transaction.';
+
+comment on function post_iaa_pop is 'post_iaa_pop adds credits for agreements whose Period of Performance started on or before the business day containing as_of, and expires unused credits for agreements whose PoP ended before it. Credits granted by the customer''s tier are not counted as unused. It returns the IDs of the transactions it created. This function must be run in a transaction.';
+
+---- create above / drop below ----
+
+create or replace function post_iaa_pop(
+  as_of timestamptz default now()
+)
+returns table (
+  transaction_id integer
+)
+language plpgsql
+as $$
+declare
+  tz constant text := 'America/New_York';
+  today date := (as_of at time zone tz)::date;
+  agreement iaa%rowtype;
+  v_id int;
+  v_pool bigint;
+  v_reserved bigint;
+  v_unused bigint;
+  v_successor int;
+begin
+  -- Step 1: Add credits for agreements whose PoP has started.
+  for agreement in
+    select *
+    from iaa as i
+    where i.start_transaction_id is null
+    and i.pop_start <= today
+    order by i.pop_start, i.id
+    for update
+  loop
+    v_id := post_credit_pool_transaction(
+      agreement.customer_id,
+      agreement.amount_microcredits,
+      agreement.pop_start::timestamp at time zone tz,
+      format('IAA %s period of performance started', coalesce(nullif(agreement.number, ''), '#' || agreement.id)),
+      'iaa_pop_start'
+    );
+    update iaa set start_transaction_id = v_id where id = agreement.id;
+    transaction_id := v_id;
+    return next;
+  end loop;
+
+  -- Step 2: Expire or roll over unused credits for agreements whose PoP has ended. Credits are assumed to be used in the order their agreements end, so credits in the pool belong to the agreements that end last.
+  for agreement in
+    select *
+    from iaa as i
+    where i.start_transaction_id is not null
+    and i.ended_at is null
+    and i.pop_end < today
+    order by i.pop_end, i.id
+    for update
+  loop
+    select coalesce(sum(e.amount_microcredits * e.direction), 0) into v_pool
+    from entry as e
+    join account as a
+    on e.account_id = a.id
+    join account_type as at
+    on a.type = at.id
+    where a.customer_id = agreement.customer_id
+    and at.name = 'credit_pool';
+
+    select coalesce(sum(
+      case when i.start_transaction_id is not null then i.amount_microcredits else 0 end
+      + i.rolled_over_microcredits
+    ), 0) into v_reserved
+    from iaa as i
+    where i.customer_id = agreement.customer_id
+    and i.id <> agreement.id
+    and i.ended_at is null;
+
+    v_unused := greatest(0, least(
+      agreement.amount_microcredits + agreement.rolled_over_microcredits,
+      v_pool - v_reserved
+    ));
+
+    if agreement.rollover and v_unused > 0 then
+      select i.id into v_successor
+      from iaa as i
+      where i.customer_id = agreement.customer_id
+      and i.id <> agreement.id
+      and i.ended_at is null
+      and i.pop_end > agreement.pop_end
+      order by i.pop_start, i.id
+      limit 1;
+
+      if v_successor is not null then
+        update iaa
+        set rolled_over_microcredits = rolled_over_microcredits + v_unused
+        where id = v_successor;
+        v_unused := 0;
+      end if;
+    end if;
+
+    v_id := null;
+    if v_unused > 0 then
+      v_id := post_credit_pool_transaction(
+        agreement.customer_id,
+        -v_unused,
+        (agreement.pop_end + 1)::timestamp at time zone tz,
+        format('IAA %s period of performance ended', coalesce(nullif(agreement.number, ''), '#' || agreement.id)),
+        'iaa_pop_end'
+      );
+      transaction_id := v_id;
+      return next;
+    end if;
+
+    update iaa
+    set
+      end_transaction_id = v_id,
+      ended_at = now()
+    where id = agreement.id;
+  end loop;
+end $$;
+
+comment on function post_iaa_pop is 'post_iaa_pop adds credits for agreements whose Period of Performance started on or before the business day containing as_of, and expires unused credits for agreements whose PoP ended before it. It returns the IDs of the transactions it created. This function must be run in a transaction.';
+
+comment on function post_iaa_pop is 'post_iaa_pop adds credits for agreements whose Period of Performance started on or before the business day containing as_of, and expires unused credits for agreements whose PoP ended before it. It returns the IDs of the transactions it created. This function must be run in a transaction.';
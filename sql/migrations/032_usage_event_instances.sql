@@ -0,0 +1,10 @@
+alter table usage_event_interval
+add column instances int not null default 1
+	constraint usage_event_interval_instances_check check (instances >= 0);
+
+comment on column usage_event_interval.instances is 'Instances is the number of instances the resource ran during the interval, e.g. of an app process. Value is the total of all instances. Intervals recorded before instances were counted have one.';
+
+---- create above / drop below ----
+
+alter table usage_event_interval
+drop column if exists instances;
@@ -0,0 +1,363 @@
+-- Same as reverse_transaction from 010, but transactions with adjustments that have not been reversed cannot be reversed. Otherwise the adjustments would stay posted after the original was undone.
+create or replace function reverse_transaction(
+  p_transaction_id int,
+  p_occurred_at timestamptz default now(),
+  p_description text default null
+)
+returns int
+language plpgsql
+as $$
+declare
+  v_id int;
+begin
+  -- Lock the original so an adjustment cannot be posted while it is reversed.
+  perform 1 from transaction where id = p_transaction_id for update;
+
+  if exists (
+    select 1
+    from transaction as adj
+    where adj.original_transaction_id = p_transaction_id
+    and adj.type = 'adjustment'
+    and not exists (
+      select 1
+      from transaction as rev
+      where rev.original_transaction_id = adj.id
+      and rev.type = 'reversal'
+    )
+  ) then
+    raise exception
+      using
+        errcode = '55006', -- object_in_use
+        message = format('ledger error: transaction %s has adjustments that must be reversed first', p_transaction_id);
+  end if;
+
+  insert into transaction as txn (customer_id, occurred_at, description, type, original_transaction_id)
+  select
+    t.customer_id,
+    p_occurred_at,
+    coalesce(p_description, format('Reversal of transaction %s', t.id)),
+    'reversal',
+    t.id
+  from transaction as t
+  where t.id = p_transaction_id
+    and t.type <> 'reversal'
+  returning txn.id into v_id;
+
+  if v_id is null then
+    raise exception
+      using
+        errcode = 'P0002', -- no_data_found
+        message = format('ledger error: transaction %s does not exist or is a reversal', p_transaction_id);
+  end if;
+
+  -- Swap the direction of every entry in the original transaction.
+  insert into entry (transaction_id, account_id, direction, amount_microcredits)
+  select v_id, e.account_id, -e.direction, e.amount_microcredits
+  from entry as e
+  where e.transaction_id = p_transaction_id;
+
+  return v_id;
+end $$;
+
+comment on function reverse_transaction is 'reverse_transaction posts a transaction that undoes p_transaction_id and returns its ID. The original transaction is not modified. Transactions with adjustments that have not been reversed cannot be reversed.';
+
+-- Same as adjust_transaction from 011, but reversed transactions cannot be adjusted.
+create or replace function adjust_transaction(
+  p_transaction_id int,
+  p_amount_microcredits bigint,
+  p_occurred_at timestamptz default now(),
+  p_description text default null
+)
+returns int
+language plpgsql
+as $$
+declare
+  v_id int;
+  v_amounts int;
+begin
+  -- Lock the original so it cannot be reversed while it is adjusted.
+  perform 1 from transaction where id = p_transaction_id for update;
+
+  if exists (
+    select 1
+    from transaction as rev
+    where rev.original_transaction_id = p_transaction_id
+    and rev.type = 'reversal'
+  ) then
+    raise exception
+      using
+        errcode = '55000', -- object_not_in_prerequisite_state
+        message = format('ledger error: transaction %s was reversed and cannot be adjusted', p_transaction_id);
+  end if;
+
+  if p_amount_microcredits = 0 then
+    raise exception
+      using
+        errcode = '22023', -- invalid_parameter_value
+        message = 'ledger error: adjustment amount must not be zero';
+  end if;
+
+  -- An adjustment changes every entry by the same amount, so it only makes sense when every entry has the same amount.
+  select count(distinct e.amount_microcredits) into v_amounts
+  from entry as e
+  where e.transaction_id = p_transaction_id;
+
+  if v_amounts > 1 then
+    raise exception
+      using
+        errcode = '22023', -- invalid_parameter_value
+        message = format('ledger error: transaction %s cannot be adjusted because its entries have different amounts', p_transaction_id);
+  end if;
+
+  insert into transaction as txn (customer_id, occurred_at, description, type, original_transaction_id)
+  select
+    t.customer_id,
+    p_occurred_at,
+    coalesce(p_description, format('Adjustment of transaction %s', t.id)),
+    'adjustment',
+    t.id
+  from transaction as t
+  where t.id = p_transaction_id
+    and t.type <> 'reversal'
+  returning txn.id into v_id;
+
+  if v_id is null then
+    raise exception
+      using
+        errcode = 'P0002', -- no_data_found
+        message = format('ledger error: transaction %s does not exist or is a reversal', p_transaction_id);
+  end if;
+
+  -- A positive amount moves more credits in the same direction as the original; a negative amount moves credits back.
+  insert into entry (transaction_id, account_id, direction, amount_microcredits)
+  select
+    v_id,
+    e.account_id,
+    e.direction * sign(p_amount_microcredits)::int,
+    abs(p_amount_microcredits)
+  from entry as e
+  where e.transaction_id = p_transaction_id;
+
+  return v_id;
+end $$;
+
+comment on function adjust_transaction is 'adjust_transaction posts a transaction that changes the amount of p_transaction_id by p_amount_microcredits and returns its ID. The original transaction is not modified. Reversed transactions cannot be adjusted.';
+
+-- Same as reverse_usage from 011, but adjustments are reversed before the usage they adjust, which reverse_transaction now requires.
+create or replace function reverse_usage (
+	as_of timestamptz default now()
+)
+returns table(
+	transaction_id integer
+)
+language plpgsql
+as $$
+declare
+	ps timestamptz;
+	pe timestamptz;
+	v_description text;
+	t record;
+begin
+	select period_start, period_end into ps, pe from bounds_month_prev(as_of);
+	v_description := format('Reversal of monthly usage %s--%s', to_char(ps, 'YYYY-MM-DD'), to_char(pe, 'YYYY-MM-DD'));
+
+	-- Clear calculated amounts so the month is repriced if it is posted again, e.g. after a price correction.
+	update measurement as m
+	set
+		amount_microcredits = null,
+		price_id = null
+	from reading as rd
+	where rd.id = m.reading_id
+	and ps <= rd.created_at_utc
+	and rd.created_at_utc < pe;
+
+	-- Usage posted for the period, and adjustments to it, that have not been reversed. Adjustments are ordered first.
+	for t in
+		select cur.id
+		from transaction as cur
+		left join transaction as orig
+		on cur.original_transaction_id = orig.id
+		where (
+			(cur.type = 'usage_post' and cur.occurred_at = pe)
+			or (cur.type = 'adjustment' and orig.type = 'usage_post' and orig.occurred_at = pe)
+		)
+		and not exists (
+			select 1
+			from transaction as rev
+			where rev.original_transaction_id = cur.id
+			and rev.type = 'reversal'
+		)
+		order by cur.type = 'usage_post', cur.id
+	loop
+		transaction_id := reverse_transaction(t.id, now(), v_description);
+		return next;
+	end loop;
+end $$;
+
+comment on function reverse_usage is 'reverse_usage reverses all usage posted for the month preceding as_of, including adjustments to it, and returns the IDs of the reversal transactions. This function must be run in a transaction.';
+
+---- create above / drop below ----
+
+-- Restore reverse_transaction from 010, and adjust_transaction and reverse_usage from 011.
+create or replace function reverse_transaction(
+  p_transaction_id int,
+  p_occurred_at timestamptz default now(),
+  p_description text default null
+)
+returns int
+language plpgsql
+as $$
+declare
+  v_id int;
+begin
+  insert into transaction as txn (customer_id, occurred_at, description, type, original_transaction_id)
+  select
+    t.customer_id,
+    p_occurred_at,
+    coalesce(p_description, format('Reversal of transaction %s', t.id)),
+    'reversal',
+    t.id
+  from transaction as t
+  where t.id = p_transaction_id
+    and t.type <> 'reversal'
+  returning txn.id into v_id;
+
+  if v_id is null then
+    raise exception
+      using
+        errcode = 'P0002', -- no_data_found
+        message = format('ledger error: transaction %s does not exist or is a reversal', p_transaction_id);
+  end if;
+
+  -- Swap the direction of every entry in the original transaction.
+  insert into entry (transaction_id, account_id, direction, amount_microcredits)
+  select v_id, e.account_id, -e.direction, e.amount_microcredits
+  from entry as e
+  where e.transaction_id = p_transaction_id;
+
+  return v_id;
+end $$;
+
+comment on function reverse_transaction is 'reverse_transaction posts a transaction that undoes p_transaction_id and returns its ID. The original transaction is not modified.';
+
+create or replace function adjust_transaction(
+  p_transaction_id int,
+  p_amount_microcredits bigint,
+  p_occurred_at timestamptz default now(),
+  p_description text default null
+)
+returns int
+language plpgsql
+as $$
+declare
+  v_id int;
+  v_amounts int;
+begin
+  if p_amount_microcredits = 0 then
+    raise exception
+      using
+        errcode = '22023', -- invalid_parameter_value
+        message = 'ledger error: adjustment amount must not be zero';
+  end if;
+
+  -- An adjustment changes every entry by the same amount, so it only makes sense when every entry has the same amount.
+  select count(distinct e.amount_microcredits) into v_amounts
+  from entry as e
+  where e.transaction_id = p_transaction_id;
+
+  if v_amounts > 1 then
+    raise exception
+      using
+        errcode = '22023', -- invalid_parameter_value
+        message = format('ledger error: transaction %s cannot be adjusted because its entries have different amounts', p_transaction_id);
+  end if;
+
+  insert into transaction as txn (customer_id, occurred_at, description, type, original_transaction_id)
+  select
+    t.customer_id,
+    p_occurred_at,
+    coalesce(p_description, format('Adjustment of transaction %s', t.id)),
+    'adjustment',
+    t.id
+  from transaction as t
+  where t.id = p_transaction_id
+    and t.type <> 'reversal'
+  returning txn.id into v_id;
+
+  if v_id is null then
+    raise exception
+      using
+        errcode = 'P0002', -- no_data_found
+        message = format('ledger error: transaction %s does not exist or is a reversal', p_transaction_id);
+  end if;
+
+  -- A positive amount moves more credits in the same direction as the original; a negative amount moves credits back.
+  insert into entry (transaction_id, account_id, direction, amount_microcredits)
+  select
+    v_id,
+    e.account_id,
+    e.direction * sign(p_amount_microcredits)::int,
+    abs(p_amount_microcredits)
+  from entry as e
+  where e.transaction_id = p_transaction_id;
+
+  return v_id;
+end $$;
+
+comment on function adjust_transaction is 'adjust_transaction posts a transaction that changes the amount of p_transaction_id by p_amount_microcredits and returns its ID. The original transaction is not modified.';
+
+create or replace function reverse_usage (
+	as_of timestamptz default now()
+)
+returns table(
+	transaction_id integer
+)
+language plpgsql
+as $$
+declare
+	ps timestamptz;
+	pe timestamptz;
+begin
+	select period_start, period_end into ps, pe from bounds_month_prev(as_of);
+
+	-- Clear calculated amounts so the month is repriced if it is posted again, e.g. after a price correction.
+	update measurement as m
+	set
+		amount_microcredits = null,
+		price_id = null
+	from reading as rd
+	where rd.id = m.reading_id
+	and ps <= rd.created_at_utc
+	and rd.created_at_utc < pe;
+
+	return query
+	select reverse_transaction(
+		t.id,
+		now(),
+		format('Reversal of monthly usage %s--%s', to_char(ps, 'YYYY-MM-DD'), to_char(pe, 'YYYY-MM-DD'))
+	)
+	from transaction as t
+	where (
+		-- Usage posted for the period, and adjustments to it.
+		(t.type = 'usage_post' and t.occurred_at = pe)
+		or (
+			t.type = 'adjustment'
+			and exists (
+				select 1
+				from transaction as orig
+				where orig.id = t.original_transaction_id
+				and orig.type = 'usage_post'
+				and orig.occurred_at = pe
+			)
+		)
+	)
+	and not exists (
+		select 1
+		from transaction as rev
+		where rev.original_transaction_id = t.id
+		and rev.type = 'reversal'
+	)
+	order by t.id;
+end $$;
+
+comment on function reverse_usage is 'reverse_usage reverses all usage posted for the month preceding as_of, including adjustments to it, and returns the IDs of the reversal transactions. This function must be run in a transaction.';
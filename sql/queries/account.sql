@@ -5,3 +5,22 @@ JOIN customer c on a.customer_id = c.id
 WHERE c.name = $1
 AND a.type = $2
 LIMIT 1;
+
+-- name: GetCustomerBalances :many
+-- GetCustomerBalances returns the total debits, credits, and balance of each of the customer's accounts. Balances are positive when the account holds value in the sense the billing service uses it: credit_pool is the number of credits remaining, credits_used is the number consumed, and liabilities is the number owed to the customer. Liabilities increase with credits and every other account increases with debits.
+SELECT
+  at.id AS account_type,
+  at.name AS account_type_name,
+  a.id AS account_id,
+  COALESCE(SUM(e.amount_microcredits) FILTER (WHERE e.direction = 1), 0)::bigint AS debits_microcredits,
+  COALESCE(SUM(e.amount_microcredits) FILTER (WHERE e.direction = -1), 0)::bigint AS credits_microcredits,
+  (
+    CASE at.name WHEN 'liabilities' THEN -1 ELSE 1 END
+    * COALESCE(SUM(e.amount_microcredits * e.direction), 0)
+  )::bigint AS balance_microcredits
+FROM account AS a
+INNER JOIN account_type AS at ON a.type = at.id
+LEFT JOIN entry AS e ON a.id = e.account_id
+WHERE a.customer_id = $1
+GROUP BY at.id, at.name, a.id
+ORDER BY at.id;
@@ -0,0 +1,185 @@
+-- name: ListCustomerFunding :many
+-- ListCustomerFunding returns, for each customer, the inputs needed to decide whether their credits are running low as of as_of: the credit pool balance, usage that has been measured but not yet posted, usage measured since window_start, and the credits and last day of the Period of Performance of agreements that have started and not ended. Usage is not priced until its month is closed, so the cost of unpriced usage is estimated from the price that was valid when it was read, as in ListResourceCosts. Closing a month posts the usage of every customer that has any, so usage of customers with no posts is unposted only since the end of the last closed month, or the start of the previous month if none has been closed.
+WITH last_close AS (
+  SELECT
+    COALESCE(
+      MAX(posted.occurred_at),
+      date_trunc('month', (sqlc.arg(as_of)::timestamptz AT TIME ZONE 'America/New_York') - INTERVAL '1 month') AT TIME ZONE 'America/New_York'
+    ) AS closed_until
+  FROM transaction AS posted
+  WHERE
+    posted.type = 'usage_post'
+    AND NOT EXISTS (
+      SELECT 1
+      FROM transaction AS rev
+      WHERE
+        rev.original_transaction_id = posted.id
+        AND rev.type = 'reversal'
+    )
+),
+last_post AS (
+  SELECT
+    c.id AS customer_id,
+    COALESCE((
+      SELECT MAX(posted.occurred_at)
+      FROM transaction AS posted
+      WHERE
+        posted.customer_id = c.id
+        AND posted.type = 'usage_post'
+        AND NOT EXISTS (
+          SELECT 1
+          FROM transaction AS rev
+          WHERE
+            rev.original_transaction_id = posted.id
+            AND rev.type = 'reversal'
+        )
+    ), (SELECT lc.closed_until FROM last_close AS lc)) AS posted_until
+  FROM customer AS c
+),
+usage AS (
+  SELECT
+    o.customer_id,
+    rd.created_at_utc,
+    COALESCE(
+      m.amount_microcredits,
+      CASE
+        -- Accruing kinds are priced per unit-hour.
+        WHEN k.accrues THEN floor(p.microcredits_per_unit * m.value * rd.interval_seconds / (p.unit * 3600))
+        ELSE p.microcredits_per_unit * m.value / p.unit
+      END
+    ) AS microcredits
+  FROM reading_intervals(
+    LEAST((SELECT MIN(lp.posted_until) FROM last_post AS lp), sqlc.arg(window_start)::timestamptz),
+    sqlc.arg(as_of)::timestamptz
+  ) AS rd
+  INNER JOIN measurement AS m ON rd.reading_id = m.reading_id AND rd.meter = m.meter
+  INNER JOIN resource AS r ON m.meter = r.meter AND m.resource_natural_id = r.natural_id
+  INNER JOIN resource_kind AS k ON r.meter = k.meter AND r.kind_natural_id = k.natural_id
+  INNER JOIN cf_org AS o ON r.cf_org_id = o.id
+  LEFT JOIN price AS p
+    ON
+      r.meter = p.meter
+      AND r.kind_natural_id = p.kind_natural_id
+      AND p.valid_during @> rd.created_at_utc
+)
+SELECT
+  c.id AS customer_id,
+  c.name AS customer_name,
+  (sqlc.arg(as_of)::timestamptz AT TIME ZONE 'America/New_York')::date AS business_date,
+  COALESCE((
+    SELECT SUM(e.amount_microcredits * e.direction)
+    FROM entry AS e
+    INNER JOIN account AS a ON e.account_id = a.id
+    INNER JOIN account_type AS at ON a.type = at.id
+    WHERE
+      a.customer_id = c.id
+      AND at.name = 'credit_pool'
+  ), 0)::bigint AS pool_microcredits,
+  COALESCE((
+    SELECT SUM(u.microcredits)
+    FROM usage AS u
+    INNER JOIN last_post AS lp ON u.customer_id = lp.customer_id
+    WHERE
+      u.customer_id = c.id
+      AND u.created_at_utc >= lp.posted_until
+  ), 0)::bigint AS unposted_microcredits,
+  COALESCE((
+    SELECT SUM(u.microcredits)
+    FROM usage AS u
+    WHERE
+      u.customer_id = c.id
+      AND u.created_at_utc >= sqlc.arg(window_start)::timestamptz
+  ), 0)::bigint AS recent_microcredits,
+  COALESCE((
+    SELECT SUM(i.amount_microcredits + i.rolled_over_microcredits)
+    FROM iaa AS i
+    WHERE
+      i.customer_id = c.id
+      AND i.start_transaction_id IS NOT NULL
+      AND i.ended_at IS NULL
+  ), 0)::bigint AS funded_microcredits,
+  (
+    SELECT MAX(i.pop_end)
+    FROM iaa AS i
+    WHERE
+      i.customer_id = c.id
+      AND i.start_transaction_id IS NOT NULL
+      AND i.ended_at IS NULL
+  )::date AS funded_until
+FROM customer AS c
+ORDER BY c.name;
+
+-- name: ListAlertThresholds :many
+-- ListAlertThresholds lists the thresholds for the customer, or for all customers if customer_id is null.
+SELECT * FROM alert_threshold
+WHERE
+  sqlc.narg(customer_id)::uuid IS NULL
+  OR customer_id = sqlc.narg(customer_id)::uuid
+ORDER BY customer_id, percent_consumed;
+
+-- name: DeleteAlertThresholds :exec
+DELETE FROM alert_threshold
+WHERE customer_id = $1;
+
+-- name: CreateAlertThresholds :exec
+INSERT INTO alert_threshold (customer_id, percent_consumed)
+SELECT sqlc.arg(customer_id)::uuid, UNNEST(sqlc.arg(percents_consumed)::int [])
+ON CONFLICT DO NOTHING;
+
+-- name: CreateAlert :one
+-- CreateAlert records an alert. If an alert of the same kind has already been recorded for the threshold and funding level, no row is returned.
+INSERT INTO alert (
+  customer_id,
+  kind,
+  percent_consumed,
+  funded_microcredits,
+  remaining_microcredits,
+  daily_burn_microcredits,
+  projected_exhaustion_date,
+  funded_until
+) VALUES (
+  $1, $2, $3, $4, $5, $6, $7, $8
+)
+ON CONFLICT ON CONSTRAINT alert_uq DO NOTHING
+RETURNING *;
+
+-- name: ListAlerts :many
+-- ListAlerts lists the most recent alerts for the customer, or for all customers if customer_id is null.
+SELECT
+  sqlc.embed(a),
+  c.name AS customer_name
+FROM alert AS a
+INNER JOIN customer AS c ON a.customer_id = c.id
+WHERE
+  sqlc.narg(customer_id)::uuid IS NULL
+  OR a.customer_id = sqlc.narg(customer_id)::uuid
+ORDER BY a.created_at DESC, a.id DESC
+LIMIT sqlc.arg(page_size)
+OFFSET sqlc.arg(page_offset);
+
+-- name: ListUnnotifiedAlerts :many
+-- ListUnnotifiedAlerts lists the alerts that have not been delivered by every notifier, with the names of the notifiers that have delivered them.
+SELECT
+  sqlc.embed(a),
+  c.name AS customer_name,
+  ARRAY(
+    SELECT d.notifier
+    FROM alert_delivery AS d
+    WHERE d.alert_id = a.id
+    ORDER BY d.notifier
+  )::text [] AS delivered_to
+FROM alert AS a
+INNER JOIN customer AS c ON a.customer_id = c.id
+WHERE a.notified_at IS NULL
+ORDER BY a.created_at, a.id;
+
+-- name: CreateAlertDelivery :exec
+-- CreateAlertDelivery records that the notifier delivered the alert. It does nothing if the delivery was already recorded.
+INSERT INTO alert_delivery (alert_id, notifier)
+VALUES ($1, $2)
+ON CONFLICT (alert_id, notifier) DO NOTHING;
+
+-- name: MarkAlertNotified :exec
+UPDATE alert
+SET notified_at = $2
+WHERE id = $1;
@@ -26,3 +26,50 @@ INSERT INTO cf_org (id)
 SELECT DISTINCT id
 FROM UNNEST(sqlc.arg(ids)::uuid[]) AS id
 ON CONFLICT DO NOTHING;
+
+-- name: ListCFOrgsByCustomer :many
+SELECT * FROM cf_org
+WHERE customer_id = $1
+ORDER BY name, id;
+
+-- name: SetCFOrgCustomer :one
+-- SetCFOrgCustomer assigns the CF org to a customer, or unassigns it if customer_id is null. The org is created if it does not exist yet, so orgs can be assigned before their usage is first recorded.
+INSERT INTO cf_org (id, customer_id)
+VALUES (sqlc.arg(id), sqlc.narg(customer_id))
+ON CONFLICT (id) DO UPDATE
+SET customer_id = excluded.customer_id
+RETURNING *;
+
+-- name: ListOrphanCFOrgs :many
+-- ListOrphanCFOrgs lists CF orgs that are not assigned to a customer but have measurements, with the number of measurements and when the most recent was taken. Usage in these orgs cannot be billed until they are assigned.
+SELECT
+  sqlc.embed(o),
+  COUNT(*)::bigint AS measurement_count,
+  MAX(rd.created_at_utc)::timestamptz AS last_measured_at
+FROM cf_org AS o
+INNER JOIN resource AS r ON o.id = r.cf_org_id
+INNER JOIN measurement AS m ON r.meter = m.meter AND r.natural_id = m.resource_natural_id
+INNER JOIN reading AS rd ON m.reading_id = rd.id
+WHERE o.customer_id IS NULL
+GROUP BY o.id
+ORDER BY last_measured_at DESC, o.id;
+
+-- name: SyncCFOrg :one
+-- SyncCFOrg creates or updates a CF org with the name and metadata read from Cloud Foundry. The org's customer is not changed.
+INSERT INTO cf_org (id, name, suspended, labels, annotations, synced_at)
+VALUES (
+  sqlc.arg(id),
+  sqlc.arg(name),
+  sqlc.arg(suspended),
+  sqlc.arg(labels),
+  sqlc.arg(annotations),
+  sqlc.arg(synced_at)
+)
+ON CONFLICT (id) DO UPDATE
+SET
+  name = excluded.name,
+  suspended = excluded.suspended,
+  labels = excluded.labels,
+  annotations = excluded.annotations,
+  synced_at = excluded.synced_at
+RETURNING *;
@@ -10,10 +10,14 @@ WHERE name ~* $1;
 SELECT * FROM customer
 ORDER BY name;
 
--- name: UpdateCustomer :exec
+-- name: UpdateCustomer :one
+-- UpdateCustomer changes the customer's name and time zone. Null arguments leave their columns unchanged.
 UPDATE customer
-  set name = $2
-WHERE id = $1;
+SET
+  name = coalesce(sqlc.narg(name), name),
+  time_zone = coalesce(sqlc.narg(time_zone), time_zone)
+WHERE id = sqlc.arg(id)
+RETURNING *;
 
 -- name: DeleteCustomer :exec
 DELETE FROM customer
@@ -39,3 +43,16 @@ accts AS (
 )
 SELECT id
 FROM cust;
+
+-- name: ListCustomersByCFOrgIDs :many
+-- ListCustomersByCFOrgIDs lists the customers that own any of the given CF orgs.
+SELECT c.*
+FROM customer AS c
+WHERE EXISTS (
+  SELECT 1
+  FROM cf_org AS o
+  WHERE
+    o.customer_id = c.id
+    AND o.id = ANY(sqlc.arg(cf_org_ids)::uuid [])
+)
+ORDER BY c.name;
@@ -0,0 +1,58 @@
+-- name: ListResourceCosts :many
+-- ListResourceCosts sums the usage and cost of each of a customer's resources over each hour or day (granularity) of the readings taken in [since, until). Usage in months that have not been closed is not priced yet, so its cost is estimated from the price that was valid when it was read, which is the price it gets when its month is closed. billed_microcredits is only the cost of priced measurements. Estimated is true if any of the usage was in backfilled readings. If cf_org_ids is not null, only resources in those CF orgs are included.
+SELECT
+  date_trunc(sqlc.arg(granularity)::text, rd.created_at_utc, 'UTC')::timestamptz AS charge_period_start,
+  c.name AS customer_name,
+  o.id AS cf_org_id,
+  coalesce(o.name, o.id::text)::text AS cf_org_name,
+  r.meter,
+  r.natural_id AS resource_natural_id,
+  r.kind_natural_id,
+  coalesce(k.name, nullif(r.kind_natural_id, ''), r.meter)::text AS kind_name,
+  k.accrues,
+  coalesce(rn.path::text, '')::text AS path,
+  coalesce(p.id, 0)::int AS price_id,
+  coalesce(p.unit_of_measure, k.unit_of_measure, '')::text AS unit_of_measure,
+  coalesce(p.microcredits_per_unit, 0)::bigint AS microcredits_per_unit,
+  coalesce(p.unit, 1)::bigint AS unit,
+  sum(
+    CASE
+      WHEN k.accrues THEN m.value * rd.interval_seconds / 3600
+      ELSE m.value
+    END
+  )::float8 AS quantity,
+  coalesce(sum(m.amount_microcredits), 0)::bigint AS billed_microcredits,
+  coalesce(sum(
+    coalesce(
+      m.amount_microcredits,
+      CASE
+        -- Accruing kinds are priced per unit-hour.
+        WHEN k.accrues THEN floor(p.microcredits_per_unit * m.value * rd.interval_seconds / (p.unit * 3600))
+        ELSE p.microcredits_per_unit * m.value / p.unit
+      END
+    )
+  ), 0)::bigint AS effective_microcredits,
+  bool_or(rd.provenance <> 'measured')::bool AS estimated
+FROM reading_intervals(sqlc.arg(since)::timestamptz, sqlc.arg(until)::timestamptz) AS rd
+JOIN measurement AS m
+  ON rd.reading_id = m.reading_id AND rd.meter = m.meter
+JOIN resource AS r
+  ON m.meter = r.meter AND m.resource_natural_id = r.natural_id
+JOIN resource_kind AS k
+  ON r.meter = k.meter AND r.kind_natural_id = k.natural_id
+JOIN cf_org AS o
+  ON r.cf_org_id = o.id
+JOIN customer AS c
+  ON o.customer_id = c.id
+LEFT JOIN price AS p
+  ON
+    r.meter = p.meter
+    AND r.kind_natural_id = p.kind_natural_id
+    AND p.valid_during @> rd.created_at_utc
+LEFT JOIN resource_node AS rn
+  ON rn.customer_id = c.id AND rn.resource_natural_id = r.natural_id
+WHERE
+  c.id = sqlc.arg(customer_id)
+  AND (sqlc.narg(cf_org_ids)::uuid [] IS NULL OR o.id = ANY(sqlc.narg(cf_org_ids)::uuid []))
+GROUP BY charge_period_start, c.name, o.id, r.meter, r.natural_id, k.name, k.accrues, k.unit_of_measure, rn.path, p.id
+ORDER BY charge_period_start, r.meter, r.natural_id, p.id;
@@ -0,0 +1,45 @@
+-- name: CreateIAA :one
+INSERT INTO iaa (
+  customer_id,
+  number,
+  amount_microcredits,
+  pop_start,
+  pop_end,
+  rollover
+) VALUES (
+  $1, $2, $3, $4, $5, $6
+)
+RETURNING *;
+
+-- name: GetIAA :one
+SELECT * FROM iaa
+WHERE id = $1 LIMIT 1;
+
+-- name: GetIAAForUpdate :one
+SELECT * FROM iaa
+WHERE id = $1 LIMIT 1
+FOR UPDATE;
+
+-- name: ListIAAs :many
+-- ListIAAs lists agreements for the customer, or all agreements if customer_id is null.
+SELECT * FROM iaa
+WHERE
+  sqlc.narg(customer_id)::uuid IS NULL
+  OR customer_id = sqlc.narg(customer_id)::uuid
+ORDER BY customer_id, pop_start, id;
+
+-- name: UpdateIAA :one
+UPDATE iaa
+SET
+  number = $2,
+  amount_microcredits = $3,
+  pop_start = $4,
+  pop_end = $5,
+  rollover = $6
+WHERE id = $1
+RETURNING *;
+
+-- name: PostIAAPop :many
+-- PostIAAPop adds credits to customer credit pools for agreements whose Period of Performance has started and expires unused credits for agreements whose PoP has ended, as of as_of. Returns the IDs of the transactions created.
+SELECT transaction_id
+FROM POST_IAA_POP($1);
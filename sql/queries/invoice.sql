@@ -0,0 +1,73 @@
+-- name: ListUninvoicedUsagePostIDs :many
+-- ListUninvoicedUsagePostIDs lists the usage_post transactions that occurred at period_end, the end of a posting period, have not been reversed, and have no invoice.
+SELECT t.id
+FROM transaction AS t
+WHERE
+  t.type = 'usage_post'
+  AND t.occurred_at = sqlc.arg(period_end)
+  AND NOT EXISTS (
+    SELECT 1
+    FROM transaction AS rev
+    WHERE
+      rev.original_transaction_id = t.id
+      AND rev.type = 'reversal'
+  )
+  AND NOT EXISTS (
+    SELECT 1
+    FROM invoice AS i
+    WHERE i.transaction_id = t.id
+  )
+ORDER BY t.id;
+
+-- name: CreateInvoice :one
+-- CreateInvoice creates the invoice for a usage_post transaction, with the next invoice number, and returns its ID.
+SELECT CREATE_INVOICE(sqlc.arg(transaction_id)::int)::int AS id;
+
+-- name: GetInvoice :one
+-- GetInvoice returns an invoice by ID. Reversed is true if its transaction has been reversed, in which case the invoice is void.
+SELECT
+  sqlc.embed(i),
+  EXISTS (
+    SELECT 1
+    FROM transaction AS rev
+    WHERE
+      rev.original_transaction_id = i.transaction_id
+      AND rev.type = 'reversal'
+  ) AS reversed
+FROM invoice AS i
+WHERE i.id = $1;
+
+-- name: GetInvoiceByNumber :one
+-- GetInvoiceByNumber returns an invoice by number. See GetInvoice.
+SELECT
+  sqlc.embed(i),
+  EXISTS (
+    SELECT 1
+    FROM transaction AS rev
+    WHERE
+      rev.original_transaction_id = i.transaction_id
+      AND rev.type = 'reversal'
+  ) AS reversed
+FROM invoice AS i
+WHERE i.number = $1;
+
+-- name: ListInvoices :many
+-- ListInvoices lists invoices, most recent first, optionally filtered by customer. See GetInvoice.
+SELECT
+  sqlc.embed(i),
+  EXISTS (
+    SELECT 1
+    FROM transaction AS rev
+    WHERE
+      rev.original_transaction_id = i.transaction_id
+      AND rev.type = 'reversal'
+  ) AS reversed
+FROM invoice AS i
+WHERE sqlc.narg(customer_id)::uuid IS NULL OR i.customer_id = sqlc.narg(customer_id)
+ORDER BY i.number DESC
+LIMIT sqlc.arg(page_size) OFFSET sqlc.arg(page_offset);
+
+-- name: ListInvoiceLines :many
+SELECT * FROM invoice_line
+WHERE invoice_id = $1
+ORDER BY line;
@@ -0,0 +1,50 @@
+-- name: BulkCreateMeasurementAnomalies :exec
+-- BulkCreateMeasurementAnomalies records anomalies in bulk. Errors are joined with newlines, because sqlc cannot unnest a two-dimensional array. If an anomaly already exists for the Reading and resource, that input item is ignored.
+INSERT INTO measurement_anomaly (
+	reading_id,
+	meter,
+	resource_natural_id,
+	kind_natural_id,
+	cf_org_id,
+	customer_id,
+	value,
+	errors,
+	recorded
+) SELECT reading_id, meter, resource_natural_id, kind_natural_id, cf_org_id, customer_id, value, string_to_array(errors, E'\n'), recorded FROM
+	UNNEST(
+		sqlc.arg(reading_id)::int[],
+		sqlc.arg(meter)::text[],
+		sqlc.arg(resource_natural_id)::text[],
+		sqlc.arg(kind_natural_id)::text[],
+		sqlc.arg(cf_org_id)::uuid[],
+		sqlc.arg(customer_id)::uuid[],
+		sqlc.arg(value)::int[],
+		sqlc.arg(errors)::text[],
+		sqlc.arg(recorded)::boolean[]
+	) AS a (reading_id, meter, resource_natural_id, kind_natural_id, cf_org_id, customer_id, value, errors, recorded)
+ON CONFLICT DO NOTHING;
+
+-- name: ListMeasurementAnomalies :many
+-- ListMeasurementAnomalies lists anomalies, most recent Reading first, optionally filtered by Reading and meter.
+SELECT * FROM measurement_anomaly
+WHERE (sqlc.narg(reading_id)::int IS NULL OR reading_id = sqlc.narg(reading_id))
+	AND (sqlc.narg(meter)::text IS NULL OR meter = sqlc.narg(meter))
+ORDER BY reading_id DESC, meter, resource_natural_id
+LIMIT sqlc.arg(page_size) OFFSET sqlc.arg(page_offset);
+
+-- name: SummarizeMeasurementAnomalies :many
+-- SummarizeMeasurementAnomalies counts the anomalies of each meter in each Reading taken in [since, until). Orphaned anomalies are those whose usage could not be attributed to a CF org.
+SELECT
+	a.reading_id,
+	r.created_at_utc AS reading_created_at,
+	a.meter,
+	count(*) AS anomalies,
+	sum(a.value)::bigint AS value,
+	count(*) FILTER (WHERE a.cf_org_id IS NULL) AS orphaned,
+	coalesce(sum(a.value) FILTER (WHERE a.cf_org_id IS NULL), 0)::bigint AS orphaned_value
+FROM measurement_anomaly a
+JOIN reading r ON r.id = a.reading_id
+WHERE r.created_at_utc >= sqlc.arg(since)::timestamptz
+	AND r.created_at_utc < sqlc.arg(until)::timestamptz
+GROUP BY a.reading_id, r.created_at_utc, a.meter
+ORDER BY r.created_at_utc DESC, a.meter;
@@ -2,3 +2,47 @@
 INSERT INTO price (id, meter, kind_natural_id, unit_of_measure, microcredits_per_unit, unit, valid_during)
 VALUES ($1, $2, $3, $4, $5, $6, $7)
 RETURNING *;
+
+-- name: ListPrices :many
+-- ListPrices lists prices, optionally only those for one meter or resource kind, ordered by resource kind and then by when each price takes effect.
+SELECT *
+FROM price
+WHERE (sqlc.narg(meter)::text IS NULL OR meter = sqlc.narg(meter))
+  AND (sqlc.narg(kind_natural_id)::text IS NULL OR kind_natural_id = sqlc.narg(kind_natural_id))
+ORDER BY meter, kind_natural_id, lower(valid_during) NULLS FIRST;
+
+-- name: GetPrice :one
+SELECT *
+FROM price
+WHERE id = $1;
+
+-- name: ClosePrice :one
+-- ClosePrice ends the price for the resource kind that is valid at the start of effective_date in business time (America/New_York), so a new price can take effect then. It returns pgx.ErrNoRows if no price is valid then, or if the valid price takes effect at the same time.
+UPDATE price
+SET valid_during = tstzrange(lower(valid_during), sqlc.arg(effective_date)::date::timestamp AT TIME ZONE 'America/New_York')
+WHERE meter = sqlc.arg(meter)
+  AND kind_natural_id = sqlc.arg(kind_natural_id)
+  AND valid_during @> (sqlc.arg(effective_date)::date::timestamp AT TIME ZONE 'America/New_York')
+  AND (lower_inf(valid_during) OR lower(valid_during) < sqlc.arg(effective_date)::date::timestamp AT TIME ZONE 'America/New_York')
+RETURNING *;
+
+-- name: CreatePrice :one
+-- CreatePrice creates a price for the resource kind that is valid from the start of effective_date in business time (America/New_York) until a new price is published.
+INSERT INTO price (meter, kind_natural_id, unit_of_measure, microcredits_per_unit, unit, valid_during)
+VALUES (
+  sqlc.arg(meter),
+  sqlc.arg(kind_natural_id),
+  sqlc.arg(unit_of_measure),
+  sqlc.arg(microcredits_per_unit),
+  sqlc.arg(unit),
+  tstzrange(sqlc.arg(effective_date)::date::timestamp AT TIME ZONE 'America/New_York', NULL)
+)
+RETURNING *;
+
+-- name: CountPricedMeasurements :one
+-- CountPricedMeasurements counts measurements priced with the price whose readings were taken at or after the start of effective_date in business time (America/New_York).
+SELECT COUNT(*)::bigint AS measurement_count
+FROM measurement AS m
+INNER JOIN reading AS rd ON m.reading_id = rd.id
+WHERE m.price_id = sqlc.arg(price_id)
+  AND rd.created_at_utc >= sqlc.arg(effective_date)::date::timestamp AT TIME ZONE 'America/New_York';
@@ -24,3 +24,140 @@ INSERT INTO reading (
 	$1, $2, $3
 )
 RETURNING *;
+
+-- name: GetLatestReadingTime :one
+-- GetLatestReadingTime returns the time of the most recent measured Reading in which the meter succeeded; see reading_succeeded. Manual one-off Readings only measure a single app, so they are ignored, as are backfilled Readings. It returns [pgx.ErrNoRows] if no such Readings exist.
+SELECT r.created_at_utc FROM reading r
+WHERE r.provenance = 'measured'
+AND reading_succeeded(r, sqlc.arg(meter)::text)
+ORDER BY r.created_at DESC
+LIMIT 1;
+
+-- name: BulkCreateReadingMeterStatuses :exec
+-- BulkCreateReadingMeterStatuses records the result of each meter in a Reading. Empty errors are stored as null. If a status already exists for the Reading and meter, that input item is ignored.
+INSERT INTO reading_meter_status (
+	reading_id,
+	meter,
+	started_at,
+	duration_ms,
+	succeeded,
+	error,
+	measurement_count
+) SELECT reading_id, meter, started_at, duration_ms, succeeded, nullif(error, ''), measurement_count FROM
+	UNNEST(
+		sqlc.arg(reading_id)::int[],
+		sqlc.arg(meter)::text[],
+		sqlc.arg(started_at)::timestamptz[],
+		sqlc.arg(duration_ms)::int[],
+		sqlc.arg(succeeded)::boolean[],
+		sqlc.arg(error)::text[],
+		sqlc.arg(measurement_count)::int[]
+	) AS s (reading_id, meter, started_at, duration_ms, succeeded, error, measurement_count)
+ON CONFLICT DO NOTHING;
+
+-- name: ListReadingMeterStatuses :many
+-- ListReadingMeterStatuses returns the status of each meter in a Reading.
+SELECT * FROM reading_meter_status
+WHERE reading_id = $1
+ORDER BY meter;
+
+-- name: ListReadingGaps :many
+-- ListReadingGaps returns the start of each hour in [since, until) in which no Reading was taken. Hours are in UTC, like reading_hourly_uq.
+SELECT h.hour::timestamptz AS hour
+FROM generate_series(
+	date_trunc('hour', sqlc.arg(since)::timestamptz AT TIME ZONE 'UTC') AT TIME ZONE 'UTC',
+	sqlc.arg(until)::timestamptz - interval '1 hour',
+	interval '1 hour'
+) AS h (hour)
+WHERE NOT EXISTS (
+	SELECT 1 FROM reading r
+	WHERE r.created_at_utc >= h.hour
+		AND r.created_at_utc < h.hour + interval '1 hour'
+)
+ORDER BY h.hour;
+
+-- name: GetReadingBefore :one
+-- GetReadingBefore returns the latest Reading taken before the given time. It returns [pgx.ErrNoRows] if there is none.
+SELECT * FROM reading
+WHERE created_at_utc < sqlc.arg(before)::timestamptz
+ORDER BY created_at_utc DESC
+LIMIT 1;
+
+-- name: GetReadingAfter :one
+-- GetReadingAfter returns the earliest Reading taken at or after the given time. It returns [pgx.ErrNoRows] if there is none.
+SELECT * FROM reading
+WHERE created_at_utc >= sqlc.arg(after)::timestamptz
+ORDER BY created_at_utc
+LIMIT 1;
+
+-- name: CountPricedReadingMeasurements :one
+-- CountPricedReadingMeasurements returns the number of the Reading's measurements that have been priced.
+SELECT count(*) FROM measurement
+WHERE reading_id = $1 AND amount_microcredits IS NOT NULL;
+
+-- name: CreateBackfillReading :one
+-- CreateBackfillReading creates a Reading for an hour in which no Reading was taken, with the given provenance. It returns [pgx.ErrNoRows] if a Reading already exists for the hour.
+INSERT INTO reading (
+	created_at, periodic, provenance
+) VALUES (
+	$1, false, $2
+)
+ON CONFLICT (date_trunc('hour', created_at))
+DO NOTHING
+RETURNING *;
+
+-- name: SetReadingProvenance :exec
+UPDATE reading SET provenance = $2 WHERE id = $1;
+
+-- name: InterpolateMeasurements :execrows
+-- InterpolateMeasurements estimates measurements of accruing resource kinds for a backfilled Reading, by linear interpolation between the Readings before and after it. fraction is how far the backfilled Reading is from the previous one, between 0 and 1. Only resources measured in both adjacent Readings are estimated. Existing measurements, like reconstructed ones, are not replaced. Other kinds are not estimated, because their meters measure usage since their last measured Reading.
+INSERT INTO measurement (reading_id, meter, resource_natural_id, value)
+SELECT
+	sqlc.arg(reading_id)::int,
+	prev.meter,
+	prev.resource_natural_id,
+	round(prev.value + (next.value - prev.value) * sqlc.arg(fraction)::float8)::int
+FROM measurement prev
+JOIN measurement next
+	ON next.meter = prev.meter AND next.resource_natural_id = prev.resource_natural_id
+JOIN resource r
+	ON r.meter = prev.meter AND r.natural_id = prev.resource_natural_id
+JOIN resource_kind k
+	ON k.meter = r.meter AND k.natural_id = r.kind_natural_id
+WHERE prev.reading_id = sqlc.arg(prev_reading_id)::int
+	AND next.reading_id = sqlc.arg(next_reading_id)::int
+	AND k.accrues
+ON CONFLICT DO NOTHING;
+
+-- name: CanReconstructAppMemory :one
+-- CanReconstructAppMemory returns true if the usage event meter has processed app usage events past the given time and has intervals that started at or before it, so the memory allocated to app processes at that time can be reconstructed.
+SELECT
+	EXISTS (
+		SELECT 1 FROM usage_event_checkpoint
+		WHERE meter = 'cfusageevents' AND source = 'app' AND last_event_at >= sqlc.arg(at)::timestamptz
+	)
+	AND EXISTS (
+		SELECT 1 FROM usage_event_interval
+		WHERE meter = 'cfusageevents' AND started_at <= sqlc.arg(at)::timestamptz
+	);
+
+-- name: ReconstructAppMemoryMeasurements :execrows
+-- ReconstructAppMemoryMeasurements creates measurements of the cfapps meter's memory kind for a backfilled Reading from the intervals recorded by the usage event meter. The memory of each instance of a process is its share of the value of the process's interval that was open at the given time. Only instances already known to the cfapps meter are measured.
+INSERT INTO measurement (reading_id, meter, resource_natural_id, value)
+SELECT DISTINCT ON (r.natural_id)
+	sqlc.arg(reading_id)::int,
+	r.meter,
+	r.natural_id,
+	iv.value / iv.instances
+FROM usage_event_interval iv
+CROSS JOIN LATERAL generate_series(0, iv.instances - 1) AS i (index)
+JOIN resource r
+	ON r.meter = 'cfapps'
+	AND r.kind_natural_id = 'memory'
+	AND r.natural_id = replace(iv.resource_natural_id, ':cfusageevents', ':' || i.index || ':memory')
+WHERE iv.meter = 'cfusageevents'
+	AND iv.kind_natural_id = 'memory'
+	AND iv.started_at <= sqlc.arg(at)::timestamptz
+	AND (iv.stopped_at IS NULL OR iv.stopped_at > sqlc.arg(at)::timestamptz)
+ORDER BY r.natural_id, iv.started_at DESC
+ON CONFLICT DO NOTHING;
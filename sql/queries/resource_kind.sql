@@ -29,3 +29,47 @@ FROM
     sqlc.arg(natural_ids)::text[]
   ) AS r(meter, natural_id)
 ON CONFLICT (meter, natural_id) DO NOTHING;
+
+-- name: SyncResourceKind :one
+-- SyncResourceKind creates or updates a kind with information from the catalog of the system it is read from. Kinds that were deprecated and have returned to the catalog are no longer deprecated.
+INSERT INTO resource_kind (meter, natural_id, name, broker, active, deprecated_at, synced_at)
+VALUES (sqlc.arg(meter), sqlc.arg(natural_id), sqlc.arg(name), sqlc.arg(broker), sqlc.arg(active), NULL, sqlc.arg(synced_at))
+ON CONFLICT (meter, natural_id) DO UPDATE
+SET
+  name = excluded.name,
+  broker = excluded.broker,
+  active = excluded.active,
+  deprecated_at = NULL,
+  synced_at = excluded.synced_at
+RETURNING *;
+
+-- name: DeprecateResourceKinds :many
+-- DeprecateResourceKinds marks kinds of the meter whose natural IDs are not in natural_ids as deprecated and inactive, unless they are already deprecated. Use it after syncing every kind in a meter's catalog.
+UPDATE resource_kind
+SET
+  active = false,
+  deprecated_at = sqlc.arg(deprecated_at)
+WHERE meter = sqlc.arg(meter)
+  AND NOT (natural_id = ANY(sqlc.arg(natural_ids)::text[]))
+  AND deprecated_at IS NULL
+RETURNING *;
+
+-- name: ListUnpricedResourceKinds :many
+-- ListUnpricedResourceKinds lists kinds that have measurements taken when no price for the kind was valid, with the number of such measurements and when the most recent was taken. These measurements will not be billed.
+SELECT
+  sqlc.embed(k),
+  COUNT(*)::bigint AS measurement_count,
+  MAX(rd.created_at_utc)::timestamptz AS last_measured_at
+FROM resource_kind AS k
+INNER JOIN resource AS r ON k.meter = r.meter AND k.natural_id = r.kind_natural_id
+INNER JOIN measurement AS m ON r.meter = m.meter AND r.natural_id = m.resource_natural_id
+INNER JOIN reading AS rd ON m.reading_id = rd.id
+WHERE NOT EXISTS (
+  SELECT 1
+  FROM price AS p
+  WHERE p.meter = k.meter
+    AND p.kind_natural_id = k.natural_id
+    AND p.valid_during @> rd.created_at_utc
+)
+GROUP BY k.meter, k.natural_id
+ORDER BY last_measured_at DESC, k.meter, k.natural_id;
@@ -20,25 +20,37 @@ select * from resource_node
 where customer_id = $1 and path ~ sqlc.arg(path)::lquery;
 
 -- name: GetUsageByPath :many
+-- GetUsageByPath sums the usage of a customer's nodes that match path, rolled up by period, org, generalized space, space, and resource. Readings are included from since until until. If either is null, it is the start of the period after (or before) periods from now. Periods begin in time_zone, or the customer's time zone if it is null. Costs are in US dollars at usd_per_credit. If cf_org_ids is not null, only usage of resources in those CF orgs is included.
 with
-  reads (id, created_at) as (
+  bounds as (
     select
-      id,
-      created_at
-    from reading
-    where created_at >= date_trunc(
-        @period,
-        current_date + (@after::int || ' ' || @period)::interval
-      )
-      and created_at < date_trunc(
-        @period,
-        current_date + (@before::int || ' ' || @period)::interval
-      )
-    order by created_at desc
+      coalesce(sqlc.narg(time_zone)::text, c.time_zone) as time_zone,
+      coalesce(
+        sqlc.narg(since)::timestamptz,
+        period_start(@period::text, now(), coalesce(sqlc.narg(time_zone)::text, c.time_zone), @after::int)
+      ) as since,
+      coalesce(
+        sqlc.narg(until)::timestamptz,
+        period_start(@period::text, now(), coalesce(sqlc.narg(time_zone)::text, c.time_zone), @before::int)
+      ) as until
+    from customer as c
+    where c.id = @customer_id
+  ),
+
+  reads (id, created_at, time_zone) as (
+    select
+      r.id,
+      r.created_at_utc,
+      b.time_zone
+    from reading as r
+      cross join bounds as b
+    where
+      r.created_at_utc >= b.since
+      and r.created_at_utc < b.until
   )
 
 select
-  date_trunc(@period, r.created_at) as period,
+  period_start(@period::text, r.created_at, r.time_zone)::timestamptz as period,
   coalesce(subltree(rn.path, 2, 3)::text, '') as l1,
   coalesce(regexp_replace(
     subltree(rn.path, 3, 4)::text,
@@ -49,13 +61,15 @@ select
   coalesce(subpath(rn.path, 4)::text, '') as l4,
   sum(m.amount_microcredits) as total_microcredits,
   round(sum(m.amount_microcredits) * 1e-6, 3) as total_credits,
-  round(sum(m.amount_microcredits) * 1e-6 * 50, 2) as total_cost
+  round(sum(m.amount_microcredits) * 1e-6 * @usd_per_credit::float8::numeric, 2) as total_cost
 from resource_node as rn
   inner join measurement as m on rn.resource_natural_id = m.resource_natural_id
   inner join reads as r on m.reading_id = r.id
+  inner join resource as res on m.meter = res.meter and m.resource_natural_id = res.natural_id
 where
   rn.customer_id = @customer_id
   and rn.path ~ @path::lquery
+  and (sqlc.narg(cf_org_ids)::uuid [] is null or res.cf_org_id = any(sqlc.narg(cf_org_ids)::uuid []))
 group by rollup (period, l1, l2, l3, l4)
 order by l1;
 
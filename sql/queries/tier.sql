@@ -6,13 +6,14 @@ WHERE id = $1 LIMIT 1;
 SELECT * FROM tier
 ORDER BY name;
 
--- name: UpdateTier :exec
+-- name: UpdateTier :one
 UPDATE tier
   set name = $2,
   tier_credits = $3
-  WHERE id = $1;
+  WHERE id = $1
+RETURNING *;
 
--- name: DeleteTier :exec
+-- name: DeleteTier :execrows
 DELETE FROM tier
 WHERE id = $1;
 
@@ -23,3 +24,15 @@ INSERT INTO tier (
   $1, $2
 )
 RETURNING *;
+
+-- name: SetCustomerTier :one
+-- SetCustomerTier assigns the customer to a tier, or removes them from their tier if tier_id is null.
+UPDATE customer
+SET tier_id = sqlc.narg(tier_id)
+WHERE id = sqlc.arg(id)
+RETURNING *;
+
+-- name: PostTierGrants :many
+-- PostTierGrants adds the credits included in each customer's tier to their credit pool for the month containing as_of. Returns the IDs of the transactions created.
+SELECT transaction_id
+FROM POST_TIER_GRANTS($1);
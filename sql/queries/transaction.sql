@@ -21,3 +21,130 @@ FROM
   entry
   LEFT JOIN account ON entry.account_id = account.id
   LEFT JOIN account_type ON account.type = account_type.id;
+
+-- name: ListUsagePostIDs :many
+-- ListUsagePostIDs lists the usage_post transactions that occurred at period_end, the end of a posting period, and have not been reversed.
+SELECT t.id
+FROM transaction AS t
+WHERE
+  t.type = 'usage_post'
+  AND t.occurred_at = sqlc.arg(period_end)
+  AND NOT EXISTS (
+    SELECT 1
+    FROM transaction AS rev
+    WHERE
+      rev.original_transaction_id = t.id
+      AND rev.type = 'reversal'
+  )
+ORDER BY t.id;
+
+-- name: ListTransactionSummaries :many
+-- ListTransactionSummaries returns one row for each transaction in ids, with the name of its customer and the total amount debited by its entries.
+SELECT
+  t.id,
+  t.occurred_at,
+  t.type,
+  t.description,
+  t.customer_id,
+  c.name AS customer_name,
+  t.original_transaction_id,
+  COALESCE(SUM(e.amount_microcredits) FILTER (WHERE e.direction = 1), 0)::bigint AS amount_microcredits
+FROM transaction AS t
+INNER JOIN customer AS c ON t.customer_id = c.id
+LEFT JOIN entry AS e ON t.id = e.transaction_id
+WHERE t.id = ANY(sqlc.arg(ids)::int [])
+GROUP BY t.id, c.name
+ORDER BY c.name, t.id;
+
+-- name: ReverseUsage :many
+-- ReverseUsage reverses the usage posted for the month preceding as_of by posting offsetting transactions. It returns the IDs of the reversal transactions.
+SELECT transaction_id
+FROM REVERSE_USAGE($1);
+
+-- name: ListTransactionCorrections :many
+-- ListTransactionCorrections lists the reversals and adjustments posted against a transaction.
+SELECT *
+FROM transaction
+WHERE original_transaction_id = $1
+ORDER BY id;
+
+-- name: ReverseTransaction :one
+-- ReverseTransaction posts a transaction that undoes the entries of transaction_id and returns the new transaction's ID.
+SELECT REVERSE_TRANSACTION(
+  sqlc.arg(transaction_id)::int,
+  sqlc.arg(occurred_at)::timestamptz,
+  sqlc.narg(description)::text
+)::int AS id;
+
+-- name: AdjustTransaction :one
+-- AdjustTransaction posts a transaction that changes the amount of transaction_id by amount_microcredits and returns the new transaction's ID.
+SELECT ADJUST_TRANSACTION(
+  sqlc.arg(transaction_id)::int,
+  sqlc.arg(amount_microcredits)::bigint,
+  sqlc.arg(occurred_at)::timestamptz,
+  sqlc.narg(description)::text
+)::int AS id;
+
+-- name: ListCustomerStatement :many
+-- ListCustomerStatement lists a customer's transactions that occurred from the start of start_date until the start of end_date in business time (America/New_York), oldest first, with the change each made to the customer's accounts and the running balance of each account after it. Balances follow the conventions of GetCustomerBalances. Running balances include transactions before start_date. A null bound is unbounded. TotalCount is the number of transactions in the range, regardless of page_size and page_offset.
+WITH changes AS (
+  SELECT
+    t.id,
+    t.occurred_at,
+    t.type,
+    t.description,
+    t.original_transaction_id,
+    COALESCE(SUM(e.amount_microcredits * e.direction) FILTER (WHERE at.name = 'credit_pool'), 0)::bigint AS credit_pool_change,
+    COALESCE(SUM(e.amount_microcredits * e.direction) FILTER (WHERE at.name = 'credits_used'), 0)::bigint AS credits_used_change,
+    COALESCE(-SUM(e.amount_microcredits * e.direction) FILTER (WHERE at.name = 'liabilities'), 0)::bigint AS liabilities_change
+  FROM transaction AS t
+  INNER JOIN entry AS e ON t.id = e.transaction_id
+  INNER JOIN account AS a ON e.account_id = a.id
+  INNER JOIN account_type AS at ON a.type = at.id
+  WHERE t.customer_id = sqlc.arg(customer_id)
+  GROUP BY t.id
+),
+
+running AS (
+  SELECT
+    c.id,
+    c.occurred_at,
+    c.type,
+    c.description,
+    c.original_transaction_id,
+    c.credit_pool_change,
+    c.credits_used_change,
+    c.liabilities_change,
+    (SUM(c.credit_pool_change) OVER w)::bigint AS credit_pool_balance,
+    (SUM(c.credits_used_change) OVER w)::bigint AS credits_used_balance,
+    (SUM(c.liabilities_change) OVER w)::bigint AS liabilities_balance
+  FROM changes AS c
+  WINDOW w AS (ORDER BY c.occurred_at, c.id)
+)
+
+SELECT
+  r.id,
+  r.occurred_at,
+  r.type,
+  r.description,
+  r.original_transaction_id,
+  r.credit_pool_change,
+  r.credits_used_change,
+  r.liabilities_change,
+  r.credit_pool_balance,
+  r.credits_used_balance,
+  r.liabilities_balance,
+  (COUNT(*) OVER ())::bigint AS total_count
+FROM running AS r
+WHERE
+  (
+    sqlc.narg(start_date)::date IS NULL
+    OR r.occurred_at >= sqlc.narg(start_date)::date::timestamp AT TIME ZONE 'America/New_York'
+  )
+  AND (
+    sqlc.narg(end_date)::date IS NULL
+    OR r.occurred_at < sqlc.narg(end_date)::date::timestamp AT TIME ZONE 'America/New_York'
+  )
+ORDER BY r.occurred_at, r.id
+LIMIT sqlc.arg(page_size)::int
+OFFSET sqlc.arg(page_offset)::int;
@@ -0,0 +1,45 @@
+-- name: GetUsageEventCheckpoint :one
+-- GetUsageEventCheckpoint returns the last event processed by the meter from the source. It returns [pgx.ErrNoRows] if the meter has not processed any events from the source.
+SELECT * FROM usage_event_checkpoint
+WHERE meter = $1 AND source = $2;
+
+-- name: UpsertUsageEventCheckpoint :exec
+INSERT INTO usage_event_checkpoint (meter, source, last_event_guid, last_event_at, updated_at)
+VALUES ($1, $2, $3, $4, now())
+ON CONFLICT (meter, source) DO UPDATE
+SET
+  last_event_guid = excluded.last_event_guid,
+  last_event_at = excluded.last_event_at,
+  updated_at = excluded.updated_at;
+
+-- name: OpenUsageEventInterval :exec
+-- OpenUsageEventInterval starts an interval for a resource. If the interval already exists, for instance because an event was processed twice, it is not changed.
+INSERT INTO usage_event_interval (
+  meter, resource_natural_id, kind_natural_id, value, instances, started_at, cf_org_id, space_guid, space_name, name
+) VALUES (
+  $1, $2, $3, $4, $5, $6, $7, $8, $9, $10
+)
+ON CONFLICT (meter, resource_natural_id, started_at) DO NOTHING;
+
+-- name: CloseUsageEventIntervals :exec
+-- CloseUsageEventIntervals stops the open intervals of a resource that started at or before stopped_at, except the interval that started at except_started_at, if given.
+UPDATE usage_event_interval
+SET stopped_at = sqlc.arg(stopped_at)
+WHERE meter = sqlc.arg(meter)
+  AND resource_natural_id = sqlc.arg(resource_natural_id)
+  AND stopped_at IS NULL
+  AND started_at <= sqlc.arg(stopped_at)
+  AND started_at IS DISTINCT FROM sqlc.narg(except_started_at);
+
+-- name: ListUsageEventIntervals :many
+-- ListUsageEventIntervals lists the meter's intervals that overlap the period [since, until).
+SELECT * FROM usage_event_interval
+WHERE meter = sqlc.arg(meter)
+  AND started_at < sqlc.arg(until)
+  AND (stopped_at IS NULL OR stopped_at > sqlc.arg(since))
+ORDER BY resource_natural_id, started_at;
+
+-- name: DeleteUsageEventIntervals :execrows
+-- DeleteUsageEventIntervals deletes the meter's intervals that stopped at or before the given time.
+DELETE FROM usage_event_interval
+WHERE meter = sqlc.arg(meter) AND stopped_at <= sqlc.arg(before);